@@ -0,0 +1,168 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// operator namespace 와 ClusterManager namespace 에서 기본 profile 로 사용하는 이름
+	DefaultAddonProfileName = "default"
+)
+
+// AddonSourceType 은 app-of-apps helm chart 를 가져오는 repository 의 종류이다.
+// +kubebuilder:validation:Enum=git;helm;oci
+type AddonSourceType string
+
+const (
+	// git repository 의 path 에 있는 helm chart
+	AddonSourceTypeGit = AddonSourceType("git")
+	// helm repository 의 chart
+	AddonSourceTypeHelm = AddonSourceType("helm")
+	// OCI registry 의 helm chart
+	AddonSourceTypeOCI = AddonSourceType("oci")
+)
+
+// ClusterAddonProfileSpec defines the desired state of ClusterAddonProfile
+type ClusterAddonProfileSpec struct {
+	// Type of the repository serving the app-of-apps helm chart (git, helm, oci). Defaults to git
+	SourceType AddonSourceType `json:"sourceType,omitempty"`
+	// Git repository, helm repository or OCI registry of the app-of-apps helm chart
+	RepoURL string `json:"repoURL,omitempty"`
+	// Target revision (branch, tag or commit) of the git repository, or version of the helm chart
+	TargetRevision string `json:"targetRevision,omitempty"`
+	// Path of the app-of-apps helm chart in the git repository. Used for git source only
+	Path string `json:"path,omitempty"`
+	// Name of the app-of-apps helm chart. Used for helm and oci sources only
+	Chart string `json:"chart,omitempty"`
+	// Secret having the credentials of the repository (username, password, sshPrivateKey, tlsClientCertData, tlsClientCertKey).
+	// The secret must be in the namespace of the profile
+	CredentialsSecretRef *coreV1.SecretReference `json:"credentialsSecretRef,omitempty"`
+	// Helm value files of the app-of-apps helm chart
+	ValueFiles []string `json:"valueFiles,omitempty"`
+	// Helm values under global (ex. domain, privateRegistry)
+	Global map[string]string `json:"global,omitempty"`
+	// Enable flags and helm values of the modules (ex. efk, grafanaOperator, hyperregistry)
+	Modules map[string]AddonModule `json:"modules,omitempty"`
+}
+
+// AddonModule defines the enable flag and helm values of a module
+type AddonModule struct {
+	// Whether the module is installed. If not set, the default of the helm chart is used
+	Enabled *bool `json:"enabled,omitempty"`
+	// Helm values under modules.<module> (ex. subdomain)
+	Values map[string]string `json:"values,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:path=clusteraddonprofiles,scope=Namespaced,shortName=cap
+// +kubebuilder:printcolumn:name="Repo",type="string",JSONPath=".spec.repoURL",description="git repository of app-of-apps"
+// +kubebuilder:printcolumn:name="Revision",type="string",JSONPath=".spec.targetRevision",description="target revision of app-of-apps"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+// ClusterAddonProfile is the Schema for the clusteraddonprofiles API
+// The profile named default in the operator namespace applies to all clusters,
+// the profile named default in a namespace applies to the clusters in the namespace,
+// and the profile named after a ClusterManager applies to the cluster only.
+// Later profiles override the former ones.
+type ClusterAddonProfile struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec ClusterAddonProfileSpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// ClusterAddonProfileList contains a list of ClusterAddonProfile
+type ClusterAddonProfileList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ClusterAddonProfile `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ClusterAddonProfile{}, &ClusterAddonProfileList{})
+}
+
+// Merge 는 override 에 지정된 값들로 profile 을 덮어쓴다.
+// map 은 key 별로, module 의 values 는 module 의 key 별로 덮어쓴다.
+// source type 이 바뀌는 경우에는 이전 source 의 path, chart, value file 들을 사용하지 않는다.
+func (in *ClusterAddonProfileSpec) Merge(override *ClusterAddonProfileSpec) {
+	if override.SourceType != "" && override.SourceType != in.GetSourceType() {
+		in.Path = ""
+		in.Chart = ""
+		in.ValueFiles = nil
+	}
+	if override.SourceType != "" {
+		in.SourceType = override.SourceType
+	}
+	if override.RepoURL != "" {
+		in.RepoURL = override.RepoURL
+	}
+	if override.TargetRevision != "" {
+		in.TargetRevision = override.TargetRevision
+	}
+	if override.Path != "" {
+		in.Path = override.Path
+	}
+	if override.Chart != "" {
+		in.Chart = override.Chart
+	}
+	if override.CredentialsSecretRef != nil {
+		ref := *override.CredentialsSecretRef
+		in.CredentialsSecretRef = &ref
+	}
+	if len(override.ValueFiles) != 0 {
+		in.ValueFiles = append([]string{}, override.ValueFiles...)
+	}
+
+	if len(override.Global) != 0 && in.Global == nil {
+		in.Global = map[string]string{}
+	}
+	for key, value := range override.Global {
+		in.Global[key] = value
+	}
+
+	if len(override.Modules) != 0 && in.Modules == nil {
+		in.Modules = map[string]AddonModule{}
+	}
+	for name, module := range override.Modules {
+		merged := in.Modules[name]
+		if module.Enabled != nil {
+			enabled := *module.Enabled
+			merged.Enabled = &enabled
+		}
+		if len(module.Values) != 0 {
+			values := map[string]string{}
+			for key, value := range merged.Values {
+				values[key] = value
+			}
+			for key, value := range module.Values {
+				values[key] = value
+			}
+			merged.Values = values
+		}
+		in.Modules[name] = merged
+	}
+}
+
+// GetSourceType 은 source type 을 반환한다. 지정하지 않은 경우 git 이다.
+func (in *ClusterAddonProfileSpec) GetSourceType() AddonSourceType {
+	if in.SourceType == "" {
+		return AddonSourceTypeGit
+	}
+	return in.SourceType
+}
@@ -53,6 +53,85 @@ type ClusterManagerSpec struct {
 	// KubernetesVersion string `json:"kubernetesVersion"`
 	// The owner of cluster
 	// Owner string `json:"owner"`
+	// +optional
+	// multicluster ingress 를 통해 single cluster 로 들어가는 요청에 적용할 정책
+	TrafficPolicy *TrafficPolicy `json:"trafficPolicy,omitempty"`
+	// +optional
+	// argocd 의 app-of-apps application 의 자동 sync 정책
+	ApplicationSyncPolicy *ApplicationSyncPolicy `json:"applicationSyncPolicy,omitempty"`
+	// +optional
+	// argocd 가 single cluster 에 접근할 때 사용하는 인증 방식
+	ArgoClusterAuth *ArgoClusterAuth `json:"argoClusterAuth,omitempty"`
+}
+
+// ArgoClusterAuthMode 는 argocd 가 single cluster 에 접근할 때 사용하는 인증 방식이다.
+// +kubebuilder:validation:Enum=auto;bearerToken;awsIAM;exec;clientCertificate
+type ArgoClusterAuthMode string
+
+const (
+	// kubeconfig 의 user 가 EKS 의 exec provider 를 사용하면 awsIAM 을, 그렇지 않으면 bearerToken 을 사용한다.
+	ArgoClusterAuthModeAuto = ArgoClusterAuthMode("auto")
+	// single cluster 의 argocd-manager service account 로 TokenRequest 를 통해 발급받은 token
+	ArgoClusterAuthModeBearerToken = ArgoClusterAuthMode("bearerToken")
+	// EKS 의 AWS IAM 인증
+	ArgoClusterAuthModeAWSIAM = ArgoClusterAuthMode("awsIAM")
+	// kubeconfig 의 user 에 있는 exec provider. operator 에 허용된 command 만 사용할 수 있다.
+	ArgoClusterAuthModeExec = ArgoClusterAuthMode("exec")
+	// kubeconfig 의 user 에 있는 client certificate
+	ArgoClusterAuthModeClientCertificate = ArgoClusterAuthMode("clientCertificate")
+)
+
+// ArgoClusterAuth 는 argocd 가 single cluster 에 접근할 때 사용하는 인증 방식이다.
+type ArgoClusterAuth struct {
+	// Authentication mode (auto, bearerToken, awsIAM, exec, clientCertificate). Defaults to auto
+	Mode ArgoClusterAuthMode `json:"mode,omitempty"`
+	// Name of the EKS cluster for awsIAM mode. If not set, it is taken from the exec args of the kubeconfig
+	AWSClusterName string `json:"awsClusterName,omitempty"`
+	// IAM role to assume for awsIAM mode. If not set, it is taken from the exec args of the kubeconfig
+	// only when the role is allowed by the operator (ARGO_CLUSTER_AWS_ROLE_ARNS)
+	AWSRoleARN string `json:"awsRoleARN,omitempty"`
+}
+
+// ApplicationSyncPolicy 는 app-of-apps application 의 자동 sync 정책이다.
+// 지정하지 않은 항목은 operator 의 전역 설정을 따른다.
+type ApplicationSyncPolicy struct {
+	// Whether argocd syncs the application automatically when the git repository changes
+	Automated *bool `json:"automated,omitempty"`
+	// Whether resources removed from the git repository are deleted during automated sync
+	Prune *bool `json:"prune,omitempty"`
+	// Whether resources modified in the cluster are reverted during automated sync
+	SelfHeal *bool `json:"selfHeal,omitempty"`
+}
+
+// TrafficPolicy 는 console 사용자들의 요청이 single cluster 에 몰리지 않도록 제한하는 정책이다.
+// 각 항목은 traefik middleware 로 만들어져 cluster 의 ingress 에 적용된다.
+type TrafficPolicy struct {
+	// +optional
+	// 사용자(token) 별 요청 수 제한
+	RateLimit *RateLimitPolicy `json:"rateLimit,omitempty"`
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	// 사용자(token) 별 동시에 처리중인 요청 수 제한
+	InFlightLimit int64 `json:"inFlightLimit,omitempty"`
+	// +optional
+	// 요청을 허용할 source ip 범위 (CIDR 또는 ip)
+	SourceRanges []string `json:"sourceRanges,omitempty"`
+	// +optional
+	// single cluster 로 전달할 때 추가할 request header
+	RequestHeaders map[string]string `json:"requestHeaders,omitempty"`
+}
+
+type RateLimitPolicy struct {
+	// +kubebuilder:validation:Minimum=1
+	// period 동안 허용하는 평균 요청 수
+	Average int64 `json:"average"`
+	// +optional
+	// 평균 요청 수를 계산하는 기준 시간 (ex. 1s, 1m). 기본값은 1s
+	Period string `json:"period,omitempty"`
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	// 순간적으로 허용하는 최대 요청 수
+	Burst int64 `json:"burst,omitempty"`
 }
 
 // ProviderAwsSpec defines
@@ -125,6 +204,16 @@ type ClusterManagerStatus struct {
 	AuthClientReady       bool                    `json:"authClientReady,omitempty"`
 	OpenSearchReady       bool                    `json:"openSearchReady,omitempty"`
 	ApplicationLink       string                  `json:"applicationLink,omitempty"`
+	// argocd 의 app-of-apps application 과 하위 application 들의 sync, health 상태
+	ArgoApplication *ArgoApplicationStatus `json:"argoApplication,omitempty"`
+	// cluster 의 argocd AppProject 와 app-of-apps application 설정의 동기화 상태
+	ArgoProject *ArgoProjectStatus `json:"argoProject,omitempty"`
+	// cluster 를 위해 HyperAuth 에 만든 리소스들의 동기화 상태
+	HyperAuth *HyperAuthStatus `json:"hyperAuth,omitempty"`
+	// reconcile 단계(phase)별 마지막 수행 결과
+	ReconcilePhases []ReconcilePhaseStatus `json:"reconcilePhases,omitempty"`
+	// reconcile 결과로 확인된 상태들 (ex. GatewayTLSVerified)
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
 	// UpgradeRequeueCount   int                     `json:"upgradeRequeueCount,omitempty"`
 
 	// will be deprecated
@@ -132,6 +221,75 @@ type ClusterManagerStatus struct {
 	// HyperregistryOidcReady bool                    `json:"hyperregistryOidcReady,omitempty"`
 }
 
+// ReconcilePhaseStatus 는 reconcile 단계 하나의 수행 결과를 나타낸다.
+// 불필요한 status update 를 막기 위해 State, Message, RequeueAfter 가 바뀐 경우에만 갱신된다.
+type ReconcilePhaseStatus struct {
+	Name               string      `json:"name"`
+	State              string      `json:"state"`
+	Message            string      `json:"message,omitempty"`
+	RequeueAfter       string      `json:"requeueAfter,omitempty"`
+	Duration           string      `json:"duration,omitempty"`
+	LastTransitionTime metav1.Time `json:"lastTransitionTime,omitempty"`
+}
+
+// ArgoApplicationStatus 는 app-of-apps application 의 sync, health 상태를 나타낸다.
+type ArgoApplicationStatus struct {
+	Name         string `json:"name,omitempty"`
+	SyncStatus   string `json:"syncStatus,omitempty"`
+	HealthStatus string `json:"healthStatus,omitempty"`
+	// sync 된 git revision
+	Revision string `json:"revision,omitempty"`
+	// 마지막 sync operation 의 결과 (Succeeded, Failed, Error, Running)
+	OperationPhase   string `json:"operationPhase,omitempty"`
+	OperationMessage string `json:"operationMessage,omitempty"`
+	// 마지막 sync operation 이 시작된 시간
+	OperationStartedTime *metav1.Time `json:"operationStartedTime,omitempty"`
+	// app-of-apps 가 만든 하위 application 의 수와, 그 중 sync 되고 healthy 한 application 의 수
+	Applications      int `json:"applications,omitempty"`
+	ReadyApplications int `json:"readyApplications,omitempty"`
+	// degraded, missing 상태이거나 sync 에 실패한 하위 application 목록
+	FailedApplications []FailedArgoApplication `json:"failedApplications,omitempty"`
+	LastTransitionTime metav1.Time             `json:"lastTransitionTime,omitempty"`
+}
+
+// ArgoProjectStatus 는 cluster member 와 설정을 argocd AppProject 에 마지막으로 반영한 상태를 나타낸다.
+type ArgoProjectStatus struct {
+	// 마지막으로 반영한 ClusterManager 와 operator 설정의 hash
+	// cluster member 는 hypercloud api-server 에서 조회하므로 포함하지 않고 주기적으로 다시 반영한다.
+	DesiredHash  string       `json:"desiredHash,omitempty"`
+	LastSyncTime *metav1.Time `json:"lastSyncTime,omitempty"`
+}
+
+// FailedArgoApplication 은 정상적으로 배포되지 않은 하위 application 을 나타낸다.
+type FailedArgoApplication struct {
+	Name         string `json:"name"`
+	SyncStatus   string `json:"syncStatus,omitempty"`
+	HealthStatus string `json:"healthStatus,omitempty"`
+	Message      string `json:"message,omitempty"`
+}
+
+// IsReady 는 app-of-apps 와 모든 하위 application 이 sync 되고 healthy 한지 반환한다.
+func (in *ArgoApplicationStatus) IsReady() bool {
+	return in != nil &&
+		in.SyncStatus == "Synced" &&
+		in.HealthStatus == "Healthy" &&
+		in.ReadyApplications == in.Applications &&
+		len(in.FailedApplications) == 0
+}
+
+// HyperAuthStatus 는 cluster 를 위해 HyperAuth 에 만든 리소스들의 동기화 상태를 나타낸다.
+type HyperAuthStatus struct {
+	// operator 가 관리하는 HyperAuth client 와 group 의 이름. desired state 에서 빠지면 삭제한다.
+	Clients []string `json:"clients,omitempty"`
+	Groups  []string `json:"groups,omitempty"`
+	// 마지막으로 동기화한 desired state 의 hash
+	DesiredHash  string       `json:"desiredHash,omitempty"`
+	LastSyncTime *metav1.Time `json:"lastSyncTime,omitempty"`
+	// 리소스를 만든 HyperAuth realm. realm 이 바뀌면 이전 realm 의 리소스를 삭제한다.
+	// 생성한 cluster 는 ServiceInstance 를 만들 때 api server 의 issuer 로 사용한 realm 을 기록하며 이후 바뀌지 않는다.
+	Realm string `json:"realm,omitempty"`
+}
+
 type ClusterManagerPhase string
 
 const (
@@ -199,10 +357,26 @@ const (
 	ClusterTypeCreated    = "created"
 	ClusterTypeRegistered = "registered"
 
+	// hub 에서 single cluster 의 gateway 로의 TLS 검증 결과
+	ConditionTypeGatewayTLSVerified = "GatewayTLSVerified"
+	// HyperAuth 의 client, role, group 등이 desired state 와 같은지 동기화한 결과
+	ConditionTypeHyperAuthSynced = "HyperAuthSynced"
+
 	AnnotationKeyClmApiserver = "clustermanager.cluster.tmax.io/apiserver"
 	AnnotationKeyClmGateway   = "clustermanager.cluster.tmax.io/gateway"
 	AnnotationKeyClmSuffix    = "clustermanager.cluster.tmax.io/suffix"
 	AnnotationKeyClmDomain    = "clustermanager.cluster.tmax.io/domain"
+	// gateway 를 통해 추가로 노출할 single cluster 의 api 이름 목록 (ex. "grafana,loki,alertmanager")
+	AnnotationKeyClmExposedPaths = "clustermanager.cluster.tmax.io/exposed-paths"
+	// 값이 있으면 app-of-apps application 을 한번 sync 하고 annotation 을 지운다.
+	AnnotationKeyClmSyncNow = "clustermanager.cluster.tmax.io/sync-now"
+	// 값을 바꾸면 HyperAuth client 들의 secret 을 새로 만든다. (ex. 요청한 시각)
+	// 처리한 값은 client secret 들을 보관하는 secret 의 같은 annotation 에 기록한다.
+	AnnotationKeyClmRotateHyperAuthSecrets = "clustermanager.cluster.tmax.io/rotate-hyperauth-secrets"
+	// ClusterManager 나 namespace 에 지정하면 operator 에 설정한 realm 대신 이 HyperAuth realm 을 사용한다.
+	// ClusterManager 에 지정한 realm 이 namespace 에 지정한 realm 보다 우선한다.
+	// 생성한 cluster 는 api server 의 oidc issuer 로 사용한 realm 을 바꿀 수 없으므로 처음 기록한 realm 을 계속 사용한다.
+	AnnotationKeyClmHyperAuthRealm = "clustermanager.cluster.tmax.io/hyperauth-realm"
 
 	LabelKeyClmName               = "clustermanager.cluster.tmax.io/clm-name"
 	LabelKeyClmNamespace          = "clustermanager.cluster.tmax.io/clm-namespace"
@@ -16,6 +16,9 @@ package v1alpha1
 
 import (
 	"errors"
+	"fmt"
+	"net"
+	"time"
 
 	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -54,8 +57,7 @@ var _ webhook.Validator = &ClusterManager{}
 func (r *ClusterManager) ValidateCreate() error {
 	ClusterManagerWebhookLogger.Info("validate create", "name", r.Name)
 
-	// TODO(user): fill in your validation logic upon object creation.
-	return nil
+	return r.Spec.TrafficPolicy.Validate()
 }
 
 // ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
@@ -68,6 +70,10 @@ func (r *ClusterManager) ValidateUpdate(old runtime.Object) error {
 		return errors.New("cannot modify clusterManager.Annotations.owner")
 	}
 
+	if err := r.Spec.TrafficPolicy.Validate(); err != nil {
+		return err
+	}
+
 	// if r.Status.Ready == false {
 	// 	if !reflect.DeepEqual(r.Status.Members, oldClusterClaim.Status.Members) {
 	// 		return errors.New("Cannot modify members when cluster status is not ready")
@@ -132,3 +138,29 @@ func (r *ClusterManager) ValidateDelete() error {
 
 	return nil
 }
+
+// Validate 는 traffic policy 의 값들이 traefik middleware 로 만들 수 있는 값인지 확인한다.
+func (p *TrafficPolicy) Validate() error {
+	if p == nil {
+		return nil
+	}
+
+	if p.RateLimit != nil {
+		if p.RateLimit.Average < 1 {
+			return errors.New("spec.trafficPolicy.rateLimit.average must be greater than 0")
+		}
+		if p.RateLimit.Period != "" {
+			if period, err := time.ParseDuration(p.RateLimit.Period); err != nil || period <= 0 {
+				return fmt.Errorf("spec.trafficPolicy.rateLimit.period [%s] is not a valid duration", p.RateLimit.Period)
+			}
+		}
+	}
+
+	for _, sourceRange := range p.SourceRanges {
+		if _, _, err := net.ParseCIDR(sourceRange); err != nil && net.ParseIP(sourceRange) == nil {
+			return fmt.Errorf("spec.trafficPolicy.sourceRanges [%s] is not a valid CIDR or ip", sourceRange)
+		}
+	}
+
+	return nil
+}
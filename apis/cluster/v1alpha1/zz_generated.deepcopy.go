@@ -21,15 +21,229 @@ package v1alpha1
 
 import (
 	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AddonModule) DeepCopyInto(out *AddonModule) {
+	*out = *in
+	if in.Enabled != nil {
+		in, out := &in.Enabled, &out.Enabled
+		*out = new(bool)
+		**out = **in
+	}
+	if in.Values != nil {
+		in, out := &in.Values, &out.Values
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AddonModule.
+func (in *AddonModule) DeepCopy() *AddonModule {
+	if in == nil {
+		return nil
+	}
+	out := new(AddonModule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ApplicationSyncPolicy) DeepCopyInto(out *ApplicationSyncPolicy) {
+	*out = *in
+	if in.Automated != nil {
+		in, out := &in.Automated, &out.Automated
+		*out = new(bool)
+		**out = **in
+	}
+	if in.Prune != nil {
+		in, out := &in.Prune, &out.Prune
+		*out = new(bool)
+		**out = **in
+	}
+	if in.SelfHeal != nil {
+		in, out := &in.SelfHeal, &out.SelfHeal
+		*out = new(bool)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApplicationSyncPolicy.
+func (in *ApplicationSyncPolicy) DeepCopy() *ApplicationSyncPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(ApplicationSyncPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ArgoApplicationStatus) DeepCopyInto(out *ArgoApplicationStatus) {
+	*out = *in
+	if in.OperationStartedTime != nil {
+		in, out := &in.OperationStartedTime, &out.OperationStartedTime
+		*out = (*in).DeepCopy()
+	}
+	if in.FailedApplications != nil {
+		in, out := &in.FailedApplications, &out.FailedApplications
+		*out = make([]FailedArgoApplication, len(*in))
+		copy(*out, *in)
+	}
+	in.LastTransitionTime.DeepCopyInto(&out.LastTransitionTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ArgoApplicationStatus.
+func (in *ArgoApplicationStatus) DeepCopy() *ArgoApplicationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ArgoApplicationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ArgoClusterAuth) DeepCopyInto(out *ArgoClusterAuth) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ArgoClusterAuth.
+func (in *ArgoClusterAuth) DeepCopy() *ArgoClusterAuth {
+	if in == nil {
+		return nil
+	}
+	out := new(ArgoClusterAuth)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ArgoProjectStatus) DeepCopyInto(out *ArgoProjectStatus) {
+	*out = *in
+	if in.LastSyncTime != nil {
+		in, out := &in.LastSyncTime, &out.LastSyncTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ArgoProjectStatus.
+func (in *ArgoProjectStatus) DeepCopy() *ArgoProjectStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ArgoProjectStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterAddonProfile) DeepCopyInto(out *ClusterAddonProfile) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterAddonProfile.
+func (in *ClusterAddonProfile) DeepCopy() *ClusterAddonProfile {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterAddonProfile)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ClusterAddonProfile) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterAddonProfileList) DeepCopyInto(out *ClusterAddonProfileList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ClusterAddonProfile, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterAddonProfileList.
+func (in *ClusterAddonProfileList) DeepCopy() *ClusterAddonProfileList {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterAddonProfileList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ClusterAddonProfileList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterAddonProfileSpec) DeepCopyInto(out *ClusterAddonProfileSpec) {
+	*out = *in
+	if in.CredentialsSecretRef != nil {
+		in, out := &in.CredentialsSecretRef, &out.CredentialsSecretRef
+		*out = new(v1.SecretReference)
+		**out = **in
+	}
+	if in.ValueFiles != nil {
+		in, out := &in.ValueFiles, &out.ValueFiles
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Global != nil {
+		in, out := &in.Global, &out.Global
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Modules != nil {
+		in, out := &in.Modules, &out.Modules
+		*out = make(map[string]AddonModule, len(*in))
+		for key, val := range *in {
+			(*out)[key] = *val.DeepCopy()
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterAddonProfileSpec.
+func (in *ClusterAddonProfileSpec) DeepCopy() *ClusterAddonProfileSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterAddonProfileSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ClusterManager) DeepCopyInto(out *ClusterManager) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	out.Spec = in.Spec
+	in.Spec.DeepCopyInto(&out.Spec)
 	in.Status.DeepCopyInto(&out.Status)
 	out.AwsSpec = in.AwsSpec
 	out.VsphereSpec = in.VsphereSpec
@@ -88,6 +302,21 @@ func (in *ClusterManagerList) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ClusterManagerSpec) DeepCopyInto(out *ClusterManagerSpec) {
 	*out = *in
+	if in.TrafficPolicy != nil {
+		in, out := &in.TrafficPolicy, &out.TrafficPolicy
+		*out = new(TrafficPolicy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ApplicationSyncPolicy != nil {
+		in, out := &in.ApplicationSyncPolicy, &out.ApplicationSyncPolicy
+		*out = new(ApplicationSyncPolicy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ArgoClusterAuth != nil {
+		in, out := &in.ArgoClusterAuth, &out.ArgoClusterAuth
+		*out = new(ArgoClusterAuth)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterManagerSpec.
@@ -108,6 +337,35 @@ func (in *ClusterManagerStatus) DeepCopyInto(out *ClusterManagerStatus) {
 		*out = make([]v1.NodeSystemInfo, len(*in))
 		copy(*out, *in)
 	}
+	if in.ArgoApplication != nil {
+		in, out := &in.ArgoApplication, &out.ArgoApplication
+		*out = new(ArgoApplicationStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ArgoProject != nil {
+		in, out := &in.ArgoProject, &out.ArgoProject
+		*out = new(ArgoProjectStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.HyperAuth != nil {
+		in, out := &in.HyperAuth, &out.HyperAuth
+		*out = new(HyperAuthStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ReconcilePhases != nil {
+		in, out := &in.ReconcilePhases, &out.ReconcilePhases
+		*out = make([]ReconcilePhaseStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterManagerStatus.
@@ -214,6 +472,50 @@ func (in *ClusterRegistrationStatus) DeepCopy() *ClusterRegistrationStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FailedArgoApplication) DeepCopyInto(out *FailedArgoApplication) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FailedArgoApplication.
+func (in *FailedArgoApplication) DeepCopy() *FailedArgoApplication {
+	if in == nil {
+		return nil
+	}
+	out := new(FailedArgoApplication)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HyperAuthStatus) DeepCopyInto(out *HyperAuthStatus) {
+	*out = *in
+	if in.Clients != nil {
+		in, out := &in.Clients, &out.Clients
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Groups != nil {
+		in, out := &in.Groups, &out.Groups
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.LastSyncTime != nil {
+		in, out := &in.LastSyncTime, &out.LastSyncTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HyperAuthStatus.
+func (in *HyperAuthStatus) DeepCopy() *HyperAuthStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(HyperAuthStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ProviderAwsSpec) DeepCopyInto(out *ProviderAwsSpec) {
 	*out = *in
@@ -244,6 +546,22 @@ func (in *ProviderVsphereSpec) DeepCopy() *ProviderVsphereSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReconcilePhaseStatus) DeepCopyInto(out *ReconcilePhaseStatus) {
+	*out = *in
+	in.LastTransitionTime.DeepCopyInto(&out.LastTransitionTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReconcilePhaseStatus.
+func (in *ReconcilePhaseStatus) DeepCopy() *ReconcilePhaseStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ReconcilePhaseStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ResourceType) DeepCopyInto(out *ResourceType) {
 	*out = *in
@@ -258,3 +576,50 @@ func (in *ResourceType) DeepCopy() *ResourceType {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RateLimitPolicy) DeepCopyInto(out *RateLimitPolicy) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RateLimitPolicy.
+func (in *RateLimitPolicy) DeepCopy() *RateLimitPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(RateLimitPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TrafficPolicy) DeepCopyInto(out *TrafficPolicy) {
+	*out = *in
+	if in.RateLimit != nil {
+		in, out := &in.RateLimit, &out.RateLimit
+		*out = new(RateLimitPolicy)
+		**out = **in
+	}
+	if in.SourceRanges != nil {
+		in, out := &in.SourceRanges, &out.SourceRanges
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.RequestHeaders != nil {
+		in, out := &in.RequestHeaders, &out.RequestHeaders
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TrafficPolicy.
+func (in *TrafficPolicy) DeepCopy() *TrafficPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(TrafficPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -0,0 +1,120 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	argocdV1alpha1 "github.com/argoproj/argo-cd/v2/pkg/apis/application/v1alpha1"
+	util "github.com/tmax-cloud/hypercloud-multi-operator/controllers/util"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+const applicationWebhookPath = "/validate-argoproj-io-v1alpha1-application"
+
+// +kubebuilder:webhook:verbs=create;update,path=/validate-argoproj-io-v1alpha1-application,mutating=false,failurePolicy=fail,groups=argoproj.io,resources=applications,versions=v1alpha1,name=validation.webhook.application,admissionReviewVersions=v1beta1;v1,sideEffects=None
+
+// ApplicationValidator 는 app-of-apps application 이 만드는 하위 application 이 cluster project 만 사용하도록 한다.
+// 하위 application 은 project 를 자유롭게 지정할 수 있으므로, 생성 단계에서 막지 않으면
+// 다른 cluster 의 project 나 default project 를 통해 다른 cluster 나 hub cluster 에 배포할 수 있다.
+type ApplicationValidator struct {
+	Client  client.Reader
+	Log     logr.Logger
+	decoder *admission.Decoder
+}
+
+// SetupApplicationWebhookWithManager 는 argocd Application 의 validating webhook 을 등록한다.
+func SetupApplicationWebhookWithManager(mgr ctrl.Manager) {
+	mgr.GetWebhookServer().Register(applicationWebhookPath, &webhook.Admission{
+		Handler: &ApplicationValidator{
+			Client: mgr.GetAPIReader(),
+			Log:    ctrl.Log.WithName("webhooks").WithName("Application"),
+		},
+	})
+}
+
+func (v *ApplicationValidator) InjectDecoder(d *admission.Decoder) error {
+	v.decoder = d
+	return nil
+}
+
+func (v *ApplicationValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	application := &argocdV1alpha1.Application{}
+	if err := v.decoder.Decode(req, application); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	if application.Namespace != util.ArgoNamespace {
+		return admission.Allowed("")
+	}
+
+	for _, parentName := range getArgoTrackingApplications(application) {
+		if parentName == application.Name {
+			continue
+		}
+		parent := &argocdV1alpha1.Application{}
+		key := types.NamespacedName{Name: parentName, Namespace: util.ArgoNamespace}
+		if err := v.Client.Get(ctx, key, parent); errors.IsNotFound(err) {
+			continue
+		} else if err != nil {
+			return admission.Errored(http.StatusInternalServerError, err)
+		}
+
+		if err := validateChildApplicationProject(parent, application); err != nil {
+			v.Log.Info("Deny Application of foreign project", "application", application.Name, "parent", parent.Name, "project", application.Spec.Project)
+			return admission.Denied(err.Error())
+		}
+	}
+	return admission.Allowed("")
+}
+
+// getArgoTrackingApplications 는 argocd 가 리소스를 관리하는 application 을 표시한 label 과 annotation 으로 부터
+// application 을 만든 application 의 이름을 찾는다.
+// tracking 방식에 따라 둘 중 하나는 manifest 에 적힌 값이 그대로 남으므로 둘 다 확인한다.
+func getArgoTrackingApplications(application *argocdV1alpha1.Application) []string {
+	names := []string{}
+	if name := application.Labels[util.LabelKeyArgoInstance]; name != "" {
+		names = append(names, name)
+	}
+	// annotation tracking 을 사용하는 경우 "<application>:<group>/<kind>:<namespace>/<name>" 형태이다.
+	if trackingID := application.Annotations[util.AnnotationKeyArgoTrackingID]; trackingID != "" {
+		if name := strings.SplitN(trackingID, ":", 2)[0]; name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// validateChildApplicationProject 는 operator 가 만든 app-of-apps application 의 하위 application 이 cluster project 를 사용하는지 확인한다.
+func validateChildApplicationProject(parent, child *argocdV1alpha1.Application) error {
+	prefix := parent.Labels[util.LabelKeyArgoTargetCluster]
+	if parent.Labels[util.LabelKeyArgoAppType] != util.ArgoAppTypeAppOfApp || prefix == "" {
+		return nil
+	}
+	if child.Spec.Project != prefix {
+		return fmt.Errorf("application [%s] created by [%s] must use project [%s], not [%s]", child.Name, parent.Name, prefix, child.Spec.Project)
+	}
+	return nil
+}
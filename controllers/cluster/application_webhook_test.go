@@ -0,0 +1,155 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	argocdV1alpha1 "github.com/argoproj/argo-cd/v2/pkg/apis/application/v1alpha1"
+	util "github.com/tmax-cloud/hypercloud-multi-operator/controllers/util"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+func TestApplicationValidator(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := argocdV1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	appOfApps := &argocdV1alpha1.Application{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "ns-cluster-applications",
+			Namespace: util.ArgoNamespace,
+			Labels: map[string]string{
+				util.LabelKeyArgoTargetCluster: "ns-cluster",
+				util.LabelKeyArgoAppType:       util.ArgoAppTypeAppOfApp,
+			},
+		},
+	}
+	otherApp := &argocdV1alpha1.Application{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "user-applications",
+			Namespace: util.ArgoNamespace,
+		},
+	}
+	validator := &ApplicationValidator{
+		Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(appOfApps, otherApp).Build(),
+		Log:    ctrl.Log.WithName("webhooks").WithName("Application"),
+	}
+	decoder, err := admission.NewDecoder(scheme)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := validator.InjectDecoder(decoder); err != nil {
+		t.Fatal(err)
+	}
+
+	newApplication := func(namespace, project string, labels, annotations map[string]string) *argocdV1alpha1.Application {
+		return &argocdV1alpha1.Application{
+			TypeMeta: metav1.TypeMeta{
+				APIVersion: argocdV1alpha1.SchemeGroupVersion.String(),
+				Kind:       argocdV1alpha1.ApplicationSchemaGroupVersionKind.Kind,
+			},
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "child",
+				Namespace:   namespace,
+				Labels:      labels,
+				Annotations: annotations,
+			},
+			Spec: argocdV1alpha1.ApplicationSpec{Project: project},
+		}
+	}
+	instanceOf := func(name string) map[string]string {
+		return map[string]string{util.LabelKeyArgoInstance: name}
+	}
+	trackedBy := func(name string) map[string]string {
+		return map[string]string{util.AnnotationKeyArgoTrackingID: name + ":argoproj.io/Application:argocd/child"}
+	}
+
+	tests := []struct {
+		name        string
+		application *argocdV1alpha1.Application
+		wantAllowed bool
+	}{
+		{
+			name:        "child uses cluster project",
+			application: newApplication(util.ArgoNamespace, "ns-cluster", instanceOf(appOfApps.Name), nil),
+			wantAllowed: true,
+		},
+		{
+			name:        "child uses default project",
+			application: newApplication(util.ArgoNamespace, "default", instanceOf(appOfApps.Name), nil),
+		},
+		{
+			name:        "child uses other cluster project",
+			application: newApplication(util.ArgoNamespace, "ns-other", instanceOf(appOfApps.Name), nil),
+		},
+		{
+			name:        "child tracked by annotation uses foreign project",
+			application: newApplication(util.ArgoNamespace, "default", nil, trackedBy(appOfApps.Name)),
+		},
+		{
+			name:        "label of manifest does not hide annotation tracking",
+			application: newApplication(util.ArgoNamespace, "default", instanceOf(otherApp.Name), trackedBy(appOfApps.Name)),
+		},
+		{
+			name:        "child of application not managed by operator",
+			application: newApplication(util.ArgoNamespace, "default", instanceOf(otherApp.Name), nil),
+			wantAllowed: true,
+		},
+		{
+			name:        "application without parent",
+			application: newApplication(util.ArgoNamespace, "default", nil, nil),
+			wantAllowed: true,
+		},
+		{
+			name:        "parent is not found",
+			application: newApplication(util.ArgoNamespace, "default", instanceOf("unknown"), nil),
+			wantAllowed: true,
+		},
+		{
+			name:        "application in other namespace",
+			application: newApplication("default", "default", instanceOf(appOfApps.Name), nil),
+			wantAllowed: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw, err := json.Marshal(tt.application)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req := admission.Request{
+				AdmissionRequest: admissionv1.AdmissionRequest{
+					Operation: admissionv1.Create,
+					Object:    runtime.RawExtension{Raw: raw},
+				},
+			}
+			res := validator.Handle(context.Background(), req)
+			if res.Allowed != tt.wantAllowed {
+				t.Errorf("Handle() allowed = %v, want %v (%v)", res.Allowed, tt.wantAllowed, res.Result)
+			}
+		})
+	}
+}
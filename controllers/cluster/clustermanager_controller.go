@@ -17,6 +17,7 @@ package controllers
 import (
 	"context"
 
+	argocdV1alpha1 "github.com/argoproj/argo-cd/v2/pkg/apis/application/v1alpha1"
 	"github.com/go-logr/logr"
 	certmanagerV1 "github.com/jetstack/cert-manager/pkg/apis/certmanager/v1"
 	servicecatalogv1beta1 "github.com/kubernetes-sigs/service-catalog/pkg/apis/servicecatalog/v1beta1"
@@ -26,15 +27,19 @@ import (
 
 	coreV1 "k8s.io/api/core/v1"
 	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
-	kerrors "k8s.io/apimachinery/pkg/util/errors"
 
 	capiV1alpha3 "sigs.k8s.io/cluster-api/api/v1alpha3"
 	controlplanev1 "sigs.k8s.io/cluster-api/controlplane/kubeadm/api/v1alpha3"
 	"sigs.k8s.io/cluster-api/util/patch"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/event"
@@ -47,10 +52,15 @@ import (
 // ClusterManagerReconciler reconciles a ClusterManager object
 type ClusterManagerReconciler struct {
 	client.Client
-	Log    logr.Logger
-	Scheme *runtime.Scheme
+	Log           logr.Logger
+	Scheme        *runtime.Scheme
+	RemoteWatches *util.RemoteWatchManager
+	// HyperAuth module configmap 을 읽고 watch 하는 operator namespace 의 cache.
+	// manager 의 cache 는 ClusterManager 의 label 이 있는 ConfigMap 만 가진다.
+	OperatorCache cache.Cache
 }
 
+// +kubebuilder:rbac:groups=cluster.tmax.io,resources=clusteraddonprofiles,verbs=get;list;watch
 // +kubebuilder:rbac:groups=cluster.tmax.io,resources=clustermanagers,verbs=create;delete;get;list;patch;update;watch
 // +kubebuilder:rbac:groups=cluster.tmax.io,resources=clustermanagers/status,verbs=get;list;patch;update;watch
 // +kubebuilder:rbac:groups=cluster.x-k8s.io,resources=clusters,verbs=get;list;watch
@@ -65,10 +75,11 @@ type ClusterManagerReconciler struct {
 // +kubebuilder:rbac:groups=servicecatalog.k8s.io,resources=serviceinstances/status,verbs=get;list;patch;update;watch
 // +kubebuilder:rbac:groups=cert-manager.io,resources=certificates,verbs=create;delete;get;list;patch;update;watch
 // +kubebuilder:rbac:groups=networking.k8s.io,resources=ingresses,verbs=create;delete;get;list;patch;update;watch
-// +kubebuilder:rbac:groups="",resources=services;endpoints,verbs=create;delete;get;list;patch;update;watch
-// +kubebuilder:rbac:groups=traefik.containo.us,resources=middlewares,verbs=create;delete;get;list;patch;update;watch
+// +kubebuilder:rbac:groups="",resources=configmaps;services;endpoints,verbs=create;delete;get;list;patch;update;watch
+// +kubebuilder:rbac:groups=traefik.containo.us,resources=middlewares;serverstransports,verbs=create;delete;get;list;patch;update;watch
+// +kubebuilder:rbac:groups=gateway.networking.k8s.io,resources=httproutes;backendtlspolicies,verbs=create;delete;get;list;patch;update;watch
 // +kubebuilder:rbac:groups=coordination.k8s.io,resources=leases,verbs=create;delete;get;list;patch;update;watch
-// +kubebuilder:rbac:groups=argoproj.io,resources=applications,verbs=create;delete;get;list;patch;update;watch
+// +kubebuilder:rbac:groups=argoproj.io,resources=applications;appprojects,verbs=create;delete;get;list;patch;update;watch
 
 func (r *ClusterManagerReconciler) Reconcile(ctx context.Context, req ctrl.Request) (_ ctrl.Result, reterr error) {
 	_ = context.Background()
@@ -78,6 +89,7 @@ func (r *ClusterManagerReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 	clusterManager := &clusterV1alpha1.ClusterManager{}
 	if err := r.Get(context.TODO(), req.NamespacedName, clusterManager); errors.IsNotFound(err) {
 		log.Info("ClusterManager resource not found. Ignoring since object must be deleted")
+		r.RemoteWatches.Stop(req.NamespacedName)
 		return ctrl.Result{}, nil
 	} else if err != nil {
 		log.Error(err, "Failed to get ClusterManager")
@@ -161,23 +173,146 @@ func (r *ClusterManagerReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 // 	return ctrl.Result{}, nil
 // }
 
-// reconcile handles cluster reconciliation.
-func (r *ClusterManagerReconciler) reconcile(ctx context.Context, clusterManager *clusterV1alpha1.ClusterManager) (ctrl.Result, error) {
-	phases := []func(context.Context, *clusterV1alpha1.ClusterManager) (ctrl.Result, error){}
-	phases = append(phases, r.ReadyReconcilePhase)
+// clusterManagerPhase 는 reconcile 단계의 선언과, 해당 단계가 ClusterManager 의 Status.Phase 에 미치는 영향을 함께 가진다.
+type clusterManagerPhase struct {
+	util.Phase
+	// 일반 phase 는 완료(Done)되었을 때, Exclusive phase 는 수행중일 때 ClusterManager 가 가지는 Status.Phase
+	// 여러 phase 가 해당하면 나중에 선언된 phase 를 따른다.
+	StatusPhase clusterV1alpha1.ClusterManagerPhase
+}
+
+// phases 는 ClusterManager 를 reconcile 하기 위한 phase 들을 선언한다.
+// 새로운 phase 는 이 곳에 이름, 의존성, precondition, 완료 조건을 선언하여 추가한다.
+func (r *ClusterManagerReconciler) phases(clusterManager *clusterV1alpha1.ClusterManager) []clusterManagerPhase {
+	// 같은 단계의 phase 들은 동시에 수행되므로, 각 phase 는 ClusterManager 의 복사본을 수정하고 수행을 마치면 원본에 merge 한다.
+	object := util.NewPhaseObject(clusterManager)
+	bind := func(phase func(context.Context, *clusterV1alpha1.ClusterManager) (ctrl.Result, error)) func(context.Context) (ctrl.Result, error) {
+		return object.Bind(func(ctx context.Context, obj client.Object) (ctrl.Result, error) {
+			return phase(ctx, obj.(*clusterV1alpha1.ClusterManager))
+		})
+	}
+	status := &clusterManager.Status
+	isCreated := clusterManager.Labels[clusterV1alpha1.LabelKeyClmClusterType] == clusterV1alpha1.ClusterTypeCreated
+	isUpgrading := func() bool {
+		return status.Version != "" && clusterManager.Spec.Version != status.Version
+	}
+	isMasterScaling := func() bool {
+		return status.MasterNum != 0 && clusterManager.Spec.MasterNum != status.MasterNum
+	}
+	isWorkerScaling := func() bool {
+		return status.WorkerNum != 0 && clusterManager.Spec.WorkerNum != status.WorkerNum
+	}
+	// 모든 리소스가 만들어졌더라도 application 이 sync 되고 healthy 해야 Ready 가 된다.
+	readyStatusPhase := clusterV1alpha1.ClusterManagerPhaseReady
+	if !status.ArgoApplication.IsReady() {
+		readyStatusPhase = clusterV1alpha1.ClusterManagerPhaseSyncNeeded
+	}
 
-	if clusterManager.Labels[clusterV1alpha1.LabelKeyClmClusterType] == clusterV1alpha1.ClusterTypeCreated {
+	phases := []clusterManagerPhase{
+		{
+			Phase: util.Phase{
+				Name: phaseReadyReconcile,
+				Run:  bind(r.ReadyReconcilePhase),
+			},
+		},
+	}
+
+	// cluster 가 준비되었는지 판단하는 phase. 공통 phase 들은 이 phase 이후에 수행된다.
+	clusterReadyPhase := phaseUpdateClusterManagerStatus
+	if isCreated {
 		// cluster claim 으로 cluster 를 생성한 경우에만 수행
+		clusterReadyPhase = phaseKubeadmControlPlaneUpdate
 		phases = append(
 			phases,
 			// cluster manager 의  metadata 와 provider 정보를 service instance 의 parameter 값에 넣어 service instance 를 생성한다.
-			r.CreateServiceInstance,
+			clusterManagerPhase{
+				Phase: util.Phase{
+					Name:      phaseCreateServiceInstance,
+					DependsOn: []string{phaseReadyReconcile},
+					Done: func() bool {
+						return clusterManager.Annotations[clusterV1alpha1.AnnotationKeyClmSuffix] != ""
+					},
+					Run: bind(r.CreateServiceInstance),
+				},
+			},
 			// cluster manager 가 바라봐야 할 cluster 의 endpoint 를 annotation 으로 달아준다.
-			r.SetEndpoint,
+			clusterManagerPhase{
+				Phase: util.Phase{
+					Name:      phaseSetEndpoint,
+					DependsOn: []string{phaseReadyReconcile},
+					Done: func() bool {
+						return clusterManager.Annotations[clusterV1alpha1.AnnotationKeyClmApiserver] != ""
+					},
+					Run: bind(r.SetEndpoint),
+				},
+			},
 			// cluster claim 을 통해, cluster 의 spec 을 변경한 경우, 그에 맞게 master 노드의 spec 을 업데이트 해준다.
-			r.kubeadmControlPlaneUpdate,
+			clusterManagerPhase{
+				Phase: util.Phase{
+					Name:      phaseKubeadmControlPlaneUpdate,
+					DependsOn: []string{phaseReadyReconcile},
+					Run:       bind(r.kubeadmControlPlaneUpdate),
+				},
+			},
 			// cluster claim 을 통해, cluster 의 spec 을 변경한 경우, 그에 맞게 worker 노드의 spec 을 업데이트 해준다.
-			r.machineDeploymentUpdate,
+			clusterManagerPhase{
+				Phase: util.Phase{
+					Name:      phaseMachineDeploymentUpdate,
+					DependsOn: []string{phaseReadyReconcile},
+					Run:       bind(r.machineDeploymentUpdate),
+				},
+			},
+		)
+
+		// special case- capi upgrade/master scaling/worker scaling
+		// 아래 phase 들이 수행되어야 하는 경우에는 다른 phase 들은 수행하지 않는다.
+		upgradeDependsOn := []string{}
+		if clusterManager.Spec.Provider == clusterV1alpha1.ProviderVSphere {
+			upgradeDependsOn = append(upgradeDependsOn, phaseCreateUpgradeServiceInstance)
+			phases = append(phases, clusterManagerPhase{
+				Phase: util.Phase{
+					Name:         phaseCreateUpgradeServiceInstance,
+					Precondition: isUpgrading,
+					Run:          bind(r.CreateUpgradeServiceInstance),
+					Exclusive:    true,
+				},
+				StatusPhase: clusterV1alpha1.ClusterManagerPhaseUpgrading,
+			})
+		}
+		phases = append(
+			phases,
+			clusterManagerPhase{
+				Phase: util.Phase{
+					Name:         phaseClusterUpgrade,
+					DependsOn:    upgradeDependsOn,
+					Precondition: isUpgrading,
+					Run:          bind(r.ClusterUpgrade),
+					Exclusive:    true,
+				},
+				StatusPhase: clusterV1alpha1.ClusterManagerPhaseUpgrading,
+			},
+			clusterManagerPhase{
+				Phase: util.Phase{
+					Name: phaseControlplaneScaling,
+					Precondition: func() bool {
+						return !isUpgrading() && isMasterScaling()
+					},
+					Run:       bind(r.ControlplaneScaling),
+					Exclusive: true,
+				},
+				StatusPhase: clusterV1alpha1.ClusterManagerPhaseScaling,
+			},
+			clusterManagerPhase{
+				Phase: util.Phase{
+					Name: phaseWorkerScaling,
+					Precondition: func() bool {
+						return !isUpgrading() && !isMasterScaling() && isWorkerScaling()
+					},
+					Run:       bind(r.WorkerScaling),
+					Exclusive: true,
+				},
+				StatusPhase: clusterV1alpha1.ClusterManagerPhaseScaling,
+			},
 		)
 	} else {
 		// cluster 를 등록한 경우에만 수행
@@ -186,71 +321,185 @@ func (r *ClusterManagerReconciler) reconcile(ctx context.Context, clusterManager
 		// cluster manager 에 k8s version을 업데이트 해주고,
 		// single cluster 의 nodes 를 가져와 ready 상태의 worker node 와 master node의 개수를 업데이트해준다.
 		// 또한, 해당 cluster 의 provider 이름 (Aws/Vsphere) 을 업데이트 해주는 과정을 진행한다.
-		phases = append(phases, r.UpdateClusterManagerStatus)
+		phases = append(phases, clusterManagerPhase{
+			Phase: util.Phase{
+				Name:      phaseUpdateClusterManagerStatus,
+				DependsOn: []string{phaseReadyReconcile},
+				Done: func() bool {
+					return status.ControlPlaneReady
+				},
+				Run: bind(r.UpdateClusterManagerStatus),
+			},
+		})
 	}
+
 	// 공통적으로 수행
 	phases = append(
 		phases,
+		// single cluster 의 리소스 변경을 감지하기 위한 informer 를 시작한다.
+		clusterManagerPhase{
+			Phase: util.Phase{
+				Name:      phaseWatchRemoteResources,
+				DependsOn: []string{clusterReadyPhase},
+				Precondition: func() bool {
+					return status.ControlPlaneReady && r.RemoteWatches != nil
+				},
+				Run: bind(r.WatchRemoteResources),
+			},
+		},
 		// Argocd 연동을 위해 필요한 정보를 kube-config 로 부터 가져와 secret을 생성한다.
-		r.CreateArgocdResources,
+		clusterManagerPhase{
+			Phase: util.Phase{
+				Name:      phaseCreateArgocdResources,
+				DependsOn: []string{clusterReadyPhase},
+				Precondition: func() bool {
+					return status.ControlPlaneReady && status.Ready
+				},
+				Done: func() bool {
+					return status.ArgoReady
+				},
+				Run: bind(r.CreateArgocdResources),
+			},
+			StatusPhase: clusterV1alpha1.ClusterManagerPhaseSyncNeeded,
+		},
+		// TokenRequest 로 발급받은 token 들이 만료되기 전에 다시 발급받아 갱신한다.
+		clusterManagerPhase{
+			Phase: util.Phase{
+				Name:      phaseRefreshServiceAccountTokens,
+				DependsOn: []string{phaseCreateArgocdResources},
+				Run:       bind(r.RefreshServiceAccountTokens),
+			},
+		},
+		// cluster member 가 바뀐 경우 AppProject 의 role 을 갱신한다.
+		clusterManagerPhase{
+			Phase: util.Phase{
+				Name:      phaseSyncArgocdProject,
+				DependsOn: []string{phaseCreateArgocdResources},
+				Run:       bind(r.SyncArgocdProject),
+			},
+		},
+		// ApplicationSet 의 cluster generator 를 위해 ClusterManager 의 label 을 argocd cluster secret 에 전파한다.
+		clusterManagerPhase{
+			Phase: util.Phase{
+				Name:      phaseSyncArgocdClusterLabels,
+				DependsOn: []string{phaseCreateArgocdResources},
+				Run:       bind(r.SyncArgocdClusterLabels),
+			},
+		},
+		// sync-now annotation 이 있으면 app-of-apps application 을 sync 한다.
+		clusterManagerPhase{
+			Phase: util.Phase{
+				Name:      phaseSyncApplication,
+				DependsOn: []string{phaseCreateArgocdResources},
+				Precondition: func() bool {
+					return clusterManager.Annotations[clusterV1alpha1.AnnotationKeyClmSyncNow] != ""
+				},
+				Run: bind(r.SyncApplication),
+			},
+		},
+		// app-of-apps application 의 sync, health 상태를 status 에 반영한다.
+		clusterManagerPhase{
+			Phase: util.Phase{
+				Name:      phaseSyncApplicationStatus,
+				DependsOn: []string{phaseCreateArgocdResources},
+				Run:       bind(r.SyncApplicationStatus),
+			},
+		},
 		// single cluster 의 api gateway service 의 주소로 gateway service 생성
-		r.CreateGatewayResources,
+		clusterManagerPhase{
+			Phase: util.Phase{
+				Name:      phaseCreateGatewayResources,
+				DependsOn: []string{phaseCreateArgocdResources},
+				Done: func() bool {
+					return status.GatewayReady
+				},
+				Run: bind(r.CreateGatewayResources),
+			},
+			StatusPhase: clusterV1alpha1.ClusterManagerPhaseProcessing,
+		},
+		// single cluster 의 gateway 주소가 바뀐 경우 gateway service 와 endpoint 를 다시 만든다.
+		clusterManagerPhase{
+			Phase: util.Phase{
+				Name:      phaseSyncGatewayAddresses,
+				DependsOn: []string{phaseCreateGatewayResources},
+				Precondition: func() bool {
+					return status.GatewayReady
+				},
+				Run: bind(r.SyncGatewayAddresses),
+			},
+		},
 		// Kibana, Grafana, Kiali 등 모듈과 HyperAuth oidc 연동을 위한 resource 생성 작업 (HyperAuth 계정정보로 여러 모듈에 로그인 가능)
 		// HyperAuth caller 를 통해 admin token 을 가져와 각 모듈 마다 HyperAuth client 를 생성후, 모듈에 따른 resource들을 추가한다.
 		// HyperRegistry를 위한 admin group 또한 생성해준다.
-		r.CreateHyperAuthResources,
+		clusterManagerPhase{
+			Phase: util.Phase{
+				Name:      phaseCreateHyperAuthResources,
+				DependsOn: []string{phaseCreateArgocdResources},
+				Done: func() bool {
+					return status.AuthClientReady
+				},
+				Run: bind(r.CreateHyperAuthResources),
+			},
+		},
+		// HyperAuth 의 리소스를 desired state 와 주기적으로 비교하여, 삭제되거나 수정된 리소스를 되돌리고 preset 의 변경을 반영한다.
+		clusterManagerPhase{
+			Phase: util.Phase{
+				Name:      phaseSyncHyperAuthResources,
+				DependsOn: []string{phaseCreateHyperAuthResources},
+				Run:       bind(r.SyncHyperAuthResources),
+			},
+		},
 		// // hyperregistry domain 을 single cluster 의 ingress 로 부터 가져와 oidc 연동설정
 		// r.SetHyperregistryOidcConfig,
 		// Traefik 을 통하기 위한 리소스인 certificate, ingress, middleware를 생성한다.
 		// 콘솔에서 ingress를 조회하여 LNB에 cluster를 listing 해주므로 cluster가 완전히 join되고 나서
 		// LNB에 리스팅 될 수 있게 해당 프로세스를 가장 마지막에 수행한다.
-		r.CreateTraefikResources,
+		clusterManagerPhase{
+			Phase: util.Phase{
+				Name:      phaseCreateTraefikResources,
+				DependsOn: []string{phaseCreateGatewayResources, phaseCreateHyperAuthResources},
+				// operator 설정이나 노출할 path 가 바뀐 경우 ingress 를 갱신하기 위해 다시 수행한다.
+				Done: func() bool {
+					return status.TraefikReady &&
+						clusterManager.Annotations[util.AnnotationKeyIngressConfigHash] == GetIngressConfigHash(clusterManager)
+				},
+				Run: bind(r.CreateTraefikResources),
+			},
+			StatusPhase: readyStatusPhase,
+		},
 	)
 
-	// special case- capi upgrade/master scaling/worker scaling
-	if clusterManager.Labels[clusterV1alpha1.LabelKeyClmClusterType] == clusterV1alpha1.ClusterTypeCreated {
-		if clusterManager.Status.Version != "" && clusterManager.Spec.Version != clusterManager.Status.Version {
-			phases = []func(context.Context, *clusterV1alpha1.ClusterManager) (ctrl.Result, error){}
-			if clusterManager.Spec.Provider == clusterV1alpha1.ProviderVSphere {
-				phases = append(phases, r.CreateUpgradeServiceInstance)
-			}
-			phases = append(phases, r.ClusterUpgrade)
-		} else if clusterManager.Status.MasterNum != 0 && clusterManager.Spec.MasterNum != clusterManager.Status.MasterNum {
-			phases = []func(context.Context, *clusterV1alpha1.ClusterManager) (ctrl.Result, error){r.ControlplaneScaling}
-		} else if clusterManager.Status.WorkerNum != 0 && clusterManager.Spec.WorkerNum != clusterManager.Status.WorkerNum {
-			phases = []func(context.Context, *clusterV1alpha1.ClusterManager) (ctrl.Result, error){r.WorkerScaling}
-		}
-	}
+	return phases
+}
 
-	res := ctrl.Result{}
-	errs := []error{}
-	// phases 를 돌면서, append 한 함수들을 순차적으로 수행하고,
-	// error가 있는지 체크하여 error가 있으면 무조건 requeue
-	// 이때는 가장 최초로 error가 발생한 phase의 requeue after time을 따라감
-	// 모든 error를 최종적으로 aggregate하여 반환할 수 있도록 리스트로 반환
-	// error는 없지만 다시 requeue 가 되어야 하는 phase들이 존재하는 경우
-	// LowestNonZeroResult 함수를 통해 requeueAfter time 이 가장 짧은 함수를 찾는다.
-	for _, phase := range phases {
-		// Call the inner reconciliation methods.
-		phaseResult, err := phase(ctx, clusterManager)
-		if err != nil {
-			errs = append(errs, err)
-		}
-		if len(errs) > 0 {
-			continue
-		}
+// reconcile handles cluster reconciliation.
+func (r *ClusterManagerReconciler) reconcile(ctx context.Context, clusterManager *clusterV1alpha1.ClusterManager) (ctrl.Result, error) {
+	log := r.Log.WithValues("clustermanager", clusterManager.GetNamespacedName())
 
-		// Aggregate phases which requeued without err
-		res = util.LowestNonZeroResult(res, phaseResult)
+	phases := []util.Phase{}
+	for _, phase := range r.phases(clusterManager) {
+		phases = append(phases, phase.Phase)
 	}
 
-	return res, kerrors.NewAggregate(errs)
+	// phase 들은 의존성 순서대로 수행하고, 의존하는 phase 가 완료되지 않은 phase 는 수행하지 않는다.
+	// 서로 의존하지 않는 phase 들은 동시에 수행한다.
+	// error 가 있으면 무조건 requeue 되며 모든 error 를 aggregate 하여 반환한다.
+	// error 는 없지만 다시 requeue 가 되어야 하는 phase 들이 존재하는 경우
+	// requeueAfter time 이 가장 짧은 phase 의 result 를 따른다.
+	res, outcomes, err := util.RunPhases(ctx, phases)
+	util.LogPhaseOutcomes(log, outcomes)
+	SetReconcilePhaseStatus(clusterManager, outcomes)
+
+	return res, err
 }
 
 func (r *ClusterManagerReconciler) reconcileDelete(ctx context.Context, clusterManager *clusterV1alpha1.ClusterManager) (reconcile.Result, error) {
 	log := r.Log.WithValues("clustermanager", clusterManager.GetNamespacedName())
 	log.Info("Start reconcile phase for delete")
 
+	// 삭제중인 cluster 에 대해서는 remote 리소스를 watch 할 필요가 없다.
+	r.RemoteWatches.Stop(clusterManager.GetNamespacedName())
+
 	// sjoh - kubeconfig가 없으면 cluster가 삭제된 것을 가정, 없으면 skip한다.
 	// key := types.NamespacedName{
 	// 	Name:      clusterManager.Name + util.KubeconfigSuffix,
@@ -261,9 +510,11 @@ func (r *ClusterManagerReconciler) reconcileDelete(ctx context.Context, clusterM
 	// 	return ctrl.Result{}, err
 	// }
 
-	// ArgoCD application이 모두 삭제되었는지 테스트
-	if err := r.CheckApplicationRemains(clusterManager); err != nil {
-		return ctrl.Result{RequeueAfter: requeueAfter10Second}, err
+	// argocd namespace 의 application 과 cluster secret 은 owner reference 로 gc 되지 않으므로 직접 삭제한다.
+	if deleted, err := r.DeleteCrossNamespaceResources(clusterManager); err != nil {
+		return ctrl.Result{}, err
+	} else if !deleted {
+		return ctrl.Result{RequeueAfter: requeueAfter10Second}, nil
 	}
 
 	// ClusterAPI-provider-aws의 경우, lb type의 svc가 남아있으면 infra nlb deletion이 stuck걸리면서 클러스터가 지워지지 않는 버그가 있음
@@ -272,7 +523,8 @@ func (r *ClusterManagerReconciler) reconcileDelete(ctx context.Context, clusterM
 		return ctrl.Result{}, err
 	}
 
-	if err := r.DeleteTraefikResources(clusterManager); err != nil {
+	// 같은 namespace 의 리소스는 owner reference 로 gc 되므로, owner reference 가 없는 이전 버전의 리소스만 삭제한다.
+	if err := r.DeleteUnownedResources(clusterManager); err != nil {
 		return ctrl.Result{}, err
 	}
 
@@ -361,29 +613,24 @@ func (r *ClusterManagerReconciler) reconcilePhase(_ context.Context, clusterMana
 		clusterManager.Status.SetTypedPhase(clusterV1alpha1.ClusterManagerPhaseProcessing)
 	}
 
-	if clusterManager.Status.ArgoReady {
-		clusterManager.Status.SetTypedPhase(clusterV1alpha1.ClusterManagerPhaseSyncNeeded)
-	}
-
-	if clusterManager.Status.GatewayReady {
-		clusterManager.Status.SetTypedPhase(clusterV1alpha1.ClusterManagerPhaseProcessing)
-	}
-
-	if clusterManager.Status.TraefikReady {
-		clusterManager.Status.SetTypedPhase(clusterV1alpha1.ClusterManagerPhaseReady)
-	}
-
-	// cluster scaling
-	if (clusterManager.Status.MasterNum != 0 && clusterManager.Spec.MasterNum != clusterManager.Status.MasterNum) ||
-		(clusterManager.Status.WorkerNum != 0 && clusterManager.Spec.WorkerNum != clusterManager.Status.WorkerNum) {
-		clusterManager.Status.SetTypedPhase(clusterV1alpha1.ClusterManagerPhaseScaling)
-		return
+	// 완료된 phase 중 가장 나중에 선언된 phase 의 StatusPhase 를 따르고,
+	// upgrade/scaling 처럼 수행중인 exclusive phase 가 있다면 그 StatusPhase 를 따른다.
+	exclusivePhase := clusterV1alpha1.ClusterManagerPhase("")
+	for _, phase := range r.phases(clusterManager) {
+		if phase.StatusPhase == "" {
+			continue
+		}
+		if phase.Exclusive {
+			if exclusivePhase == "" && (phase.Precondition == nil || phase.Precondition()) {
+				exclusivePhase = phase.StatusPhase
+			}
+		} else if phase.Done != nil && phase.Done() {
+			clusterManager.Status.SetTypedPhase(phase.StatusPhase)
+		}
 	}
 
-	// cluster upgrading
-	if clusterManager.Status.Version != "" && clusterManager.Status.Version != clusterManager.Spec.Version {
-		clusterManager.Status.SetTypedPhase(clusterV1alpha1.ClusterManagerPhaseUpgrading)
-		return
+	if exclusivePhase != "" {
+		clusterManager.Status.SetTypedPhase(exclusivePhase)
 	}
 }
 
@@ -409,10 +656,24 @@ func (r *ClusterManagerReconciler) SetupWithManager(mgr ctrl.Manager) error {
 						newclm.Status.ControlPlaneEndpoint != ""
 					isSubResourceNotReady := !newclm.Status.ArgoReady || !newclm.Status.TraefikReady || !newclm.Status.GatewayReady
 
+					labelPrefix := util.GetArgoClusterLabelPrefix()
+					isClusterLabelUpdate := !equality.Semantic.DeepEqual(
+						filterLabelsByPrefix(oldclm.Labels, labelPrefix),
+						filterLabelsByPrefix(newclm.Labels, labelPrefix),
+					)
+					isExposedPathsUpdate := oldclm.Annotations[clusterV1alpha1.AnnotationKeyClmExposedPaths] != newclm.Annotations[clusterV1alpha1.AnnotationKeyClmExposedPaths]
+					isArgoClusterAuthUpdate := !equality.Semantic.DeepEqual(oldclm.Spec.ArgoClusterAuth, newclm.Spec.ArgoClusterAuth)
+					isSyncPolicyUpdate := !equality.Semantic.DeepEqual(oldclm.Spec.ApplicationSyncPolicy, newclm.Spec.ApplicationSyncPolicy)
+					isSyncRequested := oldclm.Annotations[clusterV1alpha1.AnnotationKeyClmSyncNow] != newclm.Annotations[clusterV1alpha1.AnnotationKeyClmSyncNow] &&
+						newclm.Annotations[clusterV1alpha1.AnnotationKeyClmSyncNow] != ""
+					isSecretRotationRequested := oldclm.Annotations[clusterV1alpha1.AnnotationKeyClmRotateHyperAuthSecrets] != newclm.Annotations[clusterV1alpha1.AnnotationKeyClmRotateHyperAuthSecrets] &&
+						newclm.Annotations[clusterV1alpha1.AnnotationKeyClmRotateHyperAuthSecrets] != ""
+					isHyperAuthRealmUpdate := oldclm.Annotations[clusterV1alpha1.AnnotationKeyClmHyperAuthRealm] != newclm.Annotations[clusterV1alpha1.AnnotationKeyClmHyperAuthRealm]
 					isUpgrade := oldclm.Spec.Version != "" && oldclm.Spec.Version != newclm.Spec.Version
 					isScaling := oldclm.Spec.MasterNum != newclm.Spec.MasterNum ||
 						oldclm.Spec.WorkerNum != newclm.Spec.WorkerNum
-					if isDelete || isControlPlaneEndpointUpdate || isFinalized || isUpgrade || isScaling {
+					if isDelete || isControlPlaneEndpointUpdate || isFinalized || isUpgrade || isScaling ||
+						isSyncPolicyUpdate || isSyncRequested || isSecretRotationRequested || isHyperAuthRealmUpdate || isExposedPathsUpdate || isClusterLabelUpdate || isArgoClusterAuthUpdate {
 						return true
 					} else {
 						if newclm.Labels[clusterV1alpha1.LabelKeyClmClusterType] == clusterV1alpha1.ClusterTypeCreated {
@@ -438,6 +699,13 @@ func (r *ClusterManagerReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		return err
 	}
 
+	if r.RemoteWatches != nil {
+		controller.Watch(
+			r.RemoteWatches.Source(),
+			&handler.EnqueueRequestForObject{},
+		)
+	}
+
 	controller.Watch(
 		&source.Kind{Type: &capiV1alpha3.Cluster{}},
 		handler.EnqueueRequestsFromMapFunc(r.requeueClusterManagersForCluster),
@@ -504,11 +772,90 @@ func (r *ClusterManagerReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		},
 	)
 
+	// app-of-apps application 과 하위 application 의 sync, health 상태가 바뀌면 status 를 갱신한다.
+	controller.Watch(
+		&source.Kind{Type: &argocdV1alpha1.Application{}},
+		handler.EnqueueRequestsFromMapFunc(r.requeueClusterManagersForApplication),
+		predicate.Funcs{
+			UpdateFunc: func(e event.UpdateEvent) bool {
+				return isApplicationStatusChanged(e.ObjectOld, e.ObjectNew)
+			},
+			CreateFunc: func(e event.CreateEvent) bool {
+				return false
+			},
+			DeleteFunc: func(e event.DeleteEvent) bool {
+				return true
+			},
+			GenericFunc: func(e event.GenericEvent) bool {
+				return false
+			},
+		},
+	)
+
+	// ClusterAddonProfile 이 바뀌면 profile 이 적용되는 cluster 들의 application 을 다시 만든다.
+	controller.Watch(
+		&source.Kind{Type: &clusterV1alpha1.ClusterAddonProfile{}},
+		handler.EnqueueRequestsFromMapFunc(r.requeueClusterManagersForAddonProfile),
+		predicate.Funcs{
+			UpdateFunc: func(e event.UpdateEvent) bool {
+				return e.ObjectOld.GetGeneration() != e.ObjectNew.GetGeneration()
+			},
+			CreateFunc: func(e event.CreateEvent) bool {
+				return true
+			},
+			DeleteFunc: func(e event.DeleteEvent) bool {
+				return true
+			},
+			GenericFunc: func(e event.GenericEvent) bool {
+				return false
+			},
+		},
+	)
+
+	// HyperAuth module configmap 이 바뀌면 모든 cluster 의 HyperAuth 리소스를 다시 동기화한다.
+	controller.Watch(
+		source.NewKindWithCache(&coreV1.ConfigMap{}, r.OperatorCache),
+		handler.EnqueueRequestsFromMapFunc(r.requeueClusterManagersForHyperAuthModules),
+		predicate.Funcs{
+			UpdateFunc: func(e event.UpdateEvent) bool {
+				return isHyperAuthModuleConfigMap(e.ObjectNew) &&
+					!equality.Semantic.DeepEqual(e.ObjectOld.(*coreV1.ConfigMap).Data, e.ObjectNew.(*coreV1.ConfigMap).Data)
+			},
+			CreateFunc: func(e event.CreateEvent) bool {
+				return isHyperAuthModuleConfigMap(e.Object)
+			},
+			DeleteFunc: func(e event.DeleteEvent) bool {
+				return isHyperAuthModuleConfigMap(e.Object)
+			},
+			GenericFunc: func(e event.GenericEvent) bool {
+				return false
+			},
+		},
+	)
+
+	// operator 가 만든 hub cluster 의 리소스가 삭제되거나 다른 곳에서 수정된 경우 다시 만들어 되돌린다.
 	subResources := []client.Object{
 		&certmanagerV1.Certificate{},
 		&networkingv1.Ingress{},
 		&coreV1.Service{},
+		&coreV1.Endpoints{},
+		&coreV1.Secret{},
+		&coreV1.ConfigMap{},
 		&traefikV1alpha1.Middleware{},
+		&traefikV1alpha1.ServersTransport{},
+	}
+	// Gateway API 는 설치되지 않았을 수 있으므로 crd 가 있는 경우에만 watch 한다.
+	// operator 가 시작된 뒤에 crd 를 설치한 경우에는 operator 를 다시 시작해야 watch 된다.
+	for _, gvk := range []schema.GroupVersionKind{httpRouteGVK, backendTLSPolicyGVK} {
+		if _, err := mgr.GetRESTMapper().RESTMapping(gvk.GroupKind(), gvk.Version); meta.IsNoMatchError(err) {
+			r.Log.Info("Skip watching " + gvk.Kind + ". CRD is not installed")
+			continue
+		} else if err != nil {
+			return err
+		}
+		resource := &unstructured.Unstructured{}
+		resource.SetGroupVersionKind(gvk)
+		subResources = append(subResources, resource)
 	}
 	for _, resource := range subResources {
 		controller.Watch(
@@ -516,14 +863,13 @@ func (r *ClusterManagerReconciler) SetupWithManager(mgr ctrl.Manager) error {
 			handler.EnqueueRequestsFromMapFunc(r.requeueClusterManagersForSubresources),
 			predicate.Funcs{
 				UpdateFunc: func(e event.UpdateEvent) bool {
-					return false
+					return isSubResource(e.ObjectNew) && isSubResourceChanged(e.ObjectOld, e.ObjectNew)
 				},
 				CreateFunc: func(e event.CreateEvent) bool {
 					return false
 				},
 				DeleteFunc: func(e event.DeleteEvent) bool {
-					_, ok := e.Object.GetLabels()[clusterV1alpha1.LabelKeyClmName]
-					return ok
+					return isSubResource(e.Object)
 				},
 				GenericFunc: func(e event.GenericEvent) bool {
 					return false
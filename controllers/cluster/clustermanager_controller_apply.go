@@ -0,0 +1,99 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	clusterV1alpha1 "github.com/tmax-cloud/hypercloud-multi-operator/apis/cluster/v1alpha1"
+	util "github.com/tmax-cloud/hypercloud-multi-operator/controllers/util"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// apply 는 hub cluster 의 리소스를 server-side apply 로 원하는 상태로 맞춘다.
+// operator 가 지정한 field 를 다른 manager 가 수정한 경우에도 operator 의 값으로 되돌린다.
+// obj 에는 operator 가 관리하는 field 만 채워져 있어야 하며, apply 후에는 서버의 object 로 갱신된다.
+// ClusterManager 와 같은 namespace 의 리소스는 owner reference 를 가지므로 ClusterManager 가 삭제되면 gc 로 함께 삭제되고,
+// 다른 namespace 의 리소스(ex. argocd cluster secret)는 label 로 추적하여 DeleteCrossNamespaceResources 에서 삭제한다.
+func (r *ClusterManagerReconciler) apply(clusterManager *clusterV1alpha1.ClusterManager, obj client.Object) (controllerutil.OperationResult, error) {
+	gvk, err := apiutil.GVKForObject(obj, r.Scheme)
+	if err != nil {
+		return controllerutil.OperationResultNone, err
+	}
+	if obj.GetNamespace() == clusterManager.Namespace {
+		if err := controllerutil.SetControllerReference(clusterManager, obj, r.Scheme); err != nil {
+			return controllerutil.OperationResultNone, err
+		}
+	}
+	labels := obj.GetLabels()
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	labels[clusterV1alpha1.LabelKeyClmName] = clusterManager.Name
+	labels[clusterV1alpha1.LabelKeyClmNamespace] = clusterManager.Namespace
+	obj.SetLabels(labels)
+	obj.GetObjectKind().SetGroupVersionKind(gvk)
+	obj.SetResourceVersion("")
+	obj.SetManagedFields(nil)
+
+	// 변경 여부를 구분하기 위해 현재 resource version 을 조회한다.
+	var current client.Object
+	if _, ok := obj.(*unstructured.Unstructured); ok {
+		current = &unstructured.Unstructured{}
+		current.GetObjectKind().SetGroupVersionKind(gvk)
+	} else {
+		newObj, err := r.Scheme.New(gvk)
+		if err != nil {
+			return controllerutil.OperationResultNone, err
+		}
+		current = newObj.(client.Object)
+	}
+	key := types.NamespacedName{
+		Name:      obj.GetName(),
+		Namespace: obj.GetNamespace(),
+	}
+	result := controllerutil.OperationResultUpdated
+	if err := r.Get(context.TODO(), key, current); errors.IsNotFound(err) {
+		result = controllerutil.OperationResultCreated
+	} else if err != nil {
+		return controllerutil.OperationResultNone, err
+	}
+
+	if err := r.Patch(context.TODO(), obj, client.Apply, client.FieldOwner(util.FieldOwner), client.ForceOwnership); err != nil {
+		return controllerutil.OperationResultNone, err
+	}
+
+	if result == controllerutil.OperationResultUpdated && current.GetResourceVersion() == obj.GetResourceVersion() {
+		return controllerutil.OperationResultNone, nil
+	}
+	return result, nil
+}
+
+// logApplyResult 는 apply 로 리소스가 생성되거나 변경된 경우에만 로그를 남긴다.
+func logApplyResult(log logr.Logger, result controllerutil.OperationResult, resource string) {
+	switch result {
+	case controllerutil.OperationResultCreated:
+		log.Info("Create " + resource + " successfully")
+	case controllerutil.OperationResultUpdated:
+		log.Info("Update " + resource + " successfully")
+	}
+}
@@ -0,0 +1,234 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	argocdV1alpha1 "github.com/argoproj/argo-cd/v2/pkg/apis/application/v1alpha1"
+	clusterV1alpha1 "github.com/tmax-cloud/hypercloud-multi-operator/apis/cluster/v1alpha1"
+	"github.com/tmax-cloud/hypercloud-multi-operator/controllers/util"
+
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// GetArgocdClusterAuthMode 는 argocd cluster secret 에 사용할 인증 방식을 반환한다.
+// ClusterManager 에 지정하지 않았거나 auto 인 경우 kubeconfig 의 user 로 판단한다.
+// kubeconfig 는 cluster 를 등록한 사용자가 제공하므로, exec 는 자동으로 선택하지 않는다.
+func GetArgocdClusterAuthMode(clusterManager *clusterV1alpha1.ClusterManager, authInfo *clientcmdapi.AuthInfo) clusterV1alpha1.ArgoClusterAuthMode {
+	if auth := clusterManager.Spec.ArgoClusterAuth; auth != nil &&
+		auth.Mode != "" && auth.Mode != clusterV1alpha1.ArgoClusterAuthModeAuto {
+		return auth.Mode
+	}
+
+	// client certificate 는 cluster admin 권한을 가지는 경우가 많으므로 자동으로 선택하지 않는다.
+	if authInfo == nil || authInfo.Exec == nil {
+		return clusterV1alpha1.ArgoClusterAuthModeBearerToken
+	}
+	if isAWSExecProvider(authInfo.Exec) {
+		return clusterV1alpha1.ArgoClusterAuthModeAWSIAM
+	}
+	return clusterV1alpha1.ArgoClusterAuthModeBearerToken
+}
+
+// newArgocdClusterConfig 는 인증 방식에 따라 argocd cluster secret 의 config 를 만든다.
+// token 은 bearerToken 방식에서만 사용한다.
+func newArgocdClusterConfig(
+	clusterManager *clusterV1alpha1.ClusterManager,
+	mode clusterV1alpha1.ArgoClusterAuthMode,
+	cluster *clientcmdapi.Cluster,
+	authInfo *clientcmdapi.AuthInfo,
+	token string,
+) (*argocdV1alpha1.ClusterConfig, error) {
+	config := &argocdV1alpha1.ClusterConfig{
+		TLSClientConfig: argocdV1alpha1.TLSClientConfig{
+			Insecure: false,
+			CAData:   cluster.CertificateAuthorityData,
+		},
+	}
+
+	switch mode {
+	case clusterV1alpha1.ArgoClusterAuthModeBearerToken:
+		config.BearerToken = token
+	case clusterV1alpha1.ArgoClusterAuthModeAWSIAM:
+		clusterName, roleARN := "", ""
+		if authInfo.Exec != nil {
+			clusterName, roleARN = parseAWSExecArgs(authInfo.Exec)
+		}
+		// awsIAM 은 자동으로 선택될 수 있으므로, kubeconfig 에 있는 role 은 operator 에 허용된 경우에만 사용한다.
+		if roleARN != "" && !containsArgs(getAllowedAWSRoleARNs(clusterManager), roleARN) {
+			roleARN = ""
+		}
+		if auth := clusterManager.Spec.ArgoClusterAuth; auth != nil {
+			if auth.AWSClusterName != "" {
+				clusterName = auth.AWSClusterName
+			}
+			if auth.AWSRoleARN != "" {
+				roleARN = auth.AWSRoleARN
+			}
+		}
+		if clusterName == "" {
+			return nil, fmt.Errorf("cannot find EKS cluster name for awsIAM auth mode")
+		}
+		config.AWSAuthConfig = &argocdV1alpha1.AWSAuthConfig{
+			ClusterName: clusterName,
+			RoleARN:     roleARN,
+		}
+	case clusterV1alpha1.ArgoClusterAuthModeExec:
+		if authInfo.Exec == nil {
+			return nil, fmt.Errorf("kubeconfig has no exec provider for exec auth mode")
+		}
+		// exec provider 의 command 는 hub 의 argocd application controller 에서 실행되므로
+		// operator 에 설정된 command 만 허용한다.
+		if !containsArgs(util.GetArgoClusterExecCommands(), authInfo.Exec.Command) {
+			return nil, fmt.Errorf("exec command [%s] is not allowed. allowed commands are [%s]",
+				authInfo.Exec.Command, strings.Join(util.GetArgoClusterExecCommands(), ", "))
+		}
+		if err := validateExecArgs(authInfo.Exec, getAllowedAWSRoleARNs(clusterManager)); err != nil {
+			return nil, err
+		}
+		env := map[string]string{}
+		for _, e := range authInfo.Exec.Env {
+			if containsArgs(util.ArgoClusterExecEnvs, e.Name) {
+				env[e.Name] = e.Value
+			}
+		}
+		config.ExecProviderConfig = &argocdV1alpha1.ExecProviderConfig{
+			Command:     authInfo.Exec.Command,
+			Args:        authInfo.Exec.Args,
+			Env:         env,
+			APIVersion:  authInfo.Exec.APIVersion,
+			InstallHint: authInfo.Exec.InstallHint,
+		}
+	case clusterV1alpha1.ArgoClusterAuthModeClientCertificate:
+		if len(authInfo.ClientCertificateData) == 0 || len(authInfo.ClientKeyData) == 0 {
+			return nil, fmt.Errorf("kubeconfig has no client certificate for clientCertificate auth mode")
+		}
+		config.TLSClientConfig.CertData = authInfo.ClientCertificateData
+		config.TLSClientConfig.KeyData = authInfo.ClientKeyData
+	default:
+		return nil, fmt.Errorf("unknown argocd cluster auth mode [%s]", mode)
+	}
+
+	return config, nil
+}
+
+// isAWSExecProvider 는 exec provider 가 EKS token 을 발급하는지 반환한다.
+// (aws eks get-token, aws-iam-authenticator token)
+func isAWSExecProvider(exec *clientcmdapi.ExecConfig) bool {
+	switch filepath.Base(exec.Command) {
+	case "aws":
+		return containsArgs(exec.Args, "eks", "get-token")
+	case "aws-iam-authenticator":
+		return containsArgs(exec.Args, "token")
+	}
+	return false
+}
+
+// parseAWSExecArgs 는 exec provider 의 args 에서 EKS cluster 이름과 IAM role 을 찾는다.
+func parseAWSExecArgs(exec *clientcmdapi.ExecConfig) (string, string) {
+	clusterNameFlags := []string{"--cluster-name", "-i", "--cluster-id"}
+	roleFlags := []string{"--role-arn", "-r", "--role"}
+
+	clusterName, roleARN := "", ""
+	for i := 0; i < len(exec.Args); i++ {
+		flag, value := exec.Args[i], ""
+		if index := strings.Index(flag, "="); index != -1 {
+			flag, value = flag[:index], flag[index+1:]
+		} else if i+1 < len(exec.Args) {
+			value = exec.Args[i+1]
+		}
+
+		if containsArgs(clusterNameFlags, flag) {
+			clusterName = value
+		} else if containsArgs(roleFlags, flag) {
+			roleARN = value
+		}
+	}
+
+	return clusterName, roleARN
+}
+
+// validateExecArgs 는 exec provider 의 args 가 command 별로 허용된 형태인지 확인한다.
+// 허용된 command 라도 args 로 다른 동작을 하거나 허용되지 않은 role 을 사용할 수 있으므로 args 도 검증한다.
+func validateExecArgs(exec *clientcmdapi.ExecConfig, allowedRoleARNs []string) error {
+	rule, ok := util.ArgoClusterExecArgs[filepath.Base(exec.Command)]
+	if !ok {
+		if len(exec.Args) > 0 {
+			return fmt.Errorf("exec command [%s] does not allow args", exec.Command)
+		}
+		return nil
+	}
+
+	if len(exec.Args) < len(rule.Subcommand) {
+		return fmt.Errorf("exec args of [%s] must start with [%s]", exec.Command, strings.Join(rule.Subcommand, " "))
+	}
+	for i, subcommand := range rule.Subcommand {
+		if exec.Args[i] != subcommand {
+			return fmt.Errorf("exec args of [%s] must start with [%s]", exec.Command, strings.Join(rule.Subcommand, " "))
+		}
+	}
+
+	for i := len(rule.Subcommand); i < len(exec.Args); i++ {
+		flag, value := exec.Args[i], ""
+		if index := strings.Index(flag, "="); index != -1 {
+			flag, value = flag[:index], flag[index+1:]
+		} else if i+1 < len(exec.Args) {
+			i++
+			value = exec.Args[i]
+		}
+
+		if !containsArgs(rule.Flags, flag) {
+			return fmt.Errorf("exec arg [%s] of [%s] is not allowed. allowed flags are [%s]", flag, exec.Command, strings.Join(rule.Flags, ", "))
+		}
+		if value == "" || strings.HasPrefix(value, "-") {
+			return fmt.Errorf("exec arg [%s] of [%s] has no value", flag, exec.Command)
+		}
+		if containsArgs(rule.RoleFlags, flag) && !containsArgs(allowedRoleARNs, value) {
+			return fmt.Errorf("IAM role [%s] of exec args is not allowed", value)
+		}
+	}
+
+	return nil
+}
+
+// getAllowedAWSRoleARNs 는 kubeconfig 에 지정된 대로 사용할 수 있는 IAM role 목록을 반환한다.
+// operator 에 허용된 role 과 ClusterManager 에 지정한 role 을 사용할 수 있다.
+func getAllowedAWSRoleARNs(clusterManager *clusterV1alpha1.ClusterManager) []string {
+	roleARNs := util.GetArgoClusterAWSRoleARNs()
+	if auth := clusterManager.Spec.ArgoClusterAuth; auth != nil && auth.AWSRoleARN != "" {
+		roleARNs = append(roleARNs, auth.AWSRoleARN)
+	}
+	return roleARNs
+}
+
+// containsArgs 는 args 에 values 가 모두 있는지 반환한다.
+func containsArgs(args []string, values ...string) bool {
+	for _, value := range values {
+		found := false
+		for _, arg := range args {
+			if arg == value {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,346 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	clusterV1alpha1 "github.com/tmax-cloud/hypercloud-multi-operator/apis/cluster/v1alpha1"
+	util "github.com/tmax-cloud/hypercloud-multi-operator/controllers/util"
+
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+const (
+	testAllowedRoleARN = "arn:aws:iam::123456789012:role/argocd"
+	testOtherRoleARN   = "arn:aws:iam::123456789012:role/admin"
+)
+
+func setAllowedRoleARNs(t *testing.T, roleARNs string) {
+	os.Setenv(util.ARGO_CLUSTER_AWS_ROLE_ARNS, roleARNs)
+	t.Cleanup(func() {
+		os.Unsetenv(util.ARGO_CLUSTER_AWS_ROLE_ARNS)
+	})
+}
+
+func newClusterManagerWithAuth(auth *clusterV1alpha1.ArgoClusterAuth) *clusterV1alpha1.ClusterManager {
+	clm := &clusterV1alpha1.ClusterManager{}
+	clm.Spec.ArgoClusterAuth = auth
+	return clm
+}
+
+func TestGetArgocdClusterAuthMode(t *testing.T) {
+	tests := []struct {
+		name     string
+		auth     *clusterV1alpha1.ArgoClusterAuth
+		authInfo *clientcmdapi.AuthInfo
+		want     clusterV1alpha1.ArgoClusterAuthMode
+	}{
+		{
+			name:     "no auth info",
+			authInfo: nil,
+			want:     clusterV1alpha1.ArgoClusterAuthModeBearerToken,
+		},
+		{
+			name:     "token user",
+			authInfo: &clientcmdapi.AuthInfo{Token: "token"},
+			want:     clusterV1alpha1.ArgoClusterAuthModeBearerToken,
+		},
+		{
+			name:     "client certificate is not selected automatically",
+			authInfo: &clientcmdapi.AuthInfo{ClientCertificateData: []byte("cert"), ClientKeyData: []byte("key")},
+			want:     clusterV1alpha1.ArgoClusterAuthModeBearerToken,
+		},
+		{
+			name: "aws eks get-token",
+			authInfo: &clientcmdapi.AuthInfo{Exec: &clientcmdapi.ExecConfig{
+				Command: "aws",
+				Args:    []string{"eks", "get-token", "--cluster-name", "eks"},
+			}},
+			want: clusterV1alpha1.ArgoClusterAuthModeAWSIAM,
+		},
+		{
+			name: "aws-iam-authenticator token",
+			authInfo: &clientcmdapi.AuthInfo{Exec: &clientcmdapi.ExecConfig{
+				Command: "/usr/local/bin/aws-iam-authenticator",
+				Args:    []string{"token", "-i", "eks"},
+			}},
+			want: clusterV1alpha1.ArgoClusterAuthModeAWSIAM,
+		},
+		{
+			name: "other aws command",
+			authInfo: &clientcmdapi.AuthInfo{Exec: &clientcmdapi.ExecConfig{
+				Command: "aws",
+				Args:    []string{"sts", "get-caller-identity"},
+			}},
+			want: clusterV1alpha1.ArgoClusterAuthModeBearerToken,
+		},
+		{
+			name: "exec is not selected automatically",
+			authInfo: &clientcmdapi.AuthInfo{Exec: &clientcmdapi.ExecConfig{
+				Command: "kubelogin",
+				Args:    []string{"get-token"},
+			}},
+			want: clusterV1alpha1.ArgoClusterAuthModeBearerToken,
+		},
+		{
+			name: "auto mode",
+			auth: &clusterV1alpha1.ArgoClusterAuth{Mode: clusterV1alpha1.ArgoClusterAuthModeAuto},
+			authInfo: &clientcmdapi.AuthInfo{Exec: &clientcmdapi.ExecConfig{
+				Command: "aws",
+				Args:    []string{"eks", "get-token", "--cluster-name", "eks"},
+			}},
+			want: clusterV1alpha1.ArgoClusterAuthModeAWSIAM,
+		},
+		{
+			name:     "mode on spec",
+			auth:     &clusterV1alpha1.ArgoClusterAuth{Mode: clusterV1alpha1.ArgoClusterAuthModeClientCertificate},
+			authInfo: &clientcmdapi.AuthInfo{Token: "token"},
+			want:     clusterV1alpha1.ArgoClusterAuthModeClientCertificate,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := GetArgocdClusterAuthMode(newClusterManagerWithAuth(tt.auth), tt.authInfo); got != tt.want {
+				t.Errorf("GetArgocdClusterAuthMode() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseAWSExecArgs(t *testing.T) {
+	tests := []struct {
+		name            string
+		args            []string
+		wantClusterName string
+		wantRoleARN     string
+	}{
+		{
+			name:            "aws eks get-token",
+			args:            []string{"eks", "get-token", "--cluster-name", "eks", "--role-arn", testAllowedRoleARN},
+			wantClusterName: "eks",
+			wantRoleARN:     testAllowedRoleARN,
+		},
+		{
+			name:            "flags with equal sign",
+			args:            []string{"eks", "get-token", "--cluster-name=eks", "--role-arn=" + testAllowedRoleARN},
+			wantClusterName: "eks",
+			wantRoleARN:     testAllowedRoleARN,
+		},
+		{
+			name:            "aws-iam-authenticator short flags",
+			args:            []string{"token", "-i", "eks", "-r", testAllowedRoleARN},
+			wantClusterName: "eks",
+			wantRoleARN:     testAllowedRoleARN,
+		},
+		{
+			name:            "aws-iam-authenticator long flags",
+			args:            []string{"token", "--cluster-id", "eks", "--role", testAllowedRoleARN},
+			wantClusterName: "eks",
+			wantRoleARN:     testAllowedRoleARN,
+		},
+		{
+			name:            "no role",
+			args:            []string{"eks", "get-token", "--region", "ap-northeast-2", "--cluster-name", "eks"},
+			wantClusterName: "eks",
+		},
+		{
+			name: "flag without value",
+			args: []string{"eks", "get-token", "--cluster-name"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clusterName, roleARN := parseAWSExecArgs(&clientcmdapi.ExecConfig{Args: tt.args})
+			if clusterName != tt.wantClusterName || roleARN != tt.wantRoleARN {
+				t.Errorf("parseAWSExecArgs() = %q, %q, want %q, %q", clusterName, roleARN, tt.wantClusterName, tt.wantRoleARN)
+			}
+		})
+	}
+}
+
+func TestNewArgocdClusterConfigAWSIAMRole(t *testing.T) {
+	setAllowedRoleARNs(t, testAllowedRoleARN)
+
+	tests := []struct {
+		name        string
+		auth        *clusterV1alpha1.ArgoClusterAuth
+		roleARN     string
+		wantRoleARN string
+	}{
+		{
+			name:        "allowed role of kubeconfig",
+			roleARN:     testAllowedRoleARN,
+			wantRoleARN: testAllowedRoleARN,
+		},
+		{
+			name:        "role of kubeconfig is ignored unless allowed",
+			roleARN:     testOtherRoleARN,
+			wantRoleARN: "",
+		},
+		{
+			name:        "role on spec",
+			auth:        &clusterV1alpha1.ArgoClusterAuth{AWSRoleARN: testOtherRoleARN},
+			roleARN:     testAllowedRoleARN,
+			wantRoleARN: testOtherRoleARN,
+		},
+		{
+			name:        "role of kubeconfig is allowed when it is on spec",
+			auth:        &clusterV1alpha1.ArgoClusterAuth{AWSRoleARN: testOtherRoleARN},
+			roleARN:     testOtherRoleARN,
+			wantRoleARN: testOtherRoleARN,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			authInfo := &clientcmdapi.AuthInfo{Exec: &clientcmdapi.ExecConfig{
+				Command: "aws",
+				Args:    []string{"eks", "get-token", "--cluster-name", "eks", "--role-arn", tt.roleARN},
+			}}
+			config, err := newArgocdClusterConfig(newClusterManagerWithAuth(tt.auth), clusterV1alpha1.ArgoClusterAuthModeAWSIAM, &clientcmdapi.Cluster{}, authInfo, "")
+			if err != nil {
+				t.Fatalf("newArgocdClusterConfig() error = %v", err)
+			}
+			if config.AWSAuthConfig.ClusterName != "eks" {
+				t.Errorf("ClusterName = %q, want %q", config.AWSAuthConfig.ClusterName, "eks")
+			}
+			if config.AWSAuthConfig.RoleARN != tt.wantRoleARN {
+				t.Errorf("RoleARN = %q, want %q", config.AWSAuthConfig.RoleARN, tt.wantRoleARN)
+			}
+		})
+	}
+}
+
+func TestValidateExecArgs(t *testing.T) {
+	allowed := []string{testAllowedRoleARN}
+
+	tests := []struct {
+		name    string
+		command string
+		args    []string
+		wantErr string
+	}{
+		{
+			name:    "aws eks get-token",
+			command: "aws",
+			args:    []string{"eks", "get-token", "--cluster-name", "eks", "--region", "ap-northeast-2"},
+		},
+		{
+			name:    "aws eks get-token with allowed role",
+			command: "aws",
+			args:    []string{"eks", "get-token", "--cluster-name=eks", "--role-arn=" + testAllowedRoleARN},
+		},
+		{
+			name:    "aws eks get-token with other role",
+			command: "aws",
+			args:    []string{"eks", "get-token", "--cluster-name", "eks", "--role-arn", testOtherRoleARN},
+			wantErr: "is not allowed",
+		},
+		{
+			name:    "aws other subcommand",
+			command: "aws",
+			args:    []string{"s3", "cp", "s3://bucket/script", "/tmp/script"},
+			wantErr: "must start with",
+		},
+		{
+			name:    "aws without subcommand",
+			command: "aws",
+			args:    []string{"eks"},
+			wantErr: "must start with",
+		},
+		{
+			name:    "aws profile flag",
+			command: "aws",
+			args:    []string{"eks", "get-token", "--cluster-name", "eks", "--profile", "admin"},
+			wantErr: "is not allowed",
+		},
+		{
+			name:    "aws endpoint flag",
+			command: "aws",
+			args:    []string{"eks", "get-token", "--cluster-name", "eks", "--endpoint-url=https://attacker"},
+			wantErr: "is not allowed",
+		},
+		{
+			name:    "positional arg",
+			command: "aws",
+			args:    []string{"eks", "get-token", "extra"},
+			wantErr: "is not allowed",
+		},
+		{
+			name:    "flag without value",
+			command: "aws",
+			args:    []string{"eks", "get-token", "--cluster-name"},
+			wantErr: "has no value",
+		},
+		{
+			name:    "flag followed by flag",
+			command: "aws",
+			args:    []string{"eks", "get-token", "--cluster-name", "--region", "ap-northeast-2"},
+			wantErr: "has no value",
+		},
+		{
+			name:    "aws-iam-authenticator token",
+			command: "/usr/local/bin/aws-iam-authenticator",
+			args:    []string{"token", "-i", "eks", "-r", testAllowedRoleARN},
+		},
+		{
+			name:    "aws-iam-authenticator other role",
+			command: "aws-iam-authenticator",
+			args:    []string{"token", "-i", "eks", "--role", testOtherRoleARN},
+			wantErr: "is not allowed",
+		},
+		{
+			name:    "argocd-k8s-auth aws",
+			command: "argocd-k8s-auth",
+			args:    []string{"aws", "--cluster-name", "eks"},
+		},
+		{
+			name:    "argocd-k8s-auth other provider",
+			command: "argocd-k8s-auth",
+			args:    []string{"gcp"},
+			wantErr: "must start with",
+		},
+		{
+			name:    "command without rule and args",
+			command: "gke-gcloud-auth-plugin",
+		},
+		{
+			name:    "command without rule with args",
+			command: "gke-gcloud-auth-plugin",
+			args:    []string{"--version"},
+			wantErr: "does not allow args",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateExecArgs(&clientcmdapi.ExecConfig{Command: tt.command, Args: tt.args}, allowed)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("validateExecArgs() error = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("validateExecArgs() error = %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
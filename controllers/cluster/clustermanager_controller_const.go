@@ -7,6 +7,8 @@ const (
 	requeueAfter20Second = 20 * time.Second
 	requeueAfter30Second = 30 * time.Second
 	requeueAfter1Minute  = 1 * time.Minute
+	requeueAfter5Minute  = 5 * time.Minute
+	requeueAfter1Hour    = 1 * time.Hour
 )
 
 const (
@@ -18,3 +20,60 @@ const (
 	CAPI_CONTROLPLANE_LABEL_KEY = "cluster.x-k8s.io/control-plane"
 	CAPI_WORKER_LABEL_KEY       = "cluster.x-k8s.io/deployment-name"
 )
+
+// reconcile phase 이름
+const (
+	phaseReadyReconcile               = "ReadyReconcilePhase"
+	phaseUpdateClusterManagerStatus   = "UpdateClusterManagerStatus"
+	phaseCreateServiceInstance        = "CreateServiceInstance"
+	phaseSetEndpoint                  = "SetEndpoint"
+	phaseKubeadmControlPlaneUpdate    = "KubeadmControlPlaneUpdate"
+	phaseMachineDeploymentUpdate      = "MachineDeploymentUpdate"
+	phaseCreateUpgradeServiceInstance = "CreateUpgradeServiceInstance"
+	phaseClusterUpgrade               = "ClusterUpgrade"
+	phaseControlplaneScaling          = "ControlplaneScaling"
+	phaseWorkerScaling                = "WorkerScaling"
+	phaseWatchRemoteResources         = "WatchRemoteResources"
+	phaseCreateArgocdResources        = "CreateArgocdResources"
+	phaseRefreshServiceAccountTokens  = "RefreshServiceAccountTokens"
+	phaseSyncArgocdProject            = "SyncArgocdProject"
+	phaseSyncArgocdClusterLabels      = "SyncArgocdClusterLabels"
+	phaseSyncApplication              = "SyncApplication"
+	phaseSyncApplicationStatus        = "SyncApplicationStatus"
+	phaseCreateGatewayResources       = "CreateGatewayResources"
+	phaseSyncGatewayAddresses         = "SyncGatewayAddresses"
+	phaseCreateHyperAuthResources     = "CreateHyperAuthResources"
+	phaseSyncHyperAuthResources       = "SyncHyperAuthResources"
+	phaseCreateTraefikResources       = "CreateTraefikResources"
+)
+
+// GatewayTLSVerified condition 의 reason
+const (
+	gatewayTLSReasonVerified           = "Verified"
+	gatewayTLSReasonVerificationFailed = "VerificationFailed"
+	gatewayTLSReasonCANotFound         = "CANotFound"
+	gatewayTLSReasonUnreachable        = "Unreachable"
+)
+
+// HyperAuthSynced condition 의 reason
+const (
+	hyperAuthReasonSynced         = "Synced"
+	hyperAuthReasonSyncFailed     = "SyncFailed"
+	hyperAuthReasonSecretNotFound = "PasswordSecretNotFound"
+)
+
+// traffic policy 로 만드는 middleware 이름의 suffix
+const (
+	trafficPolicyIPAllowListSuffix = "-ipallowlist"
+	trafficPolicyRateLimitSuffix   = "-ratelimit"
+	trafficPolicyInFlightSuffix    = "-inflight"
+	trafficPolicyHeadersSuffix     = "-headers"
+)
+
+// middleware chain 에 추가되는 순서
+var trafficPolicyMiddlewareSuffixes = []string{
+	trafficPolicyIPAllowListSuffix,
+	trafficPolicyRateLimitSuffix,
+	trafficPolicyInFlightSuffix,
+	trafficPolicyHeadersSuffix,
+}
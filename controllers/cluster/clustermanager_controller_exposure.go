@@ -0,0 +1,504 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	clusterV1alpha1 "github.com/tmax-cloud/hypercloud-multi-operator/apis/cluster/v1alpha1"
+	util "github.com/tmax-cloud/hypercloud-multi-operator/controllers/util"
+
+	coreV1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+var (
+	httpRouteGVK = schema.GroupVersionKind{
+		Group:   "gateway.networking.k8s.io",
+		Version: "v1",
+		Kind:    "HTTPRoute",
+	}
+	backendTLSPolicyGVK = schema.GroupVersionKind{
+		Group:   "gateway.networking.k8s.io",
+		Version: "v1alpha3",
+		Kind:    "BackendTLSPolicy",
+	}
+)
+
+// ExposureBackend 는 hub 에서 single cluster 의 api 들을 외부로 노출하는 방식이다.
+// 사용할 backend 는 operator 설정(EXPOSURE_BACKEND)으로 선택하며,
+// 사용한 backend 는 ClusterManager 의 annotation 에 기록하여 삭제시에 같은 backend 로 정리한다.
+type ExposureBackend interface {
+	Name() string
+	// CreateGatewayTLS 는 hub 에서 single cluster 의 gateway 로 TLS 연결을 검증하기 위한 리소스를 만들고,
+	// gateway service 에 추가할 annotation 을 반환한다. ca 가 없으면 검증하지 않는다.
+	CreateGatewayTLS(clusterManager *clusterV1alpha1.ClusterManager, ca []byte, serverName string) (map[string]string, error)
+	// Expose 는 single cluster 의 api 들을 multicluster host 로 노출하는 리소스를 만든다.
+	Expose(clusterManager *clusterV1alpha1.ClusterManager) error
+	// Delete 는 backend 가 만든 리소스들을 삭제한다.
+	Delete(clusterManager *clusterV1alpha1.ClusterManager) error
+}
+
+// GetExposureBackend 는 이름에 해당하는 backend 를 반환한다. 알 수 없는 이름이면 traefik backend 를 반환한다.
+func (r *ClusterManagerReconciler) GetExposureBackend(name string) ExposureBackend {
+	if name == util.ExposureBackendGatewayAPI {
+		return &gatewayAPIExposure{r: r}
+	}
+	return &traefikExposure{r: r}
+}
+
+// traefikExposure 는 networking.k8s.io Ingress 와 traefik Middleware, ServersTransport 로 노출한다.
+type traefikExposure struct {
+	r *ClusterManagerReconciler
+}
+
+func (e *traefikExposure) Name() string {
+	return util.ExposureBackendTraefik
+}
+
+func (e *traefikExposure) CreateGatewayTLS(clusterManager *clusterV1alpha1.ClusterManager, ca []byte, serverName string) (map[string]string, error) {
+	serversTransport := "insecure@file"
+	if len(ca) != 0 {
+		var err error
+		serversTransport, err = e.r.CreateGatewayServersTransport(clusterManager, ca, serverName)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return map[string]string{
+		util.AnnotationKeyTraefikServerScheme:    "https",
+		util.AnnotationKeyTraefikServerTransport: serversTransport,
+	}, nil
+}
+
+func (e *traefikExposure) Expose(clusterManager *clusterV1alpha1.ClusterManager) error {
+	if err := e.r.CreateCertificate(clusterManager); err != nil {
+		return err
+	}
+
+	if err := e.r.CreateMiddleware(clusterManager); err != nil {
+		return err
+	}
+
+	before, after, err := e.r.CreateTrafficPolicyMiddlewares(clusterManager)
+	if err != nil {
+		return err
+	}
+
+	middlewares, proxyMiddlewares := newIngressMiddlewares(clusterManager, before, after)
+	if err := e.r.CreateIngress(clusterManager, middlewares); err != nil {
+		return err
+	}
+
+	if err := e.r.CreateProxyIngress(clusterManager, proxyMiddlewares); err != nil {
+		return err
+	}
+
+	// ingress 의 tls secret 이 만들어질 때 까지 기다린다.
+	return e.r.CheckCertificateReady(clusterManager)
+}
+
+// newIngressMiddlewares 는 api 들의 ingress 와 cluster proxy ingress 의 middleware annotation 값을 만든다.
+// traffic policy 로 요청을 먼저 제한한 뒤 jwt 검증과 prefix 제거를 거쳐 header 를 추가한다.
+// cluster proxy 는 token 을 직접 검증하고 전체 path 를 사용하므로 traffic policy 의 middleware 들만 사용한다.
+func newIngressMiddlewares(clusterManager *clusterV1alpha1.ClusterManager, before, after []string) (string, string) {
+	middlewares := append([]string{}, before...)
+	middlewares = append(middlewares,
+		"api-gateway-system-jwt-decode-auth@kubernetescrd",
+		clusterManager.GetNamespacedPrefix()+"-prefix@kubernetescrd",
+	)
+	middlewares = append(middlewares, after...)
+
+	proxyMiddlewares := append(append([]string{}, before...), after...)
+	return strings.Join(middlewares, ","), strings.Join(proxyMiddlewares, ",")
+}
+
+func (e *traefikExposure) Delete(clusterManager *clusterV1alpha1.ClusterManager) error {
+	if err := e.r.DeleteCertificate(clusterManager); err != nil {
+		return err
+	}
+
+	if err := e.r.DeleteCertSecret(clusterManager); err != nil {
+		return err
+	}
+
+	if err := e.r.DeleteIngress(clusterManager); err != nil {
+		return err
+	}
+
+	if err := e.r.DeleteProxyIngress(clusterManager); err != nil {
+		return err
+	}
+
+	if err := e.r.DeleteMiddleware(clusterManager); err != nil {
+		return err
+	}
+
+	if err := e.r.DeleteTrafficPolicyMiddlewares(clusterManager); err != nil {
+		return err
+	}
+
+	return e.r.DeleteGatewayServersTransport(clusterManager)
+}
+
+// gatewayAPIExposure 는 operator 설정(GATEWAY_API_PARENT)의 Gateway 에 연결되는 HTTPRoute 와 BackendTLSPolicy 로 노출한다.
+// console 이 노출된 cluster 를 찾을 수 있도록 요청을 처리하지 않는 조회용 ingress 를 함께 만든다.
+// listener 의 certificate 는 Gateway 에서 관리하므로 Certificate 는 만들지 않는다.
+// jwt 인증은 operator 설정(GATEWAY_API_AUTH_POLICY)의 Gateway policy 가 처리하며, policy 가 없으면 api 들을 노출하지 않는다.
+// single cluster 의 gateway 주소가 hostname 인 경우 gateway service 는 ExternalName service 가 되는데,
+// 대부분의 Gateway 구현체는 ExternalName service 를 backend 로 허용하지 않으므로 ip 주소를 가지는 gateway 를 사용해야 한다.
+type gatewayAPIExposure struct {
+	r *ClusterManagerReconciler
+}
+
+func (e *gatewayAPIExposure) Name() string {
+	return util.ExposureBackendGatewayAPI
+}
+
+func (e *gatewayAPIExposure) CreateGatewayTLS(clusterManager *clusterV1alpha1.ClusterManager, ca []byte, serverName string) (map[string]string, error) {
+	log := e.r.Log.WithValues("clustermanager", clusterManager.GetNamespacedName())
+
+	// BackendTLSPolicy 는 hostname 이 필요하므로, server name 이 없는 경우(ip)에는 검증하지 않는다.
+	if len(ca) == 0 || serverName == "" {
+		log.Info("Skip BackendTLSPolicy for gateway. CA or server name is not found")
+		return map[string]string{}, nil
+	}
+
+	key := types.NamespacedName{
+		Name:      clusterManager.Name + "-gateway-ca",
+		Namespace: clusterManager.Namespace,
+	}
+	configMap := &coreV1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        key.Name,
+			Namespace:   key.Namespace,
+			Annotations: exposureAnnotations(clusterManager),
+			Labels:      exposureLabels(clusterManager),
+		},
+		Data: map[string]string{
+			util.SecretKeyCA: string(ca),
+		},
+	}
+	result, err := e.r.apply(clusterManager, configMap)
+	if err != nil {
+		log.Error(err, "Failed to apply ConfigMap for gateway CA")
+		return nil, err
+	}
+	logApplyResult(log, result, "ConfigMap for gateway CA")
+
+	policy := newUnstructured(backendTLSPolicyGVK, clusterManager, clusterManager.Name+"-gateway-tls")
+	policy.Object["spec"] = map[string]interface{}{
+		"targetRefs": []interface{}{
+			map[string]interface{}{
+				"group": "",
+				"kind":  "Service",
+				"name":  clusterManager.Name + "-gateway-service",
+			},
+		},
+		"validation": map[string]interface{}{
+			"caCertificateRefs": []interface{}{
+				map[string]interface{}{
+					"group": "",
+					"kind":  "ConfigMap",
+					"name":  key.Name,
+				},
+			},
+			"hostname": serverName,
+		},
+	}
+	if err := e.r.applyUnstructured(clusterManager, policy); err != nil {
+		return nil, err
+	}
+
+	return map[string]string{}, nil
+}
+
+func (e *gatewayAPIExposure) Expose(clusterManager *clusterV1alpha1.ClusterManager) error {
+	log := e.r.Log.WithValues("clustermanager", clusterManager.GetNamespacedName())
+
+	if clusterManager.Spec.TrafficPolicy != nil {
+		log.Info("TrafficPolicy is only supported by traefik exposure backend, skip it")
+	}
+
+	parentNamespace, parentName, err := util.GetGatewayAPIParent()
+	if err != nil {
+		return err
+	}
+	parentRefs := []interface{}{
+		map[string]interface{}{
+			"namespace": parentNamespace,
+			"name":      parentName,
+		},
+	}
+	hostnames := []interface{}{
+		GetMulticlusterHost(clusterManager),
+	}
+	urlPath := "/api/" + clusterManager.Namespace + "/" + clusterManager.Name
+
+	// cluster proxy 는 직접 token 을 검증하므로 auth policy 와 관계 없이 노출한다.
+	// 전체 path 를 사용하므로 prefix 를 제거하지 않는다.
+	proxyRoute := newUnstructured(httpRouteGVK, clusterManager, clusterManager.Name+"-proxy-route")
+	proxyRoute.Object["spec"] = map[string]interface{}{
+		"parentRefs": parentRefs,
+		"hostnames":  hostnames,
+		"rules": []interface{}{
+			map[string]interface{}{
+				"matches": []interface{}{
+					map[string]interface{}{
+						"path": map[string]interface{}{
+							"type":  "PathPrefix",
+							"value": urlPath + "/api/kubernetes",
+						},
+					},
+				},
+				"backendRefs": []interface{}{
+					map[string]interface{}{
+						"name": clusterManager.Name + "-proxy-service",
+						"port": int64(util.ClusterProxyPort),
+					},
+				},
+			},
+		},
+	}
+	if err := e.r.applyUnstructured(clusterManager, proxyRoute); err != nil {
+		return err
+	}
+
+	// 인증 없이 api 가 노출되지 않도록, auth policy 를 확인할 수 없으면 이전에 만든 route 도 지운다.
+	route := newUnstructured(httpRouteGVK, clusterManager, clusterManager.Name+"-route")
+	if err := e.checkAuthPolicy(clusterManager, parentNamespace, parentName); err != nil {
+		if err := e.r.Delete(context.TODO(), route); err != nil && !errors.IsNotFound(err) && !meta.IsNoMatchError(err) {
+			log.Error(err, "Failed to delete HTTPRoute ["+route.GetName()+"]")
+			return err
+		}
+		if err := e.r.DeleteIngress(clusterManager); err != nil {
+			return err
+		}
+		return err
+	}
+
+	// traefik 의 prefix middleware 와 같이 /api/<namespace>/<cluster> prefix 를 제거하여 gateway 로 전달한다.
+	rules := []interface{}{}
+	for _, exposedPath := range GetExposedPaths(clusterManager) {
+		rules = append(rules, map[string]interface{}{
+			"matches": []interface{}{
+				map[string]interface{}{
+					"path": map[string]interface{}{
+						"type":  "PathPrefix",
+						"value": urlPath + "/api/" + exposedPath,
+					},
+				},
+			},
+			"filters": []interface{}{
+				map[string]interface{}{
+					"type": "URLRewrite",
+					"urlRewrite": map[string]interface{}{
+						"path": map[string]interface{}{
+							"type":               "ReplacePrefixMatch",
+							"replacePrefixMatch": "/api/" + exposedPath,
+						},
+					},
+				},
+			},
+			"backendRefs": []interface{}{
+				map[string]interface{}{
+					"name": clusterManager.Name + "-gateway-service",
+					"port": int64(443),
+				},
+			},
+		})
+	}
+	route.Object["spec"] = map[string]interface{}{
+		"parentRefs": parentRefs,
+		"hostnames":  hostnames,
+		"rules":      rules,
+	}
+	if err := e.r.applyUnstructured(clusterManager, route); err != nil {
+		return err
+	}
+
+	// console 은 multicluster label 을 가지는 ingress 로 노출된 cluster 의 api 들을 찾으므로, 조회용 ingress 를 유지한다.
+	return e.r.CreateDiscoveryIngress(clusterManager)
+}
+
+// checkAuthPolicy 는 single cluster 의 api 요청을 인증하는 Gateway 의 policy 가 설정되어 있고,
+// 존재하며, route 가 연결되는 Gateway 나 route 에 적용되는지 확인한다.
+func (e *gatewayAPIExposure) checkAuthPolicy(clusterManager *clusterV1alpha1.ClusterManager, parentNamespace, parentName string) error {
+	log := e.r.Log.WithValues("clustermanager", clusterManager.GetNamespacedName())
+
+	groupKind, namespace, name, err := util.GetGatewayAPIAuthPolicy()
+	if err != nil {
+		log.Error(err, "Refuse to expose APIs of remote cluster without auth policy")
+		return err
+	}
+	mapping, err := e.r.RESTMapper().RESTMapping(groupKind)
+	if err != nil {
+		log.Error(err, "Failed to find auth policy kind ["+groupKind.String()+"]")
+		return err
+	}
+
+	policy := &unstructured.Unstructured{}
+	policy.SetGroupVersionKind(mapping.GroupVersionKind)
+	key := types.NamespacedName{
+		Name:      name,
+		Namespace: namespace,
+	}
+	if err := e.r.Get(context.TODO(), key, policy); err != nil {
+		log.Error(err, "Failed to get auth policy ["+groupKind.String()+"/"+key.String()+"]")
+		return err
+	}
+
+	if err := validateAuthPolicyTargetRefs(policy, clusterManager, parentNamespace, parentName); err != nil {
+		log.Error(err, "Refuse to expose APIs of remote cluster with auth policy for other targets")
+		return err
+	}
+
+	return nil
+}
+
+// validateAuthPolicyTargetRefs 는 policy 의 targetRef(s) 중 하나가 route 가 연결되는 Gateway 전체나 api 들의 HTTPRoute 를 가리키는지 확인한다.
+// 특정 listener(sectionName)에만 적용되는 policy 는 multicluster host 의 listener 인지 알 수 없으므로 허용하지 않는다.
+// targetRef 에 namespace 가 없으면 policy 의 namespace 를 사용한다.
+func validateAuthPolicyTargetRefs(policy *unstructured.Unstructured, clusterManager *clusterV1alpha1.ClusterManager, parentNamespace, parentName string) error {
+	targetRefs, _, err := unstructured.NestedSlice(policy.Object, "spec", "targetRefs")
+	if err != nil {
+		return err
+	}
+	// 이전 policy attachment 방식은 하나의 targetRef 를 사용한다.
+	if targetRef, found, err := unstructured.NestedMap(policy.Object, "spec", "targetRef"); err != nil {
+		return err
+	} else if found {
+		targetRefs = append(targetRefs, targetRef)
+	}
+
+	for _, item := range targetRefs {
+		targetRef, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		group, _, _ := unstructured.NestedString(targetRef, "group")
+		kind, _, _ := unstructured.NestedString(targetRef, "kind")
+		name, _, _ := unstructured.NestedString(targetRef, "name")
+		namespace, _, _ := unstructured.NestedString(targetRef, "namespace")
+		sectionName, _, _ := unstructured.NestedString(targetRef, "sectionName")
+		if namespace == "" {
+			namespace = policy.GetNamespace()
+		}
+		if group != httpRouteGVK.Group || sectionName != "" {
+			continue
+		}
+
+		switch {
+		case kind == "Gateway" && namespace == parentNamespace && name == parentName:
+			return nil
+		case kind == httpRouteGVK.Kind && namespace == clusterManager.Namespace && name == clusterManager.Name+"-route":
+			return nil
+		}
+	}
+
+	return fmt.Errorf("auth policy [%s/%s] does not target Gateway [%s/%s] or HTTPRoute [%s/%s]",
+		policy.GetNamespace(), policy.GetName(), parentNamespace, parentName, clusterManager.Namespace, clusterManager.Name+"-route")
+}
+
+func (e *gatewayAPIExposure) Delete(clusterManager *clusterV1alpha1.ClusterManager) error {
+	log := e.r.Log.WithValues("clustermanager", clusterManager.GetNamespacedName())
+
+	targets := []*unstructured.Unstructured{
+		newUnstructured(httpRouteGVK, clusterManager, clusterManager.Name+"-route"),
+		newUnstructured(httpRouteGVK, clusterManager, clusterManager.Name+"-proxy-route"),
+		newUnstructured(backendTLSPolicyGVK, clusterManager, clusterManager.Name+"-gateway-tls"),
+	}
+	for _, target := range targets {
+		err := e.r.Delete(context.TODO(), target)
+		if errors.IsNotFound(err) || meta.IsNoMatchError(err) {
+			log.Info("Cannot find " + target.GetKind() + " [" + target.GetName() + "]. Maybe already deleted")
+		} else if err != nil {
+			log.Error(err, "Failed to delete "+target.GetKind()+" ["+target.GetName()+"]")
+			return err
+		} else {
+			log.Info("Delete " + target.GetKind() + " [" + target.GetName() + "] successfully")
+		}
+	}
+
+	configMap := &coreV1.ConfigMap{}
+	configMap.Name = clusterManager.Name + "-gateway-ca"
+	configMap.Namespace = clusterManager.Namespace
+	if err := e.r.Delete(context.TODO(), configMap); errors.IsNotFound(err) {
+		log.Info("Cannot find ConfigMap for gateway CA. Maybe already deleted")
+	} else if err != nil {
+		log.Error(err, "Failed to delete ConfigMap for gateway CA")
+		return err
+	} else {
+		log.Info("Delete ConfigMap for gateway CA successfully")
+	}
+
+	return e.r.DeleteIngress(clusterManager)
+}
+
+// applyUnstructured 는 typed client 가 없는 리소스(Gateway API)를 server-side apply 로 생성하거나 갱신한다.
+func (r *ClusterManagerReconciler) applyUnstructured(clusterManager *clusterV1alpha1.ClusterManager, obj *unstructured.Unstructured) error {
+	log := r.Log.WithValues("clustermanager", clusterManager.GetNamespacedName())
+	kind := obj.GetKind()
+
+	result, err := r.apply(clusterManager, obj)
+	if meta.IsNoMatchError(err) {
+		err := fmt.Errorf("%s is not installed: %w", obj.GroupVersionKind().String(), err)
+		log.Error(err, "Failed to apply "+kind)
+		return err
+	} else if err != nil {
+		log.Error(err, "Failed to apply "+kind)
+		return err
+	}
+	logApplyResult(log, result, kind)
+
+	return nil
+}
+
+func newUnstructuredList(gvk schema.GroupVersionKind) *unstructured.UnstructuredList {
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+	return list
+}
+
+func newUnstructured(gvk schema.GroupVersionKind, clusterManager *clusterV1alpha1.ClusterManager, name string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(gvk)
+	obj.SetName(name)
+	obj.SetNamespace(clusterManager.Namespace)
+	obj.SetAnnotations(exposureAnnotations(clusterManager))
+	obj.SetLabels(exposureLabels(clusterManager))
+	return obj
+}
+
+func exposureAnnotations(clusterManager *clusterV1alpha1.ClusterManager) map[string]string {
+	return map[string]string{
+		util.AnnotationKeyOwner:   clusterManager.Annotations[util.AnnotationKeyCreator],
+		util.AnnotationKeyCreator: clusterManager.Annotations[util.AnnotationKeyCreator],
+	}
+}
+
+func exposureLabels(clusterManager *clusterV1alpha1.ClusterManager) map[string]string {
+	return map[string]string{
+		clusterV1alpha1.LabelKeyClmName: clusterManager.Name,
+	}
+}
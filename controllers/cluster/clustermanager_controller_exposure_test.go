@@ -0,0 +1,118 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"testing"
+
+	clusterV1alpha1 "github.com/tmax-cloud/hypercloud-multi-operator/apis/cluster/v1alpha1"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestValidateAuthPolicyTargetRefs(t *testing.T) {
+	clm := &clusterV1alpha1.ClusterManager{
+		ObjectMeta: metav1.ObjectMeta{Name: "cluster", Namespace: "ns"},
+	}
+	newPolicy := func(spec map[string]interface{}) *unstructured.Unstructured {
+		policy := &unstructured.Unstructured{Object: map[string]interface{}{"spec": spec}}
+		policy.SetNamespace("gateway-system")
+		policy.SetName("jwt")
+		return policy
+	}
+	targetRefs := func(refs ...map[string]interface{}) map[string]interface{} {
+		items := []interface{}{}
+		for _, ref := range refs {
+			items = append(items, ref)
+		}
+		return map[string]interface{}{"targetRefs": items}
+	}
+	gateway := func(name string) map[string]interface{} {
+		return map[string]interface{}{"group": "gateway.networking.k8s.io", "kind": "Gateway", "name": name}
+	}
+
+	tests := []struct {
+		name    string
+		spec    map[string]interface{}
+		wantErr bool
+	}{
+		{
+			name: "targets parent gateway",
+			spec: targetRefs(gateway("gateway")),
+		},
+		{
+			name: "targets parent gateway among others",
+			spec: targetRefs(gateway("other"), gateway("gateway")),
+		},
+		{
+			name: "targets route of cluster",
+			spec: targetRefs(map[string]interface{}{
+				"group": "gateway.networking.k8s.io", "kind": "HTTPRoute", "name": "cluster-route", "namespace": "ns",
+			}),
+		},
+		{
+			name: "single targetRef",
+			spec: map[string]interface{}{"targetRef": gateway("gateway")},
+		},
+		{
+			name:    "no target",
+			spec:    map[string]interface{}{},
+			wantErr: true,
+		},
+		{
+			name:    "targets other gateway",
+			spec:    targetRefs(gateway("other")),
+			wantErr: true,
+		},
+		{
+			name: "targets gateway of other namespace",
+			spec: targetRefs(map[string]interface{}{
+				"group": "gateway.networking.k8s.io", "kind": "Gateway", "name": "gateway", "namespace": "other",
+			}),
+			wantErr: true,
+		},
+		{
+			name: "targets listener of parent gateway",
+			spec: targetRefs(map[string]interface{}{
+				"group": "gateway.networking.k8s.io", "kind": "Gateway", "name": "gateway", "sectionName": "console",
+			}),
+			wantErr: true,
+		},
+		{
+			name: "targets route of other cluster",
+			spec: targetRefs(map[string]interface{}{
+				"group": "gateway.networking.k8s.io", "kind": "HTTPRoute", "name": "other-route", "namespace": "ns",
+			}),
+			wantErr: true,
+		},
+		{
+			name: "targets service with same name",
+			spec: targetRefs(map[string]interface{}{
+				"group": "", "kind": "Service", "name": "gateway",
+			}),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateAuthPolicyTargetRefs(newPolicy(tt.spec), clm, "gateway-system", "gateway")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateAuthPolicyTargetRefs() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
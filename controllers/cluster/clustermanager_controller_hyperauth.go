@@ -0,0 +1,242 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+
+	clusterV1alpha1 "github.com/tmax-cloud/hypercloud-multi-operator/apis/cluster/v1alpha1"
+	hyperauthCaller "github.com/tmax-cloud/hypercloud-multi-operator/controllers/hyperAuth"
+	"github.com/tmax-cloud/hypercloud-multi-operator/controllers/util"
+
+	coreV1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// setHyperAuthClientSecrets 는 hub 의 secret 에 보관한 cluster 의 client secret 들을 desired state 의 client 들에 채운다.
+// secret 이 없는 client 가 있거나 rotation 이 요청된 경우 pending 을 반환하고,
+// hyperAuthClient 가 주어지면 빠진 secret 을 채워 hub 의 secret 에 저장한다.
+// HyperAuth 에 이미 있는 client 는 사용 중인 secret 을 그대로 가져오고, 새로운 client 이거나 rotation 이 요청된 경우에만 새로 만든다.
+func (r *ClusterManagerReconciler) setHyperAuthClientSecrets(ctx context.Context, clusterManager *clusterV1alpha1.ClusterManager, desired *hyperauthCaller.DesiredState, hyperAuthClient *hyperauthCaller.HyperAuthClient) (bool, error) {
+	log := r.Log.WithValues("clustermanager", clusterManager.GetNamespacedName())
+
+	key := types.NamespacedName{
+		Name:      clusterManager.Name + util.HyperAuthClientSecretSuffix,
+		Namespace: clusterManager.Namespace,
+	}
+	current := &coreV1.Secret{}
+	if err := r.Get(ctx, key, current); err != nil && !errors.IsNotFound(err) {
+		log.Error(err, "Failed to get Secret for HyperAuth clients")
+		return false, err
+	}
+
+	rotation := clusterManager.Annotations[clusterV1alpha1.AnnotationKeyClmRotateHyperAuthSecrets]
+	rotate := rotation != "" && rotation != current.Annotations[clusterV1alpha1.AnnotationKeyClmRotateHyperAuthSecrets]
+	generate := hyperAuthClient != nil
+
+	pending := rotate
+	missing := []string{}
+	for _, client := range desired.Clients {
+		if len(current.Data[client.ClientId]) == 0 {
+			pending = true
+			missing = append(missing, client.ClientId)
+		}
+	}
+	// 이전 버전에서 만든 client 의 secret 은 hub 에 없으므로 HyperAuth 에서 가져온다.
+	existing := map[string]string{}
+	if generate && !rotate && len(missing) != 0 {
+		var err error
+		if existing, err = hyperAuthClient.GetExistingClientSecrets(ctx, missing); err != nil {
+			log.Error(err, "Failed to get secrets of existing HyperAuth clients")
+			return false, err
+		}
+	}
+
+	data := map[string][]byte{}
+	for i, client := range desired.Clients {
+		value := current.Data[client.ClientId]
+		if generate && (len(value) == 0 || rotate) {
+			if secret, ok := existing[client.ClientId]; ok {
+				value = []byte(secret)
+			} else {
+				secret, err := generateClientSecret()
+				if err != nil {
+					return false, err
+				}
+				value = []byte(secret)
+			}
+		}
+		desired.Clients[i].Secret = string(value)
+		data[client.ClientId] = value
+	}
+	// 더 이상 사용하지 않는 client 의 secret 은 다음에 저장할 때 지운다.
+	if !generate || (!pending && len(data) == len(current.Data)) {
+		return pending, nil
+	}
+
+	secret := &coreV1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      key.Name,
+			Namespace: key.Namespace,
+			Labels: map[string]string{
+				util.LabelKeyClmSecretType: util.ClmSecretTypeHyperAuthClient,
+			},
+			Annotations: map[string]string{
+				clusterV1alpha1.AnnotationKeyClmRotateHyperAuthSecrets: rotation,
+			},
+		},
+		Type: coreV1.SecretTypeOpaque,
+		Data: data,
+	}
+	result, err := r.apply(clusterManager, secret)
+	if err != nil {
+		log.Error(err, "Failed to apply Secret for HyperAuth clients")
+		return false, err
+	}
+	logApplyResult(log, result, "Secret for HyperAuth clients")
+	if rotate {
+		log.Info("Rotate HyperAuth client secrets", "rotation", rotation)
+	}
+
+	return pending, nil
+}
+
+// applyRemoteHyperAuthClientSecrets 는 single cluster 의 module 들이 oidc 연동에 사용할 client id, secret, issuer 를
+// module 의 namespace 에 secret 으로 만든다. namespace 가 아직 없으면 함께 만든다.
+func (r *ClusterManagerReconciler) applyRemoteHyperAuthClientSecrets(ctx context.Context, clusterManager *clusterV1alpha1.ClusterManager, desired hyperauthCaller.DesiredState, realm string) error {
+	log := r.Log.WithValues("clustermanager", clusterManager.GetNamespacedName())
+
+	if len(desired.ClientSecrets) == 0 {
+		return nil
+	}
+
+	kubeconfigSecret, err := r.GetKubeconfigSecret(clusterManager)
+	if err != nil {
+		log.Error(err, "Failed to get kubeconfig secret")
+		return err
+	}
+	remoteClientset, err := util.GetRemoteK8sClient(kubeconfigSecret)
+	if err != nil {
+		log.Error(err, "Failed to get remoteK8sClient")
+		return err
+	}
+
+	clientSecrets := map[string]string{}
+	for _, client := range desired.Clients {
+		clientSecrets[client.ClientId] = client.Secret
+	}
+	for _, config := range desired.ClientSecrets {
+		_, err := remoteClientset.
+			CoreV1().
+			Namespaces().
+			Get(ctx, config.Namespace, metav1.GetOptions{})
+		if errors.IsNotFound(err) {
+			namespace := &coreV1.Namespace{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: config.Namespace,
+				},
+			}
+			_, err := remoteClientset.
+				CoreV1().
+				Namespaces().
+				Create(ctx, namespace, metav1.CreateOptions{})
+			if err != nil && !errors.IsAlreadyExists(err) {
+				log.Error(err, "Cannot create Namespace ["+config.Namespace+"] to remote cluster")
+				return err
+			}
+		} else if err != nil {
+			log.Error(err, "Failed to get Namespace ["+config.Namespace+"] from remote cluster")
+			return err
+		}
+
+		data := map[string][]byte{
+			hyperauthCaller.CLIENT_SECRET_KEY_CLIENT_ID:     []byte(config.ClientId),
+			hyperauthCaller.CLIENT_SECRET_KEY_CLIENT_SECRET: []byte(clientSecrets[config.ClientId]),
+			hyperauthCaller.CLIENT_SECRET_KEY_ISSUER:        []byte(util.GetHyperAuthIssuer(realm)),
+		}
+		current, err := remoteClientset.
+			CoreV1().
+			Secrets(config.Namespace).
+			Get(ctx, config.Name, metav1.GetOptions{})
+		if errors.IsNotFound(err) {
+			secret := &coreV1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      config.Name,
+					Namespace: config.Namespace,
+					Labels: map[string]string{
+						util.LabelKeyClmSecretType: util.ClmSecretTypeHyperAuthClient,
+					},
+				},
+				Type: coreV1.SecretTypeOpaque,
+				Data: data,
+			}
+			_, err := remoteClientset.
+				CoreV1().
+				Secrets(config.Namespace).
+				Create(ctx, secret, metav1.CreateOptions{})
+			if err != nil {
+				log.Error(err, "Cannot create Secret ["+config.Name+"] to remote cluster")
+				return err
+			}
+			log.Info("Create Secret [" + config.Name + "] for HyperAuth client to remote cluster successfully")
+			continue
+		} else if err != nil {
+			log.Error(err, "Failed to get Secret ["+config.Name+"] from remote cluster")
+			return err
+		}
+
+		if isSecretDataEqual(current.Data, data) {
+			continue
+		}
+		current.Data = data
+		_, err = remoteClientset.
+			CoreV1().
+			Secrets(config.Namespace).
+			Update(ctx, current, metav1.UpdateOptions{})
+		if err != nil {
+			log.Error(err, "Cannot update Secret ["+config.Name+"] to remote cluster")
+			return err
+		}
+		log.Info("Update Secret [" + config.Name + "] for HyperAuth client to remote cluster successfully")
+	}
+
+	return nil
+}
+
+// generateClientSecret 은 HyperAuth client 의 secret 으로 사용할 임의의 문자열을 만든다.
+func generateClientSecret() (string, error) {
+	buf := make([]byte, hyperauthCaller.HYPERAUTH_CLIENT_SECRET_LENGTH)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+func isSecretDataEqual(a map[string][]byte, b map[string][]byte) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for key, value := range a {
+		if !bytes.Equal(value, b[key]) {
+			return false
+		}
+	}
+	return true
+}
@@ -20,11 +20,11 @@ import (
 	"fmt"
 	"regexp"
 	"strings"
+	"time"
 
 	argocdV1alpha1 "github.com/argoproj/argo-cd/v2/pkg/apis/application/v1alpha1"
 	servicecatalogv1beta1 "github.com/kubernetes-sigs/service-catalog/pkg/apis/servicecatalog/v1beta1"
 	clusterV1alpha1 "github.com/tmax-cloud/hypercloud-multi-operator/apis/cluster/v1alpha1"
-	hyperauthCaller "github.com/tmax-cloud/hypercloud-multi-operator/controllers/hyperAuth"
 	util "github.com/tmax-cloud/hypercloud-multi-operator/controllers/util"
 
 	coreV1 "k8s.io/api/core/v1"
@@ -32,12 +32,13 @@ import (
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
-	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/kubernetes"
 
 	// cpavV1alpha3 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1alpha3"
 	capiV1alpha3 "sigs.k8s.io/cluster-api/api/v1alpha3"
 	controlplanev1 "sigs.k8s.io/cluster-api/controlplane/kubeadm/api/v1alpha3"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"sigs.k8s.io/yaml"
 )
@@ -88,9 +89,6 @@ func (r *ClusterManagerReconciler) ReadyReconcilePhase(ctx context.Context, clus
 }
 
 func (r *ClusterManagerReconciler) UpdateClusterManagerStatus(ctx context.Context, clusterManager *clusterV1alpha1.ClusterManager) (ctrl.Result, error) {
-	if clusterManager.Status.ControlPlaneReady {
-		return ctrl.Result{}, nil
-	}
 	log := r.Log.WithValues("clustermanager", clusterManager.GetNamespacedName())
 	log.Info("Start to reconcile phase for UpdateClusterManagerStatus")
 
@@ -204,9 +202,6 @@ func (r *ClusterManagerReconciler) UpdateClusterManagerStatus(ctx context.Contex
 }
 
 func (r *ClusterManagerReconciler) CreateServiceInstance(ctx context.Context, clusterManager *clusterV1alpha1.ClusterManager) (ctrl.Result, error) {
-	if clusterManager.Annotations[clusterV1alpha1.AnnotationKeyClmSuffix] != "" {
-		return ctrl.Result{}, nil
-	}
 	log := r.Log.WithValues("clustermanager", clusterManager.GetNamespacedName())
 	log.Info("Start to reconcile phase for CreateServiceInstance")
 
@@ -253,7 +248,18 @@ func (r *ClusterManagerReconciler) CreateServiceInstance(ctx context.Context, cl
 	}
 
 	if err := r.Get(context.TODO(), key, &servicecatalogv1beta1.ServiceInstance{}); errors.IsNotFound(err) {
-		clusterJson, err := Marshaling(&ClusterParameter{}, *clusterManager)
+		// single cluster 의 api server 는 cluster 의 realm 을 oidc issuer 로 사용한다.
+		realm, err := util.GetHyperAuthRealmFor(ctx, r.Client, clusterManager)
+		if err != nil {
+			log.Error(err, "Failed to get HyperAuth realm")
+			return ctrl.Result{}, err
+		}
+		// api server 의 issuer 가 된 realm 을 기록하여 이후에 namespace 의 realm 설정이 바뀌어도 계속 사용한다.
+		if clusterManager.Status.HyperAuth == nil {
+			clusterManager.Status.HyperAuth = &clusterV1alpha1.HyperAuthStatus{}
+		}
+		clusterManager.Status.HyperAuth.Realm = realm
+		clusterJson, err := Marshaling(&ClusterParameter{realm: realm}, *clusterManager)
 		if err != nil {
 			log.Error(err, "Failed to marshal cluster parameters")
 		}
@@ -326,9 +332,6 @@ func (r *ClusterManagerReconciler) CreateUpgradeServiceInstance(ctx context.Cont
 }
 
 func (r *ClusterManagerReconciler) SetEndpoint(ctx context.Context, clusterManager *clusterV1alpha1.ClusterManager) (ctrl.Result, error) {
-	if clusterManager.Annotations[clusterV1alpha1.AnnotationKeyClmApiserver] != "" {
-		return ctrl.Result{}, nil
-	}
 	log := r.Log.WithValues("clustermanager", clusterManager.GetNamespacedName())
 	log.Info("Start to reconcile phase for SetEndpoint")
 
@@ -621,12 +624,8 @@ func (r *ClusterManagerReconciler) machineDeploymentUpdate(ctx context.Context,
 	return ctrl.Result{}, nil
 }
 
-func (r *ClusterManagerReconciler) CreateArgocdResources(ctx context.Context, clusterManager *clusterV1alpha1.ClusterManager) (ctrl.Result, error) {
-	if !clusterManager.Status.ControlPlaneReady || !clusterManager.Status.Ready || clusterManager.Status.ArgoReady {
-		return ctrl.Result{}, nil
-	}
-	log := r.Log.WithValues("ClusterManager", clusterManager.GetNamespacedName())
-	log.Info("Start to reconcile phase for CreateArgocdResources")
+func (r *ClusterManagerReconciler) WatchRemoteResources(ctx context.Context, clusterManager *clusterV1alpha1.ClusterManager) (ctrl.Result, error) {
+	log := r.Log.WithValues("clustermanager", clusterManager.GetNamespacedName())
 
 	kubeconfigSecret, err := r.GetKubeconfigSecret(clusterManager)
 	if err != nil {
@@ -634,97 +633,74 @@ func (r *ClusterManagerReconciler) CreateArgocdResources(ctx context.Context, cl
 		return ctrl.Result{RequeueAfter: requeueAfter10Second}, nil
 	}
 
-	kubeConfig, err := clientcmd.Load(kubeconfigSecret.Data["value"])
-	if err != nil {
-		log.Error(err, "Failed to get kubeconfig data from secret")
+	// kubeconfig 와 cluster owner 가 변경되지 않았다면 기존 informer 를 그대로 사용한다.
+	serviceAccounts := []string{util.ArgoServiceAccount, ownerServiceAccountName(clusterManager)}
+	if err := r.RemoteWatches.Watch(clusterManager.GetNamespacedName(), kubeconfigSecret, serviceAccounts); err != nil {
+		log.Error(err, "Failed to watch remote resources")
 		return ctrl.Result{}, err
 	}
 
-	remoteClientset, err := util.GetRemoteK8sClient(kubeconfigSecret)
-	if err != nil {
-		log.Error(err, "Failed to get remoteK8sClient")
-		return ctrl.Result{}, err
-	}
+	return ctrl.Result{}, nil
+}
 
-	// single cluster에서 secret을 조회
-	// argocd-manager service account의 token을 얻기 위한 secret
-	tokenSecret, err := remoteClientset.
-		CoreV1().
-		Secrets(util.KubeNamespace).
-		Get(context.TODO(), util.ArgoServiceAccountTokenSecret, metav1.GetOptions{})
-	if errors.IsNotFound(err) {
-		log.Info("Service account secret not found. Wait for creating")
+func (r *ClusterManagerReconciler) CreateArgocdResources(ctx context.Context, clusterManager *clusterV1alpha1.ClusterManager) (ctrl.Result, error) {
+	log := r.Log.WithValues("ClusterManager", clusterManager.GetNamespacedName())
+	log.Info("Start to reconcile phase for CreateArgocdResources")
+
+	kubeconfigSecret, err := r.GetKubeconfigSecret(clusterManager)
+	if err != nil {
+		log.Error(err, "Failed to get kubeconfig secret")
 		return ctrl.Result{RequeueAfter: requeueAfter10Second}, nil
-	} else if err != nil {
-		log.Error(err, "Failed to get service account secret")
-		return ctrl.Result{}, err
 	}
 
-	// token secret이 잘들어가 있는지 check
-	if string(tokenSecret.Data["token"]) == "" {
-		log.Info("Service account secret token data not found. Wait for creating")
+	key := types.NamespacedName{
+		Name:      kubeconfigSecret.Annotations[util.AnnotationKeyArgoClusterSecret],
+		Namespace: util.ArgoNamespace,
+	}
+	argocdClusterSecret := &coreV1.Secret{}
+	if err := r.Get(context.TODO(), key, argocdClusterSecret); err != nil && !errors.IsNotFound(err) {
+		log.Error(err, "Failed to get Argocd Secret for remote cluster")
+		return ctrl.Result{}, err
+	} else if err == nil && !argocdClusterSecret.GetDeletionTimestamp().IsZero() {
 		return ctrl.Result{Requeue: true}, nil
 	}
 
-	// ArgoCD single cluster 연동을 위한 secret에 들어가야 할 데이터를 생성
-	configJson, err := json.Marshal(
-		&argocdV1alpha1.ClusterConfig{
-			BearerToken: string(tokenSecret.Data["token"]),
-			TLSClientConfig: argocdV1alpha1.TLSClientConfig{
-				Insecure: false,
-				CAData:   kubeConfig.Clusters[kubeConfig.Contexts[kubeConfig.CurrentContext].Cluster].CertificateAuthorityData,
-			},
-		},
-	)
+	_, authInfo, err := util.LoadKubeconfigEntries(kubeconfigSecret)
 	if err != nil {
-		log.Error(err, "Failed to marshal cluster authorization parameters")
+		log.Error(err, "Failed to get kubeconfig data from secret")
 		return ctrl.Result{}, err
 	}
 
-	// master cluster에 secret 생성
-	// ArgoCD에서 single cluster를 연동하기 위한 secret
-	clusterName := strings.Split(kubeconfigSecret.Name, util.KubeconfigSuffix)[0]
-	key := types.NamespacedName{
-		Name:      kubeconfigSecret.Annotations[util.AnnotationKeyArgoClusterSecret],
-		Namespace: util.ArgoNamespace,
-	}
-	argocdClusterSecret := &coreV1.Secret{}
-	if err := r.Get(context.TODO(), key, argocdClusterSecret); errors.IsNotFound(err) {
-		argocdClusterSecret = &coreV1.Secret{
-			ObjectMeta: metav1.ObjectMeta{
-				Name:      key.Name,
-				Namespace: key.Namespace,
-				Annotations: map[string]string{
-					util.AnnotationKeyOwner:         kubeconfigSecret.Annotations[util.AnnotationKeyOwner],
-					util.AnnotationKeyCreator:       kubeconfigSecret.Annotations[util.AnnotationKeyCreator],
-					util.AnnotationKeyArgoManagedBy: util.ArgoApiGroup,
-				},
-				Labels: map[string]string{
-					util.LabelKeyClmSecretType:           util.ClmSecretTypeArgo,
-					util.LabelKeyArgoSecretType:          util.ArgoSecretTypeCluster,
-					clusterV1alpha1.LabelKeyClmName:      clusterManager.Name,
-					clusterV1alpha1.LabelKeyClmNamespace: clusterManager.Namespace,
-				},
-				Finalizers: []string{
-					clusterV1alpha1.ClusterManagerFinalizer,
-				},
-			},
-			StringData: map[string]string{
-				"config": string(configJson),
-				"name":   clusterName,
-				"server": kubeConfig.Clusters[kubeConfig.Contexts[kubeConfig.CurrentContext].Cluster].Server,
-			},
-		}
-		if err := r.Create(context.TODO(), argocdClusterSecret); err != nil {
-			log.Error(err, "Cannot create Argocd Secret for remote cluster")
+	// bearer token 방식인 경우에만 single cluster의 argocd-manager service account에 대해
+	// TokenRequest API를 통해 유효기간이 정해진 token을 발급
+	token, expiration := "", time.Time{}
+	if GetArgocdClusterAuthMode(clusterManager, authInfo) == clusterV1alpha1.ArgoClusterAuthModeBearerToken {
+		remoteClientset, err := util.GetRemoteK8sClient(kubeconfigSecret)
+		if err != nil {
+			log.Error(err, "Failed to get remoteK8sClient")
 			return ctrl.Result{}, err
 		}
-		log.Info("Create Argocd Secret for remote cluster successfully")
-	} else if err != nil {
-		log.Error(err, "Failed to get Argocd Secret for remote cluster")
+
+		token, expiration, err = util.RequestServiceAccountToken(remoteClientset, util.KubeNamespace, util.ArgoServiceAccount)
+		if errors.IsNotFound(err) {
+			log.Info("Service account for argocd not found. Wait for creating")
+			return r.waitForRemoteResource(clusterManager, requeueAfter10Second), nil
+		} else if err != nil {
+			log.Error(err, "Failed to request service account token")
+			return ctrl.Result{}, err
+		}
+	}
+
+	// master cluster에 ArgoCD에서 single cluster를 연동하기 위한 secret 생성
+	// kubeconfig 가 바뀐 경우 (인증서 rotation, api-server endpoint 변경 등)나 다른 곳에서 수정한 경우에도 apply 로 갱신한다.
+	// bearer token 방식인 경우 token 은 새로 발급받았으므로 항상 갱신된다.
+	if err := r.ApplyArgocdClusterSecret(clusterManager, kubeconfigSecret, token, expiration); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// application 이 single cluster 외의 cluster 에 배포되지 않도록 cluster 별 project 를 먼저 생성한다.
+	if err := r.ApplyArgocdProject(clusterManager, kubeconfigSecret); err != nil {
 		return ctrl.Result{}, err
-	} else if !argocdClusterSecret.GetDeletionTimestamp().IsZero() {
-		return ctrl.Result{Requeue: true}, nil
 	}
 
 	if err := r.CreateApplication(clusterManager); err != nil {
@@ -749,10 +725,190 @@ func (r *ClusterManagerReconciler) CreateArgocdResources(ctx context.Context, cl
 	return ctrl.Result{}, nil
 }
 
-func (r *ClusterManagerReconciler) CreateGatewayResources(ctx context.Context, clusterManager *clusterV1alpha1.ClusterManager) (reconcile.Result, error) {
-	if !clusterManager.Status.ArgoReady || clusterManager.Status.GatewayReady {
-		return ctrl.Result{}, nil
+// cluster member 는 hypercloud api-server 에서 관리되므로, 주기적으로 조회하여 AppProject 의 role 에 반영한다.
+// ClusterManager 나 operator 의 설정이 바뀐 경우에는 주기와 관계없이 바로 반영한다.
+func (r *ClusterManagerReconciler) SyncArgocdProject(ctx context.Context, clusterManager *clusterV1alpha1.ClusterManager) (ctrl.Result, error) {
+	log := r.Log.WithValues("clustermanager", clusterManager.GetNamespacedName())
+
+	kubeconfigSecret, err := r.GetKubeconfigSecret(clusterManager)
+	if err != nil {
+		log.Error(err, "Failed to get kubeconfig secret")
+		return ctrl.Result{RequeueAfter: requeueAfter10Second}, nil
+	}
+
+	profile, found, err := r.GetAddonProfile(clusterManager)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	interval := util.GetArgoProjectSyncInterval()
+	hash := GetArgocdProjectConfigHash(clusterManager, kubeconfigSecret, profile)
+	if !IsArgocdProjectSyncNeeded(clusterManager, hash, interval) {
+		return ctrl.Result{RequeueAfter: interval}, nil
+	}
+
+	if err := r.ApplyArgocdProject(clusterManager, kubeconfigSecret); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// repository credentials 가 바뀐 경우 repository secret 을 갱신한다.
+	if found {
+		if err := r.ApplyArgocdRepositorySecret(clusterManager, profile); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	key := types.NamespacedName{
+		Name:      clusterManager.GetNamespacedPrefix() + "-applications",
+		Namespace: util.ArgoNamespace,
+	}
+	application := &argocdV1alpha1.Application{}
+	if err := r.Get(context.TODO(), key, application); err != nil && !errors.IsNotFound(err) {
+		log.Error(err, "Failed to get ArgoCD Application")
+		return ctrl.Result{}, err
+	} else if err == nil {
+		if err := r.UpdateApplicationProject(clusterManager, application); err != nil {
+			return ctrl.Result{}, err
+		}
+
+		if err := r.UpdateApplicationSyncPolicy(clusterManager, application); err != nil {
+			return ctrl.Result{}, err
+		}
 	}
+
+	now := metav1.Now()
+	clusterManager.Status.ArgoProject = &clusterV1alpha1.ArgoProjectStatus{
+		DesiredHash:  hash,
+		LastSyncTime: &now,
+	}
+	return ctrl.Result{RequeueAfter: interval}, nil
+}
+
+// ClusterManager 의 label 을 argocd cluster secret 에 전파한다.
+func (r *ClusterManagerReconciler) SyncArgocdClusterLabels(ctx context.Context, clusterManager *clusterV1alpha1.ClusterManager) (ctrl.Result, error) {
+	if err := r.SyncArgocdClusterSecretLabels(clusterManager); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// sync-now annotation 이 있으면 app-of-apps application 에 sync operation 을 요청하고 annotation 을 지운다.
+// operation 의 결과는 SyncApplicationStatus 에서 status 에 반영된다.
+func (r *ClusterManagerReconciler) SyncApplication(ctx context.Context, clusterManager *clusterV1alpha1.ClusterManager) (ctrl.Result, error) {
+	log := r.Log.WithValues("clustermanager", clusterManager.GetNamespacedName())
+
+	key := types.NamespacedName{
+		Name:      clusterManager.GetNamespacedPrefix() + "-applications",
+		Namespace: util.ArgoNamespace,
+	}
+	application := &argocdV1alpha1.Application{}
+	if err := r.Get(context.TODO(), key, application); errors.IsNotFound(err) {
+		log.Info("Waiting for ArgoCD Application to be created")
+		return ctrl.Result{RequeueAfter: requeueAfter10Second}, nil
+	} else if err != nil {
+		log.Error(err, "Failed to get ArgoCD Application")
+		return ctrl.Result{}, err
+	}
+
+	isRunning := application.Operation != nil ||
+		(application.Status.OperationState != nil && !application.Status.OperationState.Phase.Completed())
+	if isRunning {
+		log.Info("Waiting for running operation of ArgoCD Application to be completed")
+		return ctrl.Result{RequeueAfter: requeueAfter10Second}, nil
+	}
+
+	prune := false
+	if automated := newApplicationSyncPolicyAutomated(clusterManager); automated != nil {
+		prune = automated.Prune
+	}
+	application.Operation = &argocdV1alpha1.Operation{
+		Sync: &argocdV1alpha1.SyncOperation{
+			Revision: application.Spec.Source.TargetRevision,
+			Prune:    prune,
+		},
+		InitiatedBy: argocdV1alpha1.OperationInitiator{
+			Username: util.ArgoOperationInitiator,
+		},
+	}
+	if err := r.Update(context.TODO(), application, client.FieldOwner(util.FieldOwner)); err != nil {
+		log.Error(err, "Failed to request sync of ArgoCD Application")
+		return ctrl.Result{}, err
+	}
+
+	delete(clusterManager.Annotations, clusterV1alpha1.AnnotationKeyClmSyncNow)
+	log.Info("Request sync of ArgoCD Application successfully")
+	return ctrl.Result{}, nil
+}
+
+// app-of-apps application 과 하위 application 들의 sync, health 상태를 ClusterManager status 에 반영한다.
+// application 이 바뀌면 watch 를 통해 다시 수행되며, 주기적으로도 상태를 다시 확인한다.
+func (r *ClusterManagerReconciler) SyncApplicationStatus(ctx context.Context, clusterManager *clusterV1alpha1.ClusterManager) (ctrl.Result, error) {
+	log := r.Log.WithValues("clustermanager", clusterManager.GetNamespacedName())
+
+	key := types.NamespacedName{
+		Name:      clusterManager.GetNamespacedPrefix() + "-applications",
+		Namespace: util.ArgoNamespace,
+	}
+	application := &argocdV1alpha1.Application{}
+	if err := r.Get(context.TODO(), key, application); errors.IsNotFound(err) {
+		log.Info("Waiting for ArgoCD Application to be created")
+		SetArgoApplicationStatus(clusterManager, nil)
+		return ctrl.Result{RequeueAfter: requeueAfter1Minute}, nil
+	} else if err != nil {
+		log.Error(err, "Failed to get ArgoCD Application")
+		return ctrl.Result{}, err
+	}
+
+	appList := &argocdV1alpha1.ApplicationList{}
+	if err := r.List(context.TODO(), appList, client.InNamespace(util.ArgoNamespace)); err != nil {
+		log.Error(err, "Failed to list ArgoCD Applications")
+		return ctrl.Result{}, err
+	}
+
+	if err := r.DeleteForeignProjectApplications(clusterManager, application, appList.Items); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	status := newArgoApplicationStatus(application, appList.Items)
+	SetArgoApplicationStatus(clusterManager, status)
+
+	if !status.IsReady() {
+		log.Info("ArgoCD Application is not synced or healthy yet",
+			"sync", status.SyncStatus,
+			"health", status.HealthStatus,
+			"ready", fmt.Sprintf("%d/%d", status.ReadyApplications, status.Applications),
+			"failed", len(status.FailedApplications),
+		)
+		return ctrl.Result{RequeueAfter: requeueAfter1Minute}, nil
+	}
+
+	return ctrl.Result{RequeueAfter: requeueAfter5Minute}, nil
+}
+
+// TokenRequest 로 발급받은 token 은 유효기간이 있으므로, 만료되기 전에 다시 발급받아
+// Argocd cluster secret 과 jwt-decode 를 위한 service account token secret 을 갱신한다.
+func (r *ClusterManagerReconciler) RefreshServiceAccountTokens(ctx context.Context, clusterManager *clusterV1alpha1.ClusterManager) (ctrl.Result, error) {
+	log := r.Log.WithValues("clustermanager", clusterManager.GetNamespacedName())
+
+	if err := r.RefreshArgocdClusterSecretToken(clusterManager); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// service account token secret 은 traefik 리소스를 만들면서 생성된다.
+	if clusterManager.Status.TraefikReady {
+		if err := r.CreateServiceAccountSecret(clusterManager); isWaitingForRemote(err) {
+			log.Info("Waiting for service account from remote cluster")
+			return r.waitForRemoteResource(clusterManager, requeueAfter10Second), nil
+		} else if err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	return ctrl.Result{RequeueAfter: requeueAfter1Hour}, nil
+}
+
+func (r *ClusterManagerReconciler) CreateGatewayResources(ctx context.Context, clusterManager *clusterV1alpha1.ClusterManager) (reconcile.Result, error) {
 	log := r.Log.WithValues("clustermanager", clusterManager.GetNamespacedName())
 	log.Info("Start to reconcile phase for CreateGatewayResources")
 
@@ -768,54 +924,63 @@ func (r *ClusterManagerReconciler) CreateGatewayResources(ctx context.Context, c
 		return ctrl.Result{}, err
 	}
 
-	// host domain or ip를 얻기 위해 single cluster의
-	// api-gateway-system 네임스페이스의 gateway service를 조회
-	gatewayService, err := remoteClientset.
-		CoreV1().
-		Services(util.ApiGatewayNamespace).
-		Get(context.TODO(), "gateway", metav1.GetOptions{})
-	if errors.IsNotFound(err) {
-		log.Info("Cannot find Service for gateway. Wait for installing api-gateway. Requeue after 1 min")
-		return ctrl.Result{RequeueAfter: requeueAfter1Minute}, nil
+	address, err := r.getGatewayAddress(clusterManager, remoteClientset)
+	if isWaitingForRemote(err) {
+		log.Info("Service for api-gateway is not Ready. Wait for gateway address", "reason", err.Error())
+		return r.waitForRemoteResource(clusterManager, requeueAfter1Minute), nil
 	} else if err != nil {
-		log.Error(err, "Failed to get Service for gateway")
 		return ctrl.Result{}, err
 	}
 
-	// single cluster의 gateway service가 LoadBalancer가 아닐 경우에는(시나리오상 NodePort일 경우)
-	// k8s api-server의 endpoint도 NodePort로 되어있을 것이므로
-	// k8s api-server의 domain host를 gateway service의 endpoint로 사용
-	// single cluster의 k8s api-server domain과 gateway service의 domain중
-	// 어떤 것을 이용해야 할지 앞의 로직에서 annotation key를 통해 전달
-	annotationKey := clusterV1alpha1.AnnotationKeyClmApiserver
-	if gatewayService.Spec.Type != coreV1.ServiceTypeNodePort {
-		if gatewayService.Status.LoadBalancer.Ingress == nil {
-			err := fmt.Errorf("service for gateway's type is not LoadBalancer or not ready")
-			log.Error(err, "Service for api-gateway is not Ready. Requeue after 1 min")
-			return ctrl.Result{Requeue: true, RequeueAfter: requeueAfter1Minute}, err
-		}
+	// master cluster에 service 생성
+	// single cluster의 gateway 주소가 hostname 이면 external name type의 service 로,
+	// ip address 이면 selector 가 없는 service 와 모든 ip 를 가지는 endpoint 로 연결한다.
+	// gateway 의 certificate 를 검증할 수 있도록 CA 와 server name 을 사용하는 리소스를 backend 에 따라 만든다.
+	// (traefik: ServersTransport, gateway-api: BackendTLSPolicy)
+	// CA 를 찾지 못한 경우에는 기존처럼 검증하지 않고, status condition 에 기록한다.
+	// 검증에 실패한 CA 를 사용하면 gateway 로의 연결이 끊어지므로, 검증을 통과한 경우에만 CA 를 사용하는 리소스로 바꾸고
+	// 그렇지 않으면 이전에 사용하던 설정을 유지한다. 이전 설정이 없으면 검증하지 않는다.
+	ca, err := GetGatewayCA(remoteClientset, kubeconfigSecret)
+	if err != nil {
+		log.Error(err, "Failed to get CA for gateway")
+		return ctrl.Result{}, err
+	}
+	var verifyErr error
+	if len(ca) == 0 {
+		log.Info("Cannot find CA for gateway. Skip verification of gateway certificate")
+		verifyErr = errGatewayCANotFound
+	} else if verifyErr = VerifyGatewayTLS(address.Host(), address.Port, address.ServerName, ca); verifyErr != nil {
+		log.Info("Failed to verify certificate of gateway", "reason", verifyErr.Error())
+	}
+	SetGatewayTLSCondition(clusterManager, verifyErr)
 
-		ingress := gatewayService.Status.LoadBalancer.Ingress[0]
-		hostnameOrIp := ingress.Hostname + ingress.IP
-		if hostnameOrIp == "" {
-			err := fmt.Errorf("service for gateway doesn't have both hostname and ip address")
-			log.Error(err, "Service for api-gateway is not Ready. Requeue after 1 min")
-			return ctrl.Result{Requeue: true, RequeueAfter: requeueAfter1Minute}, err
+	var backendAnnotations map[string]string
+	if len(ca) != 0 && verifyErr != nil {
+		if backendAnnotations, err = r.getGatewayBackendAnnotations(clusterManager); err != nil {
+			return ctrl.Result{}, err
+		}
+		if backendAnnotations == nil {
+			ca = nil
+		} else {
+			log.Info("Keep previous TLS settings for gateway until certificate of gateway is verified")
+		}
+	}
+	if backendAnnotations == nil {
+		backendAnnotations, err = r.GetExposureBackend(util.GetExposureBackend()).CreateGatewayTLS(clusterManager, ca, address.ServerName)
+		if err != nil {
+			return ctrl.Result{}, err
 		}
+	}
 
-		clusterManager.Annotations[clusterV1alpha1.AnnotationKeyClmGateway] = hostnameOrIp
-		annotationKey = clusterV1alpha1.AnnotationKeyClmGateway
+	if err := r.CreateGatewayService(clusterManager, address, backendAnnotations); err != nil {
+		return ctrl.Result{}, err
 	}
 
-	// master cluster에 service 생성
-	// single cluster의 gateway service로 연결시켜줄 external name type의 service
-	// 앞에서 받은 annotation key를 이용하여 service의 endpoint가 설정 됨
-	// ip address의 경우 k8s 기본 정책상으로는 endpoint resource로 생성하여 연결을 하는게 일반적인데
-	// ip address도 external name type service의 external name의 value로 넣을 수 있기 때문에
-	// 리소스 관리를 최소화 하기 위해 external name type으로 동일하게 생성
-	if err := r.CreateGatewayService(clusterManager, annotationKey); err != nil {
+	if err := r.CreateGatewayEndpoint(clusterManager, address); err != nil {
 		return ctrl.Result{}, err
 	}
+	clusterManager.Annotations[clusterV1alpha1.AnnotationKeyClmGateway] = address.Host()
+	clusterManager.Annotations[util.AnnotationKeyGatewayAddresses] = address.String()
 
 	// For migration from b5.0.26.6 > b5.0.26.7
 	// 리소스 이름 및 status 이름 변경에 대응하기 위한 migration 코드
@@ -834,87 +999,83 @@ func (r *ClusterManagerReconciler) CreateGatewayResources(ctx context.Context, c
 	return ctrl.Result{}, nil
 }
 
-func (r *ClusterManagerReconciler) CreateHyperAuthResources(ctx context.Context, clusterManager *clusterV1alpha1.ClusterManager) (reconcile.Result, error) {
-	if !clusterManager.Status.GatewayReady || clusterManager.Status.AuthClientReady {
-		return ctrl.Result{}, nil
-	}
+// SyncGatewayAddresses 는 single cluster 의 gateway 주소(LoadBalancer ingress, node ip)가 바뀌었는지 확인하고,
+// 바뀐 경우 gateway service 와 endpoint 를 다시 만들도록 CreateGatewayResources 를 다시 수행시킨다.
+func (r *ClusterManagerReconciler) SyncGatewayAddresses(ctx context.Context, clusterManager *clusterV1alpha1.ClusterManager) (reconcile.Result, error) {
 	log := r.Log.WithValues("clustermanager", clusterManager.GetNamespacedName())
-	log.Info("Start to reconcile phase for CreateHyperauthClient")
 
-	// Hyperauth의 password를 가져오기 위한 secret을 조회
-	key := types.NamespacedName{
-		Name:      "passwords",
-		Namespace: "hyperauth",
+	// remote informer 가 동작하지 않는 경우에는 주기적으로 확인한다.
+	result := ctrl.Result{}
+	if !r.RemoteWatches.IsWatching(clusterManager.GetNamespacedName()) {
+		result.RequeueAfter = requeueAfter5Minute
+	}
+
+	kubeconfigSecret, err := r.GetKubeconfigSecret(clusterManager)
+	if err != nil {
+		log.Error(err, "Failed to get kubeconfig secret")
+		return ctrl.Result{RequeueAfter: requeueAfter10Second}, nil
 	}
-	secret := &coreV1.Secret{}
-	if err := r.Get(context.TODO(), key, secret); errors.IsNotFound(err) {
-		log.Info("Hyperauth password secret is not found")
+
+	remoteClientset, err := util.GetRemoteK8sClient(kubeconfigSecret)
+	if err != nil {
+		log.Error(err, "Failed to get remoteK8sClient")
 		return ctrl.Result{}, err
+	}
+
+	address, err := r.getGatewayAddress(clusterManager, remoteClientset)
+	if isWaitingForRemote(err) {
+		// LoadBalancer 의 ingress 가 잠시 비어있는 경우에는 기존 주소를 유지한다.
+		log.Info("Cannot get gateway address. Keep current gateway address", "reason", err.Error())
+		return result, nil
 	} else if err != nil {
-		log.Error(err, "Failed to get hyperauth password secret")
 		return ctrl.Result{}, err
 	}
 
-	// Hyperauth와 연동해야 하는 module 리스트는 정해져 있으므로, preset.go에서 관리
-	// cluster마다 client 이름이 달라야 해서 {namespace}-{cluster name} 를 prefix로
-	// 붙여주기로 했기 때문에, preset을 기본토대로 prefix를 추가하여 리턴하도록 구성
-	// client 생성 (kibana, grafana, kiali, jaeger, hyperregistry, opensearch)
-	clientConfigs := hyperauthCaller.GetClientConfigPreset(clusterManager.GetNamespacedPrefix())
-	for _, config := range clientConfigs {
-		if err := hyperauthCaller.CreateClient(config, secret); err != nil {
-			log.Error(err, "Failed to create hyperauth client ["+config.ClientId+"] for single cluster")
-			return ctrl.Result{RequeueAfter: requeueAfter10Second}, err
-		}
+	if address.String() != clusterManager.Annotations[util.AnnotationKeyGatewayAddresses] {
+		log.Info("Gateway address is changed",
+			"old", clusterManager.Annotations[util.AnnotationKeyGatewayAddresses],
+			"new", address.String(),
+		)
+		clusterManager.Status.GatewayReady = false
+		return ctrl.Result{Requeue: true}, nil
 	}
 
-	// protocol mapper 생성 (kibana, jaeger, hyperregistry, opensearch)
-	protocolMapperMappingConfigs := hyperauthCaller.GetMappingProtocolMapperToClientConfigPreset(clusterManager.GetNamespacedPrefix())
-	for _, config := range protocolMapperMappingConfigs {
-		if err := hyperauthCaller.CreateClientLevelProtocolMapper(config, secret); err != nil {
-			log.Error(err, "Failed to create hyperauth protocol mapper ["+config.ClientId+"] for single cluster")
-			return ctrl.Result{RequeueAfter: requeueAfter10Second}, err
-		}
-	}
+	return result, nil
+}
 
-	// client-level role을 생성하고 role에 cluster admin 계정을 mapping (kibana, jaeger, opensearch)
-	clientLevelRoleConfigs := hyperauthCaller.GetClientLevelRoleConfigPreset(clusterManager.GetNamespacedPrefix())
-	for _, config := range clientLevelRoleConfigs {
-		if err := hyperauthCaller.CreateClientLevelRole(config, secret); err != nil {
-			log.Error(err, "Failed to create hyperauth client-level role ["+config.ClientId+"] for single cluster")
-			return ctrl.Result{RequeueAfter: requeueAfter10Second}, err
-		}
+// getGatewayAddress 는 single cluster 의 api-gateway-system 네임스페이스의 gateway service 를 조회하여 gateway 주소를 구한다.
+func (r *ClusterManagerReconciler) getGatewayAddress(clusterManager *clusterV1alpha1.ClusterManager, remoteClientset *kubernetes.Clientset) (*GatewayAddress, error) {
+	log := r.Log.WithValues("clustermanager", clusterManager.GetNamespacedName())
 
-		userEmail := clusterManager.Annotations[util.AnnotationKeyOwner]
-		if err := hyperauthCaller.AddClientLevelRolesToUserRoleMapping(config, userEmail, secret); err != nil {
-			log.Error(err, "Failed to add client-level role to user role mapping ["+config.ClientId+"] for single cluster")
-			return ctrl.Result{RequeueAfter: requeueAfter10Second}, err
-		}
+	gatewayService, err := remoteClientset.
+		CoreV1().
+		Services(util.ApiGatewayNamespace).
+		Get(context.TODO(), util.ApiGatewayServiceName, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return nil, waitingForRemoteError{fmt.Errorf("cannot find Service for gateway. Wait for installing api-gateway")}
+	} else if err != nil {
+		log.Error(err, "Failed to get Service for gateway")
+		return nil, err
 	}
 
-	// client와 client scope를 매핑 (kiali)
-	clientScopeMappingConfig := hyperauthCaller.GetClientScopeMappingPreset(clusterManager.GetNamespacedPrefix())
-	for _, config := range clientScopeMappingConfig {
-		err := hyperauthCaller.AddClientScopeToClient(config, secret)
-		if err != nil {
-			log.Error(err, "Failed to add client scope to client ["+config.ClientId+"] for single cluster")
-			return ctrl.Result{RequeueAfter: requeueAfter10Second}, err
-		}
+	address, err := GetGatewayAddress(remoteClientset, clusterManager, gatewayService)
+	if err != nil && !isWaitingForRemote(err) {
+		log.Error(err, "Failed to get gateway address")
 	}
+	return address, err
+}
 
-	// group을 생성하고 cluster owner에게 group을 mapping
-	groupConfig := hyperauthCaller.GetGroupConfigPreset(clusterManager.GetNamespacedPrefix())
-	for _, config := range groupConfig {
-		err := hyperauthCaller.CreateGroup(config, secret)
-		if err != nil {
-			log.Error(err, "Failed to create group ["+config.Name+"] for single cluster")
-			return ctrl.Result{RequeueAfter: requeueAfter10Second}, err
-		}
+func (r *ClusterManagerReconciler) CreateHyperAuthResources(ctx context.Context, clusterManager *clusterV1alpha1.ClusterManager) (reconcile.Result, error) {
+	log := r.Log.WithValues("clustermanager", clusterManager.GetNamespacedName())
+	log.Info("Start to reconcile phase for CreateHyperauthClient")
 
-		err = hyperauthCaller.AddGroupToUser(clusterManager.Annotations[util.AnnotationKeyOwner], config, secret)
-		if err != nil {
-			log.Error(err, "Failed to add group to user ["+config.Name+"] for single cluster")
-			return ctrl.Result{RequeueAfter: requeueAfter10Second}, err
-		}
+	// Hyperauth와 연동해야 하는 module 리스트는 정해져 있으므로, preset.go에서 관리
+	// cluster마다 client 이름이 달라야 해서 {namespace}-{cluster name} 를 prefix로
+	// 붙여주기로 했기 때문에, preset을 기본토대로 prefix를 추가하여 리턴하도록 구성
+	// client (kibana, grafana, kiali, jaeger, hyperregistry, opensearch), protocol mapper, client-level role,
+	// client scope mapping, group 을 만들고 cluster owner 에게 role 과 group 을 mapping 한다.
+	if err := r.SyncHyperAuthResourcesForSingleCluster(ctx, clusterManager); err != nil {
+		return ctrl.Result{RequeueAfter: requeueAfter10Second}, err
 	}
 
 	log.Info("Create clients for single cluster successfully")
@@ -922,6 +1083,20 @@ func (r *ClusterManagerReconciler) CreateHyperAuthResources(ctx context.Context,
 	return ctrl.Result{}, nil
 }
 
+// HyperAuth 의 리소스가 삭제되거나 수정된 경우, 또는 preset 이 바뀐 경우 이를 반영하기 위해 주기적으로 다시 동기화한다.
+func (r *ClusterManagerReconciler) SyncHyperAuthResources(ctx context.Context, clusterManager *clusterV1alpha1.ClusterManager) (ctrl.Result, error) {
+	interval := util.GetHyperAuthSyncInterval()
+	if !r.IsHyperAuthSyncNeeded(ctx, clusterManager, interval) {
+		return ctrl.Result{RequeueAfter: interval}, nil
+	}
+
+	if err := r.SyncHyperAuthResourcesForSingleCluster(ctx, clusterManager); err != nil {
+		return ctrl.Result{RequeueAfter: requeueAfter10Second}, err
+	}
+
+	return ctrl.Result{RequeueAfter: interval}, nil
+}
+
 // func (r *ClusterManagerReconciler) SetHyperregistryOidcConfig(ctx context.Context, clusterManager *clusterV1alpha1.ClusterManager) (reconcile.Result, error) {
 // 	if !clusterManager.Status.AuthClientReady || clusterManager.Status.HyperregistryOidcReady {
 // 		return ctrl.Result{}, nil
@@ -969,7 +1144,12 @@ func (r *ClusterManagerReconciler) CreateHyperAuthResources(ctx context.Context,
 
 // 	// hyperregistry의 경우 configmap이나 deploy의 env로 oidc 정보를 줄 수 없게 되어 있어서
 // 	// http request를 생성하여 oidc 정보를 put 할 수 있도록 구현
-// 	hyperauthDomain := "https://" + os.Getenv("AUTH_SUBDOMAIN") + "." + os.Getenv("HC_DOMAIN") + "/auth/realms/tmax"
+// 	realm, err := util.GetHyperAuthRealmFor(context.TODO(), r.Client, clusterManager)
+// 	if err != nil {
+// 		log.Error(err, "Failed to get HyperAuth realm")
+// 		return ctrl.Result{}, err
+// 	}
+// 	hyperauthDomain := util.GetHyperAuthIssuer(realm)
 // 	config := util.OidcConfig{
 // 		AuthMode:         "oidc_auth",
 // 		OidcAdminGroup:   "admin",
@@ -995,29 +1175,49 @@ func (r *ClusterManagerReconciler) CreateHyperAuthResources(ctx context.Context,
 // }
 
 func (r *ClusterManagerReconciler) CreateTraefikResources(ctx context.Context, clusterManager *clusterV1alpha1.ClusterManager) (ctrl.Result, error) {
-	if !clusterManager.Status.AuthClientReady || clusterManager.Status.TraefikReady {
-		return ctrl.Result{}, nil
-	}
 	log := r.Log.WithValues("clustermanager", clusterManager.GetNamespacedName())
 	log.Info("Start to reconcile phase for CreateTraefikResources")
 
-	if err := r.CreateCertificate(clusterManager); err != nil {
-		return ctrl.Result{}, err
+	// 노출 방식(backend)이 바뀐 경우 이전 backend 의 리소스를 정리하고,
+	// gateway 의 TLS 리소스도 새 backend 로 다시 만들 수 있도록 gateway 부터 다시 진행한다.
+	backend := r.GetExposureBackend(util.GetExposureBackend())
+	if used, ok := clusterManager.Annotations[util.AnnotationKeyExposureBackend]; ok && used != backend.Name() {
+		log.Info("Exposure backend is changed", "from", used, "to", backend.Name())
+		if err := r.GetExposureBackend(used).Delete(clusterManager); err != nil {
+			return ctrl.Result{}, err
+		}
+		clusterManager.Annotations[util.AnnotationKeyExposureBackend] = backend.Name()
+		clusterManager.Status.GatewayReady = false
+		clusterManager.Status.TraefikReady = false
+		return ctrl.Result{Requeue: true}, nil
 	}
 
-	if err := r.CreateMiddleware(clusterManager); err != nil {
+	if err := r.CreateServiceAccountSecret(clusterManager); isWaitingForRemote(err) {
+		log.Info("Waiting for service account from remote cluster")
+		return r.waitForRemoteResource(clusterManager, requeueAfter10Second), nil
+	} else if err != nil {
 		return ctrl.Result{}, err
 	}
 
-	if err := r.CreateServiceAccountSecret(clusterManager); err != nil {
+	// kubernetes api 는 사용자로 impersonate 하는 cluster proxy 를 통해 single cluster 로 전달한다.
+	if err := r.CreateProxyService(clusterManager); err != nil {
 		return ctrl.Result{}, err
 	}
 
-	if err := r.CreateIngress(clusterManager); err != nil {
+	if _, invalidPaths := splitExposedPaths(clusterManager); len(invalidPaths) != 0 {
+		log.Info("Ignore exposed paths that are not DNS labels", "paths", invalidPaths)
+	}
+	if err := backend.Expose(clusterManager); isCertificateNotReady(err) {
+		// certificate 의 상태가 바뀌면 다시 reconcile 된다.
+		log.Info("Waiting for certificate to be ready", "reason", err.Error())
+		return ctrl.Result{RequeueAfter: requeueAfter1Minute}, nil
+	} else if err != nil {
 		return ctrl.Result{}, err
 	}
 
-	log.Info("Create traefik resources successfully")
+	log.Info("Create traefik resources successfully", "backend", backend.Name())
+	clusterManager.Annotations[util.AnnotationKeyExposureBackend] = backend.Name()
+	clusterManager.Annotations[util.AnnotationKeyIngressConfigHash] = GetIngressConfigHash(clusterManager)
 	clusterManager.Status.TraefikReady = true
 	return ctrl.Result{}, nil
 }
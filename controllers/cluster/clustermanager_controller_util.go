@@ -16,11 +16,20 @@ package controllers
 
 import (
 	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
 	"encoding/json"
+	stderrors "errors"
 	"fmt"
+	"net"
 	"os"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	argocdV1alpha1 "github.com/argoproj/argo-cd/v2/pkg/apis/application/v1alpha1"
 	certmanagerV1 "github.com/jetstack/cert-manager/pkg/apis/certmanager/v1"
@@ -33,15 +42,23 @@ import (
 	capiV1alpha3 "sigs.k8s.io/cluster-api/api/v1alpha3"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 
 	servicecatalogv1beta1 "github.com/kubernetes-sigs/service-catalog/pkg/apis/servicecatalog/v1beta1"
 	coreV1 "k8s.io/api/core/v1"
 	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
 )
 
 type Parameters interface {
@@ -56,10 +73,16 @@ type ClusterParameter struct {
 	Owner             string
 	KubernetesVersion string
 	HyperAuthUrl      string
+
+	// HyperAuthUrl 에 사용할 realm. 비어 있으면 operator 의 realm 을 사용한다.
+	realm string
 }
 
 func (p *ClusterParameter) SetParameter(clusterManager clusterV1alpha1.ClusterManager) {
-	hyperauthDomain := "https://" + os.Getenv("AUTH_SUBDOMAIN") + "." + os.Getenv("HC_DOMAIN") + "/auth/realms/tmax"
+	if p.realm == "" {
+		p.realm = util.GetHyperAuthRealm()
+	}
+	hyperauthDomain := util.GetHyperAuthIssuer(p.realm)
 	p.Namespace = clusterManager.Namespace
 	p.ClusterName = clusterManager.Name
 	p.Owner = clusterManager.Annotations[util.AnnotationKeyOwner]
@@ -275,6 +298,172 @@ func (r *ClusterManagerReconciler) GetUpgradeMachinesInfo(clusterManager *cluste
 	return machineUpgrade, nil
 }
 
+// SetReconcilePhaseStatus 는 phase 별 수행 결과를 ClusterManager 의 status 에 기록한다.
+// 결과가 바뀌지 않은 phase 는 이전 기록을 유지하여, 매 reconcile 마다 status 가 update 되지 않도록 한다.
+// error message 는 요청마다 달라질 수 있으므로, 계속 실패하는 phase 는 처음 실패했을 때의 message 를 유지한다.
+func SetReconcilePhaseStatus(c *clusterV1alpha1.ClusterManager, outcomes []util.PhaseOutcome) {
+	previous := map[string]clusterV1alpha1.ReconcilePhaseStatus{}
+	for _, phaseStatus := range c.Status.ReconcilePhases {
+		previous[phaseStatus.Name] = phaseStatus
+	}
+
+	phaseStatuses := []clusterV1alpha1.ReconcilePhaseStatus{}
+	for _, outcome := range outcomes {
+		phaseStatus := clusterV1alpha1.ReconcilePhaseStatus{
+			Name:    outcome.Name,
+			State:   string(outcome.State),
+			Message: outcome.Message,
+		}
+		if outcome.State == util.PhaseStateRequeued {
+			phaseStatus.RequeueAfter = outcome.Result.RequeueAfter.String()
+		}
+
+		prev, ok := previous[outcome.Name]
+		// 이전에 수행이 완료된 phase 가 이번 reconcile 에서 skip 된 경우에는 완료 기록을 유지한다.
+		isStillDone := outcome.State == util.PhaseStateDone && prev.State == string(util.PhaseStateSucceeded)
+		isStillFailed := outcome.State == util.PhaseStateFailed && prev.State == phaseStatus.State
+		if ok && (isStillDone || isStillFailed ||
+			(prev.State == phaseStatus.State && prev.Message == phaseStatus.Message && prev.RequeueAfter == phaseStatus.RequeueAfter)) {
+			phaseStatuses = append(phaseStatuses, prev)
+			continue
+		}
+
+		if outcome.Duration != 0 {
+			phaseStatus.Duration = outcome.Duration.Round(time.Millisecond).String()
+		}
+		phaseStatus.LastTransitionTime = metav1.Now()
+		phaseStatuses = append(phaseStatuses, phaseStatus)
+	}
+	c.Status.ReconcilePhases = phaseStatuses
+}
+
+// SetArgoApplicationStatus 는 app-of-apps application 의 상태를 status 에 반영한다.
+// 불필요한 status update 를 막기 위해 상태가 바뀐 경우에만 LastTransitionTime 을 갱신한다.
+func SetArgoApplicationStatus(c *clusterV1alpha1.ClusterManager, status *clusterV1alpha1.ArgoApplicationStatus) {
+	if status == nil {
+		c.Status.ArgoApplication = nil
+		return
+	}
+
+	if prev := c.Status.ArgoApplication; prev != nil {
+		status.LastTransitionTime = prev.LastTransitionTime
+		if equality.Semantic.DeepEqual(prev, status) {
+			return
+		}
+	}
+	status.LastTransitionTime = metav1.Now()
+	c.Status.ArgoApplication = status
+}
+
+// DeleteForeignProjectApplications 는 app-of-apps application 이 만든 하위 application 중 cluster project 가 아닌 것을 삭제한다.
+// 하위 application 의 project 는 ApplicationValidator 가 생성 단계에서 막으므로,
+// webhook 을 등록하기 전에 만들어진 application 들을 정리하기 위해 사용한다.
+func (r *ClusterManagerReconciler) DeleteForeignProjectApplications(clusterManager *clusterV1alpha1.ClusterManager, application *argocdV1alpha1.Application, applications []argocdV1alpha1.Application) error {
+	log := r.Log.WithValues("clustermanager", clusterManager.GetNamespacedName())
+
+	children := map[string]bool{}
+	for _, resource := range application.Status.Resources {
+		if resource.Kind == argocdV1alpha1.ApplicationSchemaGroupVersionKind.Kind &&
+			resource.Group == argocdV1alpha1.ApplicationSchemaGroupVersionKind.Group &&
+			resource.Namespace == util.ArgoNamespace {
+			children[resource.Name] = true
+		}
+	}
+
+	for i := range applications {
+		child := &applications[i]
+		if !children[child.Name] || child.Name == application.Name ||
+			child.Spec.Project == clusterManager.GetNamespacedPrefix() || child.DeletionTimestamp != nil {
+			continue
+		}
+		if err := r.Delete(context.TODO(), child); err != nil && !errors.IsNotFound(err) {
+			log.Error(err, "Failed to delete Application ["+child.Name+"] of foreign project")
+			return err
+		}
+		log.Info("Delete Application ["+child.Name+"] of foreign project", "project", child.Spec.Project)
+	}
+
+	return nil
+}
+
+// newArgoApplicationStatus 는 app-of-apps application 과 하위 application 들로부터 상태를 만든다.
+// 하위 application 은 app-of-apps 가 관리하는 리소스 중 Application 들이다.
+func newArgoApplicationStatus(application *argocdV1alpha1.Application, applications []argocdV1alpha1.Application) *clusterV1alpha1.ArgoApplicationStatus {
+	status := &clusterV1alpha1.ArgoApplicationStatus{
+		Name:         application.Name,
+		SyncStatus:   string(application.Status.Sync.Status),
+		HealthStatus: string(application.Status.Health.Status),
+		Revision:     application.Status.Sync.Revision,
+	}
+	if application.Status.OperationState != nil {
+		status.OperationPhase = string(application.Status.OperationState.Phase)
+		status.OperationMessage = application.Status.OperationState.Message
+		startedAt := application.Status.OperationState.StartedAt
+		status.OperationStartedTime = &startedAt
+	}
+
+	children := map[string]*argocdV1alpha1.Application{}
+	for i := range applications {
+		children[applications[i].Name] = &applications[i]
+	}
+
+	for _, resource := range application.Status.Resources {
+		if resource.Kind != argocdV1alpha1.ApplicationSchemaGroupVersionKind.Kind ||
+			resource.Group != argocdV1alpha1.ApplicationSchemaGroupVersionKind.Group {
+			continue
+		}
+		status.Applications++
+
+		child, ok := children[resource.Name]
+		if !ok {
+			status.FailedApplications = append(status.FailedApplications, clusterV1alpha1.FailedArgoApplication{
+				Name:         resource.Name,
+				SyncStatus:   string(resource.Status),
+				HealthStatus: util.ArgoHealthStatusMissing,
+			})
+			continue
+		}
+
+		syncStatus := child.Status.Sync.Status
+		healthStatus := string(child.Status.Health.Status)
+		if syncStatus == argocdV1alpha1.SyncStatusCodeSynced && healthStatus == util.ArgoHealthStatusHealthy {
+			status.ReadyApplications++
+			continue
+		}
+
+		operationPhase := ""
+		operationMessage := ""
+		if child.Status.OperationState != nil {
+			operationPhase = string(child.Status.OperationState.Phase)
+			operationMessage = child.Status.OperationState.Message
+		}
+		isFailed := healthStatus == util.ArgoHealthStatusDegraded ||
+			healthStatus == util.ArgoHealthStatusMissing ||
+			operationPhase == util.ArgoOperationPhaseFailed ||
+			operationPhase == util.ArgoOperationPhaseError
+		if !isFailed {
+			continue
+		}
+
+		message := child.Status.Health.Message
+		if operationPhase == util.ArgoOperationPhaseFailed || operationPhase == util.ArgoOperationPhaseError {
+			message = operationMessage
+		}
+		status.FailedApplications = append(status.FailedApplications, clusterV1alpha1.FailedArgoApplication{
+			Name:         child.Name,
+			SyncStatus:   string(syncStatus),
+			HealthStatus: healthStatus,
+			Message:      message,
+		})
+	}
+
+	sort.Slice(status.FailedApplications, func(i, j int) bool {
+		return status.FailedApplications[i].Name < status.FailedApplications[j].Name
+	})
+
+	return status
+}
+
 func SetApplicationLink(c *clusterV1alpha1.ClusterManager, subdomain string) {
 	c.Status.ApplicationLink = strings.Join(
 		[]string{
@@ -312,488 +501,1840 @@ func (r *ClusterManagerReconciler) GetKubeconfigSecret(clusterManager *clusterV1
 	return kubeconfigSecret, nil
 }
 
+// waitingForRemoteError는 single cluster 의 리소스가 아직 준비되지 않아 기다려야 하는 경우를 나타낸다.
+type waitingForRemoteError struct {
+	error
+}
+
+func isWaitingForRemote(err error) bool {
+	_, ok := err.(waitingForRemoteError)
+	return ok
+}
+
+// errGatewayCANotFound는 gateway 의 certificate 를 검증할 CA 를 찾지 못한 경우를 나타낸다.
+var errGatewayCANotFound = stderrors.New("cannot find CA for gateway from gateway tls secret and kubeconfig")
+
+// errCertificateNotReady는 cert-manager 가 multicluster ingress 의 certificate 를 아직 발급하지 않은 경우를 나타낸다.
+var errCertificateNotReady = stderrors.New("certificate for multicluster ingress is not ready")
+
+func isCertificateNotReady(err error) bool {
+	return stderrors.Is(err, errCertificateNotReady)
+}
+
+// single cluster 의 리소스를 기다려야 하는 경우의 requeue 정책을 반환한다.
+// remote informer 가 동작 중이라면 remote 리소스의 변경이 바로 ClusterManager 를 requeue 시키므로
+// polling 주기를 길게 가져가고, 그렇지 않은 경우에는 주어진 주기로 polling 한다.
+func (r *ClusterManagerReconciler) waitForRemoteResource(clusterManager *clusterV1alpha1.ClusterManager, after time.Duration) ctrl.Result {
+	if r.RemoteWatches.IsWatching(clusterManager.GetNamespacedName()) {
+		return ctrl.Result{RequeueAfter: requeueAfter5Minute}
+	}
+	return ctrl.Result{RequeueAfter: after}
+}
+
 func (r *ClusterManagerReconciler) CreateCertificate(clusterManager *clusterV1alpha1.ClusterManager) error {
 	log := r.Log.WithValues("clustermanager", clusterManager.GetNamespacedName())
 
-	key := types.NamespacedName{
-		Name:      clusterManager.Name + "-certificate",
-		Namespace: clusterManager.Namespace,
-	}
-	err := r.Get(context.TODO(), key, &certmanagerV1.Certificate{})
-	if errors.IsNotFound(err) {
-		certificate := &certmanagerV1.Certificate{
-			ObjectMeta: metav1.ObjectMeta{
-				Name:      key.Name,
-				Namespace: key.Namespace,
-				Annotations: map[string]string{
-					util.AnnotationKeyOwner:   clusterManager.Annotations[util.AnnotationKeyCreator],
-					util.AnnotationKeyCreator: clusterManager.Annotations[util.AnnotationKeyCreator],
-				},
-				Labels: map[string]string{
-					clusterV1alpha1.LabelKeyClmName: clusterManager.Name,
-				},
+	// issuer 나 host 설정이 바뀌었거나 다른 곳에서 수정한 경우에도 apply 로 되돌린다.
+	certificate := &certmanagerV1.Certificate{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      clusterManager.Name + "-certificate",
+			Namespace: clusterManager.Namespace,
+			Annotations: map[string]string{
+				util.AnnotationKeyOwner:   clusterManager.Annotations[util.AnnotationKeyCreator],
+				util.AnnotationKeyCreator: clusterManager.Annotations[util.AnnotationKeyCreator],
 			},
-			Spec: certmanagerV1.CertificateSpec{
-				SecretName: clusterManager.Name + "-service-cert",
-				IsCA:       false,
-				Usages: []certmanagerV1.KeyUsage{
-					certmanagerV1.UsageDigitalSignature,
-					certmanagerV1.UsageKeyEncipherment,
-					certmanagerV1.UsageServerAuth,
-					certmanagerV1.UsageClientAuth,
-				},
-				DNSNames: []string{
-					"multicluster." + clusterManager.Annotations[clusterV1alpha1.AnnotationKeyClmDomain],
-				},
-				IssuerRef: certmanagerMetaV1.ObjectReference{
-					Name:  "tmaxcloud-issuer",
-					Kind:  certmanagerV1.ClusterIssuerKind,
-					Group: certmanagerV1.SchemeGroupVersion.Group,
-				},
+			Labels: map[string]string{
+				clusterV1alpha1.LabelKeyClmName: clusterManager.Name,
 			},
-		}
-		if err := r.Create(context.TODO(), certificate); err != nil {
-			log.Error(err, "Failed to Create Certificate")
-			return err
-		}
-
-		log.Info("Create Certificate successfully")
-		ctrl.SetControllerReference(clusterManager, certificate, r.Scheme)
-		return nil
+		},
+		Spec: certmanagerV1.CertificateSpec{
+			SecretName: clusterManager.Name + "-service-cert",
+			IsCA:       false,
+			Usages: []certmanagerV1.KeyUsage{
+				certmanagerV1.UsageDigitalSignature,
+				certmanagerV1.UsageKeyEncipherment,
+				certmanagerV1.UsageServerAuth,
+				certmanagerV1.UsageClientAuth,
+			},
+			DNSNames: []string{
+				GetMulticlusterHost(clusterManager),
+			},
+			IssuerRef: certmanagerMetaV1.ObjectReference{
+				Name:  util.GetClusterIssuer(),
+				Kind:  certmanagerV1.ClusterIssuerKind,
+				Group: certmanagerV1.SchemeGroupVersion.Group,
+			},
+		},
+	}
+	result, err := r.apply(clusterManager, certificate)
+	if err != nil {
+		log.Error(err, "Failed to apply Certificate")
+		return err
 	}
+	logApplyResult(log, result, "Certificate")
 
-	return err
+	return nil
 }
 
-func (r *ClusterManagerReconciler) CreateIngress(clusterManager *clusterV1alpha1.ClusterManager) error {
+// CheckCertificateReady 는 cert-manager 가 certificate 를 발급하여 Ready 상태가 되었는지 확인한다.
+func (r *ClusterManagerReconciler) CheckCertificateReady(clusterManager *clusterV1alpha1.ClusterManager) error {
 	log := r.Log.WithValues("clustermanager", clusterManager.GetNamespacedName())
 
 	key := types.NamespacedName{
-		Name:      clusterManager.Name + "-ingress",
+		Name:      clusterManager.Name + "-certificate",
 		Namespace: clusterManager.Namespace,
 	}
-	err := r.Get(context.TODO(), key, &networkingv1.Ingress{})
-	if errors.IsNotFound(err) {
-		provider := "tmax-cloud"
-		pathType := networkingv1.PathTypePrefix
-		prefixMiddleware := clusterManager.GetNamespacedPrefix() + "-prefix@kubernetescrd"
-		multiclusterDNS := "multicluster." + clusterManager.Annotations[clusterV1alpha1.AnnotationKeyClmDomain]
-		urlPath := "/api/" + clusterManager.Namespace + "/" + clusterManager.Name
-		ingress := &networkingv1.Ingress{
-			ObjectMeta: metav1.ObjectMeta{
-				Name:      key.Name,
-				Namespace: key.Namespace,
-				Annotations: map[string]string{
-					util.AnnotationKeyTraefikEntrypoints: "websecure",
-					util.AnnotationKeyTraefikMiddlewares: "api-gateway-system-jwt-decode-auth@kubernetescrd," + prefixMiddleware,
-					util.AnnotationKeyOwner:              clusterManager.Annotations[util.AnnotationKeyCreator],
-					util.AnnotationKeyCreator:            clusterManager.Annotations[util.AnnotationKeyCreator],
-				},
-				Labels: map[string]string{
-					util.LabelKeyHypercloudIngress:  "multicluster",
-					clusterV1alpha1.LabelKeyClmName: clusterManager.Name,
-				},
+	certificate := &certmanagerV1.Certificate{}
+	if err := r.Get(context.TODO(), key, certificate); err != nil {
+		log.Error(err, "Failed to get Certificate")
+		return err
+	}
+
+	if !isCertificateReady(certificate) {
+		return errCertificateNotReady
+	}
+	return nil
+}
+
+// CreateIngress 는 single cluster 의 gateway 로 전달할 path 들을 가지는 ingress 를 생성한다.
+// 노출할 path 는 operator 설정(MULTICLUSTER_EXPOSED_PATHS)과 ClusterManager 의 annotation 으로 정해지며,
+// 설정이 바뀐 경우 ingress 를 갱신한다.
+func (r *ClusterManagerReconciler) CreateIngress(clusterManager *clusterV1alpha1.ClusterManager, middlewares string) error {
+	log := r.Log.WithValues("clustermanager", clusterManager.GetNamespacedName())
+
+	// ingress class, host, 노출할 path, middleware chain 이 바뀌었거나 다른 곳에서 수정한 경우에도 apply 로 되돌린다.
+	// (이전 버전에서 만들어진 ingress 에 남아있는 kubernetes path 도 이때 제거된다.)
+	ingress := &networkingv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      clusterManager.Name + "-ingress",
+			Namespace: clusterManager.Namespace,
+			Annotations: map[string]string{
+				util.AnnotationKeyTraefikEntrypoints: "websecure",
+				util.AnnotationKeyTraefikMiddlewares: middlewares,
+				util.AnnotationKeyOwner:              clusterManager.Annotations[util.AnnotationKeyCreator],
+				util.AnnotationKeyCreator:            clusterManager.Annotations[util.AnnotationKeyCreator],
 			},
-			Spec: networkingv1.IngressSpec{
-				IngressClassName: &provider,
-				Rules: []networkingv1.IngressRule{
-					{
-						Host: multiclusterDNS,
-						IngressRuleValue: networkingv1.IngressRuleValue{
-							HTTP: &networkingv1.HTTPIngressRuleValue{
-								Paths: []networkingv1.HTTPIngressPath{
-									{
-										Path:     urlPath + "/api/kubernetes",
-										PathType: &pathType,
-										Backend: networkingv1.IngressBackend{
-											Service: &networkingv1.IngressServiceBackend{
-												Name: clusterManager.Name + "-gateway-service",
-												Port: networkingv1.ServiceBackendPort{
-													Number: 443,
-												},
-											},
-										},
-									},
-									{
-										Path:     urlPath + "/api/prometheus",
-										PathType: &pathType,
-										Backend: networkingv1.IngressBackend{
-											Service: &networkingv1.IngressServiceBackend{
-												Name: clusterManager.Name + "-gateway-service",
-												Port: networkingv1.ServiceBackendPort{
-													Number: 443,
-												},
-											},
-										},
-									},
-								},
-							},
-						},
-					},
-				},
-				TLS: []networkingv1.IngressTLS{
-					{
-						Hosts: []string{
-							multiclusterDNS,
-						},
-					},
-				},
+			Labels: map[string]string{
+				util.LabelKeyHypercloudIngress:  "multicluster",
+				clusterV1alpha1.LabelKeyClmName: clusterManager.Name,
 			},
-		}
-		if err := r.Create(context.TODO(), ingress); err != nil {
-			log.Error(err, "Failed to Create Ingress")
-			return err
-		}
-
-		log.Info("Create Ingress successfully")
-		ctrl.SetControllerReference(clusterManager, ingress, r.Scheme)
-		return nil
+		},
+		Spec: newMulticlusterIngressSpec(clusterManager, newGatewayIngressPaths(clusterManager)),
+	}
+	result, err := r.apply(clusterManager, ingress)
+	if err != nil {
+		log.Error(err, "Failed to apply Ingress")
+		return err
 	}
+	logApplyResult(log, result, "Ingress")
 
-	return err
+	return nil
 }
 
-func (r *ClusterManagerReconciler) CreateGatewayService(clusterManager *clusterV1alpha1.ClusterManager, annotationKey string) error {
+// CreateDiscoveryIngress 는 gateway-api backend 에서 console 이 노출된 cluster 와 path 들을 찾을 수 있도록 조회용 ingress 를 생성한다.
+// 요청은 HTTPRoute 로 전달되므로, jwt 인증 없이 노출되지 않도록 처리하는 ingress controller 가 없는 class 를 사용한다.
+// traefik backend 의 ingress 와 이름이 같으므로 backend 를 바꾸는 경우에도 하나만 남는다.
+func (r *ClusterManagerReconciler) CreateDiscoveryIngress(clusterManager *clusterV1alpha1.ClusterManager) error {
 	log := r.Log.WithValues("clustermanager", clusterManager.GetNamespacedName())
 
-	key := types.NamespacedName{
-		Name:      clusterManager.Name + "-gateway-service",
-		Namespace: clusterManager.Namespace,
-	}
-	err := r.Get(context.TODO(), key, &coreV1.Service{})
-	if errors.IsNotFound(err) {
-		service := &coreV1.Service{
-			ObjectMeta: metav1.ObjectMeta{
-				Name:      key.Name,
-				Namespace: key.Namespace,
-				Annotations: map[string]string{
-					util.AnnotationKeyOwner:                  clusterManager.Annotations[util.AnnotationKeyCreator],
-					util.AnnotationKeyCreator:                clusterManager.Annotations[util.AnnotationKeyCreator],
-					util.AnnotationKeyTraefikServerScheme:    "https",
-					util.AnnotationKeyTraefikServerTransport: "insecure@file",
-				},
-				Labels: map[string]string{
-					clusterV1alpha1.LabelKeyClmName: clusterManager.Name,
-				},
+	spec := newMulticlusterIngressSpec(clusterManager, newGatewayIngressPaths(clusterManager))
+	ingressClass := util.DiscoveryIngressClass
+	spec.IngressClassName = &ingressClass
+	ingress := &networkingv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      clusterManager.Name + "-ingress",
+			Namespace: clusterManager.Namespace,
+			Annotations: map[string]string{
+				util.AnnotationKeyOwner:   clusterManager.Annotations[util.AnnotationKeyCreator],
+				util.AnnotationKeyCreator: clusterManager.Annotations[util.AnnotationKeyCreator],
 			},
-			Spec: coreV1.ServiceSpec{
-				ExternalName: clusterManager.Annotations[annotationKey],
-				Ports: []coreV1.ServicePort{
-					{
-						Port:       443,
-						Protocol:   coreV1.ProtocolTCP,
-						TargetPort: intstr.FromInt(443),
+			Labels: map[string]string{
+				util.LabelKeyHypercloudIngress:  "multicluster",
+				clusterV1alpha1.LabelKeyClmName: clusterManager.Name,
+			},
+		},
+		Spec: spec,
+	}
+	result, err := r.apply(clusterManager, ingress)
+	if err != nil {
+		log.Error(err, "Failed to apply Ingress for discovery")
+		return err
+	}
+	logApplyResult(log, result, "Ingress for discovery")
+
+	return nil
+}
+
+// newGatewayIngressPaths 는 노출할 api 들을 single cluster 의 gateway 로 전달하는 ingress path 들을 만든다.
+func newGatewayIngressPaths(clusterManager *clusterV1alpha1.ClusterManager) []networkingv1.HTTPIngressPath {
+	pathType := networkingv1.PathTypePrefix
+	urlPath := "/api/" + clusterManager.Namespace + "/" + clusterManager.Name
+	paths := []networkingv1.HTTPIngressPath{}
+	for _, exposedPath := range GetExposedPaths(clusterManager) {
+		paths = append(paths, networkingv1.HTTPIngressPath{
+			Path:     urlPath + "/api/" + exposedPath,
+			PathType: &pathType,
+			Backend: networkingv1.IngressBackend{
+				Service: &networkingv1.IngressServiceBackend{
+					Name: clusterManager.Name + "-gateway-service",
+					Port: networkingv1.ServiceBackendPort{
+						Number: 443,
 					},
 				},
-				Type: coreV1.ServiceTypeExternalName,
 			},
-		}
-		if err := r.Create(context.TODO(), service); err != nil {
-			log.Error(err, "Failed to Create Service for gateway")
-			return err
-		}
-
-		log.Info("Create Service for gateway successfully")
-		ctrl.SetControllerReference(clusterManager, service, r.Scheme)
-		return nil
+		})
 	}
-
-	return err
+	return paths
 }
 
-func (r *ClusterManagerReconciler) CreateGatewayEndpoint(clusterManager *clusterV1alpha1.ClusterManager) error {
+// CreateProxyIngress 는 single cluster 의 kubernetes api 요청을 cluster proxy 로 전달하는 ingress 를 생성한다.
+// cluster proxy 가 사용자의 HyperAuth token 을 직접 검증하므로 jwt-decode middleware 와 prefix middleware 를 사용하지 않고,
+// traffic policy 의 middleware 들만 사용한다.
+func (r *ClusterManagerReconciler) CreateProxyIngress(clusterManager *clusterV1alpha1.ClusterManager, middlewares string) error {
 	log := r.Log.WithValues("clustermanager", clusterManager.GetNamespacedName())
 
-	key := types.NamespacedName{
-		Name:      clusterManager.Name + "-gateway-service",
-		Namespace: clusterManager.Namespace,
-	}
-	err := r.Get(context.TODO(), key, &coreV1.Endpoints{})
-	if errors.IsNotFound(err) {
-		endpoint := &coreV1.Endpoints{
-			ObjectMeta: metav1.ObjectMeta{
-				Name:      key.Name,
-				Namespace: key.Namespace,
-				Annotations: map[string]string{
-					util.AnnotationKeyOwner:   clusterManager.Annotations[util.AnnotationKeyCreator],
-					util.AnnotationKeyCreator: clusterManager.Annotations[util.AnnotationKeyCreator],
-				},
-				Labels: map[string]string{
-					clusterV1alpha1.LabelKeyClmName: clusterManager.Name,
-				},
+	pathType := networkingv1.PathTypePrefix
+	urlPath := "/api/" + clusterManager.Namespace + "/" + clusterManager.Name
+	ingress := &networkingv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      clusterManager.Name + "-proxy-ingress",
+			Namespace: clusterManager.Namespace,
+			Annotations: map[string]string{
+				util.AnnotationKeyTraefikEntrypoints: "websecure",
+				util.AnnotationKeyOwner:              clusterManager.Annotations[util.AnnotationKeyCreator],
+				util.AnnotationKeyCreator:            clusterManager.Annotations[util.AnnotationKeyCreator],
 			},
-			Subsets: []coreV1.EndpointSubset{
-				{
-					Addresses: []coreV1.EndpointAddress{
-						{
-							IP: clusterManager.Annotations[clusterV1alpha1.AnnotationKeyClmGateway],
-						},
-					},
-					Ports: []coreV1.EndpointPort{
-						{
-							Port:     443,
-							Protocol: coreV1.ProtocolTCP,
+			Labels: map[string]string{
+				util.LabelKeyHypercloudIngress:  "multicluster",
+				clusterV1alpha1.LabelKeyClmName: clusterManager.Name,
+			},
+		},
+		Spec: newMulticlusterIngressSpec(clusterManager, []networkingv1.HTTPIngressPath{
+			{
+				Path:     urlPath + "/api/kubernetes",
+				PathType: &pathType,
+				Backend: networkingv1.IngressBackend{
+					Service: &networkingv1.IngressServiceBackend{
+						Name: clusterManager.Name + "-proxy-service",
+						Port: networkingv1.ServiceBackendPort{
+							Number: util.ClusterProxyPort,
 						},
 					},
 				},
 			},
-		}
-		if err := r.Create(context.TODO(), endpoint); err != nil {
-			log.Error(err, "Failed to Create Endpoint for gateway")
-			return err
-		}
-
-		log.Info("Create Endpoint for gateway successfully")
-		ctrl.SetControllerReference(clusterManager, endpoint, r.Scheme)
-		return nil
+		}),
+	}
+	// apply 에서 빠진 annotation 은 삭제되므로, traffic policy 가 없어지면 middleware annotation 도 삭제된다.
+	if middlewares != "" {
+		ingress.Annotations[util.AnnotationKeyTraefikMiddlewares] = middlewares
+	}
+	result, err := r.apply(clusterManager, ingress)
+	if err != nil {
+		log.Error(err, "Failed to apply Ingress for cluster proxy")
+		return err
 	}
+	logApplyResult(log, result, "Ingress for cluster proxy")
 
-	return err
+	return nil
 }
 
-func (r *ClusterManagerReconciler) CreateMiddleware(clusterManager *clusterV1alpha1.ClusterManager) error {
-	log := r.Log.WithValues("clustermanager", clusterManager.GetNamespacedName())
-
-	key := types.NamespacedName{
-		Name:      clusterManager.Name + "-prefix",
-		Namespace: clusterManager.Namespace,
-	}
-	err := r.Get(context.TODO(), key, &traefikV1alpha1.Middleware{})
-	if errors.IsNotFound(err) {
-		middleware := &traefikV1alpha1.Middleware{
-			ObjectMeta: metav1.ObjectMeta{
-				Name:      key.Name,
-				Namespace: key.Namespace,
-				Annotations: map[string]string{
-					util.AnnotationKeyOwner:   clusterManager.Annotations[util.AnnotationKeyCreator],
-					util.AnnotationKeyCreator: clusterManager.Annotations[util.AnnotationKeyCreator],
-				},
-				Labels: map[string]string{
-					clusterV1alpha1.LabelKeyClmName: clusterManager.Name,
+// newMulticlusterIngressSpec 은 operator 설정의 ingress class 와 multicluster host 를 사용하는 ingress spec 을 만든다.
+func newMulticlusterIngressSpec(clusterManager *clusterV1alpha1.ClusterManager, paths []networkingv1.HTTPIngressPath) networkingv1.IngressSpec {
+	ingressClass := util.GetIngressClass()
+	multiclusterDNS := GetMulticlusterHost(clusterManager)
+	return networkingv1.IngressSpec{
+		IngressClassName: &ingressClass,
+		Rules: []networkingv1.IngressRule{
+			{
+				Host: multiclusterDNS,
+				IngressRuleValue: networkingv1.IngressRuleValue{
+					HTTP: &networkingv1.HTTPIngressRuleValue{
+						Paths: paths,
+					},
 				},
 			},
-			Spec: traefikV1alpha1.MiddlewareSpec{
-				StripPrefix: &dynamicv2.StripPrefix{
-					Prefixes: []string{
-						"/api/" + clusterManager.Namespace + "/" + clusterManager.Name,
-					},
+		},
+		TLS: []networkingv1.IngressTLS{
+			{
+				Hosts: []string{
+					multiclusterDNS,
 				},
 			},
+		},
+	}
+}
+
+// GetMulticlusterHost 는 single cluster 의 api 들을 노출하는 host 를 반환한다. (ex. multicluster.tmaxcloud.org)
+func GetMulticlusterHost(clusterManager *clusterV1alpha1.ClusterManager) string {
+	return util.GetMulticlusterSubdomain() + "." + clusterManager.Annotations[clusterV1alpha1.AnnotationKeyClmDomain]
+}
+
+// GetExposedPaths 는 gateway 를 통해 노출할 single cluster 의 api 이름들을 반환한다.
+// operator 설정의 기본 path 들에 ClusterManager 의 annotation 으로 지정한 path 들을 더한다.
+// kubernetes api 는 cluster proxy 를 통해 노출하므로 제외하고, path 에 그대로 사용하므로 DNS label 이 아닌 이름도 제외한다.
+func GetExposedPaths(clusterManager *clusterV1alpha1.ClusterManager) []string {
+	exposedPaths, _ := splitExposedPaths(clusterManager)
+	return exposedPaths
+}
+
+// splitExposedPaths 는 노출할 api 이름들을 사용할 수 있는 것과 DNS label 이 아니어서 사용할 수 없는 것으로 나눈다.
+func splitExposedPaths(clusterManager *clusterV1alpha1.ClusterManager) ([]string, []string) {
+	exposedPaths, invalidPaths := []string{}, []string{}
+	exists := map[string]bool{
+		"kubernetes": true,
+	}
+	candidates := append(util.GetExposedPaths(), util.SplitList(clusterManager.Annotations[clusterV1alpha1.AnnotationKeyClmExposedPaths])...)
+	for _, exposedPath := range candidates {
+		if exists[exposedPath] {
+			continue
 		}
-		if err := r.Create(context.TODO(), middleware); err != nil {
-			log.Error(err, "Failed to Create Middleware")
-			return err
+		exists[exposedPath] = true
+		if len(validation.IsDNS1123Label(exposedPath)) != 0 {
+			invalidPaths = append(invalidPaths, exposedPath)
+			continue
 		}
-
-		log.Info("Create Middleware successfully")
-		ctrl.SetControllerReference(clusterManager, middleware, r.Scheme)
-		return nil
+		exposedPaths = append(exposedPaths, exposedPath)
 	}
+	return exposedPaths, invalidPaths
+}
 
-	return err
+// GetIngressConfigHash 는 ingress, certificate, middleware 에 반영되는 설정으로 부터 hash 값을 계산한다.
+func GetIngressConfigHash(clusterManager *clusterV1alpha1.ClusterManager) string {
+	parentNamespace, parentName, _ := util.GetGatewayAPIParent()
+	config := []string{
+		util.GetExposureBackend(),
+		parentNamespace + "/" + parentName,
+		util.GetIngressClass(),
+		util.GetClusterIssuer(),
+		GetMulticlusterHost(clusterManager),
+		strings.Join(GetExposedPaths(clusterManager), ","),
+	}
+	if clusterManager.Spec.TrafficPolicy != nil {
+		trafficPolicy, _ := json.Marshal(clusterManager.Spec.TrafficPolicy)
+		config = append(config, string(trafficPolicy))
+	}
+	sum := sha256.Sum256([]byte(strings.Join(config, "\n")))
+	return hex.EncodeToString(sum[:])
 }
 
-func (r *ClusterManagerReconciler) CreateServiceAccountSecret(clusterManager *clusterV1alpha1.ClusterManager) error {
+// CreateProxyService 는 ingress 가 operator namespace 의 cluster proxy 를 바라볼 수 있도록 ExternalName service 를 생성한다.
+func (r *ClusterManagerReconciler) CreateProxyService(clusterManager *clusterV1alpha1.ClusterManager) error {
 	log := r.Log.WithValues("clustermanager", clusterManager.GetNamespacedName())
 
-	re, _ := regexp.Compile("[" + regexp.QuoteMeta(`!#$%&'"*+-/=?^_{|}~().,:;<>[]\`) + "`\\s" + "]")
-	email := clusterManager.Annotations[util.AnnotationKeyOwner]
-	adminServiceAccountName := re.ReplaceAllString(strings.Replace(email, "@", "-at-", -1), "-")
-	kubeconfigSecret, err := r.GetKubeconfigSecret(clusterManager)
-	if err != nil {
-		log.Error(err, "Failed to get kubeconfig secret")
-		return err
+	service := &coreV1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      clusterManager.Name + "-proxy-service",
+			Namespace: clusterManager.Namespace,
+			Annotations: map[string]string{
+				util.AnnotationKeyOwner:   clusterManager.Annotations[util.AnnotationKeyCreator],
+				util.AnnotationKeyCreator: clusterManager.Annotations[util.AnnotationKeyCreator],
+			},
+			Labels: map[string]string{
+				clusterV1alpha1.LabelKeyClmName: clusterManager.Name,
+			},
+		},
+		Spec: coreV1.ServiceSpec{
+			ExternalName: util.GetClusterProxyHost(),
+			Ports: []coreV1.ServicePort{
+				{
+					Port:       util.ClusterProxyPort,
+					Protocol:   coreV1.ProtocolTCP,
+					TargetPort: intstr.FromInt(util.ClusterProxyPort),
+				},
+			},
+			Type: coreV1.ServiceTypeExternalName,
+		},
 	}
-
-	remoteClientset, err := util.GetRemoteK8sClient(kubeconfigSecret)
+	result, err := r.apply(clusterManager, service)
 	if err != nil {
-		log.Error(err, "Failed to get remoteK8sClient")
+		log.Error(err, "Failed to apply Service for cluster proxy")
 		return err
 	}
+	logApplyResult(log, result, "Service for cluster proxy")
 
-	tokenSecret, err := remoteClientset.
-		CoreV1().
-		Secrets(util.KubeNamespace).
-		Get(context.TODO(), adminServiceAccountName+"-token", metav1.GetOptions{})
-	if errors.IsNotFound(err) {
-		log.Info("Waiting for create service account token secret [" + adminServiceAccountName + "]")
-		return err
-	} else if err != nil {
-		log.Error(err, "Failed to get service account token secret ["+adminServiceAccountName+"-token]")
+	return nil
+}
+
+// CreateGatewayService 는 single cluster 의 gateway 로 연결되는 service 를 생성한다.
+// gateway 주소가 hostname 인 경우에는 ExternalName service 를, ip 인 경우에는 selector 가 없는 service 를 만들고
+// CreateGatewayEndpoint 로 모든 ip 를 endpoint 로 등록한다.
+func (r *ClusterManagerReconciler) CreateGatewayService(clusterManager *clusterV1alpha1.ClusterManager, address *GatewayAddress, backendAnnotations map[string]string) error {
+	log := r.Log.WithValues("clustermanager", clusterManager.GetNamespacedName())
+
+	if err := r.migrateGatewayService(clusterManager, address, backendAnnotations); err != nil {
 		return err
 	}
 
-	if string(tokenSecret.Data["token"]) == "" {
-		log.Info("Waiting for create service account token secret [" + adminServiceAccountName + "]")
-		return fmt.Errorf("service account token secret is not found")
+	// single cluster 의 gateway 주소나 backend 의 설정(ex. servers transport)이 바뀌었거나
+	// 다른 곳에서 수정한 경우에도 apply 로 되돌린다.
+	service := &coreV1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      clusterManager.Name + "-gateway-service",
+			Namespace: clusterManager.Namespace,
+			Annotations: map[string]string{
+				util.AnnotationKeyOwner:   clusterManager.Annotations[util.AnnotationKeyCreator],
+				util.AnnotationKeyCreator: clusterManager.Annotations[util.AnnotationKeyCreator],
+			},
+			Labels: map[string]string{
+				clusterV1alpha1.LabelKeyClmName: clusterManager.Name,
+			},
+		},
+		Spec: coreV1.ServiceSpec{
+			Ports: []coreV1.ServicePort{
+				{
+					Port:       443,
+					Protocol:   coreV1.ProtocolTCP,
+					TargetPort: intstr.FromInt(int(address.Port)),
+				},
+			},
+			Type: coreV1.ServiceTypeClusterIP,
+		},
 	}
-
-	jwtDecodeSecretName := adminServiceAccountName + "-" + clusterManager.Name + "-token"
+	if address.Hostname != "" {
+		service.Spec.Type = coreV1.ServiceTypeExternalName
+		service.Spec.ExternalName = address.Hostname
+	}
+	for key, value := range backendAnnotations {
+		service.Annotations[key] = value
+	}
+	result, err := r.apply(clusterManager, service)
+	if err != nil {
+		log.Error(err, "Failed to apply Service for gateway")
+		return err
+	}
+	logApplyResult(log, result, "Service for gateway")
+
+	return nil
+}
+
+// getGatewayBackendAnnotations 는 gateway service 에 지금 적용되어 있는 backend 의 annotation 들을 반환한다.
+// gateway service 가 없으면 nil 을 반환한다.
+func (r *ClusterManagerReconciler) getGatewayBackendAnnotations(clusterManager *clusterV1alpha1.ClusterManager) (map[string]string, error) {
+	log := r.Log.WithValues("clustermanager", clusterManager.GetNamespacedName())
+
+	key := types.NamespacedName{
+		Name:      clusterManager.Name + "-gateway-service",
+		Namespace: clusterManager.Namespace,
+	}
+	service := &coreV1.Service{}
+	if err := r.Get(context.TODO(), key, service); errors.IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		log.Error(err, "Failed to get Service for gateway")
+		return nil, err
+	}
+
+	annotations := map[string]string{}
+	for _, key := range []string{util.AnnotationKeyTraefikServerScheme, util.AnnotationKeyTraefikServerTransport} {
+		if value, ok := service.Annotations[key]; ok {
+			annotations[key] = value
+		}
+	}
+	return annotations, nil
+}
+
+// migrateGatewayService 는 apply 로 바꿀 수 없는 gateway service 의 변경을 미리 반영한다.
+// service type 이 바뀌는 경우(ExternalName <-> ClusterIP)에는 cluster ip 와 external name 을 함께 비워야 하고,
+// 이전 버전에서 update 로 추가한 backend 의 annotation 은 apply 로 삭제되지 않는다.
+func (r *ClusterManagerReconciler) migrateGatewayService(clusterManager *clusterV1alpha1.ClusterManager, address *GatewayAddress, backendAnnotations map[string]string) error {
+	log := r.Log.WithValues("clustermanager", clusterManager.GetNamespacedName())
+
+	key := types.NamespacedName{
+		Name:      clusterManager.Name + "-gateway-service",
+		Namespace: clusterManager.Namespace,
+	}
+	service := &coreV1.Service{}
+	if err := r.Get(context.TODO(), key, service); errors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		log.Error(err, "Failed to get Service for gateway")
+		return err
+	}
+
+	updated := false
+	if address.Hostname != "" && service.Spec.Type != coreV1.ServiceTypeExternalName {
+		// ExternalName service 는 cluster ip 를 가질 수 없다.
+		service.Spec.Type = coreV1.ServiceTypeExternalName
+		service.Spec.ExternalName = address.Hostname
+		service.Spec.ClusterIP = ""
+		service.Spec.ClusterIPs = nil
+		service.Spec.IPFamilies = nil
+		service.Spec.IPFamilyPolicy = nil
+		updated = true
+	} else if address.Hostname == "" && service.Spec.Type != coreV1.ServiceTypeClusterIP {
+		// 이전 버전에서 ip 주소를 ExternalName 으로 만든 service 도 이때 변경된다.
+		service.Spec.Type = coreV1.ServiceTypeClusterIP
+		service.Spec.ExternalName = ""
+		updated = true
+	}
+	// 이전 backend 가 추가한 annotation 은 제거한다.
+	for _, key := range []string{util.AnnotationKeyTraefikServerScheme, util.AnnotationKeyTraefikServerTransport} {
+		if _, ok := backendAnnotations[key]; !ok {
+			if _, exists := service.Annotations[key]; exists {
+				delete(service.Annotations, key)
+				updated = true
+			}
+		}
+	}
+	if !updated {
+		return nil
+	}
+
+	// 다른 곳에서 수정한 것으로 판단하지 않도록 apply 와 같은 field manager 로 수정한다.
+	if err := r.Update(context.TODO(), service, client.FieldOwner(util.FieldOwner)); err != nil {
+		log.Error(err, "Failed to update Service for gateway")
+		return err
+	}
+	log.Info("Update Service for gateway successfully")
+	return nil
+}
+
+// GatewayAddress 는 single cluster 의 gateway 에 접근할 수 있는 주소들이다.
+// LoadBalancer 의 ingress 가 hostname 인 경우에는 Hostname 을, ip 인 경우에는 모든 ip 를 IPs 에 가진다.
+type GatewayAddress struct {
+	Hostname string
+	IPs      []string
+	Port     int32
+	// gateway 의 certificate 를 검증할 때 사용할 server name
+	ServerName string
+}
+
+// Host 는 gateway 의 certificate 를 검증할 때 접속할 host 를 반환한다.
+func (a *GatewayAddress) Host() string {
+	if a.Hostname != "" {
+		return a.Hostname
+	}
+	return a.IPs[0]
+}
+
+// String 은 gateway service 에 반영된 주소가 바뀌었는지 비교하기 위한 값을 반환한다.
+func (a *GatewayAddress) String() string {
+	hosts := a.IPs
+	if a.Hostname != "" {
+		hosts = []string{a.Hostname}
+	}
+	return strings.Join(hosts, ",") + ":" + strconv.Itoa(int(a.Port))
+}
+
+// GetGatewayAddress 는 single cluster 의 gateway service 로 부터 gateway 에 접근할 수 있는 주소들을 구한다.
+// LoadBalancer 인 경우에는 모든 LoadBalancer ingress 를 사용하고,
+// NodePort 인 경우에는 ready 상태인 node 들의 ip 와 node port 를 사용한다.
+func GetGatewayAddress(remoteClientset *kubernetes.Clientset, clusterManager *clusterV1alpha1.ClusterManager, gatewayService *coreV1.Service) (*GatewayAddress, error) {
+	if len(gatewayService.Spec.Ports) == 0 {
+		return nil, fmt.Errorf("service for gateway doesn't have any port")
+	}
+	servicePort := gatewayService.Spec.Ports[0]
+	for _, port := range gatewayService.Spec.Ports {
+		if port.Port == 443 || port.Name == "https" {
+			servicePort = port
+			break
+		}
+	}
+
+	address := &GatewayAddress{}
+	if gatewayService.Spec.Type == coreV1.ServiceTypeNodePort {
+		nodes, err := remoteClientset.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{})
+		if err != nil {
+			return nil, err
+		}
+		for _, node := range nodes.Items {
+			if ip := getNodeAddress(&node); ip != "" && util.IsNodeReady(&node) {
+				address.IPs = append(address.IPs, ip)
+			}
+		}
+		if len(address.IPs) == 0 {
+			return nil, waitingForRemoteError{fmt.Errorf("cluster doesn't have ready node for gateway node port")}
+		}
+		address.Port = servicePort.NodePort
+		// node port 로 노출된 gateway 는 k8s api-server 의 domain 으로 certificate 를 발급받는다.
+		address.ServerName = GetGatewayServerName(clusterManager.Annotations[clusterV1alpha1.AnnotationKeyClmApiserver])
+	} else {
+		for _, ingress := range gatewayService.Status.LoadBalancer.Ingress {
+			if ingress.Hostname != "" && address.Hostname == "" {
+				address.Hostname = ingress.Hostname
+			}
+			if ingress.IP != "" {
+				address.IPs = append(address.IPs, ingress.IP)
+			}
+		}
+		if address.Hostname == "" && len(address.IPs) == 0 {
+			return nil, waitingForRemoteError{fmt.Errorf("service for gateway's type is not LoadBalancer or not ready")}
+		}
+		// ExternalName service 는 하나의 hostname 만 가질 수 있으므로 hostname 이 있으면 hostname 을 사용한다.
+		if address.Hostname != "" {
+			address.IPs = nil
+		}
+		address.Port = servicePort.Port
+		address.ServerName = GetGatewayServerName(address.Host())
+	}
+	sort.Strings(address.IPs)
+
+	return address, nil
+}
+
+// getNodeAddress 는 node 의 external ip 를, 없으면 internal ip 를 반환한다.
+func getNodeAddress(node *coreV1.Node) string {
+	internalIP := ""
+	for _, nodeAddress := range node.Status.Addresses {
+		switch nodeAddress.Type {
+		case coreV1.NodeExternalIP:
+			return nodeAddress.Address
+		case coreV1.NodeInternalIP:
+			if internalIP == "" {
+				internalIP = nodeAddress.Address
+			}
+		}
+	}
+	return internalIP
+}
+
+// GetGatewayCA 는 single cluster 의 gateway 의 serving certificate 를 검증할 CA 를 반환한다.
+// gateway 의 tls secret 에 CA 가 있으면 그것을 사용하고, 없으면 kubeconfig 의 cluster CA 를 사용한다.
+func GetGatewayCA(remoteClientset *kubernetes.Clientset, kubeconfigSecret *coreV1.Secret) ([]byte, error) {
+	gatewaySecret, err := remoteClientset.
+		CoreV1().
+		Secrets(util.ApiGatewayNamespace).
+		Get(context.TODO(), util.ApiGatewayTLSSecret, metav1.GetOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return nil, err
+	}
+	if err == nil && len(gatewaySecret.Data[util.SecretKeyCA]) != 0 {
+		return gatewaySecret.Data[util.SecretKeyCA], nil
+	}
+
+	kubeConfig, err := clientcmd.Load(kubeconfigSecret.Data["value"])
+	if err != nil {
+		return nil, err
+	}
+	currentContext, ok := kubeConfig.Contexts[kubeConfig.CurrentContext]
+	if !ok {
+		return nil, fmt.Errorf("kubeconfig does not have current context")
+	}
+	if cluster, ok := kubeConfig.Clusters[currentContext.Cluster]; ok {
+		return cluster.CertificateAuthorityData, nil
+	}
+	return nil, nil
+}
+
+// GetGatewayServerName 은 gateway 의 certificate 를 검증할 때 사용할 server name 을 반환한다.
+// ip 인 경우에는 server name 을 비워서 certificate 의 ip SAN 으로 검증하도록 한다.
+func GetGatewayServerName(host string) string {
+	if net.ParseIP(host) != nil {
+		return ""
+	}
+	return host
+}
+
+// VerifyGatewayTLS 는 hub 에서 single cluster 의 gateway 로 TLS handshake 를 하여 certificate 를 검증한다.
+func VerifyGatewayTLS(host string, port int32, serverName string, ca []byte) error {
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return fmt.Errorf("failed to parse CA certificate")
+	}
+
+	dialer := &net.Dialer{Timeout: 5 * time.Second}
+	conn, err := tls.DialWithDialer(dialer, "tcp", net.JoinHostPort(host, strconv.Itoa(int(port))), &tls.Config{
+		RootCAs:    pool,
+		ServerName: serverName,
+	})
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// SetGatewayTLSCondition 은 gateway 의 TLS 검증 결과를 status condition 으로 기록한다.
+func SetGatewayTLSCondition(c *clusterV1alpha1.ClusterManager, err error) {
+	condition := metav1.Condition{
+		Type:               clusterV1alpha1.ConditionTypeGatewayTLSVerified,
+		Status:             metav1.ConditionTrue,
+		Reason:             gatewayTLSReasonVerified,
+		Message:            "certificate of gateway is verified",
+		ObservedGeneration: c.Generation,
+	}
+
+	var unknownAuthorityErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var invalidErr x509.CertificateInvalidError
+	switch {
+	case err == nil:
+	case stderrors.Is(err, errGatewayCANotFound):
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = gatewayTLSReasonCANotFound
+		condition.Message = err.Error()
+	case stderrors.As(err, &unknownAuthorityErr), stderrors.As(err, &hostnameErr), stderrors.As(err, &invalidErr):
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = gatewayTLSReasonVerificationFailed
+		condition.Message = err.Error()
+	default:
+		// gateway 에 연결할 수 없는 경우에는 검증 결과를 알 수 없다.
+		condition.Status = metav1.ConditionUnknown
+		condition.Reason = gatewayTLSReasonUnreachable
+		condition.Message = err.Error()
+	}
+	meta.SetStatusCondition(&c.Status.Conditions, condition)
+}
+
+// CreateGatewayServersTransport 는 gateway service 가 사용할 traefik ServersTransport 와 CA secret 을 생성하고,
+// ingress 에서 참조할 ServersTransport 의 이름을 반환한다.
+func (r *ClusterManagerReconciler) CreateGatewayServersTransport(clusterManager *clusterV1alpha1.ClusterManager, ca []byte, serverName string) (string, error) {
+	log := r.Log.WithValues("clustermanager", clusterManager.GetNamespacedName())
+
+	key := types.NamespacedName{
+		Name:      clusterManager.Name + "-gateway-ca",
+		Namespace: clusterManager.Namespace,
+	}
+	secret := &coreV1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      key.Name,
+			Namespace: key.Namespace,
+			Annotations: map[string]string{
+				util.AnnotationKeyOwner:   clusterManager.Annotations[util.AnnotationKeyCreator],
+				util.AnnotationKeyCreator: clusterManager.Annotations[util.AnnotationKeyCreator],
+			},
+			Labels: map[string]string{
+				clusterV1alpha1.LabelKeyClmName: clusterManager.Name,
+			},
+		},
+		Data: map[string][]byte{
+			util.SecretKeyCA: ca,
+		},
+	}
+	result, err := r.apply(clusterManager, secret)
+	if err != nil {
+		log.Error(err, "Failed to apply Secret for gateway CA")
+		return "", err
+	}
+	logApplyResult(log, result, "Secret for gateway CA")
+
+	serversTransport := &traefikV1alpha1.ServersTransport{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      clusterManager.Name + "-gateway-transport",
+			Namespace: clusterManager.Namespace,
+			Annotations: map[string]string{
+				util.AnnotationKeyOwner:   clusterManager.Annotations[util.AnnotationKeyCreator],
+				util.AnnotationKeyCreator: clusterManager.Annotations[util.AnnotationKeyCreator],
+			},
+			Labels: map[string]string{
+				clusterV1alpha1.LabelKeyClmName: clusterManager.Name,
+			},
+		},
+		Spec: traefikV1alpha1.ServersTransportSpec{
+			ServerName:     serverName,
+			RootCAsSecrets: []string{key.Name},
+		},
+	}
+	result, err = r.apply(clusterManager, serversTransport)
+	if err != nil {
+		log.Error(err, "Failed to apply ServersTransport for gateway")
+		return "", err
+	}
+	logApplyResult(log, result, "ServersTransport for gateway")
+
+	// kubernetes ingress provider 에서 kubernetescrd provider 의 ServersTransport 를 참조하는 이름
+	return clusterManager.GetNamespacedPrefix() + "-gateway-transport@kubernetescrd", nil
+}
+
+// CreateGatewayEndpoint 는 selector 가 없는 gateway service 가 single cluster 의 gateway ip 들로 연결되도록 endpoint 를 생성한다.
+// EndpointSlice 는 kube-controller-manager 가 endpoint 로 부터 만들어준다.
+// gateway 주소가 hostname 인 경우에는 ExternalName service 를 사용하므로 endpoint 를 삭제한다.
+func (r *ClusterManagerReconciler) CreateGatewayEndpoint(clusterManager *clusterV1alpha1.ClusterManager, address *GatewayAddress) error {
+	log := r.Log.WithValues("clustermanager", clusterManager.GetNamespacedName())
+
+	if address.Hostname != "" {
+		return r.DeleteGatewayEndpoint(clusterManager)
+	}
+
+	addresses := []coreV1.EndpointAddress{}
+	for _, ip := range address.IPs {
+		addresses = append(addresses, coreV1.EndpointAddress{
+			IP: ip,
+		})
+	}
+	subsets := []coreV1.EndpointSubset{
+		{
+			Addresses: addresses,
+			Ports: []coreV1.EndpointPort{
+				{
+					Port:     address.Port,
+					Protocol: coreV1.ProtocolTCP,
+				},
+			},
+		},
+	}
+
+	// LoadBalancer 의 ip 가 바뀌었거나 추가된 경우, 다른 곳에서 수정한 경우에도 apply 로 되돌린다.
+	endpoint := &coreV1.Endpoints{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      clusterManager.Name + "-gateway-service",
+			Namespace: clusterManager.Namespace,
+			Annotations: map[string]string{
+				util.AnnotationKeyOwner:   clusterManager.Annotations[util.AnnotationKeyCreator],
+				util.AnnotationKeyCreator: clusterManager.Annotations[util.AnnotationKeyCreator],
+			},
+			Labels: map[string]string{
+				clusterV1alpha1.LabelKeyClmName: clusterManager.Name,
+			},
+		},
+		Subsets: subsets,
+	}
+	result, err := r.apply(clusterManager, endpoint)
+	if err != nil {
+		log.Error(err, "Failed to apply Endpoint for gateway")
+		return err
+	}
+	logApplyResult(log, result, "Endpoint for gateway")
+
+	return nil
+}
+
+func (r *ClusterManagerReconciler) CreateMiddleware(clusterManager *clusterV1alpha1.ClusterManager) error {
+	return r.applyMiddleware(clusterManager, clusterManager.Name+"-prefix", traefikV1alpha1.MiddlewareSpec{
+		StripPrefix: &dynamicv2.StripPrefix{
+			Prefixes: []string{
+				"/api/" + clusterManager.Namespace + "/" + clusterManager.Name,
+			},
+		},
+	})
+}
+
+// CreateTrafficPolicyMiddlewares 는 ClusterManager 의 traffic policy 를 traefik middleware 들로 만들고,
+// ingress 의 middleware chain 에 추가할 middleware 이름들을 반환한다.
+// policy 에서 빠진 항목의 middleware 는 삭제한다.
+func (r *ClusterManagerReconciler) CreateTrafficPolicyMiddlewares(clusterManager *clusterV1alpha1.ClusterManager) (before []string, after []string, err error) {
+	specs := newTrafficPolicyMiddlewareSpecs(clusterManager.Spec.TrafficPolicy)
+	for _, suffix := range trafficPolicyMiddlewareSuffixes {
+		spec, ok := specs[suffix]
+		if !ok {
+			if err := r.deleteMiddleware(clusterManager, clusterManager.Name+suffix); err != nil {
+				return nil, nil, err
+			}
+			continue
+		}
+
+		if err := r.applyMiddleware(clusterManager, clusterManager.Name+suffix, spec); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	before, after = newTrafficPolicyMiddlewareChain(clusterManager, specs)
+	return before, after, nil
+}
+
+// newTrafficPolicyMiddlewareChain 은 middleware chain 에서 jwt 검증 앞(before)과 뒤(after)에 추가할 traffic policy middleware 이름들을 반환한다.
+func newTrafficPolicyMiddlewareChain(clusterManager *clusterV1alpha1.ClusterManager, specs map[string]traefikV1alpha1.MiddlewareSpec) (before []string, after []string) {
+	for _, suffix := range trafficPolicyMiddlewareSuffixes {
+		if _, ok := specs[suffix]; !ok {
+			continue
+		}
+		middleware := clusterManager.Namespace + "-" + clusterManager.Name + suffix + "@kubernetescrd"
+		// header 는 요청이 허용된 뒤 single cluster 로 전달하기 직전에 추가한다.
+		if suffix == trafficPolicyHeadersSuffix {
+			after = append(after, middleware)
+		} else {
+			before = append(before, middleware)
+		}
+	}
+	return before, after
+}
+
+// DeleteTrafficPolicyMiddlewares 는 traffic policy 로 만든 middleware 들을 삭제한다.
+func (r *ClusterManagerReconciler) DeleteTrafficPolicyMiddlewares(clusterManager *clusterV1alpha1.ClusterManager) error {
+	for _, suffix := range trafficPolicyMiddlewareSuffixes {
+		if err := r.deleteMiddleware(clusterManager, clusterManager.Name+suffix); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// newTrafficPolicyMiddlewareSpecs 는 traffic policy 의 항목별 middleware spec 을 만든다.
+// rate limit 과 in-flight limit 은 console 사용자 별로 적용되도록 Authorization header 를 기준으로 계산한다.
+func newTrafficPolicyMiddlewareSpecs(policy *clusterV1alpha1.TrafficPolicy) map[string]traefikV1alpha1.MiddlewareSpec {
+	specs := map[string]traefikV1alpha1.MiddlewareSpec{}
+	if policy == nil {
+		return specs
+	}
+
+	if len(policy.SourceRanges) > 0 {
+		specs[trafficPolicyIPAllowListSuffix] = traefikV1alpha1.MiddlewareSpec{
+			IPWhiteList: &dynamicv2.IPWhiteList{
+				SourceRange: policy.SourceRanges,
+			},
+		}
+	}
+
+	if policy.RateLimit != nil {
+		rateLimit := &traefikV1alpha1.RateLimit{
+			Average: policy.RateLimit.Average,
+			SourceCriterion: &dynamicv2.SourceCriterion{
+				RequestHeaderName: "Authorization",
+			},
+		}
+		if policy.RateLimit.Period != "" {
+			period := intstr.FromString(policy.RateLimit.Period)
+			rateLimit.Period = &period
+		}
+		if policy.RateLimit.Burst > 0 {
+			burst := policy.RateLimit.Burst
+			rateLimit.Burst = &burst
+		}
+		specs[trafficPolicyRateLimitSuffix] = traefikV1alpha1.MiddlewareSpec{
+			RateLimit: rateLimit,
+		}
+	}
+
+	if policy.InFlightLimit > 0 {
+		specs[trafficPolicyInFlightSuffix] = traefikV1alpha1.MiddlewareSpec{
+			InFlightReq: &dynamicv2.InFlightReq{
+				Amount: policy.InFlightLimit,
+				SourceCriterion: &dynamicv2.SourceCriterion{
+					RequestHeaderName: "Authorization",
+				},
+			},
+		}
+	}
+
+	if len(policy.RequestHeaders) > 0 {
+		specs[trafficPolicyHeadersSuffix] = traefikV1alpha1.MiddlewareSpec{
+			Headers: &dynamicv2.Headers{
+				CustomRequestHeaders: policy.RequestHeaders,
+			},
+		}
+	}
+
+	return specs
+}
+
+func (r *ClusterManagerReconciler) applyMiddleware(clusterManager *clusterV1alpha1.ClusterManager, name string, spec traefikV1alpha1.MiddlewareSpec) error {
+	log := r.Log.WithValues("clustermanager", clusterManager.GetNamespacedName())
+
+	middleware := &traefikV1alpha1.Middleware{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: clusterManager.Namespace,
+			Annotations: map[string]string{
+				util.AnnotationKeyOwner:   clusterManager.Annotations[util.AnnotationKeyCreator],
+				util.AnnotationKeyCreator: clusterManager.Annotations[util.AnnotationKeyCreator],
+			},
+			Labels: map[string]string{
+				clusterV1alpha1.LabelKeyClmName: clusterManager.Name,
+			},
+		},
+		Spec: spec,
+	}
+	result, err := r.apply(clusterManager, middleware)
+	if err != nil {
+		log.Error(err, "Failed to apply Middleware", "middleware", name)
+		return err
+	}
+	logApplyResult(log.WithValues("middleware", name), result, "Middleware")
+
+	return nil
+}
+
+// cluster owner 의 email 로 부터 single cluster 의 owner service account 이름을 만든다.
+func ownerServiceAccountName(clusterManager *clusterV1alpha1.ClusterManager) string {
+	re, _ := regexp.Compile("[" + regexp.QuoteMeta(`!#$%&'"*+-/=?^_{|}~().,:;<>[]\`) + "`\\s" + "]")
+	email := clusterManager.Annotations[util.AnnotationKeyOwner]
+	return re.ReplaceAllString(strings.Replace(email, "@", "-at-", -1), "-")
+}
+
+// jwt-decode 를 위해 cluster owner service account 의 token 을 가지는 secret 을 생성한다.
+// kubernetes api 는 cluster proxy 를 통해 사용자로 impersonate 하므로, 이 token 은 prometheus 요청에만 사용된다.
+// token 은 TokenRequest 로 발급받으며, 이미 secret 이 있는 경우에는 token 이 만료되기 전에 다시 발급받아 갱신한다.
+func (r *ClusterManagerReconciler) CreateServiceAccountSecret(clusterManager *clusterV1alpha1.ClusterManager) error {
+	log := r.Log.WithValues("clustermanager", clusterManager.GetNamespacedName())
+
+	adminServiceAccountName := ownerServiceAccountName(clusterManager)
+	jwtDecodeSecretName := adminServiceAccountName + "-" + clusterManager.Name + "-token"
 	key := types.NamespacedName{
 		Name:      jwtDecodeSecretName,
 		Namespace: clusterManager.Namespace,
 	}
-	jwtDecodeSecret := &coreV1.Secret{}
-	err = r.Get(context.TODO(), key, jwtDecodeSecret)
-	if errors.IsNotFound(err) {
-		secret := &coreV1.Secret{
-			ObjectMeta: metav1.ObjectMeta{
-				Name:      key.Name,
-				Namespace: key.Namespace,
-				Labels: map[string]string{
-					util.LabelKeyClmSecretType:           util.ClmSecretTypeSAToken,
-					clusterV1alpha1.LabelKeyClmName:      clusterManager.Name,
-					clusterV1alpha1.LabelKeyClmNamespace: clusterManager.Namespace,
-				},
-				Annotations: map[string]string{
-					util.AnnotationKeyOwner: clusterManager.Annotations[util.AnnotationKeyOwner],
-				},
-				Finalizers: []string{
-					clusterV1alpha1.ClusterManagerFinalizer,
-				},
+	jwtDecodeSecret := &coreV1.Secret{}
+	err := r.Get(context.TODO(), key, jwtDecodeSecret)
+	if err != nil && !errors.IsNotFound(err) {
+		log.Error(err, "Failed to get Secret for ServiceAccount token")
+		return err
+	} else if err == nil {
+		if !jwtDecodeSecret.DeletionTimestamp.IsZero() {
+			return fmt.Errorf("secret for service account token is not refreshed yet")
+		}
+		// 다른 곳에서 secret 을 수정한 경우에는 token 을 다시 발급받아 되돌린다.
+		if !util.IsTokenExpiring(jwtDecodeSecret.Annotations) && !util.IsModifiedByOthers(jwtDecodeSecret) {
+			return nil
+		}
+	}
+
+	kubeconfigSecret, err := r.GetKubeconfigSecret(clusterManager)
+	if err != nil {
+		log.Error(err, "Failed to get kubeconfig secret")
+		return err
+	}
+
+	remoteClientset, err := util.GetRemoteK8sClient(kubeconfigSecret)
+	if err != nil {
+		log.Error(err, "Failed to get remoteK8sClient")
+		return err
+	}
+
+	token, expiration, err := util.RequestServiceAccountToken(remoteClientset, util.KubeNamespace, adminServiceAccountName)
+	if errors.IsNotFound(err) {
+		log.Info("Waiting for create service account [" + adminServiceAccountName + "]")
+		return waitingForRemoteError{err}
+	} else if err != nil {
+		log.Error(err, "Failed to request service account token ["+adminServiceAccountName+"]")
+		return err
+	}
+
+	secret := &coreV1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      key.Name,
+			Namespace: key.Namespace,
+			Labels: map[string]string{
+				util.LabelKeyClmSecretType:           util.ClmSecretTypeSAToken,
+				clusterV1alpha1.LabelKeyClmName:      clusterManager.Name,
+				clusterV1alpha1.LabelKeyClmNamespace: clusterManager.Namespace,
+			},
+			Annotations: map[string]string{
+				util.AnnotationKeyOwner:           clusterManager.Annotations[util.AnnotationKeyOwner],
+				util.AnnotationKeyTokenExpiration: expiration.Format(time.RFC3339),
+			},
+			Finalizers: []string{
+				clusterV1alpha1.ClusterManagerFinalizer,
+			},
+		},
+		Data: map[string][]byte{
+			"token": []byte(token),
+		},
+	}
+	result, err := r.apply(clusterManager, secret)
+	if err != nil {
+		log.Error(err, "Failed to apply Secret for ServiceAccount token")
+		return err
+	}
+	if result == controllerutil.OperationResultCreated {
+		log.Info("Create Secret for ServiceAccount token successfully")
+		return nil
+	}
+
+	log.Info("Refresh Secret for ServiceAccount token successfully")
+	return r.DeleteLegacyServiceAccountTokenSecret(clusterManager, remoteClientset, adminServiceAccountName+"-token")
+}
+
+// ApplyArgocdClusterSecret 은 ArgoCD 에서 single cluster 를 연동하기 위한 cluster secret 을 apply 한다.
+// secret 의 server, ca 는 kubeconfig 로 부터 채우고, 인증 정보는 인증 방식에 따라
+// TokenRequest 로 발급받은 bearer token, AWS IAM, kubeconfig 의 exec provider 나 client certificate 로 채운다.
+func (r *ClusterManagerReconciler) ApplyArgocdClusterSecret(clusterManager *clusterV1alpha1.ClusterManager, kubeconfigSecret *coreV1.Secret, token string, expiration time.Time) error {
+	log := r.Log.WithValues("clustermanager", clusterManager.GetNamespacedName())
+
+	cluster, authInfo, err := util.LoadKubeconfigEntries(kubeconfigSecret)
+	if err != nil {
+		log.Error(err, "Failed to get kubeconfig data from secret")
+		return err
+	}
+
+	// ArgoCD single cluster 연동을 위한 secret에 들어가야 할 데이터를 생성
+	mode := GetArgocdClusterAuthMode(clusterManager, authInfo)
+	config, err := newArgocdClusterConfig(clusterManager, mode, cluster, authInfo, token)
+	if err != nil {
+		log.Error(err, "Failed to create cluster authorization parameters", "mode", mode)
+		return err
+	}
+	configJson, err := json.Marshal(config)
+	if err != nil {
+		log.Error(err, "Failed to marshal cluster authorization parameters")
+		return err
+	}
+
+	annotations := map[string]string{
+		util.AnnotationKeyOwner:               kubeconfigSecret.Annotations[util.AnnotationKeyOwner],
+		util.AnnotationKeyCreator:             kubeconfigSecret.Annotations[util.AnnotationKeyCreator],
+		util.AnnotationKeyArgoManagedBy:       util.ArgoApiGroup,
+		util.AnnotationKeyArgoClusterAuthMode: string(mode),
+	}
+	if mode == clusterV1alpha1.ArgoClusterAuthModeBearerToken {
+		annotations[util.AnnotationKeyTokenExpiration] = expiration.Format(time.RFC3339)
+	}
+
+	// ApplicationSet 의 cluster generator 가 cluster 들을 선택할 수 있도록 ClusterManager 의 label 들을 전파한다.
+	labels := filterLabelsByPrefix(clusterManager.Labels, util.GetArgoClusterLabelPrefix())
+	labels[util.LabelKeyClmSecretType] = util.ClmSecretTypeArgo
+	labels[util.LabelKeyArgoSecretType] = util.ArgoSecretTypeCluster
+	labels[clusterV1alpha1.LabelKeyClmName] = clusterManager.Name
+	labels[clusterV1alpha1.LabelKeyClmNamespace] = clusterManager.Namespace
+
+	clusterName := strings.Split(kubeconfigSecret.Name, util.KubeconfigSuffix)[0]
+	argocdClusterSecret := &coreV1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        kubeconfigSecret.Annotations[util.AnnotationKeyArgoClusterSecret],
+			Namespace:   util.ArgoNamespace,
+			Annotations: annotations,
+			Labels:      labels,
+			Finalizers: []string{
+				clusterV1alpha1.ClusterManagerFinalizer,
+			},
+		},
+		Data: map[string][]byte{
+			"config": configJson,
+			"name":   []byte(clusterName),
+			"server": []byte(cluster.Server),
+		},
+	}
+	result, err := r.apply(clusterManager, argocdClusterSecret)
+	if err != nil {
+		log.Error(err, "Failed to apply Argocd Secret for remote cluster")
+		return err
+	}
+	logApplyResult(log, result, "Argocd Secret for remote cluster")
+
+	return nil
+}
+
+// SyncArgocdClusterSecretLabels 는 ClusterManager 의 label 중 prefix 가 일치하는 label 들을 argocd cluster secret 에 전파한다.
+// ApplicationSet 의 cluster generator 가 이 label 로 cluster 들을 선택할 수 있다.
+// ClusterManager 에서 지워진 label 은 apply 를 통해 secret 에서도 지워진다.
+func (r *ClusterManagerReconciler) SyncArgocdClusterSecretLabels(clusterManager *clusterV1alpha1.ClusterManager) error {
+	log := r.Log.WithValues("clustermanager", clusterManager.GetNamespacedName())
+
+	kubeconfigSecret, err := r.GetKubeconfigSecret(clusterManager)
+	if err != nil {
+		log.Error(err, "Failed to get kubeconfig secret")
+		return err
+	}
+
+	key := types.NamespacedName{
+		Name:      kubeconfigSecret.Annotations[util.AnnotationKeyArgoClusterSecret],
+		Namespace: util.ArgoNamespace,
+	}
+	argocdClusterSecret := &coreV1.Secret{}
+	if err := r.Get(context.TODO(), key, argocdClusterSecret); errors.IsNotFound(err) {
+		log.Info("Cannot find Argocd Secret for remote cluster")
+		return nil
+	} else if err != nil {
+		log.Error(err, "Failed to get Argocd Secret for remote cluster")
+		return err
+	}
+
+	prefix := util.GetArgoClusterLabelPrefix()
+	desired := filterLabelsByPrefix(clusterManager.Labels, prefix)
+	current := filterLabelsByPrefix(argocdClusterSecret.Labels, prefix)
+	if equality.Semantic.DeepEqual(desired, current) {
+		return nil
+	}
+
+	// label 만 따로 수정하면 다른 곳에서 수정한 것으로 판단되므로, 현재의 인증 정보로 secret 전체를 다시 apply 한다.
+	config := &argocdV1alpha1.ClusterConfig{}
+	if err := json.Unmarshal(argocdClusterSecret.Data["config"], config); err != nil {
+		log.Error(err, "Failed to unmarshal config of Argocd Secret for remote cluster")
+		return err
+	}
+	expiration, _ := time.Parse(time.RFC3339, argocdClusterSecret.Annotations[util.AnnotationKeyTokenExpiration])
+
+	// 이전 버전에서 patch 로 추가한 label 은 apply 로 지워지지 않으므로, 지워진 label 이 있으면 먼저 제거한다.
+	base := argocdClusterSecret.DeepCopy()
+	for key := range current {
+		if _, ok := desired[key]; !ok {
+			delete(argocdClusterSecret.Labels, key)
+		}
+	}
+	if len(argocdClusterSecret.Labels) != len(base.Labels) {
+		if err := r.Patch(context.TODO(), argocdClusterSecret, client.MergeFrom(base), client.FieldOwner(util.FieldOwner)); err != nil {
+			log.Error(err, "Failed to remove labels of Argocd Secret for remote cluster")
+			return err
+		}
+	}
+
+	if err := r.ApplyArgocdClusterSecret(clusterManager, kubeconfigSecret, config.BearerToken, expiration); err != nil {
+		return err
+	}
+
+	log.Info("Update labels of Argocd Secret for remote cluster successfully")
+	return nil
+}
+
+// filterLabelsByPrefix 는 key 가 prefix 로 시작하는 label 들만 반환한다.
+func filterLabelsByPrefix(labels map[string]string, prefix string) map[string]string {
+	filtered := map[string]string{}
+	for key, value := range labels {
+		if strings.HasPrefix(key, prefix) {
+			filtered[key] = value
+		}
+	}
+	return filtered
+}
+
+// ApplyArgocdProject 는 single cluster 별 argocd AppProject 들을 생성한다.
+// cluster project 의 application 은 해당 single cluster 에만 배포할 수 있고,
+// app-of-apps project 의 application 은 hub cluster 의 argocd namespace 에 Application 만 만들 수 있다.
+// argocd 의 resource whitelist 는 project 단위로 적용되므로 두 project 를 나누어 hub cluster 의 권한을 제한한다.
+// project role 은 cluster owner 와 member 의 HyperAuth 계정(email) 및 group 에 mapping 된다.
+// email 로 role 을 mapping 하려면 argocd-rbac-cm 의 scopes 에 email 이 포함되어 있어야 한다.
+func (r *ClusterManagerReconciler) ApplyArgocdProject(clusterManager *clusterV1alpha1.ClusterManager, kubeconfigSecret *coreV1.Secret) error {
+	log := r.Log.WithValues("clustermanager", clusterManager.GetNamespacedName())
+
+	cluster, _, err := util.LoadKubeconfigEntries(kubeconfigSecret)
+	if err != nil {
+		log.Error(err, "Failed to get kubeconfig data from secret")
+		return err
+	}
+
+	members, err := util.ListClusterMembers(clusterManager.Namespace, clusterManager.Name)
+	if err != nil {
+		log.Error(err, "Failed to list cluster members")
+		return err
+	}
+
+	// hypercloud 의 cluster member role 에 따라 project role 을 나눈다.
+	groups := map[string][]string{
+		util.ArgoProjectRoleAdmin:     {},
+		util.ArgoProjectRoleDeveloper: {},
+		util.ArgoProjectRoleGuest:     {},
+	}
+	if owner := clusterManager.Annotations[util.AnnotationKeyOwner]; owner != "" {
+		groups[util.ArgoProjectRoleAdmin] = append(groups[util.ArgoProjectRoleAdmin], owner)
+	}
+	for _, member := range members {
+		if member.Status != "invited" || member.MemberId == "" ||
+			(member.Attribute != "user" && member.Attribute != "group") {
+			continue
+		}
+		role := member.Role
+		if _, ok := groups[role]; !ok {
+			role = util.ArgoProjectRoleGuest
+		}
+		groups[role] = append(groups[role], member.MemberId)
+	}
+	for role := range groups {
+		sort.Strings(groups[role])
+	}
+
+	project := newArgocdProject(clusterManager, clusterManager.GetNamespacedPrefix(), groups)
+	project.Spec.Description = "Project for single cluster " + clusterManager.GetNamespacedName().String()
+	project.Spec.Destinations = []argocdV1alpha1.ApplicationDestination{
+		{
+			Server:    cluster.Server,
+			Namespace: "*",
+		},
+	}
+	project.Spec.ClusterResourceWhitelist = []metav1.GroupKind{
+		{
+			Group: "*",
+			Kind:  "*",
+		},
+	}
+
+	// app-of-apps application 은 hub cluster 의 argocd namespace 에 하위 application 들만 생성한다.
+	appOfAppsProject := newArgocdProject(clusterManager, getArgocdAppOfAppsProjectName(clusterManager), groups)
+	appOfAppsProject.Spec.Description = "Project for app-of-apps application of single cluster " + clusterManager.GetNamespacedName().String()
+	appOfAppsProject.Spec.Destinations = []argocdV1alpha1.ApplicationDestination{
+		{
+			Server:    argocdV1alpha1.KubernetesInternalAPIServerAddr,
+			Namespace: util.ArgoNamespace,
+		},
+	}
+	appOfAppsProject.Spec.NamespaceResourceWhitelist = []metav1.GroupKind{
+		{
+			Group: argocdV1alpha1.ApplicationSchemaGroupVersionKind.Group,
+			Kind:  argocdV1alpha1.ApplicationSchemaGroupVersionKind.Kind,
+		},
+	}
+
+	for _, p := range []*argocdV1alpha1.AppProject{project, appOfAppsProject} {
+		result, err := r.apply(clusterManager, p)
+		if err != nil {
+			log.Error(err, "Failed to apply ArgoCD AppProject ["+p.Name+"]")
+			return err
+		}
+		logApplyResult(log, result, "ArgoCD AppProject ["+p.Name+"]")
+	}
+
+	return nil
+}
+
+// GetArgocdProjectConfigHash 는 AppProject, repository secret, app-of-apps application 에 반영되는 설정으로 부터 hash 값을 계산한다.
+func GetArgocdProjectConfigHash(clusterManager *clusterV1alpha1.ClusterManager, kubeconfigSecret *coreV1.Secret, profile *clusterV1alpha1.ClusterAddonProfileSpec) string {
+	config := []string{
+		clusterManager.Annotations[util.AnnotationKeyOwner],
+		clusterManager.Annotations[util.AnnotationKeyCreator],
+		util.KubeconfigHash(kubeconfigSecret),
+		strings.Join(util.GetArgoProjectSourceRepos(), ","),
+	}
+	syncPolicy, _ := json.Marshal(newApplicationSyncPolicy(clusterManager))
+	config = append(config, string(syncPolicy))
+	if profile != nil {
+		profileSpec, _ := json.Marshal(profile)
+		config = append(config, string(profileSpec))
+	}
+	sum := sha256.Sum256([]byte(strings.Join(config, "\n")))
+	return hex.EncodeToString(sum[:])
+}
+
+// IsArgocdProjectSyncNeeded 는 AppProject 를 반영한 적이 없거나, 설정이 바뀌었거나, 반영한 지 interval 이 지났는지 반환한다.
+func IsArgocdProjectSyncNeeded(clusterManager *clusterV1alpha1.ClusterManager, hash string, interval time.Duration) bool {
+	status := clusterManager.Status.ArgoProject
+	if status == nil || status.LastSyncTime == nil || status.DesiredHash != hash {
+		return true
+	}
+	return time.Since(status.LastSyncTime.Time) >= interval
+}
+
+// newArgocdProject 는 cluster member role 별 project role 을 가지는 AppProject 를 만든다.
+func newArgocdProject(clusterManager *clusterV1alpha1.ClusterManager, projectName string, groups map[string][]string) *argocdV1alpha1.AppProject {
+	actions := map[string][]string{
+		util.ArgoProjectRoleAdmin:     {"*"},
+		util.ArgoProjectRoleDeveloper: {"get", "sync"},
+		util.ArgoProjectRoleGuest:     {"get"},
+	}
+	roles := []argocdV1alpha1.ProjectRole{}
+	for _, role := range []string{util.ArgoProjectRoleAdmin, util.ArgoProjectRoleDeveloper, util.ArgoProjectRoleGuest} {
+		policies := []string{}
+		for _, action := range actions[role] {
+			policies = append(policies, fmt.Sprintf("p, proj:%s:%s, applications, %s, %s/*, allow", projectName, role, action, projectName))
+		}
+		roles = append(roles, argocdV1alpha1.ProjectRole{
+			Name:     role,
+			Policies: policies,
+			Groups:   groups[role],
+		})
+	}
+
+	return &argocdV1alpha1.AppProject{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      projectName,
+			Namespace: util.ArgoNamespace,
+			Annotations: map[string]string{
+				util.AnnotationKeyOwner:   clusterManager.Annotations[util.AnnotationKeyOwner],
+				util.AnnotationKeyCreator: clusterManager.Annotations[util.AnnotationKeyCreator],
 			},
-			Data: map[string][]byte{
-				"token": tokenSecret.Data["token"],
+			Labels: map[string]string{
+				util.LabelKeyArgoTargetCluster: clusterManager.GetNamespacedPrefix(),
 			},
+		},
+		Spec: argocdV1alpha1.AppProjectSpec{
+			SourceRepos: util.GetArgoProjectSourceRepos(),
+			Roles:       roles,
+		},
+	}
+}
+
+// getArgocdAppOfAppsProjectName 은 app-of-apps application 이 속하는 project 의 이름을 반환한다.
+func getArgocdAppOfAppsProjectName(clusterManager *clusterV1alpha1.ClusterManager) string {
+	return clusterManager.GetNamespacedPrefix() + util.ArgoAppOfAppsProjectSuffix
+}
+
+// Argocd cluster secret 의 bearer token 이 만료되기 전에 TokenRequest 로 다시 발급받아 갱신한다.
+func (r *ClusterManagerReconciler) RefreshArgocdClusterSecretToken(clusterManager *clusterV1alpha1.ClusterManager) error {
+	log := r.Log.WithValues("clustermanager", clusterManager.GetNamespacedName())
+
+	kubeconfigSecret, err := r.GetKubeconfigSecret(clusterManager)
+	if err != nil {
+		log.Error(err, "Failed to get kubeconfig secret")
+		return err
+	}
+
+	key := types.NamespacedName{
+		Name:      kubeconfigSecret.Annotations[util.AnnotationKeyArgoClusterSecret],
+		Namespace: util.ArgoNamespace,
+	}
+	argocdClusterSecret := &coreV1.Secret{}
+	if err := r.Get(context.TODO(), key, argocdClusterSecret); errors.IsNotFound(err) {
+		log.Info("Cannot find Argocd Secret for remote cluster")
+		return nil
+	} else if err != nil {
+		log.Error(err, "Failed to get Argocd Secret for remote cluster")
+		return err
+	}
+
+	_, authInfo, err := util.LoadKubeconfigEntries(kubeconfigSecret)
+	if err != nil {
+		log.Error(err, "Failed to get kubeconfig data from secret")
+		return err
+	}
+
+	// 인증 방식이 바뀐 경우에는 token 만료 여부와 관계없이 다시 만든다.
+	mode := GetArgocdClusterAuthMode(clusterManager, authInfo)
+	isModeChanged := argocdClusterSecret.Annotations[util.AnnotationKeyArgoClusterAuthMode] != string(mode)
+	if mode != clusterV1alpha1.ArgoClusterAuthModeBearerToken {
+		if !isModeChanged {
+			return nil
 		}
-		if err := r.Create(context.TODO(), secret); err != nil {
-			log.Error(err, "Failed to Create Secret for ServiceAccount token")
+		if err := r.ApplyArgocdClusterSecret(clusterManager, kubeconfigSecret, "", time.Time{}); err != nil {
 			return err
 		}
+		log.Info("Update auth mode of Argocd Secret for remote cluster successfully", "mode", mode)
+		return nil
+	}
 
-		log.Info("Create Secret for ServiceAccount token successfully")
-		ctrl.SetControllerReference(clusterManager, secret, r.Scheme)
+	if !isModeChanged && !util.IsTokenExpiring(argocdClusterSecret.Annotations) {
 		return nil
 	}
 
-	if !jwtDecodeSecret.DeletionTimestamp.IsZero() {
-		err = fmt.Errorf("secret for service account token is not refreshed yet")
+	remoteClientset, err := util.GetRemoteK8sClient(kubeconfigSecret)
+	if err != nil {
+		log.Error(err, "Failed to get remoteK8sClient")
+		return err
+	}
+
+	token, expiration, err := util.RequestServiceAccountToken(remoteClientset, util.KubeNamespace, util.ArgoServiceAccount)
+	if err != nil {
+		log.Error(err, "Failed to request service account token for argocd")
+		return err
+	}
+
+	if err := r.ApplyArgocdClusterSecret(clusterManager, kubeconfigSecret, token, expiration); err != nil {
+		return err
+	}
+
+	log.Info("Refresh token of Argocd Secret for remote cluster successfully")
+	return r.DeleteLegacyServiceAccountTokenSecret(clusterManager, remoteClientset, util.ArgoServiceAccountTokenSecret)
+}
+
+// 이전 버전에서 생성한 만료되지 않는 service account token secret 을 single cluster 에서 삭제한다.
+// hub cluster 의 token 을 TokenRequest 로 발급받은 token 으로 갱신한 후에 호출해야 한다.
+func (r *ClusterManagerReconciler) DeleteLegacyServiceAccountTokenSecret(clusterManager *clusterV1alpha1.ClusterManager, remoteClientset *kubernetes.Clientset, name string) error {
+	log := r.Log.WithValues("clustermanager", clusterManager.GetNamespacedName())
+
+	err := remoteClientset.
+		CoreV1().
+		Secrets(util.KubeNamespace).
+		Delete(context.TODO(), name, metav1.DeleteOptions{})
+	if errors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		log.Error(err, "Cannot delete legacy ServiceAccount token secret ["+name+"] from remote cluster")
+		return err
 	}
 
-	return err
+	log.Info("Delete legacy ServiceAccount token secret [" + name + "] from remote cluster successfully")
+	return nil
 }
 
+// CreateApplication 은 single cluster 에 모듈들을 설치하는 app-of-apps application 을 생성한다.
+// ClusterAddonProfile 이 있으면 profile 로 부터 helm value 들을 채워 application 을 apply 하고, profile 이 바뀌면 다시 apply 한다.
+// profile 이 없으면 사용자가 직접 채워야 하는 설명 문구로 application 을 한번만 생성한다.
 func (r *ClusterManagerReconciler) CreateApplication(clusterManager *clusterV1alpha1.ClusterManager) error {
 	log := r.Log.WithValues("clustermanager", clusterManager.GetNamespacedName())
 
+	profile, found, err := r.GetAddonProfile(clusterManager)
+	if err != nil {
+		return err
+	}
+	application := newApplication(clusterManager, profile)
+
+	if found {
+		if err := r.ApplyArgocdRepositorySecret(clusterManager, profile); err != nil {
+			return err
+		}
+
+		// argocd 가 application 의 status 를 관리하므로, status 를 제외하고 apply 한다.
+		obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(application)
+		if err != nil {
+			return err
+		}
+		delete(obj, "status")
+		unstructured.RemoveNestedField(obj, "metadata", "creationTimestamp")
+		u := &unstructured.Unstructured{Object: obj}
+		u.SetGroupVersionKind(argocdV1alpha1.ApplicationSchemaGroupVersionKind)
+
+		result, err := r.apply(clusterManager, u)
+		if err != nil {
+			log.Error(err, "Failed to apply ArgoCD Application")
+			return err
+		}
+		logApplyResult(log, result, "ArgoCD Application")
+		return nil
+	}
+
 	key := types.NamespacedName{
-		Name:      clusterManager.GetNamespacedPrefix() + "-applications",
-		Namespace: util.ArgoNamespace,
+		Name:      application.Name,
+		Namespace: application.Namespace,
 	}
-	err := r.Get(context.TODO(), key, &argocdV1alpha1.Application{})
-	if errors.IsNotFound(err) {
-		application := &argocdV1alpha1.Application{
-			ObjectMeta: metav1.ObjectMeta{
-				Name:      key.Name,
-				Namespace: key.Namespace,
-				Labels: map[string]string{
-					util.LabelKeyArgoTargetCluster: clusterManager.GetNamespacedPrefix(),
-					util.LabelKeyArgoAppType:       util.ArgoAppTypeAppOfApp,
-				},
+	current := &argocdV1alpha1.Application{}
+	if err := r.Get(context.TODO(), key, current); errors.IsNotFound(err) {
+		if err := r.Create(context.TODO(), application); err != nil {
+			log.Error(err, "Failed to Create ArgoCD Application")
+			return err
+		}
+
+		log.Info("Create ArgoCD Application successfully")
+		return nil
+	} else if err != nil {
+		log.Error(err, "Failed to get ArgoCD Application")
+		return err
+	}
+
+	return r.UpdateApplicationProject(clusterManager, current)
+}
+
+// GetAddonProfile 은 operator namespace 의 default profile, ClusterManager namespace 의 default profile,
+// ClusterManager 와 같은 이름의 profile 순서로 덮어써서 ClusterManager 에 적용할 profile 을 구한다.
+// 적용할 profile 이 하나도 없는 경우 found 는 false 이다.
+func (r *ClusterManagerReconciler) GetAddonProfile(clusterManager *clusterV1alpha1.ClusterManager) (*clusterV1alpha1.ClusterAddonProfileSpec, bool, error) {
+	log := r.Log.WithValues("clustermanager", clusterManager.GetNamespacedName())
+
+	keys := []types.NamespacedName{
+		{
+			Name:      clusterV1alpha1.DefaultAddonProfileName,
+			Namespace: util.GetOperatorNamespace(),
+		},
+		{
+			Name:      clusterV1alpha1.DefaultAddonProfileName,
+			Namespace: clusterManager.Namespace,
+		},
+		{
+			Name:      clusterManager.Name,
+			Namespace: clusterManager.Namespace,
+		},
+	}
+
+	spec := newDefaultAddonProfileSpec()
+	found := false
+	visited := map[types.NamespacedName]bool{}
+	for _, key := range keys {
+		if visited[key] {
+			continue
+		}
+		visited[key] = true
+
+		profile := &clusterV1alpha1.ClusterAddonProfile{}
+		if err := r.Get(context.TODO(), key, profile); errors.IsNotFound(err) {
+			continue
+		} else if err != nil {
+			log.Error(err, "Failed to get ClusterAddonProfile ["+key.String()+"]")
+			return nil, false, err
+		}
+		// 다른 namespace 의 secret 은 참조할 수 없다.
+		if profile.Spec.CredentialsSecretRef != nil {
+			profile.Spec.CredentialsSecretRef.Namespace = profile.Namespace
+		}
+		spec.Merge(&profile.Spec)
+		found = true
+	}
+
+	return spec, found, nil
+}
+
+// newDefaultAddonProfileSpec 은 ClusterAddonProfile 에 지정되지 않은 값들을 위한 기본 profile 이다.
+// repository 와 domain 처럼 환경마다 다른 값들은 사용자가 채워야 하는 설명 문구를 가진다.
+func newDefaultAddonProfileSpec() *clusterV1alpha1.ClusterAddonProfileSpec {
+	return &clusterV1alpha1.ClusterAddonProfileSpec{
+		RepoURL:        util.ArgoDescriptionGitRepo,
+		TargetRevision: util.ArgoDescriptionGitRevision,
+		Path:           "application/helm",
+		ValueFiles: []string{
+			"shared-values.yaml",
+			"single-values.yaml",
+		},
+		Global: map[string]string{
+			"privateRegistry":              util.ArgoDescriptionPrivateRegistry,
+			"domain":                       util.ArgoDescriptionGlobalDomain,
+			"masterSingle.hyperAuthDomain": util.ArgoDescriptionHyperAuthSubdomain,
+		},
+		Modules: map[string]clusterV1alpha1.AddonModule{
+			"gatewayBootstrap": {
+				Values: map[string]string{"console.subdomain": util.ArgoDescriptionConsoleSubdomain},
+			},
+			"efk": {
+				Values: map[string]string{"kibana.subdomain": util.ArgoDescriptionKibanaSubdomain},
+			},
+			"grafanaOperator": {
+				Values: map[string]string{"subdomain": util.ArgoDescriptionGrafanaOperatorSubdomain},
+			},
+			"helmApiserver": {
+				Values: map[string]string{"subdomain": util.ArgoDescriptionHelmApiServerSubdomain},
 			},
-			Spec: argocdV1alpha1.ApplicationSpec{
-				Destination: argocdV1alpha1.ApplicationDestination{
-					Namespace: util.ArgoNamespace,
-					Server:    argocdV1alpha1.KubernetesInternalAPIServerAddr,
+			"serviceMesh": {
+				Values: map[string]string{
+					"jaeger.subdomain": util.ArgoDescriptionJaegerSubdomain,
+					"kiali.subdomain":  util.ArgoDescriptionKialiSubdomain,
 				},
-				Project: argocdV1alpha1.DefaultAppProjectName,
-				Source: argocdV1alpha1.ApplicationSource{
-					Helm: &argocdV1alpha1.ApplicationSourceHelm{
-						ValueFiles: []string{
-							"shared-values.yaml",
-							"single-values.yaml",
-						},
-						Parameters: []argocdV1alpha1.HelmParameter{
-							{
-								Name:  "global.clusterName",
-								Value: clusterManager.Name,
-							},
-							{
-								Name:  "global.clusterNamespace",
-								Value: clusterManager.Namespace,
-							},
-							{
-								Name:  "global.privateRegistry",
-								Value: util.ArgoDescriptionPrivateRegistry,
-							},
-							{
-								Name:  "global.adminUser",
-								Value: clusterManager.Annotations[util.AnnotationKeyOwner],
-							},
-							{
-								Name:  "modules.gatewayBootstrap.console.subdomain",
-								Value: util.ArgoDescriptionConsoleSubdomain,
-							},
-							{
-								Name:  "global.domain",
-								Value: util.ArgoDescriptionGlobalDomain,
-							},
-							{
-								Name:  "global.masterSingle.hyperAuthDomain",
-								Value: util.ArgoDescriptionHyperAuthSubdomain,
-							},
-							{
-								Name:  "modules.efk.kibana.subdomain",
-								Value: util.ArgoDescriptionKibanaSubdomain,
-							},
-							{
-								Name:  "modules.grafanaOperator.subdomain",
-								Value: util.ArgoDescriptionGrafanaOperatorSubdomain,
-							},
-							{
-								Name:  "modules.helmApiserver.subdomain",
-								Value: util.ArgoDescriptionHelmApiServerSubdomain,
-							},
-							{
-								Name:  "modules.serviceMesh.jaeger.subdomain",
-								Value: util.ArgoDescriptionJaegerSubdomain,
-							},
-							{
-								Name:  "modules.serviceMesh.kiali.subdomain",
-								Value: util.ArgoDescriptionKialiSubdomain,
-							},
-							{
-								Name:  "modules.cicd.subdomain",
-								Value: util.ArgoDescriptionCicdSubdomain,
-							},
-							{
-								Name:  "modules.opensearch.dashboard.subdomain",
-								Value: util.ArgoDescriptionOpensearchSubdomain,
-							},
-							{
-								Name:  "modules.hyperregistry.core.subdomain",
-								Value: util.ArgoDescriptionHyperregistrySubdomain,
-							},
-							{
-								Name:  "modules.hyperregistry.notary.subdomain",
-								Value: util.ArgoDescriptionHyperregistryNotarySubdomain,
-							},
-							{
-								Name:  "modules.hyperregistry.storageClass",
-								Value: util.ArgoDescriptionHyperregistryStorageClass,
-							},
-							{
-								Name:  "modules.hyperregistry.storageClassDatabase",
-								Value: util.ArgoDescriptionHyperregistryDBStorageClass,
-							},
-						},
-					},
-					Path:           "application/helm",
-					RepoURL:        util.ArgoDescriptionGitRepo,
-					TargetRevision: util.ArgoDescriptionGitRevision,
+			},
+			"cicd": {
+				Values: map[string]string{"subdomain": util.ArgoDescriptionCicdSubdomain},
+			},
+			"opensearch": {
+				Values: map[string]string{"dashboard.subdomain": util.ArgoDescriptionOpensearchSubdomain},
+			},
+			"hyperregistry": {
+				Values: map[string]string{
+					"core.subdomain":       util.ArgoDescriptionHyperregistrySubdomain,
+					"notary.subdomain":     util.ArgoDescriptionHyperregistryNotarySubdomain,
+					"storageClass":         util.ArgoDescriptionHyperregistryStorageClass,
+					"storageClassDatabase": util.ArgoDescriptionHyperregistryDBStorageClass,
 				},
 			},
+		},
+	}
+}
+
+// newApplication 은 profile 로 app-of-apps application 을 만든다.
+// helm parameter 는 apply 결과가 매번 같도록 이름 순서로 정렬한다.
+func newApplication(clusterManager *clusterV1alpha1.ClusterManager, profile *clusterV1alpha1.ClusterAddonProfileSpec) *argocdV1alpha1.Application {
+	parameters := []argocdV1alpha1.HelmParameter{
+		{
+			Name:  "global.clusterName",
+			Value: clusterManager.Name,
+		},
+		{
+			Name:  "global.clusterNamespace",
+			Value: clusterManager.Namespace,
+		},
+		{
+			Name:  "global.adminUser",
+			Value: clusterManager.Annotations[util.AnnotationKeyOwner],
+		},
+		{
+			Name:  "global.project",
+			Value: clusterManager.GetNamespacedPrefix(),
+		},
+	}
+
+	values := []argocdV1alpha1.HelmParameter{}
+	for key, value := range profile.Global {
+		values = append(values, argocdV1alpha1.HelmParameter{Name: "global." + key, Value: value})
+	}
+	for name, module := range profile.Modules {
+		if module.Enabled != nil {
+			values = append(values, argocdV1alpha1.HelmParameter{
+				Name:  "modules." + name + ".enabled",
+				Value: strconv.FormatBool(*module.Enabled),
+			})
 		}
-		if err := r.Create(context.TODO(), application); err != nil {
-			log.Error(err, "Failed to Create ArgoCD Application")
+		for key, value := range module.Values {
+			values = append(values, argocdV1alpha1.HelmParameter{Name: "modules." + name + "." + key, Value: value})
+		}
+	}
+	sort.Slice(values, func(i, j int) bool {
+		return values[i].Name < values[j].Name
+	})
+	// cluster 정보는 profile 로 덮어쓸 수 없다.
+	for _, value := range values {
+		switch value.Name {
+		case "global.clusterName", "global.clusterNamespace", "global.adminUser", "global.project":
+			continue
+		}
+		parameters = append(parameters, value)
+	}
+
+	return &argocdV1alpha1.Application{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      clusterManager.GetNamespacedPrefix() + "-applications",
+			Namespace: util.ArgoNamespace,
+			Labels: map[string]string{
+				util.LabelKeyArgoTargetCluster:       clusterManager.GetNamespacedPrefix(),
+				util.LabelKeyArgoAppType:             util.ArgoAppTypeAppOfApp,
+				clusterV1alpha1.LabelKeyClmName:      clusterManager.Name,
+				clusterV1alpha1.LabelKeyClmNamespace: clusterManager.Namespace,
+			},
+		},
+		Spec: argocdV1alpha1.ApplicationSpec{
+			Destination: argocdV1alpha1.ApplicationDestination{
+				Namespace: util.ArgoNamespace,
+				Server:    argocdV1alpha1.KubernetesInternalAPIServerAddr,
+			},
+			Project:    getArgocdAppOfAppsProjectName(clusterManager),
+			Source:     newApplicationSource(profile, parameters),
+			SyncPolicy: newApplicationSyncPolicy(clusterManager),
+		},
+	}
+}
+
+// newApplicationSource 는 profile 의 source type 에 따라 app-of-apps helm chart 의 source 를 만든다.
+// git 은 repository 의 path 에 있는 chart 를, helm 과 oci 는 repository 의 chart 를 version(targetRevision) 으로 가져온다.
+func newApplicationSource(profile *clusterV1alpha1.ClusterAddonProfileSpec, parameters []argocdV1alpha1.HelmParameter) argocdV1alpha1.ApplicationSource {
+	source := argocdV1alpha1.ApplicationSource{
+		Helm: &argocdV1alpha1.ApplicationSourceHelm{
+			ValueFiles: profile.ValueFiles,
+			Parameters: parameters,
+		},
+		RepoURL:        profile.RepoURL,
+		TargetRevision: profile.TargetRevision,
+	}
+
+	switch profile.GetSourceType() {
+	case clusterV1alpha1.AddonSourceTypeHelm:
+		source.Chart = profile.Chart
+	case clusterV1alpha1.AddonSourceTypeOCI:
+		// argocd 는 OCI registry 주소를 scheme 없이 사용한다.
+		source.RepoURL = strings.TrimPrefix(profile.RepoURL, "oci://")
+		source.Chart = profile.Chart
+	default:
+		source.Path = profile.Path
+	}
+
+	return source
+}
+
+// ApplyArgocdRepositorySecret 은 app-of-apps 의 repository 를 argocd 에 연동하는 repository secret 을 apply 한다.
+// profile 에 credentials secret 이 있거나, argocd 에 등록해야만 사용할 수 있는 OCI registry 인 경우에 생성하고,
+// 그렇지 않으면 이전에 만든 secret 을 지운다.
+func (r *ClusterManagerReconciler) ApplyArgocdRepositorySecret(clusterManager *clusterV1alpha1.ClusterManager, profile *clusterV1alpha1.ClusterAddonProfileSpec) error {
+	log := r.Log.WithValues("clustermanager", clusterManager.GetNamespacedName())
+
+	key := types.NamespacedName{
+		Name:      clusterManager.GetNamespacedPrefix() + util.ArgoRepositorySecretSuffix,
+		Namespace: util.ArgoNamespace,
+	}
+
+	sourceType := profile.GetSourceType()
+	if profile.CredentialsSecretRef == nil && sourceType != clusterV1alpha1.AddonSourceTypeOCI {
+		repositorySecret := &coreV1.Secret{}
+		if err := r.Get(context.TODO(), key, repositorySecret); errors.IsNotFound(err) {
+			return nil
+		} else if err != nil {
+			log.Error(err, "Failed to get Argocd repository Secret")
+			return err
+		}
+		if !isLabeledFor(repositorySecret, clusterManager) {
+			return nil
+		}
+		if err := r.Delete(context.TODO(), repositorySecret); err != nil && !errors.IsNotFound(err) {
+			log.Error(err, "Failed to delete Argocd repository Secret")
+			return err
+		}
+		log.Info("Delete Argocd repository Secret successfully")
+		return nil
+	}
+
+	data := map[string][]byte{
+		"name": []byte(key.Name),
+		"url":  []byte(strings.TrimPrefix(profile.RepoURL, "oci://")),
+		"type": []byte(util.ArgoRepositoryTypeGit),
+	}
+	if sourceType != clusterV1alpha1.AddonSourceTypeGit {
+		data["type"] = []byte(util.ArgoRepositoryTypeHelm)
+	}
+	if sourceType == clusterV1alpha1.AddonSourceTypeOCI {
+		data["enableOCI"] = []byte("true")
+	}
+
+	if profile.CredentialsSecretRef != nil {
+		credentialsKey := types.NamespacedName{
+			Name:      profile.CredentialsSecretRef.Name,
+			Namespace: profile.CredentialsSecretRef.Namespace,
+		}
+		credentials := &coreV1.Secret{}
+		if err := r.Get(context.TODO(), credentialsKey, credentials); err != nil {
+			log.Error(err, "Failed to get repository credentials Secret ["+credentialsKey.String()+"]")
 			return err
 		}
+		for _, field := range util.ArgoRepositoryCredentialFields {
+			if value, ok := credentials.Data[field]; ok {
+				data[field] = value
+			}
+		}
+	}
+
+	repositorySecret := &coreV1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      key.Name,
+			Namespace: key.Namespace,
+			Annotations: map[string]string{
+				util.AnnotationKeyArgoManagedBy: util.ArgoApiGroup,
+			},
+			Labels: map[string]string{
+				util.LabelKeyArgoSecretType:          util.ArgoSecretTypeRepository,
+				clusterV1alpha1.LabelKeyClmName:      clusterManager.Name,
+				clusterV1alpha1.LabelKeyClmNamespace: clusterManager.Namespace,
+			},
+		},
+		Data: data,
+	}
+	result, err := r.apply(clusterManager, repositorySecret)
+	if err != nil {
+		log.Error(err, "Failed to apply Argocd repository Secret")
+		return err
+	}
+	logApplyResult(log, result, "Argocd repository Secret")
+
+	return nil
+}
+
+// newApplicationSyncPolicy 는 ClusterManager 와 operator 의 전역 설정으로 app-of-apps application 의 sync 정책을 만든다.
+// 자동 sync 를 하지 않는 경우 nil 을 반환한다.
+func newApplicationSyncPolicy(clusterManager *clusterV1alpha1.ClusterManager) *argocdV1alpha1.SyncPolicy {
+	automated := newApplicationSyncPolicyAutomated(clusterManager)
+	if automated == nil {
+		return nil
+	}
+	return &argocdV1alpha1.SyncPolicy{
+		Automated: automated,
+	}
+}
+
+func newApplicationSyncPolicyAutomated(clusterManager *clusterV1alpha1.ClusterManager) *argocdV1alpha1.SyncPolicyAutomated {
+	automated, prune, selfHeal := util.GetArgoAutoSync()
+	if policy := clusterManager.Spec.ApplicationSyncPolicy; policy != nil {
+		if policy.Automated != nil {
+			automated = *policy.Automated
+		}
+		if policy.Prune != nil {
+			prune = *policy.Prune
+		}
+		if policy.SelfHeal != nil {
+			selfHeal = *policy.SelfHeal
+		}
+	}
+
+	if !automated {
+		return nil
+	}
+	return &argocdV1alpha1.SyncPolicyAutomated{
+		Prune:    prune,
+		SelfHeal: selfHeal,
+	}
+}
+
+// UpdateApplicationProject 는 이전 버전에서 default project 나 cluster project 로 생성된 app-of-apps application 을
+// app-of-apps project 로 옮긴다. application 의 spec 은 사용자가 수정하므로 project 만 변경한다.
+func (r *ClusterManagerReconciler) UpdateApplicationProject(clusterManager *clusterV1alpha1.ClusterManager, application *argocdV1alpha1.Application) error {
+	log := r.Log.WithValues("clustermanager", clusterManager.GetNamespacedName())
+
+	if application.Spec.Project != argocdV1alpha1.DefaultAppProjectName &&
+		application.Spec.Project != clusterManager.GetNamespacedPrefix() {
+		return nil
+	}
+
+	application.Spec.Project = getArgocdAppOfAppsProjectName(clusterManager)
+	if err := r.Update(context.TODO(), application, client.FieldOwner(util.FieldOwner)); err != nil {
+		log.Error(err, "Failed to update project of ArgoCD Application")
+		return err
+	}
 
-		log.Info("Create ArgoCD Application successfully")
+	log.Info("Update project of ArgoCD Application successfully")
+	return nil
+}
+
+// UpdateApplicationSyncPolicy 는 app-of-apps application 의 자동 sync 정책을 ClusterManager 에 맞게 갱신한다.
+// 사용자가 설정했을 수 있는 syncOptions, retry 는 유지한다.
+func (r *ClusterManagerReconciler) UpdateApplicationSyncPolicy(clusterManager *clusterV1alpha1.ClusterManager, application *argocdV1alpha1.Application) error {
+	log := r.Log.WithValues("clustermanager", clusterManager.GetNamespacedName())
+
+	automated := newApplicationSyncPolicyAutomated(clusterManager)
+	var current *argocdV1alpha1.SyncPolicyAutomated
+	if application.Spec.SyncPolicy != nil {
+		current = application.Spec.SyncPolicy.Automated
+	}
+	if equality.Semantic.DeepEqual(current, automated) {
 		return nil
 	}
 
-	return err
+	if application.Spec.SyncPolicy == nil {
+		application.Spec.SyncPolicy = &argocdV1alpha1.SyncPolicy{}
+	}
+	application.Spec.SyncPolicy.Automated = automated
+	if application.Spec.SyncPolicy.IsZero() {
+		application.Spec.SyncPolicy = nil
+	}
+	if err := r.Update(context.TODO(), application, client.FieldOwner(util.FieldOwner)); err != nil {
+		log.Error(err, "Failed to update sync policy of ArgoCD Application")
+		return err
+	}
+
+	log.Info("Update sync policy of ArgoCD Application successfully", "automated", automated != nil)
+	return nil
 }
 
 func (r *ClusterManagerReconciler) DeleteCertificate(clusterManager *clusterV1alpha1.ClusterManager) error {
@@ -877,6 +2418,60 @@ func (r *ClusterManagerReconciler) DeleteIngress(clusterManager *clusterV1alpha1
 	return nil
 }
 
+func (r *ClusterManagerReconciler) DeleteProxyIngress(clusterManager *clusterV1alpha1.ClusterManager) error {
+	log := r.Log.WithValues("clustermanager", clusterManager.GetNamespacedName())
+
+	key := types.NamespacedName{
+		Name:      clusterManager.Name + "-proxy-ingress",
+		Namespace: clusterManager.Namespace,
+	}
+	ingress := &networkingv1.Ingress{}
+	err := r.Get(context.TODO(), key, ingress)
+	if errors.IsNotFound(err) {
+		return nil
+	}
+
+	if err != nil {
+		log.Error(err, "Failed to get Ingress for cluster proxy")
+		return err
+	}
+
+	if err := r.Delete(context.TODO(), ingress); err != nil {
+		log.Error(err, "Failed to delete Ingress for cluster proxy")
+		return err
+	}
+
+	log.Info("Delete Ingress for cluster proxy successfully")
+	return nil
+}
+
+func (r *ClusterManagerReconciler) DeleteProxyService(clusterManager *clusterV1alpha1.ClusterManager) error {
+	log := r.Log.WithValues("clustermanager", clusterManager.GetNamespacedName())
+
+	key := types.NamespacedName{
+		Name:      clusterManager.Name + "-proxy-service",
+		Namespace: clusterManager.Namespace,
+	}
+	service := &coreV1.Service{}
+	err := r.Get(context.TODO(), key, service)
+	if errors.IsNotFound(err) {
+		return nil
+	}
+
+	if err != nil {
+		log.Error(err, "Failed to get Service for cluster proxy")
+		return err
+	}
+
+	if err := r.Delete(context.TODO(), service); err != nil {
+		log.Error(err, "Failed to delete Service for cluster proxy")
+		return err
+	}
+
+	log.Info("Delete Service for cluster proxy successfully")
+	return nil
+}
+
 func (r *ClusterManagerReconciler) DeleteService(clusterManager *clusterV1alpha1.ClusterManager) error {
 	log := r.Log.WithValues("clustermanager", clusterManager.GetNamespacedName())
 
@@ -958,6 +2553,31 @@ func (r *ClusterManagerReconciler) DeleteMiddleware(clusterManager *clusterV1alp
 	return nil
 }
 
+func (r *ClusterManagerReconciler) deleteMiddleware(clusterManager *clusterV1alpha1.ClusterManager, name string) error {
+	log := r.Log.WithValues("clustermanager", clusterManager.GetNamespacedName())
+
+	key := types.NamespacedName{
+		Name:      name,
+		Namespace: clusterManager.Namespace,
+	}
+	middleware := &traefikV1alpha1.Middleware{}
+	err := r.Get(context.TODO(), key, middleware)
+	if errors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		log.Error(err, "Failed to get Middleware", "middleware", key.Name)
+		return err
+	}
+
+	if err := r.Delete(context.TODO(), middleware); err != nil {
+		log.Error(err, "Failed to delete Middleware", "middleware", key.Name)
+		return err
+	}
+
+	log.Info("Delete Middleware successfully", "middleware", key.Name)
+	return nil
+}
+
 func (r *ClusterManagerReconciler) DeleteGatewayService(clusterManager *clusterV1alpha1.ClusterManager) error {
 	log := r.Log.WithValues("clustermanager", clusterManager.GetNamespacedName())
 
@@ -985,6 +2605,45 @@ func (r *ClusterManagerReconciler) DeleteGatewayService(clusterManager *clusterV
 	return nil
 }
 
+func (r *ClusterManagerReconciler) DeleteGatewayServersTransport(clusterManager *clusterV1alpha1.ClusterManager) error {
+	log := r.Log.WithValues("clustermanager", clusterManager.GetNamespacedName())
+
+	key := types.NamespacedName{
+		Name:      clusterManager.Name + "-gateway-transport",
+		Namespace: clusterManager.Namespace,
+	}
+	serversTransport := &traefikV1alpha1.ServersTransport{}
+	if err := r.Get(context.TODO(), key, serversTransport); errors.IsNotFound(err) {
+		log.Info("Cannot find ServersTransport for gateway. Maybe already deleted")
+	} else if err != nil {
+		log.Error(err, "Failed to get ServersTransport for gateway")
+		return err
+	} else {
+		if err := r.Delete(context.TODO(), serversTransport); err != nil {
+			log.Error(err, "Failed to delete ServersTransport for gateway")
+			return err
+		}
+		log.Info("Delete ServersTransport for gateway successfully")
+	}
+
+	key.Name = clusterManager.Name + "-gateway-ca"
+	secret := &coreV1.Secret{}
+	if err := r.Get(context.TODO(), key, secret); errors.IsNotFound(err) {
+		log.Info("Cannot find Secret for gateway CA. Maybe already deleted")
+	} else if err != nil {
+		log.Error(err, "Failed to get Secret for gateway CA")
+		return err
+	} else {
+		if err := r.Delete(context.TODO(), secret); err != nil {
+			log.Error(err, "Failed to delete Secret for gateway CA")
+			return err
+		}
+		log.Info("Delete Secret for gateway CA successfully")
+	}
+
+	return nil
+}
+
 func (r *ClusterManagerReconciler) DeleteGatewayEndpoint(clusterManager *clusterV1alpha1.ClusterManager) error {
 	log := r.Log.WithValues("clustermanager", clusterManager.GetNamespacedName())
 
@@ -1103,18 +2762,180 @@ func (r *ClusterManagerReconciler) DeleteDeprecatedPrometheusResources(clusterMa
 	return nil
 }
 
-func (r *ClusterManagerReconciler) CheckApplicationRemains(clusterManager *clusterV1alpha1.ClusterManager) error {
+// DeleteCrossNamespaceResources 는 owner reference 로 gc 되지 않는 다른 namespace(argocd)의 리소스를 label 로 찾아 삭제한다.
+// application 이 single cluster 의 리소스를 정리하는 동안에는 cluster secret 과 project 가 필요하므로,
+// application 이 모두 삭제된 것을 확인한 뒤에 cluster secret 과 project 를 삭제한다.
+// single cluster 에 연결할 수 없거나 application 의 삭제가 timeout 을 넘기면 정리를 포기하고 finalizer 를 제거한다.
+func (r *ClusterManagerReconciler) DeleteCrossNamespaceResources(clusterManager *clusterV1alpha1.ClusterManager) (bool, error) {
+	log := r.Log.WithValues("clustermanager", clusterManager.GetNamespacedName())
+
+	// single cluster 에 연결할 수 있는지는 삭제 중인 application 이 있는 경우에만 한번 확인한다.
+	checked, reachable := false, false
+
 	appList := &argocdV1alpha1.ApplicationList{}
-	if err := r.List(context.TODO(), appList); err != nil {
-		return err
+	if err := r.List(context.TODO(), appList, client.InNamespace(util.ArgoNamespace)); err != nil {
+		log.Error(err, "Failed to list Applications")
+		return false, err
+	}
+	remains := false
+	for i := range appList.Items {
+		app := &appList.Items[i]
+		if app.Labels[util.LabelKeyArgoTargetCluster] != clusterManager.GetNamespacedPrefix() && !isLabeledFor(app, clusterManager) {
+			continue
+		}
+		remains = true
+		if deletionTimestamp := app.GetDeletionTimestamp(); !deletionTimestamp.IsZero() {
+			if !controllerutil.ContainsFinalizer(app, argocdV1alpha1.ResourcesFinalizerName) {
+				continue
+			}
+			timeout := time.Since(deletionTimestamp.Time) >= util.GetArgoApplicationDeletionTimeout()
+			if !timeout && !checked {
+				var err error
+				if reachable, err = r.isRemoteClusterReachable(clusterManager); err != nil {
+					return false, err
+				}
+				checked = true
+			}
+			if !timeout && reachable {
+				continue
+			}
+			log.Info("Give up cleaning up resources of Application ["+app.Name+"] in remote cluster", "timeout", timeout)
+			before := app.DeepCopy()
+			controllerutil.RemoveFinalizer(app, argocdV1alpha1.ResourcesFinalizerName)
+			if err := r.Patch(context.TODO(), app, client.MergeFrom(before), client.FieldOwner(util.FieldOwner)); err != nil && !errors.IsNotFound(err) {
+				log.Error(err, "Failed to remove finalizer of Application ["+app.Name+"]")
+				return false, err
+			}
+			continue
+		}
+		if err := r.Delete(context.TODO(), app); err != nil && !errors.IsNotFound(err) {
+			log.Error(err, "Failed to delete Application ["+app.Name+"]")
+			return false, err
+		}
+		log.Info("Delete Application [" + app.Name + "]")
+	}
+	if remains {
+		log.Info("Wait for Applications to be deleted")
+		return false, nil
+	}
+
+	opts := []client.ListOption{
+		client.InNamespace(util.ArgoNamespace),
+		client.MatchingLabels{
+			clusterV1alpha1.LabelKeyClmName:      clusterManager.Name,
+			clusterV1alpha1.LabelKeyClmNamespace: clusterManager.Namespace,
+		},
+	}
+	lists := []client.ObjectList{
+		&argocdV1alpha1.AppProjectList{},
+		&coreV1.SecretList{},
+	}
+	for _, list := range lists {
+		if err := r.List(context.TODO(), list, opts...); err != nil {
+			log.Error(err, "Failed to list resources in argocd namespace")
+			return false, err
+		}
+
+		objs, err := meta.ExtractList(list)
+		if err != nil {
+			return false, err
+		}
+		for _, o := range objs {
+			obj := o.(client.Object)
+			if err := r.Delete(context.TODO(), obj); err != nil && !errors.IsNotFound(err) {
+				log.Error(err, "Failed to delete ["+obj.GetName()+"] in argocd namespace")
+				return false, err
+			}
+			log.Info("Delete [" + obj.GetName() + "] in argocd namespace successfully")
+		}
+	}
+
+	return true, nil
+}
+
+// DeleteUnownedResources 는 ClusterManager 의 namespace 에서 label 은 있지만 owner reference 가 없는 리소스를 삭제한다.
+// owner reference 를 설정하기 이전 버전에서 만들어진 리소스를 위한 것이며, owner reference 가 있는 리소스는 gc 가 삭제한다.
+// cert-manager 가 만든 certificate secret 은 label 이 없으므로 이름으로 삭제한다.
+func (r *ClusterManagerReconciler) DeleteUnownedResources(clusterManager *clusterV1alpha1.ClusterManager) error {
+	log := r.Log.WithValues("clustermanager", clusterManager.GetNamespacedName())
+
+	opts := []client.ListOption{
+		client.InNamespace(clusterManager.Namespace),
+		client.MatchingLabels{
+			clusterV1alpha1.LabelKeyClmName: clusterManager.Name,
+		},
 	}
-	for _, app := range appList.Items {
-		if app.Labels[util.LabelKeyArgoTargetCluster] == clusterManager.GetNamespacedPrefix() {
-			return fmt.Errorf("application still remains")
+	lists := []client.ObjectList{
+		&certmanagerV1.CertificateList{},
+		&networkingv1.IngressList{},
+		&coreV1.ServiceList{},
+		&coreV1.EndpointsList{},
+		&coreV1.ConfigMapList{},
+		&coreV1.SecretList{},
+		&traefikV1alpha1.MiddlewareList{},
+		&traefikV1alpha1.ServersTransportList{},
+		newUnstructuredList(httpRouteGVK),
+		newUnstructuredList(backendTLSPolicyGVK),
+	}
+	for _, list := range lists {
+		if err := r.List(context.TODO(), list, opts...); meta.IsNoMatchError(err) {
+			// gateway api 와 같이 crd 가 설치되지 않은 경우
+			continue
+		} else if err != nil {
+			log.Error(err, "Failed to list resources for ClusterManager")
+			return err
+		}
+
+		objs, err := meta.ExtractList(list)
+		if err != nil {
+			return err
+		}
+		for _, o := range objs {
+			obj := o.(client.Object)
+			if metav1.IsControlledBy(obj, clusterManager) ||
+				obj.GetLabels()[util.LabelKeyClmSecretType] == util.ClmSecretTypeKubeconfig {
+				continue
+			}
+			if err := r.Delete(context.TODO(), obj); err != nil && !errors.IsNotFound(err) {
+				log.Error(err, "Failed to delete ["+obj.GetName()+"]")
+				return err
+			}
+			log.Info("Delete [" + obj.GetName() + "] without owner reference successfully")
 		}
 	}
 
-	return nil
+	return r.DeleteCertSecret(clusterManager)
+}
+
+// isRemoteClusterReachable 은 kubeconfig secret 으로 single cluster 의 api server 에 연결할 수 있는지 반환한다.
+// kubeconfig secret 이 없으면 cluster 가 이미 삭제된 것으로 본다.
+func (r *ClusterManagerReconciler) isRemoteClusterReachable(clusterManager *clusterV1alpha1.ClusterManager) (bool, error) {
+	log := r.Log.WithValues("clustermanager", clusterManager.GetNamespacedName())
+
+	kubeconfigSecret, err := r.GetKubeconfigSecret(clusterManager)
+	if errors.IsNotFound(err) {
+		return false, nil
+	} else if err != nil {
+		log.Error(err, "Failed to get kubeconfig secret")
+		return false, err
+	}
+
+	remoteClientset, err := util.GetRemoteK8sClient(kubeconfigSecret)
+	if err != nil {
+		log.Info("Failed to get remoteK8sClient", "reason", err.Error())
+		return false, nil
+	}
+	if _, err := remoteClientset.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{Limit: 1}); err != nil {
+		log.Info("Failed to get node for remote cluster", "reason", err.Error())
+		return false, nil
+	}
+	return true, nil
+}
+
+// isLabeledFor 는 리소스가 ClusterManager 를 가리키는 label 을 가지는지 확인한다.
+func isLabeledFor(o metav1.Object, clusterManager *clusterV1alpha1.ClusterManager) bool {
+	return o.GetLabels()[clusterV1alpha1.LabelKeyClmName] == clusterManager.Name &&
+		o.GetLabels()[clusterV1alpha1.LabelKeyClmNamespace] == clusterManager.Namespace
 }
 
 func (r *ClusterManagerReconciler) DeleteLoadBalancerServices(clusterManager *clusterV1alpha1.ClusterManager) error {
@@ -1174,70 +2995,252 @@ func (r *ClusterManagerReconciler) DeleteLoadBalancerServices(clusterManager *cl
 	return nil
 }
 
-func (r *ClusterManagerReconciler) DeleteTraefikResources(clusterManager *clusterV1alpha1.ClusterManager) error {
+func (r *ClusterManagerReconciler) DeleteHyperAuthResourcesForSingleCluster(clusterManager *clusterV1alpha1.ClusterManager) error {
 	log := r.Log.WithValues("clustermanager", clusterManager.GetNamespacedName())
-
-	if err := r.DeleteCertificate(clusterManager); err != nil {
+	key := types.NamespacedName{
+		Name:      "passwords",
+		Namespace: "hyperauth",
+	}
+	secret := &coreV1.Secret{}
+	if err := r.Get(context.TODO(), key, secret); errors.IsNotFound(err) {
+		log.Info("HyperAuth password secret is not found")
+		return err
+	} else if err != nil {
+		log.Error(err, "Failed to get HyperAuth password secret")
 		return err
 	}
+	realm := getHyperAuthStatusRealm(clusterManager)
+	if realm == "" {
+		var err error
+		if realm, err = util.GetHyperAuthRealmFor(context.TODO(), r.Client, clusterManager); err != nil {
+			log.Error(err, "Failed to get HyperAuth realm")
+			return err
+		}
+	}
 
-	if err := r.DeleteCertSecret(clusterManager); err != nil {
-		return err
+	return r.deleteHyperAuthResources(clusterManager, hyperauthCaller.GetHyperAuthClient(secret).ForRealm(realm))
+}
+
+// deleteHyperAuthResources 는 cluster 를 위해 realm 에 만든 HyperAuth 의 client 와 group 을 삭제한다.
+func (r *ClusterManagerReconciler) deleteHyperAuthResources(clusterManager *clusterV1alpha1.ClusterManager, hyperAuthClient *hyperauthCaller.HyperAuthClient) error {
+	log := r.Log.WithValues("clustermanager", clusterManager.GetNamespacedName(), "realm", hyperAuthClient.Realm())
+
+	// preset 에서 빠졌지만 이전에 만들었던 client 와 group 도 함께 삭제한다.
+	clientIds, groupNames := r.getManagedHyperAuthResources(clusterManager)
+	for _, clientId := range clientIds {
+		err := hyperAuthClient.DeleteClient(context.TODO(), hyperauthCaller.ClientConfig{ClientId: clientId})
+		if err != nil {
+			log.Error(err, "Failed to delete HyperAuth client ["+clientId+"] for single cluster")
+			return err
+		}
 	}
 
-	if err := r.DeleteIngress(clusterManager); err != nil {
-		return err
+	for _, name := range groupNames {
+		err := hyperAuthClient.DeleteGroup(context.TODO(), hyperauthCaller.GroupConfig{Name: name})
+		if err != nil {
+			log.Error(err, "Failed to delete HyperAuth group ["+name+"] for single cluster")
+			return err
+		}
 	}
 
-	if err := r.DeleteMiddleware(clusterManager); err != nil {
-		return err
+	log.Info("Delete HyperAuth resources for single cluster successfully")
+	return nil
+}
+
+// getHyperAuthDesiredState 는 cluster 에 설치되는 module 들을 위해 HyperAuth 에 있어야 하는 리소스들을 반환한다.
+// ClusterAddonProfile 에서 disabled 된 module 은 제외한다.
+func (r *ClusterManagerReconciler) getHyperAuthDesiredState(ctx context.Context, clusterManager *clusterV1alpha1.ClusterManager) (hyperauthCaller.DesiredState, error) {
+	modules, err := hyperauthCaller.LoadModules(ctx, r.OperatorCache)
+	if err != nil {
+		return hyperauthCaller.DesiredState{}, err
 	}
 
-	if err := r.DeleteGatewayService(clusterManager); err != nil {
-		return err
+	profile, _, err := r.GetAddonProfile(clusterManager)
+	if err != nil {
+		return hyperauthCaller.DesiredState{}, err
+	}
+	enabled := map[string]bool{}
+	params := newHyperAuthModuleTemplateParams(clusterManager)
+	params.AddonValues = map[string]map[string]string{}
+	for name, module := range profile.Modules {
+		if module.Enabled != nil {
+			enabled[name] = *module.Enabled
+		}
+		params.AddonValues[name] = module.Values
+	}
+	// module 의 host 는 profile 의 global domain 을 사용하고, 채워지지 않은 경우 cluster 의 domain 을 사용한다.
+	if domain := profile.Global["domain"]; len(validation.IsDNS1123Subdomain(domain)) == 0 {
+		params.Domain = domain
 	}
 
-	if err := r.DeleteGatewayEndpoint(clusterManager); err != nil {
-		return err
+	return hyperauthCaller.NewDesiredState(
+		hyperauthCaller.SelectModules(modules, enabled),
+		params,
+		clusterManager.Annotations[util.AnnotationKeyOwner],
+	)
+}
+
+func newHyperAuthModuleTemplateParams(clusterManager *clusterV1alpha1.ClusterManager) hyperauthCaller.ModuleTemplateParams {
+	return hyperauthCaller.ModuleTemplateParams{
+		Prefix:      clusterManager.GetNamespacedPrefix(),
+		Namespace:   clusterManager.Namespace,
+		ClusterName: clusterManager.Name,
+		Domain:      clusterManager.Annotations[clusterV1alpha1.AnnotationKeyClmDomain],
 	}
+}
 
-	log.Info("Delete traefik resources successfully")
-	return nil
+// getManagedHyperAuthResources 는 operator 가 cluster 를 위해 만들었을 수 있는 client 와 group 의 이름을 반환한다.
+// status 에 기록된 이름들과, 설치 여부와 관계없이 모든 module 의 이름들을 합친다.
+func (r *ClusterManagerReconciler) getManagedHyperAuthResources(clusterManager *clusterV1alpha1.ClusterManager) ([]string, []string) {
+	log := r.Log.WithValues("clustermanager", clusterManager.GetNamespacedName())
+
+	modules, err := hyperauthCaller.LoadModules(context.TODO(), r.OperatorCache)
+	if err != nil {
+		log.Error(err, "Failed to load HyperAuth modules. Use default modules instead")
+		modules = hyperauthCaller.DefaultModules()
+	}
+	desired, err := hyperauthCaller.NewDesiredState(modules, newHyperAuthModuleTemplateParams(clusterManager), "")
+	if err != nil {
+		log.Error(err, "Failed to render HyperAuth modules")
+	}
+
+	clientIds, groupNames := desired.ClientIds(), desired.GroupNames()
+	if status := clusterManager.Status.HyperAuth; status != nil {
+		clientIds = sets.NewString(clientIds...).Insert(status.Clients...).List()
+		groupNames = sets.NewString(groupNames...).Insert(status.Groups...).List()
+	}
+	return clientIds, groupNames
 }
 
-func (r *ClusterManagerReconciler) DeleteHyperAuthResourcesForSingleCluster(clusterManager *clusterV1alpha1.ClusterManager) error {
+// SyncHyperAuthResourcesForSingleCluster 는 cluster 를 위한 HyperAuth 의 리소스들을 desired state 와 비교하여 동기화하고,
+// 결과를 status 와 condition 에 기록한다.
+func (r *ClusterManagerReconciler) SyncHyperAuthResourcesForSingleCluster(ctx context.Context, clusterManager *clusterV1alpha1.ClusterManager) error {
 	log := r.Log.WithValues("clustermanager", clusterManager.GetNamespacedName())
+
+	// Hyperauth의 password를 가져오기 위한 secret을 조회
 	key := types.NamespacedName{
 		Name:      "passwords",
 		Namespace: "hyperauth",
 	}
 	secret := &coreV1.Secret{}
 	if err := r.Get(context.TODO(), key, secret); errors.IsNotFound(err) {
-		log.Info("HyperAuth password secret is not found")
+		log.Info("Hyperauth password secret is not found")
+		SetHyperAuthSyncedCondition(clusterManager, hyperAuthReasonSecretNotFound, err.Error())
 		return err
 	} else if err != nil {
-		log.Error(err, "Failed to get HyperAuth password secret")
+		log.Error(err, "Failed to get hyperauth password secret")
 		return err
 	}
 
-	clientConfigs := hyperauthCaller.GetClientConfigPreset(clusterManager.GetNamespacedPrefix())
-	for _, config := range clientConfigs {
-		err := hyperauthCaller.DeleteClient(config, secret)
-		if err != nil {
-			log.Error(err, "Failed to delete HyperAuth client ["+config.ClientId+"] for single cluster")
-			return err
-		}
+	realm, err := util.GetHyperAuthRealmFor(ctx, r.Client, clusterManager)
+	if err != nil {
+		log.Error(err, "Failed to get HyperAuth realm")
+		SetHyperAuthSyncedCondition(clusterManager, hyperAuthReasonSyncFailed, err.Error())
+		return err
 	}
+	hyperAuthClient := hyperauthCaller.GetHyperAuthClient(secret).ForRealm(realm)
 
-	groupConfigs := hyperauthCaller.GetGroupConfigPreset(clusterManager.GetNamespacedPrefix())
-	for _, config := range groupConfigs {
-		err := hyperauthCaller.DeleteGroup(config, secret)
-		if err != nil {
-			log.Error(err, "Failed to delete HyperAuth group ["+config.Name+"] for single cluster")
+	desired, err := r.getHyperAuthDesiredState(ctx, clusterManager)
+	if err != nil {
+		log.Error(err, "Failed to get desired state of HyperAuth resources")
+		SetHyperAuthSyncedCondition(clusterManager, hyperAuthReasonSyncFailed, err.Error())
+		return err
+	}
+	// client secret 을 HyperAuth 에 반영하기 전에 hub 의 secret 에 먼저 저장하여 잃어버리지 않도록 한다.
+	if _, err := r.setHyperAuthClientSecrets(ctx, clusterManager, &desired, hyperAuthClient); err != nil {
+		SetHyperAuthSyncedCondition(clusterManager, hyperAuthReasonSyncFailed, err.Error())
+		return err
+	}
+	managedClients, managedGroups := []string{}, []string{}
+	if status := clusterManager.Status.HyperAuth; status != nil {
+		managedClients, managedGroups = status.Clients, status.Groups
+	}
+	// realm 이 바뀐 경우 이전 realm 의 리소스를 지우고 새 realm 에 다시 만든다.
+	if previous := getHyperAuthStatusRealm(clusterManager); previous != "" && previous != realm {
+		log.Info("HyperAuth realm is changed", "previous", previous, "realm", realm)
+		if err := r.deleteHyperAuthResources(clusterManager, hyperAuthClient.ForRealm(previous)); err != nil {
+			SetHyperAuthSyncedCondition(clusterManager, hyperAuthReasonSyncFailed, err.Error())
 			return err
 		}
+		managedClients, managedGroups = []string{}, []string{}
 	}
 
-	log.Info("Delete HyperAuth resources for single cluster successfully")
+	result, err := hyperAuthClient.Sync(ctx, desired, managedClients, managedGroups)
+	if len(result.Created)+len(result.Updated)+len(result.Deleted) != 0 {
+		log.Info("Synced HyperAuth resources for single cluster", "changes", result.String())
+	}
+	if err != nil {
+		log.Error(err, "Failed to sync HyperAuth resources for single cluster")
+		SetHyperAuthSyncedCondition(clusterManager, hyperAuthReasonSyncFailed, err.Error())
+		return err
+	}
+	if err := r.applyRemoteHyperAuthClientSecrets(ctx, clusterManager, desired, realm); err != nil {
+		SetHyperAuthSyncedCondition(clusterManager, hyperAuthReasonSyncFailed, err.Error())
+		return err
+	}
+
+	now := metav1.Now()
+	clusterManager.Status.HyperAuth = &clusterV1alpha1.HyperAuthStatus{
+		Clients:      desired.ClientIds(),
+		Groups:       desired.GroupNames(),
+		DesiredHash:  desired.Hash(),
+		LastSyncTime: &now,
+		Realm:        realm,
+	}
+	SetHyperAuthSyncedCondition(clusterManager, hyperAuthReasonSynced, result.String())
 	return nil
 }
+
+// IsHyperAuthSyncNeeded 는 마지막 동기화가 실패했거나, desired state 가 바뀌었거나, 동기화한 지 interval 이 지났는지 반환한다.
+func (r *ClusterManagerReconciler) IsHyperAuthSyncNeeded(ctx context.Context, clusterManager *clusterV1alpha1.ClusterManager, interval time.Duration) bool {
+	status := clusterManager.Status.HyperAuth
+	if status == nil || status.LastSyncTime == nil ||
+		!meta.IsStatusConditionTrue(clusterManager.Status.Conditions, clusterV1alpha1.ConditionTypeHyperAuthSynced) {
+		return true
+	}
+	// cluster 의 realm 이 바뀐 경우
+	if realm, err := util.GetHyperAuthRealmFor(ctx, r.Client, clusterManager); err != nil || realm != getHyperAuthStatusRealm(clusterManager) {
+		return true
+	}
+	// module 설정이나 cluster 의 profile 이 바뀌었거나, client secret 을 새로 만들어야 하는 경우
+	desired, err := r.getHyperAuthDesiredState(ctx, clusterManager)
+	if err != nil {
+		return true
+	}
+	if pending, err := r.setHyperAuthClientSecrets(ctx, clusterManager, &desired, nil); err != nil || pending {
+		return true
+	}
+	if status.DesiredHash != desired.Hash() {
+		return true
+	}
+	return time.Since(status.LastSyncTime.Time) >= interval
+}
+
+// getHyperAuthStatusRealm 은 cluster 의 HyperAuth 리소스를 만든 realm 을 반환한다. 만든 적이 없으면 비어 있다.
+// realm 을 설정할 수 없던 이전 버전에서 만든 리소스는 기본 realm 에 있다.
+func getHyperAuthStatusRealm(clusterManager *clusterV1alpha1.ClusterManager) string {
+	status := clusterManager.Status.HyperAuth
+	if status == nil {
+		return ""
+	}
+	if status.Realm == "" {
+		return util.DefaultHyperAuthRealm
+	}
+	return status.Realm
+}
+
+// SetHyperAuthSyncedCondition 은 HyperAuth 리소스의 동기화 결과를 status condition 으로 기록한다.
+// 성공한 경우에는 message 로 변경 내역을, 실패한 경우에는 error 를 기록한다.
+func SetHyperAuthSyncedCondition(c *clusterV1alpha1.ClusterManager, reason string, message string) {
+	condition := metav1.Condition{
+		Type:               clusterV1alpha1.ConditionTypeHyperAuthSynced,
+		Status:             metav1.ConditionFalse,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: c.Generation,
+	}
+	if reason == hyperAuthReasonSynced {
+		condition.Status = metav1.ConditionTrue
+	}
+	meta.SetStatusCondition(&c.Status.Conditions, condition)
+}
@@ -0,0 +1,264 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	clusterV1alpha1 "github.com/tmax-cloud/hypercloud-multi-operator/apis/cluster/v1alpha1"
+	util "github.com/tmax-cloud/hypercloud-multi-operator/controllers/util"
+	dynamicv2 "github.com/traefik/traefik/v2/pkg/config/dynamic"
+	traefikV1alpha1 "github.com/traefik/traefik/v2/pkg/provider/kubernetes/crd/traefik/v1alpha1"
+
+	coreV1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func TestIsArgocdProjectSyncNeeded(t *testing.T) {
+	newClusterManager := func() *clusterV1alpha1.ClusterManager {
+		return &clusterV1alpha1.ClusterManager{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "cluster",
+				Namespace:   "ns",
+				Annotations: map[string]string{util.AnnotationKeyOwner: "owner@tmax.co.kr"},
+			},
+		}
+	}
+	kubeconfigSecret := &coreV1.Secret{
+		Data: map[string][]byte{"value": []byte("kubeconfig")},
+	}
+	profile := &clusterV1alpha1.ClusterAddonProfileSpec{RepoURL: "https://github.com/tmax-cloud/addons"}
+	hash := GetArgocdProjectConfigHash(newClusterManager(), kubeconfigSecret, profile)
+	synced := func(hash string, since time.Duration) *clusterV1alpha1.ArgoProjectStatus {
+		lastSyncTime := metav1.NewTime(time.Now().Add(-since))
+		return &clusterV1alpha1.ArgoProjectStatus{DesiredHash: hash, LastSyncTime: &lastSyncTime}
+	}
+
+	tests := []struct {
+		name   string
+		modify func(clm *clusterV1alpha1.ClusterManager)
+		status *clusterV1alpha1.ArgoProjectStatus
+		want   bool
+	}{
+		{
+			name:   "never synced",
+			status: nil,
+			want:   true,
+		},
+		{
+			name:   "synced recently",
+			status: synced(hash, time.Minute),
+			want:   false,
+		},
+		{
+			name:   "interval passed",
+			status: synced(hash, 10*time.Minute),
+			want:   true,
+		},
+		{
+			name:   "config changed",
+			status: synced("previous", time.Minute),
+			want:   true,
+		},
+		{
+			name: "owner changed",
+			modify: func(clm *clusterV1alpha1.ClusterManager) {
+				clm.Annotations[util.AnnotationKeyOwner] = "other@tmax.co.kr"
+			},
+			status: synced(hash, time.Minute),
+			want:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clm := newClusterManager()
+			if tt.modify != nil {
+				tt.modify(clm)
+			}
+			clm.Status.ArgoProject = tt.status
+			current := GetArgocdProjectConfigHash(clm, kubeconfigSecret, profile)
+			if got := IsArgocdProjectSyncNeeded(clm, current, 5*time.Minute); got != tt.want {
+				t.Errorf("IsArgocdProjectSyncNeeded() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetArgocdProjectConfigHash(t *testing.T) {
+	clm := &clusterV1alpha1.ClusterManager{
+		ObjectMeta: metav1.ObjectMeta{Name: "cluster", Namespace: "ns", Annotations: map[string]string{}},
+	}
+	kubeconfigSecret := &coreV1.Secret{Data: map[string][]byte{"value": []byte("kubeconfig")}}
+	profile := &clusterV1alpha1.ClusterAddonProfileSpec{RepoURL: "https://github.com/tmax-cloud/addons"}
+	hash := GetArgocdProjectConfigHash(clm, kubeconfigSecret, profile)
+
+	if GetArgocdProjectConfigHash(clm, kubeconfigSecret, profile) != hash {
+		t.Errorf("hash is not stable")
+	}
+	if GetArgocdProjectConfigHash(clm, &coreV1.Secret{Data: map[string][]byte{"value": []byte("other")}}, profile) == hash {
+		t.Errorf("hash does not change with kubeconfig")
+	}
+	if GetArgocdProjectConfigHash(clm, kubeconfigSecret, &clusterV1alpha1.ClusterAddonProfileSpec{RepoURL: "https://other"}) == hash {
+		t.Errorf("hash does not change with addon profile")
+	}
+	if GetArgocdProjectConfigHash(clm, kubeconfigSecret, nil) == hash {
+		t.Errorf("hash does not change without addon profile")
+	}
+}
+
+func TestNewTrafficPolicyMiddlewareSpecs(t *testing.T) {
+	authorization := &dynamicv2.SourceCriterion{RequestHeaderName: "Authorization"}
+	period := intstr.FromString("1m")
+	burst := int64(20)
+
+	tests := []struct {
+		name   string
+		policy *clusterV1alpha1.TrafficPolicy
+		want   map[string]traefikV1alpha1.MiddlewareSpec
+	}{
+		{
+			name:   "no policy",
+			policy: nil,
+			want:   map[string]traefikV1alpha1.MiddlewareSpec{},
+		},
+		{
+			name:   "empty policy",
+			policy: &clusterV1alpha1.TrafficPolicy{},
+			want:   map[string]traefikV1alpha1.MiddlewareSpec{},
+		},
+		{
+			name:   "rate limit",
+			policy: &clusterV1alpha1.TrafficPolicy{RateLimit: &clusterV1alpha1.RateLimitPolicy{Average: 10}},
+			want: map[string]traefikV1alpha1.MiddlewareSpec{
+				trafficPolicyRateLimitSuffix: {
+					RateLimit: &traefikV1alpha1.RateLimit{Average: 10, SourceCriterion: authorization},
+				},
+			},
+		},
+		{
+			name: "rate limit with period and burst",
+			policy: &clusterV1alpha1.TrafficPolicy{
+				RateLimit: &clusterV1alpha1.RateLimitPolicy{Average: 10, Period: "1m", Burst: 20},
+			},
+			want: map[string]traefikV1alpha1.MiddlewareSpec{
+				trafficPolicyRateLimitSuffix: {
+					RateLimit: &traefikV1alpha1.RateLimit{Average: 10, Period: &period, Burst: &burst, SourceCriterion: authorization},
+				},
+			},
+		},
+		{
+			name:   "in-flight limit",
+			policy: &clusterV1alpha1.TrafficPolicy{InFlightLimit: 5},
+			want: map[string]traefikV1alpha1.MiddlewareSpec{
+				trafficPolicyInFlightSuffix: {
+					InFlightReq: &dynamicv2.InFlightReq{Amount: 5, SourceCriterion: authorization},
+				},
+			},
+		},
+		{
+			name:   "ip allowlist",
+			policy: &clusterV1alpha1.TrafficPolicy{SourceRanges: []string{"10.0.0.0/8", "192.168.0.1"}},
+			want: map[string]traefikV1alpha1.MiddlewareSpec{
+				trafficPolicyIPAllowListSuffix: {
+					IPWhiteList: &dynamicv2.IPWhiteList{SourceRange: []string{"10.0.0.0/8", "192.168.0.1"}},
+				},
+			},
+		},
+		{
+			name:   "request headers",
+			policy: &clusterV1alpha1.TrafficPolicy{RequestHeaders: map[string]string{"X-Cluster": "cluster"}},
+			want: map[string]traefikV1alpha1.MiddlewareSpec{
+				trafficPolicyHeadersSuffix: {
+					Headers: &dynamicv2.Headers{CustomRequestHeaders: map[string]string{"X-Cluster": "cluster"}},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := newTrafficPolicyMiddlewareSpecs(tt.policy)
+			if !equality.Semantic.DeepEqual(got, tt.want) {
+				t.Errorf("newTrafficPolicyMiddlewareSpecs() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIngressMiddlewareChain(t *testing.T) {
+	clm := &clusterV1alpha1.ClusterManager{
+		ObjectMeta: metav1.ObjectMeta{Name: "cluster", Namespace: "ns"},
+	}
+	const (
+		jwt    = "api-gateway-system-jwt-decode-auth@kubernetescrd"
+		prefix = "ns-cluster-prefix@kubernetescrd"
+	)
+
+	tests := []struct {
+		name            string
+		policy          *clusterV1alpha1.TrafficPolicy
+		wantMiddlewares string
+		wantProxy       string
+	}{
+		{
+			name:            "no policy",
+			policy:          nil,
+			wantMiddlewares: jwt + "," + prefix,
+			wantProxy:       "",
+		},
+		{
+			name:            "rate limit only",
+			policy:          &clusterV1alpha1.TrafficPolicy{RateLimit: &clusterV1alpha1.RateLimitPolicy{Average: 10}},
+			wantMiddlewares: "ns-cluster-ratelimit@kubernetescrd," + jwt + "," + prefix,
+			wantProxy:       "ns-cluster-ratelimit@kubernetescrd",
+		},
+		{
+			name:            "headers only",
+			policy:          &clusterV1alpha1.TrafficPolicy{RequestHeaders: map[string]string{"X-Cluster": "cluster"}},
+			wantMiddlewares: jwt + "," + prefix + ",ns-cluster-headers@kubernetescrd",
+			wantProxy:       "ns-cluster-headers@kubernetescrd",
+		},
+		{
+			name: "all fields",
+			policy: &clusterV1alpha1.TrafficPolicy{
+				RateLimit:      &clusterV1alpha1.RateLimitPolicy{Average: 10},
+				InFlightLimit:  5,
+				SourceRanges:   []string{"10.0.0.0/8"},
+				RequestHeaders: map[string]string{"X-Cluster": "cluster"},
+			},
+			wantMiddlewares: "ns-cluster-ipallowlist@kubernetescrd,ns-cluster-ratelimit@kubernetescrd,ns-cluster-inflight@kubernetescrd," +
+				jwt + "," + prefix + ",ns-cluster-headers@kubernetescrd",
+			wantProxy: "ns-cluster-ipallowlist@kubernetescrd,ns-cluster-ratelimit@kubernetescrd,ns-cluster-inflight@kubernetescrd," +
+				"ns-cluster-headers@kubernetescrd",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before, after := newTrafficPolicyMiddlewareChain(clm, newTrafficPolicyMiddlewareSpecs(tt.policy))
+			middlewares, proxyMiddlewares := newIngressMiddlewares(clm, before, after)
+			if middlewares != tt.wantMiddlewares {
+				t.Errorf("ingress middlewares = %q, want %q", middlewares, tt.wantMiddlewares)
+			}
+			if proxyMiddlewares != tt.wantProxy {
+				t.Errorf("proxy ingress middlewares = %q, want %q", proxyMiddlewares, tt.wantProxy)
+			}
+		})
+	}
+}
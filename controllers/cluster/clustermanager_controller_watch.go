@@ -18,8 +18,14 @@ import (
 	"context"
 	"strings"
 
+	argocdV1alpha1 "github.com/argoproj/argo-cd/v2/pkg/apis/application/v1alpha1"
+	certmanagerV1 "github.com/jetstack/cert-manager/pkg/apis/certmanager/v1"
+	certmanagerMetaV1 "github.com/jetstack/cert-manager/pkg/apis/meta/v1"
 	clusterV1alpha1 "github.com/tmax-cloud/hypercloud-multi-operator/apis/cluster/v1alpha1"
+	util "github.com/tmax-cloud/hypercloud-multi-operator/controllers/util"
 
+	coreV1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/types"
 
@@ -139,10 +145,14 @@ func (r *ClusterManagerReconciler) requeueClusterManagersForSubresources(o clien
 	log := r.Log.WithValues("objectMapper", "SubresourcesToClusterManagers", "namespace", o.GetNamespace(), "name", o.GetName())
 
 	//get ClusterManager
+	// argocd cluster secret 처럼 다른 namespace 에 있는 리소스는 label 로 ClusterManager 의 namespace 를 가진다.
 	key := types.NamespacedName{
 		Name:      o.GetLabels()[clusterV1alpha1.LabelKeyClmName],
 		Namespace: o.GetNamespace(),
 	}
+	if namespace := o.GetLabels()[clusterV1alpha1.LabelKeyClmNamespace]; namespace != "" {
+		key.Namespace = namespace
+	}
 	clm := &clusterV1alpha1.ClusterManager{}
 	if err := r.Get(context.TODO(), key, clm); errors.IsNotFound(err) {
 		log.Info("ClusterManager is deleted")
@@ -156,8 +166,11 @@ func (r *ClusterManagerReconciler) requeueClusterManagersForSubresources(o clien
 		return nil
 	}
 
+	isArgo := o.GetLabels()[util.LabelKeyClmSecretType] == util.ClmSecretTypeArgo
 	isGateway := strings.Contains(o.GetName(), "gateway")
-	if isGateway {
+	if isArgo {
+		clm.Status.ArgoReady = false
+	} else if isGateway {
 		clm.Status.GatewayReady = false
 	} else {
 		clm.Status.TraefikReady = false
@@ -169,5 +182,184 @@ func (r *ClusterManagerReconciler) requeueClusterManagersForSubresources(o clien
 		return nil //??
 	}
 
+	// ready 상태가 이미 false 인 경우(ex. certificate 발급 대기)에도 reconcile 되도록 한다.
+	return []ctrl.Request{
+		{NamespacedName: key},
+	}
+}
+
+// requeueClusterManagersForApplication 은 argocd application 이 속한 ClusterManager 를 찾는다.
+// app-of-apps 에는 ClusterManager 의 label 이 있고, 하위 application 에는 cluster label 만 있다.
+func (r *ClusterManagerReconciler) requeueClusterManagersForApplication(o client.Object) []ctrl.Request {
+	log := r.Log.WithValues("objectMapper", "applicationToClusterManager", "namespace", o.GetNamespace(), "name", o.GetName())
+
+	labels := o.GetLabels()
+	if labels[clusterV1alpha1.LabelKeyClmName] != "" && labels[clusterV1alpha1.LabelKeyClmNamespace] != "" {
+		return []ctrl.Request{
+			{
+				NamespacedName: types.NamespacedName{
+					Name:      labels[clusterV1alpha1.LabelKeyClmName],
+					Namespace: labels[clusterV1alpha1.LabelKeyClmNamespace],
+				},
+			},
+		}
+	}
+
+	prefix := labels[util.LabelKeyArgoTargetCluster]
+	if prefix == "" {
+		return nil
+	}
+
+	clmList := &clusterV1alpha1.ClusterManagerList{}
+	if err := r.List(context.TODO(), clmList); err != nil {
+		log.Error(err, "Failed to list ClusterManagers")
+		return nil
+	}
+
+	for _, clm := range clmList.Items {
+		if clm.GetNamespacedPrefix() == prefix {
+			return []ctrl.Request{{NamespacedName: clm.GetNamespacedName()}}
+		}
+	}
+
 	return nil
 }
+
+// isApplicationStatusChanged 는 application 의 sync, health, operation 상태가 바뀌었는지 반환한다.
+func isApplicationStatusChanged(oldObj, newObj client.Object) bool {
+	oldApp, ok := oldObj.(*argocdV1alpha1.Application)
+	if !ok {
+		return false
+	}
+	newApp, ok := newObj.(*argocdV1alpha1.Application)
+	if !ok {
+		return false
+	}
+
+	operationPhase := func(app *argocdV1alpha1.Application) string {
+		if app.Status.OperationState == nil {
+			return ""
+		}
+		return string(app.Status.OperationState.Phase)
+	}
+
+	return oldApp.Status.Sync.Status != newApp.Status.Sync.Status ||
+		oldApp.Status.Sync.Revision != newApp.Status.Sync.Revision ||
+		oldApp.Status.Health.Status != newApp.Status.Health.Status ||
+		operationPhase(oldApp) != operationPhase(newApp) ||
+		len(oldApp.Status.Resources) != len(newApp.Status.Resources)
+}
+
+// requeueClusterManagersForAddonProfile 은 ClusterAddonProfile 이 적용되는 ClusterManager 들의 ArgoReady 를 false 로 바꿔
+// application 을 다시 만들도록 한다.
+func (r *ClusterManagerReconciler) requeueClusterManagersForAddonProfile(o client.Object) []ctrl.Request {
+	log := r.Log.WithValues("objectMapper", "AddonProfileToClusterManagers", "namespace", o.GetNamespace(), "name", o.GetName())
+
+	clmList := &clusterV1alpha1.ClusterManagerList{}
+	opts := []client.ListOption{}
+	if o.GetName() != clusterV1alpha1.DefaultAddonProfileName || o.GetNamespace() != util.GetOperatorNamespace() {
+		opts = append(opts, client.InNamespace(o.GetNamespace()))
+	}
+	if err := r.List(context.TODO(), clmList, opts...); err != nil {
+		log.Error(err, "Failed to list ClusterManagers")
+		return nil
+	}
+
+	requests := []ctrl.Request{}
+	for i := range clmList.Items {
+		clm := &clmList.Items[i]
+		if o.GetName() != clusterV1alpha1.DefaultAddonProfileName && o.GetName() != clm.Name {
+			continue
+		}
+		if !clm.GetDeletionTimestamp().IsZero() || !clm.Status.ArgoReady {
+			continue
+		}
+
+		clm.Status.ArgoReady = false
+		if err := r.Status().Update(context.TODO(), clm); err != nil {
+			log.Error(err, "Failed to update ClusterManager status", "clustermanager", clm.GetNamespacedName())
+			continue
+		}
+		requests = append(requests, ctrl.Request{NamespacedName: clm.GetNamespacedName()})
+	}
+
+	return requests
+}
+
+// isHyperAuthModuleConfigMap 은 HyperAuth 와 연동할 module 목록을 가지는 configmap 인지 확인한다.
+func isHyperAuthModuleConfigMap(o client.Object) bool {
+	return o.GetName() == util.GetHyperAuthModuleConfigMap() && o.GetNamespace() == util.GetOperatorNamespace()
+}
+
+// requeueClusterManagersForHyperAuthModules 는 HyperAuth 리소스를 만든 ClusterManager 들을 다시 reconcile 하여
+// 바뀐 module 목록으로 HyperAuth 리소스를 동기화하도록 한다.
+func (r *ClusterManagerReconciler) requeueClusterManagersForHyperAuthModules(o client.Object) []ctrl.Request {
+	log := r.Log.WithValues("objectMapper", "HyperAuthModulesToClusterManagers", "namespace", o.GetNamespace(), "name", o.GetName())
+
+	clmList := &clusterV1alpha1.ClusterManagerList{}
+	if err := r.List(context.TODO(), clmList); err != nil {
+		log.Error(err, "Failed to list ClusterManagers")
+		return nil
+	}
+
+	requests := []ctrl.Request{}
+	for _, clm := range clmList.Items {
+		if !clm.GetDeletionTimestamp().IsZero() || !clm.Status.AuthClientReady {
+			continue
+		}
+		requests = append(requests, ctrl.Request{NamespacedName: clm.GetNamespacedName()})
+	}
+
+	return requests
+}
+
+// isSubResource 는 operator 가 ClusterManager 를 위해 만든 hub cluster 의 리소스인지 확인한다.
+// kubeconfig secret 은 cluster api 나 cluster registration 이 관리하므로 제외한다.
+func isSubResource(o client.Object) bool {
+	if _, ok := o.GetLabels()[clusterV1alpha1.LabelKeyClmName]; !ok {
+		return false
+	}
+	return o.GetLabels()[util.LabelKeyClmSecretType] != util.ClmSecretTypeKubeconfig
+}
+
+// isSubResourceChanged 는 리소스가 operator 가 아닌 다른 곳에서 수정되었거나,
+// certificate 의 ready 상태가 바뀌었는지 확인한다.
+func isSubResourceChanged(oldObj client.Object, newObj client.Object) bool {
+	if oldCertificate, ok := oldObj.(*certmanagerV1.Certificate); ok {
+		if isCertificateReady(oldCertificate) != isCertificateReady(newObj.(*certmanagerV1.Certificate)) {
+			return true
+		}
+	}
+
+	if !util.IsModifiedByOthers(newObj) {
+		return false
+	}
+
+	if oldObj.GetGeneration() != newObj.GetGeneration() ||
+		!equality.Semantic.DeepEqual(oldObj.GetLabels(), newObj.GetLabels()) ||
+		!equality.Semantic.DeepEqual(oldObj.GetAnnotations(), newObj.GetAnnotations()) {
+		return true
+	}
+
+	// generation 을 사용하지 않는 core 리소스는 내용을 직접 비교한다.
+	switch old := oldObj.(type) {
+	case *coreV1.Service:
+		return !equality.Semantic.DeepEqual(old.Spec, newObj.(*coreV1.Service).Spec)
+	case *coreV1.Endpoints:
+		return !equality.Semantic.DeepEqual(old.Subsets, newObj.(*coreV1.Endpoints).Subsets)
+	case *coreV1.Secret:
+		return !equality.Semantic.DeepEqual(old.Data, newObj.(*coreV1.Secret).Data)
+	case *coreV1.ConfigMap:
+		return !equality.Semantic.DeepEqual(old.Data, newObj.(*coreV1.ConfigMap).Data)
+	}
+	return false
+}
+
+func isCertificateReady(certificate *certmanagerV1.Certificate) bool {
+	for _, condition := range certificate.Status.Conditions {
+		if condition.Type == certmanagerV1.CertificateConditionReady {
+			return condition.Status == certmanagerMetaV1.ConditionTrue
+		}
+	}
+	return false
+}
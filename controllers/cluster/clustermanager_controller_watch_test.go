@@ -0,0 +1,94 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	util "github.com/tmax-cloud/hypercloud-multi-operator/controllers/util"
+
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestIsSubResourceChanged(t *testing.T) {
+	managedBy := func(manager string) []metav1.ManagedFieldsEntry {
+		now := metav1.NewTime(time.Now())
+		return []metav1.ManagedFieldsEntry{{Manager: manager, Time: &now}}
+	}
+	newConfigMap := func(manager, ca string) *coreV1.ConfigMap {
+		return &coreV1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "cluster-gateway-ca", ManagedFields: managedBy(manager)},
+			Data:       map[string]string{util.SecretKeyCA: ca},
+		}
+	}
+	newPolicy := func(manager string, generation int64) *unstructured.Unstructured {
+		policy := &unstructured.Unstructured{}
+		policy.SetGroupVersionKind(backendTLSPolicyGVK)
+		policy.SetName("cluster-gateway-tls")
+		policy.SetGeneration(generation)
+		policy.SetManagedFields(managedBy(manager))
+		return policy
+	}
+
+	tests := []struct {
+		name   string
+		oldObj client.Object
+		newObj client.Object
+		want   bool
+	}{
+		{
+			name:   "ca configmap modified by others",
+			oldObj: newConfigMap(util.FieldOwner, "ca"),
+			newObj: newConfigMap("kubectl", "other"),
+			want:   true,
+		},
+		{
+			name:   "ca configmap applied by operator",
+			oldObj: newConfigMap(util.FieldOwner, "ca"),
+			newObj: newConfigMap(util.FieldOwner, "other"),
+			want:   false,
+		},
+		{
+			name:   "ca configmap touched by others without change",
+			oldObj: newConfigMap(util.FieldOwner, "ca"),
+			newObj: newConfigMap("kubectl", "ca"),
+			want:   false,
+		},
+		{
+			name:   "backend tls policy modified by others",
+			oldObj: newPolicy(util.FieldOwner, 1),
+			newObj: newPolicy("kubectl", 2),
+			want:   true,
+		},
+		{
+			name:   "backend tls policy applied by operator",
+			oldObj: newPolicy(util.FieldOwner, 1),
+			newObj: newPolicy(util.FieldOwner, 2),
+			want:   false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isSubResourceChanged(tt.oldObj, tt.newObj); got != tt.want {
+				t.Errorf("isSubResourceChanged() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -15,98 +15,17 @@ limitations under the License.
 package hyperAuth
 
 import (
-	"bytes"
-	"encoding/json"
-	"fmt"
-	"io/ioutil"
+	"context"
 	"net/http"
-	"net/http/httputil"
-	"net/url"
-	"os"
-	"strings"
-
-	"github.com/tmax-cloud/hypercloud-multi-operator/controllers/util"
-	coreV1 "k8s.io/api/core/v1"
 )
 
-func SetServiceDomainURI(serviceName string, urlParameter map[string]string) string {
-	for key, value := range urlParameter {
-		serviceName = strings.Replace(serviceName, "@@"+key+"@@", value, 1)
-	}
-	return "https://" + os.Getenv(util.AUTH_SUBDOMAIN) + "." + os.Getenv(util.HC_DOMAIN) + serviceName
-}
-
-func GetTokenAsAdmin(secret *coreV1.Secret) (string, error) {
-	// Make Body for Content-Type (application/x-www-form-urlencoded)
-	id, password := string(secret.Data["HYPERAUTH_ADMIN"]), string(secret.Data["HYPERAUTH_PASSWORD"])
-	data := url.Values{}
-	data.Set("grant_type", "password")
-	data.Set("username", id)
-	data.Set("password", password)
-	data.Set("client_id", "admin-cli")
-
-	// Make Request Object
-	url := SetServiceDomainURI(KEYCLOAK_ADMIN_SERVICE_GET_TOKEN, nil)
-	payload := strings.NewReader(data.Encode())
-	req, err := http.NewRequest(http.MethodPost, url, payload)
-	if err != nil {
-		return "", err
-	}
-	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
-
-	// Request with Client Object
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", err
-	}
-	defer resp.Body.Close()
-
-	// Result
-	bytes, _ := ioutil.ReadAll(resp.Body)
-	str := string(bytes)
-
-	var resultJson map[string]interface{}
-	if err := json.Unmarshal([]byte(str), &resultJson); err != nil {
-		return "", err
-	}
-	accessToken := resultJson["access_token"].(string)
-
-	return strings.Join([]string{"Bearer", accessToken}, " "), nil
-}
-
-func GetIdByClientId(clientId string, secret *coreV1.Secret) (string, error) {
-	token, err := GetTokenAsAdmin(secret)
-	if err != nil {
-		return "", err
-	}
-
-	url := SetServiceDomainURI(KEYCLOAK_ADMIN_SERVICE_GET_CLIENTS, nil)
-	req, err := http.NewRequest(http.MethodGet, url, nil)
-	if err != nil {
-		return "", err
-	}
-	req.Header.Add("Authorization", token)
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", err
-	}
-	defer resp.Body.Close()
-
-	reqDump, _ := httputil.DumpRequest(req, true)
-	if !IsOK(resp.StatusCode) {
-		return "", fmt.Errorf("failed to get client: " + string(reqDump) + "\n" + resp.Status)
-	}
-
-	respJson := &[]ClientConfig{}
-	err = json.NewDecoder(resp.Body).Decode(respJson)
+func (c *HyperAuthClient) GetIdByClientId(ctx context.Context, clientId string) (string, error) {
+	clients, err := c.ListClients(ctx)
 	if err != nil {
 		return "", err
 	}
 
-	for _, data := range *respJson {
+	for _, data := range clients {
 		if data.ClientId == clientId {
 			return data.Id, nil
 		}
@@ -115,91 +34,26 @@ func GetIdByClientId(clientId string, secret *coreV1.Secret) (string, error) {
 	return "", HyperAuthError{NotFound: true, Type: RESOURCE_TYPE_CLIENT, Name: clientId}
 }
 
-func CreateClient(config ClientConfig, secret *coreV1.Secret) error {
-	token, err := GetTokenAsAdmin(secret)
-	if err != nil {
-		return err
-	}
-
-	jsonData, err := json.Marshal(config)
-	if err != nil {
-		return err
-	}
-	payload := bytes.NewBuffer(jsonData)
-
-	url := SetServiceDomainURI(KEYCLOAK_ADMIN_SERVICE_CREATE_CLIENT, nil)
-	req, err := http.NewRequest(http.MethodPost, url, payload)
-	if err != nil {
-		return err
-	}
-	req.Header.Add("Content-Type", "application/json")
-	req.Header.Add("Authorization", token)
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	if !IsOK(resp.StatusCode) {
-		reqDump, _ := httputil.DumpRequest(req, true)
-		return fmt.Errorf("failed to create client: " + string(reqDump) + "\n" + resp.Status)
-	}
-
-	return nil
+// CreateClient 는 client 를 만들고 operator 가 만든 client 임을 attribute 로 표시한다.
+func (c *HyperAuthClient) CreateClient(ctx context.Context, config ClientConfig) error {
+	config.Attributes = managedClientAttributes(config.Attributes)
+	return c.do(ctx, "CreateClient", http.MethodPost, KEYCLOAK_ADMIN_SERVICE_CREATE_CLIENT, nil, config, nil)
 }
 
-func CreateClientLevelProtocolMapper(config ClientLevelProtocolMapperConfig, secret *coreV1.Secret) error {
-	token, err := GetTokenAsAdmin(secret)
+func (c *HyperAuthClient) CreateClientLevelProtocolMapper(ctx context.Context, config ClientLevelProtocolMapperConfig) error {
+	id, err := c.GetIdByClientId(ctx, config.ClientId)
 	if err != nil {
 		return err
 	}
 
-	id, err := GetIdByClientId(config.ClientId, secret)
-	if err != nil {
-		return err
-	}
-
-	jsonData, err := json.Marshal(config.ProtocolMapper)
-	if err != nil {
-		return err
-	}
-	payload := bytes.NewBuffer(jsonData)
-
 	params := map[string]string{
 		"id": id,
 	}
-	url := SetServiceDomainURI(KEYCLOAK_ADMIN_SERVICE_CREATE_CLIENT_PROTOCOL_MAPPERS, params)
-	req, err := http.NewRequest(http.MethodPost, url, payload)
-	if err != nil {
-		return err
-	}
-	req.Header.Add("Content-Type", "application/json")
-	req.Header.Add("Authorization", token)
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	if !IsOK(resp.StatusCode) {
-		reqDump, _ := httputil.DumpRequest(req, true)
-		return fmt.Errorf("failed to create protocol mapper: " + string(reqDump) + "\n" + resp.Status)
-	}
-
-	return nil
+	return c.do(ctx, "CreateClientLevelProtocolMapper", http.MethodPost, KEYCLOAK_ADMIN_SERVICE_CREATE_CLIENT_PROTOCOL_MAPPERS, params, config.ProtocolMapper, nil)
 }
 
-func CreateClientLevelRole(config ClientLevelRoleConfig, secret *coreV1.Secret) error {
-	token, err := GetTokenAsAdmin(secret)
-	if err != nil {
-		return err
-	}
-
-	id, err := GetIdByClientId(config.ClientId, secret)
+func (c *HyperAuthClient) CreateClientLevelRole(ctx context.Context, config ClientLevelRoleConfig) error {
+	id, err := c.GetIdByClientId(ctx, config.ClientId)
 	if err != nil {
 		return err
 	}
@@ -207,87 +61,30 @@ func CreateClientLevelRole(config ClientLevelRoleConfig, secret *coreV1.Secret)
 	data := RoleConfig{
 		Name: config.Role.Name,
 	}
-	jsonData, err := json.Marshal(data)
-	if err != nil {
-		return err
-	}
-	payload := bytes.NewBuffer(jsonData)
-
 	params := map[string]string{
 		"id": id,
 	}
-	url := SetServiceDomainURI(KEYCLOAK_ADMIN_SERVICE_CREATE_CLIENT_ROLES, params)
-	req, err := http.NewRequest(http.MethodPost, url, payload)
-	if err != nil {
-		return err
-	}
-	req.Header.Add("Content-Type", "application/json")
-	req.Header.Add("Authorization", token)
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	if !IsOK(resp.StatusCode) {
-		reqDump, _ := httputil.DumpRequest(req, true)
-		return fmt.Errorf("failed to create client-level role: " + string(reqDump) + "\n" + resp.Status)
-	}
-
-	return nil
+	return c.do(ctx, "CreateClientLevelRole", http.MethodPost, KEYCLOAK_ADMIN_SERVICE_CREATE_CLIENT_ROLES, params, data, nil)
 }
 
-func GetUserIdByEmail(userEmail string, secret *coreV1.Secret) (string, error) {
-	token, err := GetTokenAsAdmin(secret)
-	if err != nil {
-		return "", err
-	}
+func (c *HyperAuthClient) GetUserIdByEmail(ctx context.Context, userEmail string) (string, error) {
 	params := map[string]string{
 		"userEmail": userEmail,
 	}
-
-	url := SetServiceDomainURI(KEYCLOAK_ADMIN_SERVICE_GET_USERS_BY_EMAIL, params)
-	req, err := http.NewRequest(http.MethodGet, url, nil)
-	if err != nil {
-		return "", err
-	}
-	req.Header.Add("Authorization", token)
-	req.Header.Add("Content-Type", "application/json")
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", err
-	}
-	defer resp.Body.Close()
-
-	reqDump, _ := httputil.DumpRequest(req, true)
-	if !IsOK(resp.StatusCode) {
-		return "", fmt.Errorf("failed to get user by email: " + string(reqDump) + "\n" + resp.Status)
-	}
-
-	respJson := &[]UserConfig{}
-	err = json.NewDecoder(resp.Body).Decode(respJson)
-	if err != nil {
+	respJson := []UserConfig{}
+	if err := c.do(ctx, "GetUserIdByEmail", http.MethodGet, KEYCLOAK_ADMIN_SERVICE_GET_USERS_BY_EMAIL, params, nil, &respJson); err != nil {
 		return "", err
 	}
 
-	if respJson == nil || len(*respJson) == 0 {
+	if len(respJson) == 0 {
 		return "", HyperAuthError{NotFound: true, Type: RESOURCE_TYPE_USER_EMAIL, Name: userEmail}
 	}
 
-	return (*respJson)[0].Id, nil
+	return respJson[0].Id, nil
 }
 
-func GetClientRoleIdByRoleName(clientId string, roleName string, secret *coreV1.Secret) (string, error) {
-	token, err := GetTokenAsAdmin(secret)
-	if err != nil {
-		return "", err
-	}
-
-	id, err := GetIdByClientId(clientId, secret)
+func (c *HyperAuthClient) GetClientRoleIdByRoleName(ctx context.Context, clientId string, roleName string) (string, error) {
+	id, err := c.GetIdByClientId(ctx, clientId)
 	if err != nil {
 		return "", err
 	}
@@ -296,54 +93,29 @@ func GetClientRoleIdByRoleName(clientId string, roleName string, secret *coreV1.
 		"id":       id,
 		"roleName": roleName,
 	}
-	url := SetServiceDomainURI(KEYCLOAK_ADMIN_SERVICE_GET_CLIENT_ROLE_BY_NAME, params)
-	req, err := http.NewRequest(http.MethodGet, url, nil)
-	if err != nil {
-		return "", err
-	}
-	req.Header.Add("Authorization", token)
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", err
-	}
-	defer resp.Body.Close()
-
-	reqDump, _ := httputil.DumpRequest(req, true)
-	if !IsOK(resp.StatusCode) {
-		return "", fmt.Errorf("failed to get role: " + string(reqDump) + "\n" + resp.Status)
-	}
-
-	respJson := &RoleConfig{}
-	err = json.NewDecoder(resp.Body).Decode(respJson)
-	if err != nil {
-		return "", err
-	}
-	if respJson == nil {
+	respJson := RoleConfig{}
+	err = c.do(ctx, "GetClientRoleIdByRoleName", http.MethodGet, KEYCLOAK_ADMIN_SERVICE_GET_CLIENT_ROLE_BY_NAME, params, nil, &respJson)
+	if IsNotFound(err) || (err == nil && respJson.Id == "") {
 		return "", HyperAuthError{NotFound: true, Type: RESOURCE_TYPE_CLIENT_ROLE, Name: roleName}
+	} else if err != nil {
+		return "", err
 	}
 
 	return respJson.Id, nil
 }
 
-func AddClientLevelRolesToUserRoleMapping(config ClientLevelRoleConfig, userEmail string, secret *coreV1.Secret) error {
-	token, err := GetTokenAsAdmin(secret)
+func (c *HyperAuthClient) AddClientLevelRolesToUserRoleMapping(ctx context.Context, config ClientLevelRoleConfig, userEmail string) error {
+	id, err := c.GetIdByClientId(ctx, config.ClientId)
 	if err != nil {
 		return err
 	}
 
-	id, err := GetIdByClientId(config.ClientId, secret)
+	userId, err := c.GetUserIdByEmail(ctx, userEmail)
 	if err != nil {
 		return err
 	}
 
-	userId, err := GetUserIdByEmail(userEmail, secret)
-	if err != nil {
-		return err
-	}
-
-	roleId, err := GetClientRoleIdByRoleName(config.ClientId, config.Role.Name, secret)
+	roleId, err := c.GetClientRoleIdByRoleName(ctx, config.ClientId, config.Role.Name)
 	if err != nil {
 		return err
 	}
@@ -353,91 +125,36 @@ func AddClientLevelRolesToUserRoleMapping(config ClientLevelRoleConfig, userEmai
 			Name: config.Role.Name,
 		},
 	}
-	jsonData, err := json.Marshal(data)
-	if err != nil {
-		return err
-	}
-	payload := bytes.NewBuffer(jsonData)
 
 	params := map[string]string{
 		"userId": userId,
 		"id":     id,
 	}
-	url := SetServiceDomainURI(KEYCLOAK_ADMIN_SERVICE_ADD_CLIENT_ROLE_TO_USER, params)
-	req, err := http.NewRequest(http.MethodPost, url, payload)
-	if err != nil {
-		return err
-	}
-	req.Header.Add("Content-Type", "application/json")
-	req.Header.Add("Authorization", token)
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	if !IsOK(resp.StatusCode) {
-		reqDump, _ := httputil.DumpRequest(req, true)
-		return fmt.Errorf("failed to add role to user: " + string(reqDump) + "\n" + resp.Status)
-	}
-
-	return nil
+	return c.do(ctx, "AddClientLevelRolesToUserRoleMapping", http.MethodPost, KEYCLOAK_ADMIN_SERVICE_ADD_CLIENT_ROLE_TO_USER, params, data, nil)
 }
 
-func GetRealmRoleIdByRoleName(roleName string, secret *coreV1.Secret) (string, error) {
-	token, err := GetTokenAsAdmin(secret)
-	if err != nil {
-		return "", err
-	}
-
+func (c *HyperAuthClient) GetRealmRoleIdByRoleName(ctx context.Context, roleName string) (string, error) {
 	params := map[string]string{
 		"roleName": roleName,
 	}
-	url := SetServiceDomainURI(KEYCLOAK_ADMIN_SERVICE_GET_REALM_ROLE_BY_NAME, params)
-	req, err := http.NewRequest(http.MethodGet, url, nil)
-	if err != nil {
-		return "", err
-	}
-	req.Header.Add("Authorization", token)
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", err
-	}
-	defer resp.Body.Close()
-
-	reqDump, _ := httputil.DumpRequest(req, true)
-	if !IsOK(resp.StatusCode) {
-		return "", fmt.Errorf("failed to get role: " + string(reqDump) + "\n" + resp.Status)
-	}
-
-	respJson := &RoleConfig{}
-	err = json.NewDecoder(resp.Body).Decode(respJson)
-	if err != nil {
-		return "", err
-	}
-	if respJson == nil {
+	respJson := RoleConfig{}
+	err := c.do(ctx, "GetRealmRoleIdByRoleName", http.MethodGet, KEYCLOAK_ADMIN_SERVICE_GET_REALM_ROLE_BY_NAME, params, nil, &respJson)
+	if IsNotFound(err) || (err == nil && respJson.Id == "") {
 		return "", HyperAuthError{NotFound: true, Type: RESOURCE_TYPE_REALM_ROLE, Name: roleName}
+	} else if err != nil {
+		return "", err
 	}
 
 	return respJson.Id, nil
 }
 
-func AddRealmLevelRolesToUserRoleMapping(roleName string, userEmail string, secret *coreV1.Secret) error {
-	token, err := GetTokenAsAdmin(secret)
-	if err != nil {
-		return err
-	}
-
-	userId, err := GetUserIdByEmail(userEmail, secret)
+func (c *HyperAuthClient) AddRealmLevelRolesToUserRoleMapping(ctx context.Context, roleName string, userEmail string) error {
+	userId, err := c.GetUserIdByEmail(ctx, userEmail)
 	if err != nil {
 		return err
 	}
 
-	roleId, err := GetRealmRoleIdByRoleName(roleName, secret)
+	roleId, err := c.GetRealmRoleIdByRoleName(ctx, roleName)
 	if err != nil {
 		return err
 	}
@@ -447,312 +164,301 @@ func AddRealmLevelRolesToUserRoleMapping(roleName string, userEmail string, secr
 			Name: roleName,
 		},
 	}
-	jsonData, err := json.Marshal(data)
-	if err != nil {
-		return err
-	}
-	payload := bytes.NewBuffer(jsonData)
 
 	params := map[string]string{
 		"userId": userId,
 	}
-	url := SetServiceDomainURI(KEYCLOAK_ADMIN_SERVICE_ADD_REALM_ROLE_TO_USER, params)
-	req, err := http.NewRequest(http.MethodPost, url, payload)
-	if err != nil {
-		return err
-	}
-	req.Header.Add("Content-Type", "application/json")
-	req.Header.Add("Authorization", token)
+	return c.do(ctx, "AddRealmLevelRolesToUserRoleMapping", http.MethodPost, KEYCLOAK_ADMIN_SERVICE_ADD_REALM_ROLE_TO_USER, params, data, nil)
+}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return err
+func (c *HyperAuthClient) GetClientScopesIdByName(ctx context.Context, name string) (string, error) {
+	respJson := []ClientScopeConfig{}
+	if err := c.do(ctx, "GetClientScopesIdByName", http.MethodGet, KEYCLOAK_ADMIN_SERVICE_GET_CLIENT_SCOPES, nil, nil, &respJson); err != nil {
+		return "", err
 	}
-	defer resp.Body.Close()
 
-	if !IsOK(resp.StatusCode) {
-		reqDump, _ := httputil.DumpRequest(req, true)
-		return fmt.Errorf("failed to add role to user: " + string(reqDump) + "\n" + resp.Status)
+	for _, data := range respJson {
+		if data.Name == name {
+			return data.Id, nil
+		}
 	}
 
-	return nil
+	return "", HyperAuthError{NotFound: true, Type: RESOURCE_TYPE_CLIENT_SCOPE, Name: name}
 }
 
-func GetClientScopesIdByName(name string, secret *coreV1.Secret) (string, error) {
-	token, err := GetTokenAsAdmin(secret)
+func (c *HyperAuthClient) AddClientScopeToClient(ctx context.Context, config ClientScopeMappingConfig) error {
+	id, err := c.GetIdByClientId(ctx, config.ClientId)
 	if err != nil {
-		return "", err
+		return err
 	}
 
-	url := SetServiceDomainURI(KEYCLOAK_ADMIN_SERVICE_GET_CLIENT_SCOPES, nil)
-	req, err := http.NewRequest(http.MethodGet, url, nil)
+	clientScopeId, err := c.GetClientScopesIdByName(ctx, config.ClientScope.Name)
 	if err != nil {
-		return "", err
+		return err
 	}
-	req.Header.Add("Authorization", token)
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", err
+	params := map[string]string{
+		"id":            id,
+		"clientScopeId": clientScopeId,
 	}
-	defer resp.Body.Close()
+	return c.do(ctx, "AddClientScopeToClient", http.MethodPut, KEYCLOAK_ADMIN_SERVICE_ADD_DEFAULT_CLIENT_SCOPE_TO_CLIENT, params, nil, nil)
+}
 
-	if !IsOK(resp.StatusCode) {
-		reqDump, _ := httputil.DumpRequest(req, true)
-		return "", fmt.Errorf("failed to get client scope id: " + string(reqDump) + "\n" + resp.Status)
-	}
+func (c *HyperAuthClient) CreateGroup(ctx context.Context, config GroupConfig) error {
+	return c.do(ctx, "CreateGroup", http.MethodPost, KEYCLOAK_ADMIN_SERVICE_CREATE_GROUP, nil, config, nil)
+}
 
-	respJson := &[]ClientScopeConfig{}
-	err = json.NewDecoder(resp.Body).Decode(respJson)
+func (c *HyperAuthClient) GetGroupIdByName(ctx context.Context, name string) (string, error) {
+	groups, err := c.ListGroups(ctx)
 	if err != nil {
 		return "", err
 	}
 
-	for _, data := range *respJson {
+	for _, data := range groups {
 		if data.Name == name {
 			return data.Id, nil
 		}
 	}
 
-	return "", HyperAuthError{NotFound: true, Type: RESOURCE_TYPE_CLIENT_SCOPE, Name: name}
+	return "", HyperAuthError{NotFound: true, Type: RESOURCE_TYPE_GROUP, Name: name}
 }
 
-func AddClientScopeToClient(config ClientScopeMappingConfig, secret *coreV1.Secret) error {
-	token, err := GetTokenAsAdmin(secret)
-	if err != nil {
-		return err
-	}
-
-	id, err := GetIdByClientId(config.ClientId, secret)
+func (c *HyperAuthClient) AddGroupToUser(ctx context.Context, userEmail string, config GroupConfig) error {
+	userId, err := c.GetUserIdByEmail(ctx, userEmail)
 	if err != nil {
 		return err
 	}
 
-	clientScopeId, err := GetClientScopesIdByName(config.ClientScope.Name, secret)
+	groupId, err := c.GetGroupIdByName(ctx, config.Name)
 	if err != nil {
 		return err
 	}
 
 	params := map[string]string{
-		"id":            id,
-		"clientScopeId": clientScopeId,
-	}
-	url := SetServiceDomainURI(KEYCLOAK_ADMIN_SERVICE_ADD_DEFAULT_CLIENT_SCOPE_TO_CLIENT, params)
-	req, err := http.NewRequest(http.MethodPut, url, nil)
-	if err != nil {
-		return err
+		"userId":  userId,
+		"groupId": groupId,
 	}
+	return c.do(ctx, "AddGroupToUser", http.MethodPut, KEYCLOAK_ADMIN_SERVICE_ADD_GROUP_TO_USER, params, nil, nil)
+}
 
-	req.Header.Add("Content-Type", "application/json")
-	req.Header.Add("Authorization", token)
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
+func (c *HyperAuthClient) DeleteClient(ctx context.Context, config ClientConfig) error {
+	id, err := c.GetIdByClientId(ctx, config.ClientId)
+	if IsNotFound(err) {
+		return nil
+	} else if err != nil {
 		return err
 	}
-	defer resp.Body.Close()
 
-	if !IsOK(resp.StatusCode) {
-		reqDump, _ := httputil.DumpRequest(req, true)
-		return fmt.Errorf("failed to add client scope to client: " + string(reqDump) + "\n" + resp.Status)
+	params := map[string]string{
+		"id": id,
 	}
-
-	return nil
+	err = c.do(ctx, "DeleteClient", http.MethodDelete, KEYCLOAK_ADMIN_SERVICE_DELETE_CLIENT, params, nil, nil)
+	if IsNotFound(err) {
+		return nil
+	}
+	return err
 }
 
-func CreateGroup(config GroupConfig, secret *coreV1.Secret) error {
-	token, err := GetTokenAsAdmin(secret)
-	if err != nil {
+func (c *HyperAuthClient) DeleteGroup(ctx context.Context, config GroupConfig) error {
+	groupId, err := c.GetGroupIdByName(ctx, config.Name)
+	if IsNotFound(err) {
+		return nil
+	} else if err != nil {
 		return err
 	}
 
-	jsonData, err := json.Marshal(config)
-	if err != nil {
-		return err
+	params := map[string]string{
+		"groupId": groupId,
 	}
-	payload := bytes.NewBuffer(jsonData)
-
-	url := SetServiceDomainURI(KEYCLOAK_ADMIN_SERVICE_CREATE_GROUP, nil)
-	req, err := http.NewRequest(http.MethodPost, url, payload)
-	if err != nil {
-		return err
+	err = c.do(ctx, "DeleteGroup", http.MethodDelete, KEYCLOAK_ADMIN_SERVICE_DELETE_GROUP, params, nil, nil)
+	if IsNotFound(err) {
+		return nil
 	}
+	return err
+}
 
-	req.Header.Add("Content-Type", "application/json")
-	req.Header.Add("Authorization", token)
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return err
+func (c *HyperAuthClient) ListClients(ctx context.Context) ([]ClientConfig, error) {
+	respJson := []ClientConfig{}
+	if err := c.do(ctx, "ListClients", http.MethodGet, KEYCLOAK_ADMIN_SERVICE_GET_CLIENTS, nil, nil, &respJson); err != nil {
+		return nil, err
 	}
-	defer resp.Body.Close()
 
-	if !IsOK(resp.StatusCode) {
-		reqDump, _ := httputil.DumpRequest(req, true)
-		return fmt.Errorf("failed to create group: " + string(reqDump) + "\n" + resp.Status)
+	return respJson, nil
+}
+
+func (c *HyperAuthClient) ListGroups(ctx context.Context) ([]GroupConfig, error) {
+	respJson := []GroupConfig{}
+	if err := c.do(ctx, "ListGroups", http.MethodGet, KEYCLOAK_ADMIN_SERVICE_GET_GROUP, nil, nil, &respJson); err != nil {
+		return nil, err
 	}
 
-	return nil
+	return respJson, nil
 }
 
-func GetGroupIdByName(name string, secret *coreV1.Secret) (string, error) {
-	token, err := GetTokenAsAdmin(secret)
-	if err != nil {
-		return "", err
+// UpdateClient 는 client 의 설정을 desired 로 바꾼다.
+// keycloak 은 빈 값의 필드는 갱신하지 않으므로 false 도 명시적으로 보낸다.
+// 이전 버전에서 만든 client 도 operator 가 관리하는 client 로 표시한다.
+func (c *HyperAuthClient) UpdateClient(ctx context.Context, id string, config ClientConfig) error {
+	data := map[string]interface{}{
+		"clientId":                  config.ClientId,
+		"directAccessGrantsEnabled": config.DirectAccessGrantsEnabled,
+		"implicitFlowEnabled":       config.ImplicitFlowEnabled,
+		"redirectUris":              config.RedirectUris,
+		"attributes":                managedClientAttributes(config.Attributes),
 	}
-
-	url := SetServiceDomainURI(KEYCLOAK_ADMIN_SERVICE_GET_GROUP, nil)
-	req, err := http.NewRequest(http.MethodGet, url, nil)
-	if err != nil {
-		return "", err
+	if config.Secret != "" {
+		data["secret"] = config.Secret
 	}
-	req.Header.Add("Authorization", token)
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", err
+	params := map[string]string{
+		"id": id,
 	}
-	defer resp.Body.Close()
+	return c.do(ctx, "UpdateClient", http.MethodPut, KEYCLOAK_ADMIN_SERVICE_UPDATE_CLIENT, params, data, nil)
+}
 
-	reqDump, _ := httputil.DumpRequest(req, true)
-	if !IsOK(resp.StatusCode) {
-		return "", fmt.Errorf("failed to get group: " + string(reqDump) + "\n" + resp.Status)
+// GetClientSecret 은 client 의 secret 을 조회한다.
+// client 목록의 조회 결과에는 secret 이 가려져 있을 수 있으므로 별도의 api 를 사용한다.
+func (c *HyperAuthClient) GetClientSecret(ctx context.Context, id string) (string, error) {
+	params := map[string]string{
+		"id": id,
 	}
-
-	respJson := &[]GroupConfig{}
-	err = json.NewDecoder(resp.Body).Decode(respJson)
-	if err != nil {
+	respJson := CredentialRepresentation{}
+	if err := c.do(ctx, "GetClientSecret", http.MethodGet, KEYCLOAK_ADMIN_SERVICE_GET_CLIENT_SECRET, params, nil, &respJson); err != nil {
 		return "", err
 	}
 
-	for _, data := range *respJson {
-		if data.Name == name {
-			return data.Id, nil
-		}
-	}
-
-	return "", HyperAuthError{NotFound: true, Type: RESOURCE_TYPE_GROUP, Name: name}
+	return respJson.Value, nil
 }
 
-func AddGroupToUser(userEmail string, config GroupConfig, secret *coreV1.Secret) error {
-	token, err := GetTokenAsAdmin(secret)
+// GetExistingClientSecrets 는 이미 만들어진 client 들의 secret 을 clientId 별로 조회한다.
+// 아직 없거나 secret 이 없는 client 는 결과에 포함하지 않는다.
+func (c *HyperAuthClient) GetExistingClientSecrets(ctx context.Context, clientIds []string) (map[string]string, error) {
+	clients, err := c.ListClients(ctx)
 	if err != nil {
-		return err
+		return nil, err
 	}
-
-	userId, err := GetUserIdByEmail(userEmail, secret)
-	if err != nil {
-		return err
+	ids := map[string]string{}
+	for _, client := range clients {
+		ids[client.ClientId] = client.Id
 	}
 
-	groupId, err := GetGroupIdByName(config.Name, secret)
-	if err != nil {
-		return err
+	secrets := map[string]string{}
+	for _, clientId := range clientIds {
+		id, ok := ids[clientId]
+		if !ok {
+			continue
+		}
+		secret, err := c.GetClientSecret(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if secret != "" {
+			secrets[clientId] = secret
+		}
 	}
 
+	return secrets, nil
+}
+
+func (c *HyperAuthClient) ListClientProtocolMappers(ctx context.Context, id string) ([]ProtocolMapperRepresentation, error) {
 	params := map[string]string{
-		"userId":  userId,
-		"groupId": groupId,
+		"id": id,
 	}
-	url := SetServiceDomainURI(KEYCLOAK_ADMIN_SERVICE_ADD_GROUP_TO_USER, params)
-	req, err := http.NewRequest(http.MethodPut, url, nil)
-	if err != nil {
-		return err
+	respJson := []ProtocolMapperRepresentation{}
+	if err := c.do(ctx, "ListClientProtocolMappers", http.MethodGet, KEYCLOAK_ADMIN_SERVICE_GET_CLIENT_PROTOCOL_MAPPERS, params, nil, &respJson); err != nil {
+		return nil, err
 	}
-	req.Header.Add("Authorization", token)
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
+	return respJson, nil
+}
 
-	if !IsOK(resp.StatusCode) {
-		reqDump, _ := httputil.DumpRequest(req, true)
-		return fmt.Errorf("failed to add group to user: " + string(reqDump) + "\n" + resp.Status)
+func (c *HyperAuthClient) UpdateClientProtocolMapper(ctx context.Context, id string, mapper ProtocolMapperRepresentation) error {
+	params := map[string]string{
+		"id":       id,
+		"mapperId": mapper.Id,
 	}
-
-	return nil
+	return c.do(ctx, "UpdateClientProtocolMapper", http.MethodPut, KEYCLOAK_ADMIN_SERVICE_UPDATE_CLIENT_PROTOCOL_MAPPER, params, mapper, nil)
 }
 
-func DeleteClient(config ClientConfig, secret *coreV1.Secret) error {
-	token, err := GetTokenAsAdmin(secret)
-	if err != nil {
-		return err
+func (c *HyperAuthClient) DeleteClientProtocolMapper(ctx context.Context, id string, mapperId string) error {
+	params := map[string]string{
+		"id":       id,
+		"mapperId": mapperId,
 	}
-
-	id, err := GetIdByClientId(config.ClientId, secret)
+	err := c.do(ctx, "DeleteClientProtocolMapper", http.MethodDelete, KEYCLOAK_ADMIN_SERVICE_DELETE_CLIENT_PROTOCOL_MAPPER, params, nil, nil)
 	if IsNotFound(err) {
 		return nil
-	} else if err != nil {
-		return err
 	}
+	return err
+}
 
+func (c *HyperAuthClient) ListClientRoles(ctx context.Context, id string) ([]RoleConfig, error) {
 	params := map[string]string{
 		"id": id,
 	}
-	url := SetServiceDomainURI(KEYCLOAK_ADMIN_SERVICE_DELETE_CLIENT, params)
-	req, err := http.NewRequest(http.MethodDelete, url, nil)
-	if err != nil {
-		return err
+	respJson := []RoleConfig{}
+	if err := c.do(ctx, "ListClientRoles", http.MethodGet, KEYCLOAK_ADMIN_SERVICE_GET_CLIENT_ROLES, params, nil, &respJson); err != nil {
+		return nil, err
 	}
-	req.Header.Add("Authorization", token)
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return err
+	return respJson, nil
+}
+
+func (c *HyperAuthClient) DeleteClientRole(ctx context.Context, id string, roleName string) error {
+	params := map[string]string{
+		"id":       id,
+		"roleName": roleName,
+	}
+	err := c.do(ctx, "DeleteClientRole", http.MethodDelete, KEYCLOAK_ADMIN_SERVICE_DELETE_CLIENT_ROLE, params, nil, nil)
+	if IsNotFound(err) {
+		return nil
 	}
-	defer resp.Body.Close()
+	return err
+}
 
-	if !IsOK(resp.StatusCode) {
-		reqDump, _ := httputil.DumpRequest(req, true)
-		return fmt.Errorf("failed to delete client: " + string(reqDump) + "\n" + resp.Status)
+// ListUserClientRoles 는 user 에게 mapping 된 client 의 role 목록을 반환한다.
+func (c *HyperAuthClient) ListUserClientRoles(ctx context.Context, userId string, id string) ([]RoleConfig, error) {
+	params := map[string]string{
+		"userId": userId,
+		"id":     id,
+	}
+	respJson := []RoleConfig{}
+	if err := c.do(ctx, "ListUserClientRoles", http.MethodGet, KEYCLOAK_ADMIN_SERVICE_GET_USER_CLIENT_ROLES, params, nil, &respJson); err != nil {
+		return nil, err
 	}
 
-	return nil
+	return respJson, nil
 }
 
-func DeleteGroup(config GroupConfig, secret *coreV1.Secret) error {
-	token, err := GetTokenAsAdmin(secret)
-	if err != nil {
-		return err
+func (c *HyperAuthClient) ListClientScopes(ctx context.Context) ([]ClientScopeConfig, error) {
+	respJson := []ClientScopeConfig{}
+	if err := c.do(ctx, "ListClientScopes", http.MethodGet, KEYCLOAK_ADMIN_SERVICE_GET_CLIENT_SCOPES, nil, nil, &respJson); err != nil {
+		return nil, err
 	}
 
-	groupId, err := GetGroupIdByName(config.Name, secret)
-	if IsNotFound(err) {
-		return nil
-	} else if err != nil {
-		return err
-	}
+	return respJson, nil
+}
 
+// ListDefaultClientScopes 는 client 에 default 로 mapping 된 client scope 목록을 반환한다.
+func (c *HyperAuthClient) ListDefaultClientScopes(ctx context.Context, id string) ([]ClientScopeConfig, error) {
 	params := map[string]string{
-		"groupId": groupId,
+		"id": id,
 	}
-	url := SetServiceDomainURI(KEYCLOAK_ADMIN_SERVICE_DELETE_GROUP, params)
-	req, err := http.NewRequest(http.MethodDelete, url, nil)
-	if err != nil {
-		return err
+	respJson := []ClientScopeConfig{}
+	if err := c.do(ctx, "ListDefaultClientScopes", http.MethodGet, KEYCLOAK_ADMIN_SERVICE_GET_DEFAULT_CLIENT_SCOPES, params, nil, &respJson); err != nil {
+		return nil, err
 	}
-	req.Header.Add("Authorization", token)
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
+	return respJson, nil
+}
 
-	if !IsOK(resp.StatusCode) {
-		reqDump, _ := httputil.DumpRequest(req, true)
-		return fmt.Errorf("failed to delete client: " + string(reqDump) + "\n" + resp.Status)
+func (c *HyperAuthClient) ListUserGroups(ctx context.Context, userId string) ([]GroupConfig, error) {
+	params := map[string]string{
+		"userId": userId,
+	}
+	respJson := []GroupConfig{}
+	if err := c.do(ctx, "ListUserGroups", http.MethodGet, KEYCLOAK_ADMIN_SERVICE_GET_USER_GROUPS, params, nil, &respJson); err != nil {
+		return nil, err
 	}
 
-	return nil
+	return respJson, nil
 }
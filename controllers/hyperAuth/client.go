@@ -0,0 +1,320 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hyperAuth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tmax-cloud/hypercloud-multi-operator/controllers/util"
+	coreV1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// HyperAuthClient 는 HyperAuth(keycloak) 의 realm 하나에 대해 admin api 를 호출하는 client 이다.
+// admin token 을 만료 전까지 재사용하고, 멱등한 요청(GET, PUT, DELETE)은 backoff 를 두고 재시도한다.
+type HyperAuthClient struct {
+	httpClient *http.Client
+	adminId    string
+	password   string
+	maxRetries int
+	backoff    time.Duration
+	realm      string
+
+	// master realm 의 admin token 은 모든 realm 에 사용할 수 있으므로 realm 별 client 들이 공유한다.
+	token *adminToken
+}
+
+type adminToken struct {
+	mutex  sync.Mutex
+	value  string
+	expiry time.Time
+}
+
+var (
+	clientCacheMutex sync.Mutex
+	clientCache      = map[types.UID]*cachedClient{}
+)
+
+type cachedClient struct {
+	resourceVersion string
+	client          *HyperAuthClient
+}
+
+// NewHyperAuthClient 는 hyperauth password secret 의 admin 계정으로 operator 의 realm 에 요청하는 client 를 만든다.
+func NewHyperAuthClient(secret *coreV1.Secret) *HyperAuthClient {
+	return &HyperAuthClient{
+		httpClient: &http.Client{Timeout: HYPERAUTH_REQUEST_TIMEOUT},
+		adminId:    string(secret.Data["HYPERAUTH_ADMIN"]),
+		password:   string(secret.Data["HYPERAUTH_PASSWORD"]),
+		maxRetries: HYPERAUTH_MAX_RETRIES,
+		backoff:    HYPERAUTH_RETRY_BACKOFF,
+		realm:      util.GetHyperAuthRealm(),
+		token:      &adminToken{},
+	}
+}
+
+// ForRealm 은 admin token 을 공유하면서 realm 에 요청하는 client 를 반환한다.
+func (c *HyperAuthClient) ForRealm(realm string) *HyperAuthClient {
+	client := *c
+	client.realm = realm
+	return &client
+}
+
+// Realm 은 client 가 요청하는 realm 이다.
+func (c *HyperAuthClient) Realm() string {
+	return c.realm
+}
+
+// GetHyperAuthClient 는 secret 별로 client 를 공유하여 reconcile 마다 로그인하지 않도록 한다.
+// secret 이 변경되면(resourceVersion) 새로운 client 를 만든다.
+func GetHyperAuthClient(secret *coreV1.Secret) *HyperAuthClient {
+	clientCacheMutex.Lock()
+	defer clientCacheMutex.Unlock()
+
+	if cached, ok := clientCache[secret.UID]; ok && cached.resourceVersion == secret.ResourceVersion {
+		return cached.client
+	}
+
+	client := NewHyperAuthClient(secret)
+	clientCache[secret.UID] = &cachedClient{
+		resourceVersion: secret.ResourceVersion,
+		client:          client,
+	}
+	return client
+}
+
+func SetServiceDomainURI(serviceName string, urlParameter map[string]string) string {
+	for key, value := range urlParameter {
+		serviceName = strings.Replace(serviceName, "@@"+key+"@@", value, 1)
+	}
+	return "https://" + os.Getenv(util.AUTH_SUBDOMAIN) + "." + os.Getenv(util.HC_DOMAIN) + serviceName
+}
+
+// getToken 은 캐시된 admin token 을 반환하고, 만료가 임박했으면 새로 발급받는다.
+func (c *HyperAuthClient) getToken(ctx context.Context) (string, error) {
+	c.token.mutex.Lock()
+	defer c.token.mutex.Unlock()
+
+	if c.token.value != "" && time.Now().Add(HYPERAUTH_TOKEN_EXPIRY_MARGIN).Before(c.token.expiry) {
+		return c.token.value, nil
+	}
+
+	// Make Body for Content-Type (application/x-www-form-urlencoded)
+	data := url.Values{}
+	data.Set("grant_type", "password")
+	data.Set("username", c.adminId)
+	data.Set("password", c.password)
+	data.Set("client_id", "admin-cli")
+
+	newRequest := func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(
+			ctx,
+			http.MethodPost,
+			SetServiceDomainURI(KEYCLOAK_ADMIN_SERVICE_GET_TOKEN, nil),
+			strings.NewReader(data.Encode()),
+		)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+		return req, nil
+	}
+
+	// token 발급은 서버의 상태를 변경하지 않으므로 재시도한다.
+	statusCode, body, err := c.send(ctx, "GetTokenAsAdmin", true, newRequest)
+	if err != nil {
+		return "", err
+	}
+	if !IsOK(statusCode) {
+		return "", newStatusError("GetTokenAsAdmin", statusCode, body)
+	}
+
+	result := struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}{}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return "", err
+	}
+	if result.AccessToken == "" {
+		return "", HyperAuthError{
+			Operation:  "GetTokenAsAdmin",
+			StatusCode: statusCode,
+			Message:    "access_token is empty",
+		}
+	}
+
+	c.token.value = strings.Join([]string{"Bearer", result.AccessToken}, " ")
+	c.token.expiry = time.Now().Add(time.Duration(result.ExpiresIn) * time.Second)
+	return c.token.value, nil
+}
+
+// invalidateToken 은 token 이 거부된 경우 다음 요청에서 새로 발급받도록 캐시를 비운다.
+func (c *HyperAuthClient) invalidateToken(token string) {
+	c.token.mutex.Lock()
+	defer c.token.mutex.Unlock()
+
+	if c.token.value == token {
+		c.token.value = ""
+	}
+}
+
+// do 는 admin token 을 붙여 api 를 호출하고, out 이 주어지면 응답을 decode 한다.
+// 409 Conflict 는 이미 존재하는 리소스이므로 성공으로 본다.
+func (c *HyperAuthClient) do(
+	ctx context.Context,
+	operation string,
+	method string,
+	path string,
+	params map[string]string,
+	data interface{},
+	out interface{},
+) error {
+	var payload []byte
+	if data != nil {
+		jsonData, err := json.Marshal(data)
+		if err != nil {
+			return err
+		}
+		payload = jsonData
+	}
+
+	// 모든 admin api 의 path 는 client 의 realm 을 사용한다.
+	urlParameter := map[string]string{"realm": c.realm}
+	for key, value := range params {
+		urlParameter[key] = value
+	}
+
+	idempotent := method == http.MethodGet || method == http.MethodPut || method == http.MethodDelete
+	for attempt := 0; ; attempt++ {
+		token, err := c.getToken(ctx)
+		if err != nil {
+			return err
+		}
+
+		newRequest := func() (*http.Request, error) {
+			var body io.Reader
+			if payload != nil {
+				body = bytes.NewReader(payload)
+			}
+			req, err := http.NewRequestWithContext(ctx, method, SetServiceDomainURI(path, urlParameter), body)
+			if err != nil {
+				return nil, err
+			}
+			if payload != nil {
+				req.Header.Add("Content-Type", "application/json")
+			}
+			req.Header.Add("Authorization", token)
+			return req, nil
+		}
+
+		statusCode, body, err := c.send(ctx, operation, idempotent, newRequest)
+		if err != nil {
+			return err
+		}
+
+		// token 이 만료되었거나 폐기된 경우, 요청이 처리되지 않았으므로 한번만 새 token 으로 다시 요청한다.
+		if statusCode == http.StatusUnauthorized && attempt == 0 {
+			c.invalidateToken(token)
+			continue
+		}
+		if !IsOK(statusCode) {
+			return newStatusError(operation, statusCode, body)
+		}
+		if out != nil && statusCode != http.StatusConflict && len(body) != 0 {
+			return json.Unmarshal(body, out)
+		}
+		return nil
+	}
+}
+
+// send 는 요청을 보내고 응답 body 를 읽어 반환한다.
+// 멱등한 요청은 network error, 429, 5xx 응답에 대해 backoff 를 두고 재시도한다.
+func (c *HyperAuthClient) send(
+	ctx context.Context,
+	operation string,
+	idempotent bool,
+	newRequest func() (*http.Request, error),
+) (int, []byte, error) {
+	backoff := c.backoff
+	for attempt := 0; ; attempt++ {
+		req, err := newRequest()
+		if err != nil {
+			return 0, nil, err
+		}
+
+		start := time.Now()
+		statusCode, body, err := c.roundTrip(req)
+		observeRequest(operation, statusCode, err, time.Since(start))
+
+		retryable := (err != nil && ctx.Err() == nil) || isRetryableStatus(statusCode)
+		if !idempotent || !retryable || attempt >= c.maxRetries {
+			return statusCode, body, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return 0, nil, ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+func (c *HyperAuthClient) roundTrip(req *http.Request) (int, []byte, error) {
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return 0, nil, err
+	}
+	return resp.StatusCode, body, nil
+}
+
+func isRetryableStatus(statusCode int) bool {
+	return statusCode == http.StatusTooManyRequests || statusCode >= http.StatusInternalServerError
+}
+
+func newStatusError(operation string, statusCode int, body []byte) HyperAuthError {
+	message := strings.TrimSpace(string(body))
+	if len(message) > HYPERAUTH_ERROR_MESSAGE_LIMIT {
+		message = message[:HYPERAUTH_ERROR_MESSAGE_LIMIT]
+	}
+	if message == "" {
+		message = strconv.Itoa(statusCode) + " " + http.StatusText(statusCode)
+	}
+
+	return HyperAuthError{
+		NotFound:   statusCode == http.StatusNotFound,
+		Operation:  operation,
+		StatusCode: statusCode,
+		Message:    message,
+	}
+}
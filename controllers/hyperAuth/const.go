@@ -14,25 +14,37 @@ limitations under the License.
 
 package hyperAuth
 
+import "time"
+
 const (
 	// admin api
 	KEYCLOAK_ADMIN_SERVICE_GET_TOKEN                          = "/auth/realms/master/protocol/openid-connect/token"
-	KEYCLOAK_ADMIN_SERVICE_GET_CLIENTS                        = "/auth/admin/realms/tmax/clients"
-	KEYCLOAK_ADMIN_SERVICE_CREATE_CLIENT                      = "/auth/admin/realms/tmax/clients"
-	KEYCLOAK_ADMIN_SERVICE_DELETE_CLIENT                      = "/auth/admin/realms/tmax/clients/@@id@@"
-	KEYCLOAK_ADMIN_SERVICE_CREATE_CLIENT_PROTOCOL_MAPPERS     = "/auth/admin/realms/tmax/clients/@@id@@/protocol-mappers/models"
-	KEYCLOAK_ADMIN_SERVICE_CREATE_CLIENT_ROLES                = "/auth/admin/realms/tmax/clients/@@id@@/roles"
-	KEYCLOAK_ADMIN_SERVICE_GET_CLIENT_ROLE_BY_NAME            = "/auth/admin/realms/tmax/clients/@@id@@/roles/@@roleName@@"
-	KEYCLOAK_ADMIN_SERVICE_ADD_CLIENT_ROLE_TO_USER            = "/auth/admin/realms/tmax/users/@@userId@@/role-mappings/clients/@@id@@"
-	KEYCLOAK_ADMIN_SERVICE_GET_CLIENT_SCOPES                  = "/auth/admin/realms/tmax/client-scopes"
-	KEYCLOAK_ADMIN_SERVICE_ADD_DEFAULT_CLIENT_SCOPE_TO_CLIENT = "/auth/admin/realms/tmax/clients/@@id@@/default-client-scopes/@@clientScopeId@@"
-	KEYCLOAK_ADMIN_SERVICE_GET_REALM_ROLE_BY_NAME             = "/auth/admin/realms/tmax/roles/@@roleName@@"
-	KEYCLOAK_ADMIN_SERVICE_ADD_REALM_ROLE_TO_USER             = "/auth/admin/realms/tmax/users/@@userId@@/role-mappings/realm"
-	KEYCLOAK_ADMIN_SERVICE_GET_GROUP                          = "/auth/admin/realms/tmax/groups"
-	KEYCLOAK_ADMIN_SERVICE_CREATE_GROUP                       = "/auth/admin/realms/tmax/groups"
-	KEYCLOAK_ADMIN_SERVICE_DELETE_GROUP                       = "/auth/admin/realms/tmax/groups/@@groupId@@"
-	KEYCLOAK_ADMIN_SERVICE_ADD_GROUP_TO_USER                  = "/auth/admin/realms/tmax/users/@@userId@@/groups/@@groupId@@"
-	KEYCLOAK_ADMIN_SERVICE_GET_USERS_BY_EMAIL                 = "/auth/admin/realms/tmax/users?exact=true&email=@@userEmail@@"
+	KEYCLOAK_ADMIN_SERVICE_GET_CLIENTS                        = "/auth/admin/realms/@@realm@@/clients"
+	KEYCLOAK_ADMIN_SERVICE_CREATE_CLIENT                      = "/auth/admin/realms/@@realm@@/clients"
+	KEYCLOAK_ADMIN_SERVICE_DELETE_CLIENT                      = "/auth/admin/realms/@@realm@@/clients/@@id@@"
+	KEYCLOAK_ADMIN_SERVICE_CREATE_CLIENT_PROTOCOL_MAPPERS     = "/auth/admin/realms/@@realm@@/clients/@@id@@/protocol-mappers/models"
+	KEYCLOAK_ADMIN_SERVICE_CREATE_CLIENT_ROLES                = "/auth/admin/realms/@@realm@@/clients/@@id@@/roles"
+	KEYCLOAK_ADMIN_SERVICE_GET_CLIENT_ROLE_BY_NAME            = "/auth/admin/realms/@@realm@@/clients/@@id@@/roles/@@roleName@@"
+	KEYCLOAK_ADMIN_SERVICE_ADD_CLIENT_ROLE_TO_USER            = "/auth/admin/realms/@@realm@@/users/@@userId@@/role-mappings/clients/@@id@@"
+	KEYCLOAK_ADMIN_SERVICE_GET_CLIENT_SCOPES                  = "/auth/admin/realms/@@realm@@/client-scopes"
+	KEYCLOAK_ADMIN_SERVICE_ADD_DEFAULT_CLIENT_SCOPE_TO_CLIENT = "/auth/admin/realms/@@realm@@/clients/@@id@@/default-client-scopes/@@clientScopeId@@"
+	KEYCLOAK_ADMIN_SERVICE_GET_REALM_ROLE_BY_NAME             = "/auth/admin/realms/@@realm@@/roles/@@roleName@@"
+	KEYCLOAK_ADMIN_SERVICE_ADD_REALM_ROLE_TO_USER             = "/auth/admin/realms/@@realm@@/users/@@userId@@/role-mappings/realm"
+	KEYCLOAK_ADMIN_SERVICE_GET_GROUP                          = "/auth/admin/realms/@@realm@@/groups"
+	KEYCLOAK_ADMIN_SERVICE_CREATE_GROUP                       = "/auth/admin/realms/@@realm@@/groups"
+	KEYCLOAK_ADMIN_SERVICE_DELETE_GROUP                       = "/auth/admin/realms/@@realm@@/groups/@@groupId@@"
+	KEYCLOAK_ADMIN_SERVICE_ADD_GROUP_TO_USER                  = "/auth/admin/realms/@@realm@@/users/@@userId@@/groups/@@groupId@@"
+	KEYCLOAK_ADMIN_SERVICE_GET_USERS_BY_EMAIL                 = "/auth/admin/realms/@@realm@@/users?exact=true&email=@@userEmail@@"
+	KEYCLOAK_ADMIN_SERVICE_UPDATE_CLIENT                      = "/auth/admin/realms/@@realm@@/clients/@@id@@"
+	KEYCLOAK_ADMIN_SERVICE_GET_CLIENT_PROTOCOL_MAPPERS        = "/auth/admin/realms/@@realm@@/clients/@@id@@/protocol-mappers/models"
+	KEYCLOAK_ADMIN_SERVICE_UPDATE_CLIENT_PROTOCOL_MAPPER      = "/auth/admin/realms/@@realm@@/clients/@@id@@/protocol-mappers/models/@@mapperId@@"
+	KEYCLOAK_ADMIN_SERVICE_DELETE_CLIENT_PROTOCOL_MAPPER      = "/auth/admin/realms/@@realm@@/clients/@@id@@/protocol-mappers/models/@@mapperId@@"
+	KEYCLOAK_ADMIN_SERVICE_GET_CLIENT_ROLES                   = "/auth/admin/realms/@@realm@@/clients/@@id@@/roles"
+	KEYCLOAK_ADMIN_SERVICE_DELETE_CLIENT_ROLE                 = "/auth/admin/realms/@@realm@@/clients/@@id@@/roles/@@roleName@@"
+	KEYCLOAK_ADMIN_SERVICE_GET_USER_CLIENT_ROLES              = "/auth/admin/realms/@@realm@@/users/@@userId@@/role-mappings/clients/@@id@@"
+	KEYCLOAK_ADMIN_SERVICE_GET_DEFAULT_CLIENT_SCOPES          = "/auth/admin/realms/@@realm@@/clients/@@id@@/default-client-scopes"
+	KEYCLOAK_ADMIN_SERVICE_GET_USER_GROUPS                    = "/auth/admin/realms/@@realm@@/users/@@userId@@/groups"
+	KEYCLOAK_ADMIN_SERVICE_GET_CLIENT_SECRET                  = "/auth/admin/realms/@@realm@@/clients/@@id@@/client-secret"
 )
 
 const (
@@ -50,6 +62,47 @@ const (
 	RESOURCE_TYPE_REALM_ROLE   = "RealmRole"
 	RESOURCE_TYPE_CLIENT_SCOPE = "ClientScop"
 	RESOURCE_TYPE_GROUP        = "Group"
+
+	RESOURCE_TYPE_PROTOCOL_MAPPER      = "ProtocolMapper"
+	RESOURCE_TYPE_CLIENT_SCOPE_MAPPING = "ClientScopeMapping"
+	RESOURCE_TYPE_USER_CLIENT_ROLE     = "UserClientRole"
+	RESOURCE_TYPE_USER_GROUP           = "UserGroup"
+)
+
+const (
+	// admin api 요청의 timeout
+	HYPERAUTH_REQUEST_TIMEOUT = 30 * time.Second
+	// 멱등한 요청의 최대 재시도 횟수와 첫 재시도 대기 시간 (재시도 마다 2배씩 증가)
+	HYPERAUTH_MAX_RETRIES   = 3
+	HYPERAUTH_RETRY_BACKOFF = 500 * time.Millisecond
+	// admin token 만료 전에 미리 재발급 받기 위한 여유 시간
+	HYPERAUTH_TOKEN_EXPIRY_MARGIN = 10 * time.Second
+	// error 에 포함할 응답 body 의 최대 길이
+	HYPERAUTH_ERROR_MESSAGE_LIMIT = 512
+)
+
+const (
+	// operator 가 만든 client 에 기록하는 attribute. orphan sweeper 는 이 attribute 가 있는 client 만 삭제한다.
+	HYPERAUTH_CLIENT_ATTRIBUTE_MANAGED_BY = "hypercloud.tmax.io/managed-by"
+	HYPERAUTH_CLIENT_MANAGED_BY           = "hypercloud-multi-operator"
+)
+
+const (
+	// module configmap 에서 module 목록을 가지는 key
+	HYPERAUTH_MODULE_CONFIGMAP_KEY = "modules.yaml"
+	// module 에 client id 를 지정하지 않은 경우 사용하는 template
+	HYPERAUTH_DEFAULT_CLIENT_ID_TEMPLATE = "{{ .Prefix }}-{{ .Module }}"
+	// module 에 client secret 의 이름을 지정하지 않은 경우 사용하는 template
+	HYPERAUTH_DEFAULT_CLIENT_SECRET_NAME_TEMPLATE = "hyperauth-{{ .Module }}-client"
+	// 생성하는 client secret 의 길이 (byte)
+	HYPERAUTH_CLIENT_SECRET_LENGTH = 32
+)
+
+const (
+	// single cluster 의 module 이 사용하는 client secret 의 key
+	CLIENT_SECRET_KEY_CLIENT_ID     = "client-id"
+	CLIENT_SECRET_KEY_CLIENT_SECRET = "client-secret"
+	CLIENT_SECRET_KEY_ISSUER        = "issuer"
 )
 
 // const (
@@ -0,0 +1,59 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hyperAuth
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var (
+	// hyperauth api 요청 수 (operation, 응답 code 별)
+	// network error 등으로 응답을 받지 못한 경우 code 는 error 로 기록한다.
+	requestsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "hyperauth_requests_total",
+			Help: "Total number of requests to the HyperAuth admin api by operation and status code.",
+		},
+		[]string{"operation", "code"},
+	)
+
+	// hyperauth api 요청 시간
+	requestDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "hyperauth_request_duration_seconds",
+			Help:    "Latency of requests to the HyperAuth admin api by operation.",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"operation"},
+	)
+)
+
+func init() {
+	// controller-runtime 의 metrics endpoint 로 노출
+	metrics.Registry.MustRegister(requestsTotal, requestDuration)
+}
+
+func observeRequest(operation string, statusCode int, err error, duration time.Duration) {
+	code := strconv.Itoa(statusCode)
+	if err != nil {
+		code = "error"
+	}
+	requestsTotal.WithLabelValues(operation, code).Inc()
+	requestDuration.WithLabelValues(operation).Observe(duration.Seconds())
+}
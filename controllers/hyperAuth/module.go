@@ -0,0 +1,337 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hyperAuth
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/tmax-cloud/hypercloud-multi-operator/controllers/util"
+	coreV1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+// ModuleConfig 는 HyperAuth 와 oidc 연동하는 module 하나를 위해 cluster 마다 만들어야 하는 리소스들이다.
+// client id, hostname, redirect uri, client secret 의 이름, protocol mapper 의 audience, claim name,
+// group 의 name, path 에는 template 을 사용할 수 있다.
+// ({{ .Prefix }}, {{ .Namespace }}, {{ .ClusterName }}, {{ .Module }}, {{ .ClientId }}, {{ .Domain }}, {{ index .Values "key" }})
+type ModuleConfig struct {
+	Name string `json:"name"`
+	// module 을 설치하는 ClusterAddonProfile 의 module 이름 (ex. efk).
+	// cluster 의 profile 에서 disabled 된 경우 리소스를 만들지 않는다. 비어 있으면 항상 만든다.
+	AddonModule string `json:"addonModule,omitempty"`
+	// 지정하지 않으면 {{ .Prefix }}-{{ .Module }}
+	ClientId                  string `json:"clientId,omitempty"`
+	DirectAccessGrantsEnabled bool   `json:"directAccessGrantsEnabled,omitempty"`
+	ImplicitFlowEnabled       bool   `json:"implicitFlowEnabled,omitempty"`
+	// module 이 노출되는 host 목록. https://<host>/* 를 redirect uri 로 등록한다.
+	// profile 의 값이 채워지지 않아 올바른 host 가 아닌 경우는 등록하지 않는다.
+	Hostnames []string `json:"hostnames,omitempty"`
+	// hostnames 외에 추가로 등록할 redirect uri. 모든 uri 를 허용하는 "*" 는 사용할 수 없다.
+	RedirectUris []string `json:"redirectUris,omitempty"`
+	// client secret 을 만들 single cluster 의 namespace. 비어 있으면 single cluster 에 만들지 않는다.
+	SecretNamespace string `json:"secretNamespace,omitempty"`
+	// 지정하지 않으면 hyperauth-{{ .Module }}-client
+	SecretName      string                 `json:"secretName,omitempty"`
+	ProtocolMappers []ProtocolMapperConfig `json:"protocolMappers,omitempty"`
+	// client-level role 이름. cluster owner 에게 mapping 한다.
+	Roles []string `json:"roles,omitempty"`
+	// client 에 default 로 mapping 할 client scope 이름
+	ClientScopes []string `json:"clientScopes,omitempty"`
+	// cluster owner 를 추가할 group
+	Groups []GroupConfig `json:"groups,omitempty"`
+}
+
+// ModuleTemplateParams 는 module 의 template 에 사용하는 값들이다.
+type ModuleTemplateParams struct {
+	Prefix      string
+	Namespace   string
+	ClusterName string
+	Module      string
+	ClientId    string
+	// cluster 의 profile 의 global domain
+	Domain string
+	// cluster 의 profile 에서 module 의 addon module 에 지정한 값들
+	Values map[string]string
+	// addon module 별로 profile 에 지정한 값들. module 마다 Values 를 채우는 데 사용한다.
+	AddonValues map[string]map[string]string
+}
+
+// LoadModules 는 operator namespace 의 module configmap 에서 module 목록을 읽고 검증한다.
+// configmap 이 없으면 기본 module 목록(DefaultModules)을 사용한다.
+func LoadModules(ctx context.Context, c client.Reader) ([]ModuleConfig, error) {
+	key := types.NamespacedName{
+		Name:      util.GetHyperAuthModuleConfigMap(),
+		Namespace: util.GetOperatorNamespace(),
+	}
+	configMap := &coreV1.ConfigMap{}
+	if err := c.Get(ctx, key, configMap); errors.IsNotFound(err) {
+		return DefaultModules(), nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	modules, err := ParseModules([]byte(configMap.Data[HYPERAUTH_MODULE_CONFIGMAP_KEY]))
+	if err != nil {
+		return nil, fmt.Errorf("invalid hyperauth module configmap [%s]: %w", key.String(), err)
+	}
+	return modules, nil
+}
+
+// ParseModules 는 yaml 형태의 module 목록을 읽고 검증한다.
+func ParseModules(data []byte) ([]ModuleConfig, error) {
+	modules := []ModuleConfig{}
+	if err := yaml.UnmarshalStrict(data, &modules); err != nil {
+		return nil, err
+	}
+	if err := ValidateModules(modules); err != nil {
+		return nil, err
+	}
+	return modules, nil
+}
+
+// ValidateModules 는 module 이름과 client id 가 겹치지 않는지, template 이 올바른지 검증한다.
+func ValidateModules(modules []ModuleConfig) error {
+	if len(modules) == 0 {
+		return fmt.Errorf("no module is defined")
+	}
+
+	params := ModuleTemplateParams{
+		Prefix:      "namespace-cluster",
+		Namespace:   "namespace",
+		ClusterName: "cluster",
+	}
+	names := map[string]bool{}
+	clientIds := map[string]bool{}
+	for _, module := range modules {
+		if module.Name == "" {
+			return fmt.Errorf("module name must not be empty")
+		}
+		if names[module.Name] {
+			return fmt.Errorf("module [%s] is defined more than once", module.Name)
+		}
+		names[module.Name] = true
+
+		state, err := NewDesiredState([]ModuleConfig{module}, params, "")
+		if err != nil {
+			return fmt.Errorf("module [%s]: %w", module.Name, err)
+		}
+		clientId := state.Clients[0].ClientId
+		if clientIds[clientId] {
+			return fmt.Errorf("module [%s]: client id [%s] is used by another module", module.Name, clientId)
+		}
+		clientIds[clientId] = true
+		// 다른 cluster 의 client 와 구분할 수 있도록 client id 에 prefix 가 있어야 한다.
+		if !strings.Contains(clientId, params.Prefix) {
+			return fmt.Errorf("module [%s]: client id [%s] must contain {{ .Prefix }}", module.Name, clientId)
+		}
+
+		mappers := map[string]bool{}
+		for _, mapper := range module.ProtocolMappers {
+			if mapper.Name == "" || mapper.ProtocolMapper == "" {
+				return fmt.Errorf("module [%s]: name and protocolMapper of protocol mapper must not be empty", module.Name)
+			}
+			if mappers[mapper.Name] {
+				return fmt.Errorf("module [%s]: protocol mapper [%s] is defined more than once", module.Name, mapper.Name)
+			}
+			mappers[mapper.Name] = true
+		}
+		for _, role := range module.Roles {
+			if role == "" {
+				return fmt.Errorf("module [%s]: role name must not be empty", module.Name)
+			}
+		}
+		for _, uri := range state.Clients[0].RedirectUris {
+			if strings.TrimSpace(uri) == "*" {
+				return fmt.Errorf("module [%s]: redirect uri must not be a wildcard", module.Name)
+			}
+		}
+		if module.SecretNamespace != "" {
+			if errs := validation.IsDNS1123Label(module.SecretNamespace); len(errs) != 0 {
+				return fmt.Errorf("module [%s]: invalid secret namespace [%s]: %s", module.Name, module.SecretNamespace, strings.Join(errs, ", "))
+			}
+			name := state.ClientSecrets[0].Name
+			if errs := validation.IsDNS1123Subdomain(name); len(errs) != 0 {
+				return fmt.Errorf("module [%s]: invalid secret name [%s]: %s", module.Name, name, strings.Join(errs, ", "))
+			}
+		}
+		for _, group := range state.Groups {
+			if group.Name == "" {
+				return fmt.Errorf("module [%s]: group name must not be empty", module.Name)
+			}
+		}
+	}
+
+	return nil
+}
+
+// SelectModules 는 cluster 에 설치되지 않는 module 들을 제외한다.
+// enabled 에 없는 addon module 은 helm chart 의 기본값을 따르므로 설치되는 것으로 본다.
+func SelectModules(modules []ModuleConfig, enabled map[string]bool) []ModuleConfig {
+	selected := []ModuleConfig{}
+	for _, module := range modules {
+		if module.AddonModule != "" {
+			if installed, ok := enabled[module.AddonModule]; ok && !installed {
+				continue
+			}
+		}
+		selected = append(selected, module)
+	}
+	return selected
+}
+
+// NewDesiredState 는 module 들의 template 을 채워 cluster 를 위한 desired state 를 만든다.
+func NewDesiredState(modules []ModuleConfig, params ModuleTemplateParams, userEmail string) (DesiredState, error) {
+	state := DesiredState{
+		Clients:             []ClientConfig{},
+		ProtocolMappers:     []ClientLevelProtocolMapperConfig{},
+		ClientRoles:         []ClientLevelRoleConfig{},
+		ClientScopeMappings: []ClientScopeMappingConfig{},
+		Groups:              []GroupConfig{},
+		UserEmail:           userEmail,
+		ClientSecrets:       []ClientSecretConfig{},
+	}
+
+	for _, module := range modules {
+		params.Module = module.Name
+		params.ClientId = ""
+		params.Values = params.AddonValues[module.AddonModule]
+		clientId, err := renderModuleTemplate(module.ClientId, HYPERAUTH_DEFAULT_CLIENT_ID_TEMPLATE, params)
+		if err != nil {
+			return state, err
+		}
+		params.ClientId = clientId
+
+		redirectUris, err := renderRedirectUris(module, params)
+		if err != nil {
+			return state, err
+		}
+		// client secret 은 cluster 마다 생성하여 따로 채운다.
+		state.Clients = append(state.Clients, ClientConfig{
+			ClientId:                  clientId,
+			DirectAccessGrantsEnabled: module.DirectAccessGrantsEnabled,
+			ImplicitFlowEnabled:       module.ImplicitFlowEnabled,
+			RedirectUris:              redirectUris,
+		})
+
+		if module.SecretNamespace != "" {
+			name, err := renderModuleTemplate(module.SecretName, HYPERAUTH_DEFAULT_CLIENT_SECRET_NAME_TEMPLATE, params)
+			if err != nil {
+				return state, err
+			}
+			state.ClientSecrets = append(state.ClientSecrets, ClientSecretConfig{
+				ClientId:  clientId,
+				Name:      name,
+				Namespace: module.SecretNamespace,
+			})
+		}
+
+		for _, mapper := range module.ProtocolMappers {
+			for _, field := range []*string{
+				&mapper.Config.IncludedClientAudience,
+				&mapper.Config.IncludedCustomAudience,
+				&mapper.Config.ClaimName,
+			} {
+				if *field, err = renderModuleTemplate(*field, "", params); err != nil {
+					return state, err
+				}
+			}
+			if mapper.Protocol == "" {
+				mapper.Protocol = PROTOCOL_MAPPER_CONFIG_PROTOCOL_OPENID_CONNECT
+			}
+			state.ProtocolMappers = append(state.ProtocolMappers, ClientLevelProtocolMapperConfig{
+				ClientId:       clientId,
+				ProtocolMapper: mapper,
+			})
+		}
+
+		for _, role := range module.Roles {
+			state.ClientRoles = append(state.ClientRoles, ClientLevelRoleConfig{
+				ClientId: clientId,
+				Role:     RoleConfig{Name: role},
+			})
+		}
+
+		for _, scope := range module.ClientScopes {
+			state.ClientScopeMappings = append(state.ClientScopeMappings, ClientScopeMappingConfig{
+				ClientId:    clientId,
+				ClientScope: ClientScopeConfig{Name: scope},
+			})
+		}
+
+		for _, group := range module.Groups {
+			if group.Name, err = renderModuleTemplate(group.Name, "", params); err != nil {
+				return state, err
+			}
+			if group.Path, err = renderModuleTemplate(group.Path, "/"+group.Name, params); err != nil {
+				return state, err
+			}
+			group.SubGroups = []string{}
+			state.Groups = append(state.Groups, group)
+		}
+	}
+
+	return state, nil
+}
+
+// renderRedirectUris 는 module 의 hostname 들과 추가 redirect uri 들로 client 의 redirect uri 목록을 만든다.
+func renderRedirectUris(module ModuleConfig, params ModuleTemplateParams) ([]string, error) {
+	redirectUris := []string{}
+	for _, hostname := range module.Hostnames {
+		host, err := renderModuleTemplate(hostname, "", params)
+		if err != nil {
+			return nil, err
+		}
+		if len(validation.IsDNS1123Subdomain(host)) != 0 {
+			continue
+		}
+		redirectUris = append(redirectUris, "https://"+host+"/*")
+	}
+	for _, uri := range module.RedirectUris {
+		uri, err := renderModuleTemplate(uri, "", params)
+		if err != nil {
+			return nil, err
+		}
+		redirectUris = append(redirectUris, uri)
+	}
+	return redirectUris, nil
+}
+
+// renderModuleTemplate 는 text 의 template 을 채운다. text 가 비어 있으면 defaultText 를 사용한다.
+func renderModuleTemplate(text string, defaultText string, params ModuleTemplateParams) (string, error) {
+	if text == "" {
+		text = defaultText
+	}
+	if !strings.Contains(text, "{{") {
+		return text, nil
+	}
+
+	tmpl, err := template.New("").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	buf := &bytes.Buffer{}
+	if err := tmpl.Execute(buf, params); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
@@ -14,185 +14,127 @@ limitations under the License.
 
 package hyperAuth
 
-import (
-	"os"
-	"strings"
+import "github.com/tmax-cloud/hypercloud-multi-operator/controllers/util"
 
-	"github.com/tmax-cloud/hypercloud-multi-operator/controllers/util"
-)
-
-func GetClientConfigPreset(prefix string) []ClientConfig {
-	AUTH_CLIENT_SECRET := util.AUTH_CLIENT_SECRET
-	configs := []ClientConfig{
-		{
-			ClientId:                  strings.Join([]string{prefix, "kibana"}, "-"),
-			Secret:                    os.Getenv(AUTH_CLIENT_SECRET),
+// DefaultModules 는 module configmap 이 없는 경우 사용하는 기본 module 목록이다.
+// (kibana, grafana, kiali, jaeger, hyperregistry, opensearch)
+// redirect uri 는 cluster 의 profile 에 지정한 module 의 subdomain 과 global domain 으로 만든다.
+func DefaultModules() []ModuleConfig {
+	return []ModuleConfig{
+		{
+			Name:                      "kibana",
+			AddonModule:               "efk",
 			DirectAccessGrantsEnabled: true,
 			ImplicitFlowEnabled:       false,
-			RedirectUris:              []string{"*"},
+			Hostnames:                 []string{`{{ index .Values "kibana.subdomain" }}.{{ .Domain }}`},
+			SecretNamespace:           util.OpenSearchNamespace,
+			ProtocolMappers: []ProtocolMapperConfig{
+				{
+					Name:           "kibana",
+					Protocol:       PROTOCOL_MAPPER_CONFIG_PROTOCOL_OPENID_CONNECT,
+					ProtocolMapper: PROTOCOL_MAPPER_CONFIG_PROTOCOL_NAME_AUDIENCE,
+					Config: MapperConfig{
+						IncludedClientAudience: "{{ .ClientId }}",
+						IdTokenClaim:           false,
+						AccessTokenClaim:       true,
+					},
+				},
+			},
+			Roles: []string{"kibana-manager"},
 		},
 		{
-			ClientId:                  strings.Join([]string{prefix, "grafana"}, "-"),
-			Secret:                    os.Getenv(AUTH_CLIENT_SECRET),
+			Name:                      "grafana",
+			AddonModule:               "grafanaOperator",
 			DirectAccessGrantsEnabled: true,
 			ImplicitFlowEnabled:       false,
-			RedirectUris:              []string{"*"},
+			Hostnames:                 []string{`{{ index .Values "subdomain" }}.{{ .Domain }}`},
+			SecretNamespace:           util.MonitoringNamespace,
 		},
 		{
-			ClientId:                  strings.Join([]string{prefix, "kiali"}, "-"),
-			Secret:                    os.Getenv(AUTH_CLIENT_SECRET),
+			Name:                      "kiali",
+			AddonModule:               "serviceMesh",
 			DirectAccessGrantsEnabled: true,
 			ImplicitFlowEnabled:       true,
-			RedirectUris:              []string{"*"},
-		},
-		{
-			ClientId:                  strings.Join([]string{prefix, "jaeger"}, "-"),
-			Secret:                    os.Getenv(AUTH_CLIENT_SECRET),
-			DirectAccessGrantsEnabled: true,
-			ImplicitFlowEnabled:       false,
-			RedirectUris:              []string{"*"},
+			Hostnames:                 []string{`{{ index .Values "kiali.subdomain" }}.{{ .Domain }}`},
+			SecretNamespace:           util.IstioNamespace,
+			ClientScopes:              []string{"kubernetes"},
 		},
 		{
-			ClientId:                  strings.Join([]string{prefix, "hyperregistry"}, "-"),
-			Secret:                    os.Getenv(AUTH_CLIENT_SECRET),
+			Name:                      "jaeger",
+			AddonModule:               "serviceMesh",
 			DirectAccessGrantsEnabled: true,
 			ImplicitFlowEnabled:       false,
-			RedirectUris:              []string{"*"},
-		},
-		{
-			ClientId:                  strings.Join([]string{prefix, "opensearch"}, "-"),
-			Secret:                    os.Getenv(AUTH_CLIENT_SECRET),
-			DirectAccessGrantsEnabled: true,
-			ImplicitFlowEnabled:       false,
-			RedirectUris:              []string{"*"},
-		},
-	}
-
-	return configs
-}
-
-func GetMappingProtocolMapperToClientConfigPreset(prefix string) []ClientLevelProtocolMapperConfig {
-	configs := []ClientLevelProtocolMapperConfig{
-		{
-			ClientId: strings.Join([]string{prefix, "kibana"}, "-"),
-			ProtocolMapper: ProtocolMapperConfig{
-				Name:           "kibana",
-				Protocol:       PROTOCOL_MAPPER_CONFIG_PROTOCOL_OPENID_CONNECT,
-				ProtocolMapper: PROTOCOL_MAPPER_CONFIG_PROTOCOL_NAME_AUDIENCE,
-				Config: MapperConfig{
-					IncludedClientAudience: strings.Join([]string{prefix, "kibana"}, "-"),
-					IdTokenClaim:           false,
-					AccessTokenClaim:       true,
+			Hostnames:                 []string{`{{ index .Values "jaeger.subdomain" }}.{{ .Domain }}`},
+			SecretNamespace:           util.IstioNamespace,
+			ProtocolMappers: []ProtocolMapperConfig{
+				{
+					Name:           "jaeger",
+					Protocol:       PROTOCOL_MAPPER_CONFIG_PROTOCOL_OPENID_CONNECT,
+					ProtocolMapper: PROTOCOL_MAPPER_CONFIG_PROTOCOL_NAME_AUDIENCE,
+					Config: MapperConfig{
+						IncludedClientAudience: "{{ .ClientId }}",
+						IdTokenClaim:           false,
+						AccessTokenClaim:       true,
+					},
 				},
 			},
+			Roles: []string{"jaeger-manager"},
 		},
 		{
-			ClientId: strings.Join([]string{prefix, "jaeger"}, "-"),
-			ProtocolMapper: ProtocolMapperConfig{
-				Name:           "jaeger",
-				Protocol:       PROTOCOL_MAPPER_CONFIG_PROTOCOL_OPENID_CONNECT,
-				ProtocolMapper: PROTOCOL_MAPPER_CONFIG_PROTOCOL_NAME_AUDIENCE,
-				Config: MapperConfig{
-					IncludedClientAudience: strings.Join([]string{prefix, "jaeger"}, "-"),
-					IdTokenClaim:           false,
-					AccessTokenClaim:       true,
+			Name:                      "hyperregistry",
+			AddonModule:               "hyperregistry",
+			DirectAccessGrantsEnabled: true,
+			ImplicitFlowEnabled:       false,
+			Hostnames:                 []string{`{{ index .Values "core.subdomain" }}.{{ .Domain }}`},
+			SecretNamespace:           util.HyperregistryNamespace,
+			ProtocolMappers: []ProtocolMapperConfig{
+				{
+					Name:           "group",
+					Protocol:       PROTOCOL_MAPPER_CONFIG_PROTOCOL_OPENID_CONNECT,
+					ProtocolMapper: PROTOCOL_MAPPER_CONFIG_PROTOCOL_NAME_GROUP_MEMBERSHIP,
+					Config: MapperConfig{
+						ClaimName:          "group",
+						FullPath:           true,
+						IdTokenClaim:       true,
+						AccessTokenClaim:   true,
+						UserInfoTokenClaim: true,
+					},
 				},
 			},
-		},
-		{
-			ClientId: strings.Join([]string{prefix, "hyperregistry"}, "-"),
-			ProtocolMapper: ProtocolMapperConfig{
-				Name:           "group",
-				Protocol:       PROTOCOL_MAPPER_CONFIG_PROTOCOL_OPENID_CONNECT,
-				ProtocolMapper: PROTOCOL_MAPPER_CONFIG_PROTOCOL_NAME_GROUP_MEMBERSHIP,
-				Config: MapperConfig{
-					ClaimName:          "group",
-					FullPath:           true,
-					IdTokenClaim:       true,
-					AccessTokenClaim:   true,
-					UserInfoTokenClaim: true,
+			// hyperregistry 의 admin group
+			Groups: []GroupConfig{
+				{
+					Name: "{{ .Prefix }}-hyperregistry",
 				},
 			},
 		},
 		{
-			ClientId: strings.Join([]string{prefix, "opensearch"}, "-"),
-			ProtocolMapper: ProtocolMapperConfig{
-				Name:           "client roles",
-				Protocol:       PROTOCOL_MAPPER_CONFIG_PROTOCOL_OPENID_CONNECT,
-				ProtocolMapper: PROTOCOL_MAPPER_CONFIG_PROTOCOL_NAME_USER_CLIENT_ROLE,
-				Config: MapperConfig{
-					Multivalued:        true,
-					ClaimName:          "roles",
-					JsonType:           "String",
-					IdTokenClaim:       true,
-					AccessTokenClaim:   true,
-					UserInfoTokenClaim: true,
+			Name:                      "opensearch",
+			AddonModule:               "opensearch",
+			DirectAccessGrantsEnabled: true,
+			ImplicitFlowEnabled:       false,
+			Hostnames:                 []string{`{{ index .Values "dashboard.subdomain" }}.{{ .Domain }}`},
+			SecretNamespace:           util.OpenSearchNamespace,
+			ProtocolMappers: []ProtocolMapperConfig{
+				{
+					Name:           "client roles",
+					Protocol:       PROTOCOL_MAPPER_CONFIG_PROTOCOL_OPENID_CONNECT,
+					ProtocolMapper: PROTOCOL_MAPPER_CONFIG_PROTOCOL_NAME_USER_CLIENT_ROLE,
+					Config: MapperConfig{
+						Multivalued:        true,
+						ClaimName:          "roles",
+						JsonType:           "String",
+						IdTokenClaim:       true,
+						AccessTokenClaim:   true,
+						UserInfoTokenClaim: true,
+					},
 				},
 			},
-		},
-	}
-
-	return configs
-}
-
-func GetClientLevelRoleConfigPreset(prefix string) []ClientLevelRoleConfig {
-	configs := []ClientLevelRoleConfig{
-		{
-			ClientId: strings.Join([]string{prefix, "kibana"}, "-"),
-			Role: RoleConfig{
-				Name: "kibana-manager",
-			},
-		},
-		{
-			ClientId: strings.Join([]string{prefix, "jaeger"}, "-"),
-			Role: RoleConfig{
-				Name: "jaeger-manager",
-			},
-		},
-		{
-			ClientId: strings.Join([]string{prefix, "opensearch"}, "-"),
-			Role: RoleConfig{
-				Name: "opensearch-admin",
-			},
-		},
-		{
-			ClientId: strings.Join([]string{prefix, "opensearch"}, "-"),
-			Role: RoleConfig{
-				Name: "opensearch-developer",
-			},
-		},
-		{
-			ClientId: strings.Join([]string{prefix, "opensearch"}, "-"),
-			Role: RoleConfig{
-				Name: "opensearch-guest",
-			},
-		},
-	}
-
-	return configs
-}
-
-func GetClientScopeMappingPreset(prefix string) []ClientScopeMappingConfig {
-	configs := []ClientScopeMappingConfig{
-		{
-			ClientId: strings.Join([]string{prefix, "kiali"}, "-"),
-			ClientScope: ClientScopeConfig{
-				Name: "kubernetes",
+			Roles: []string{
+				"opensearch-admin",
+				"opensearch-developer",
+				"opensearch-guest",
 			},
 		},
 	}
-
-	return configs
-}
-
-func GetGroupConfigPreset(prefix string) []GroupConfig {
-	configs := []GroupConfig{
-		{
-			Name:      strings.Join([]string{prefix, "hyperregistry"}, "-"),
-			Path:      "/" + prefix + "-hyperregistry",
-			SubGroups: []string{},
-		},
-	}
-
-	return configs
 }
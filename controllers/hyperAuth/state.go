@@ -0,0 +1,509 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hyperAuth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// DesiredState 는 cluster 하나를 위해 HyperAuth 에 있어야 하는 리소스들이다.
+type DesiredState struct {
+	Clients             []ClientConfig
+	ProtocolMappers     []ClientLevelProtocolMapperConfig
+	ClientRoles         []ClientLevelRoleConfig
+	ClientScopeMappings []ClientScopeMappingConfig
+	Groups              []GroupConfig
+	// client role 과 group 을 mapping 할 user (cluster owner)
+	UserEmail string
+	// single cluster 의 module 들이 사용할 client secret. HyperAuth 의 리소스가 아니므로 Sync 에서는 사용하지 않는다.
+	ClientSecrets []ClientSecretConfig
+}
+
+// Hash 는 desired state 가 바뀌었는지 확인하기 위한 hash 를 반환한다.
+func (d DesiredState) Hash() string {
+	data, _ := json.Marshal(d)
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:])[:16]
+}
+
+// ClientIds 는 desired state 의 client 이름 목록을 반환한다.
+func (d DesiredState) ClientIds() []string {
+	ids := []string{}
+	for _, config := range d.Clients {
+		ids = append(ids, config.ClientId)
+	}
+	return ids
+}
+
+// GroupNames 는 desired state 의 group 이름 목록을 반환한다.
+func (d DesiredState) GroupNames() []string {
+	names := []string{}
+	for _, config := range d.Groups {
+		names = append(names, config.Name)
+	}
+	return names
+}
+
+// SyncResult 는 동기화하면서 변경한 리소스들이다. (<type>/<name> 형태)
+type SyncResult struct {
+	Created []string
+	Updated []string
+	Deleted []string
+}
+
+func (r *SyncResult) String() string {
+	if len(r.Created)+len(r.Updated)+len(r.Deleted) == 0 {
+		return "all resources are in sync"
+	}
+
+	changes := []string{}
+	for _, change := range []struct {
+		action    string
+		resources []string
+	}{
+		{"created", r.Created},
+		{"updated", r.Updated},
+		{"deleted", r.Deleted},
+	} {
+		if len(change.resources) != 0 {
+			changes = append(changes, change.action+" "+strings.Join(change.resources, ", "))
+		}
+	}
+	return strings.Join(changes, "; ")
+}
+
+func (r *SyncResult) created(resourceType string, name string) {
+	r.Created = append(r.Created, resourceType+"/"+name)
+}
+
+func (r *SyncResult) updated(resourceType string, name string) {
+	r.Updated = append(r.Updated, resourceType+"/"+name)
+}
+
+func (r *SyncResult) deleted(resourceType string, name string) {
+	r.Deleted = append(r.Deleted, resourceType+"/"+name)
+}
+
+// Sync 는 HyperAuth 의 리소스를 조회하여 desired state 와 비교하고, 없는 리소스는 생성, 달라진 리소스는 수정한다.
+// desired state 에 없는 protocol mapper, client role 과, managedClients, managedGroups 중 desired state 에서 빠진 client, group 은 삭제한다.
+// user 에게 추가로 mapping 된 role, group 은 사용자가 직접 추가했을 수 있으므로 삭제하지 않는다.
+func (c *HyperAuthClient) Sync(ctx context.Context, desired DesiredState, managedClients []string, managedGroups []string) (*SyncResult, error) {
+	result := &SyncResult{}
+
+	userId, err := c.GetUserIdByEmail(ctx, desired.UserEmail)
+	if err != nil {
+		return result, err
+	}
+
+	clientIds, err := c.syncClients(ctx, desired, managedClients, result)
+	if err != nil {
+		return result, err
+	}
+
+	for _, config := range desired.Clients {
+		id := clientIds[config.ClientId]
+		if err := c.syncProtocolMappers(ctx, id, config.ClientId, desired.ProtocolMappers, result); err != nil {
+			return result, err
+		}
+		if err := c.syncClientRoles(ctx, id, userId, config.ClientId, desired.ClientRoles, result); err != nil {
+			return result, err
+		}
+	}
+
+	if err := c.syncClientScopeMappings(ctx, clientIds, desired.ClientScopeMappings, result); err != nil {
+		return result, err
+	}
+
+	if err := c.syncGroups(ctx, userId, desired, managedGroups, result); err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+// syncClients 는 client 들을 동기화하고, clientId 별 keycloak 의 id 를 반환한다.
+func (c *HyperAuthClient) syncClients(ctx context.Context, desired DesiredState, managedClients []string, result *SyncResult) (map[string]string, error) {
+	clients, err := c.ListClients(ctx)
+	if err != nil {
+		return nil, err
+	}
+	existing := map[string]ClientConfig{}
+	for _, client := range clients {
+		existing[client.ClientId] = client
+	}
+
+	created := false
+	for _, config := range desired.Clients {
+		current, ok := existing[config.ClientId]
+		if !ok {
+			if err := c.CreateClient(ctx, config); err != nil {
+				return nil, err
+			}
+			result.created(RESOURCE_TYPE_CLIENT, config.ClientId)
+			created = true
+			continue
+		}
+
+		changed := isClientChanged(current, config)
+		// 조회 결과의 secret 은 가려져 있을 수 있으므로 따로 조회하여 비교한다.
+		if !changed && config.Secret != "" {
+			secret, err := c.GetClientSecret(ctx, current.Id)
+			if err != nil {
+				return nil, err
+			}
+			changed = secret != config.Secret
+		}
+		if changed {
+			if err := c.UpdateClient(ctx, current.Id, config); err != nil {
+				return nil, err
+			}
+			result.updated(RESOURCE_TYPE_CLIENT, config.ClientId)
+		}
+	}
+
+	desiredClients := toSet(desired.ClientIds())
+	for _, clientId := range managedClients {
+		current, ok := existing[clientId]
+		if !ok || desiredClients[clientId] {
+			continue
+		}
+		if err := c.DeleteClient(ctx, current); err != nil {
+			return nil, err
+		}
+		result.deleted(RESOURCE_TYPE_CLIENT, clientId)
+	}
+
+	// 새로 만든 client 의 id 를 알기 위해 다시 조회한다.
+	if created {
+		if clients, err = c.ListClients(ctx); err != nil {
+			return nil, err
+		}
+	}
+	ids := map[string]string{}
+	for _, client := range clients {
+		ids[client.ClientId] = client.Id
+	}
+	for _, clientId := range desired.ClientIds() {
+		if ids[clientId] == "" {
+			return nil, HyperAuthError{NotFound: true, Type: RESOURCE_TYPE_CLIENT, Name: clientId}
+		}
+	}
+
+	return ids, nil
+}
+
+func (c *HyperAuthClient) syncProtocolMappers(ctx context.Context, id string, clientId string, configs []ClientLevelProtocolMapperConfig, result *SyncResult) error {
+	mappers, err := c.ListClientProtocolMappers(ctx, id)
+	if err != nil {
+		return err
+	}
+	existing := map[string]ProtocolMapperRepresentation{}
+	for _, mapper := range mappers {
+		existing[mapper.Name] = mapper
+	}
+
+	params := map[string]string{
+		"id": id,
+	}
+	desired := map[string]bool{}
+	for _, config := range configs {
+		if config.ClientId != clientId {
+			continue
+		}
+		desired[config.ProtocolMapper.Name] = true
+		name := clientId + "/" + config.ProtocolMapper.Name
+
+		current, ok := existing[config.ProtocolMapper.Name]
+		if !ok {
+			if err := c.do(ctx, "CreateClientLevelProtocolMapper", http.MethodPost, KEYCLOAK_ADMIN_SERVICE_CREATE_CLIENT_PROTOCOL_MAPPERS, params, config.ProtocolMapper, nil); err != nil {
+				return err
+			}
+			result.created(RESOURCE_TYPE_PROTOCOL_MAPPER, name)
+			continue
+		}
+
+		mapper := newProtocolMapperRepresentation(config.ProtocolMapper)
+		if isProtocolMapperChanged(current, mapper) {
+			mapper.Id = current.Id
+			if err := c.UpdateClientProtocolMapper(ctx, id, mapper); err != nil {
+				return err
+			}
+			result.updated(RESOURCE_TYPE_PROTOCOL_MAPPER, name)
+		}
+	}
+
+	for _, mapper := range mappers {
+		if desired[mapper.Name] {
+			continue
+		}
+		if err := c.DeleteClientProtocolMapper(ctx, id, mapper.Id); err != nil {
+			return err
+		}
+		result.deleted(RESOURCE_TYPE_PROTOCOL_MAPPER, clientId+"/"+mapper.Name)
+	}
+
+	return nil
+}
+
+func (c *HyperAuthClient) syncClientRoles(ctx context.Context, id string, userId string, clientId string, configs []ClientLevelRoleConfig, result *SyncResult) error {
+	roles, err := c.ListClientRoles(ctx, id)
+	if err != nil {
+		return err
+	}
+	existing := map[string]RoleConfig{}
+	for _, role := range roles {
+		existing[role.Name] = role
+	}
+
+	userRoles, err := c.ListUserClientRoles(ctx, userId, id)
+	if err != nil {
+		return err
+	}
+	mapped := map[string]bool{}
+	for _, role := range userRoles {
+		mapped[role.Name] = true
+	}
+
+	params := map[string]string{
+		"id": id,
+	}
+	desired := map[string]bool{}
+	missing := []RoleConfig{}
+	created := false
+	for _, config := range configs {
+		if config.ClientId != clientId {
+			continue
+		}
+		desired[config.Role.Name] = true
+		name := clientId + "/" + config.Role.Name
+
+		if _, ok := existing[config.Role.Name]; !ok {
+			data := RoleConfig{
+				Name: config.Role.Name,
+			}
+			if err := c.do(ctx, "CreateClientLevelRole", http.MethodPost, KEYCLOAK_ADMIN_SERVICE_CREATE_CLIENT_ROLES, params, data, nil); err != nil {
+				return err
+			}
+			result.created(RESOURCE_TYPE_CLIENT_ROLE, name)
+			created = true
+		}
+		if !mapped[config.Role.Name] {
+			missing = append(missing, RoleConfig{Name: config.Role.Name})
+		}
+	}
+
+	for _, role := range roles {
+		if desired[role.Name] {
+			continue
+		}
+		if err := c.DeleteClientRole(ctx, id, role.Name); err != nil {
+			return err
+		}
+		result.deleted(RESOURCE_TYPE_CLIENT_ROLE, clientId+"/"+role.Name)
+	}
+
+	if len(missing) == 0 {
+		return nil
+	}
+
+	// 새로 만든 role 의 id 를 알기 위해 다시 조회한다.
+	if created {
+		if roles, err = c.ListClientRoles(ctx, id); err != nil {
+			return err
+		}
+	}
+	roleIds := map[string]string{}
+	for _, role := range roles {
+		roleIds[role.Name] = role.Id
+	}
+	for i := range missing {
+		missing[i].Id = roleIds[missing[i].Name]
+		if missing[i].Id == "" {
+			return HyperAuthError{NotFound: true, Type: RESOURCE_TYPE_CLIENT_ROLE, Name: missing[i].Name}
+		}
+	}
+
+	params["userId"] = userId
+	if err := c.do(ctx, "AddClientLevelRolesToUserRoleMapping", http.MethodPost, KEYCLOAK_ADMIN_SERVICE_ADD_CLIENT_ROLE_TO_USER, params, missing, nil); err != nil {
+		return err
+	}
+	for _, role := range missing {
+		result.created(RESOURCE_TYPE_USER_CLIENT_ROLE, clientId+"/"+role.Name)
+	}
+
+	return nil
+}
+
+func (c *HyperAuthClient) syncClientScopeMappings(ctx context.Context, clientIds map[string]string, configs []ClientScopeMappingConfig, result *SyncResult) error {
+	if len(configs) == 0 {
+		return nil
+	}
+
+	scopes, err := c.ListClientScopes(ctx)
+	if err != nil {
+		return err
+	}
+	scopeIds := map[string]string{}
+	for _, scope := range scopes {
+		scopeIds[scope.Name] = scope.Id
+	}
+
+	defaultScopes := map[string]map[string]bool{}
+	for _, config := range configs {
+		id := clientIds[config.ClientId]
+		if id == "" {
+			return HyperAuthError{NotFound: true, Type: RESOURCE_TYPE_CLIENT, Name: config.ClientId}
+		}
+		if _, ok := defaultScopes[id]; !ok {
+			scopes, err := c.ListDefaultClientScopes(ctx, id)
+			if err != nil {
+				return err
+			}
+			defaultScopes[id] = map[string]bool{}
+			for _, scope := range scopes {
+				defaultScopes[id][scope.Name] = true
+			}
+		}
+		if defaultScopes[id][config.ClientScope.Name] {
+			continue
+		}
+
+		scopeId := scopeIds[config.ClientScope.Name]
+		if scopeId == "" {
+			return HyperAuthError{NotFound: true, Type: RESOURCE_TYPE_CLIENT_SCOPE, Name: config.ClientScope.Name}
+		}
+		params := map[string]string{
+			"id":            id,
+			"clientScopeId": scopeId,
+		}
+		if err := c.do(ctx, "AddClientScopeToClient", http.MethodPut, KEYCLOAK_ADMIN_SERVICE_ADD_DEFAULT_CLIENT_SCOPE_TO_CLIENT, params, nil, nil); err != nil {
+			return err
+		}
+		defaultScopes[id][config.ClientScope.Name] = true
+		result.created(RESOURCE_TYPE_CLIENT_SCOPE_MAPPING, config.ClientId+"/"+config.ClientScope.Name)
+	}
+
+	return nil
+}
+
+func (c *HyperAuthClient) syncGroups(ctx context.Context, userId string, desired DesiredState, managedGroups []string, result *SyncResult) error {
+	groups, err := c.ListGroups(ctx)
+	if err != nil {
+		return err
+	}
+	existing := map[string]GroupConfig{}
+	for _, group := range groups {
+		existing[group.Name] = group
+	}
+
+	userGroups, err := c.ListUserGroups(ctx, userId)
+	if err != nil {
+		return err
+	}
+	joined := map[string]bool{}
+	for _, group := range userGroups {
+		joined[group.Name] = true
+	}
+
+	for _, config := range desired.Groups {
+		if _, ok := existing[config.Name]; !ok {
+			if err := c.CreateGroup(ctx, config); err != nil {
+				return err
+			}
+			result.created(RESOURCE_TYPE_GROUP, config.Name)
+		}
+		if !joined[config.Name] {
+			if err := c.AddGroupToUser(ctx, desired.UserEmail, config); err != nil {
+				return err
+			}
+			result.created(RESOURCE_TYPE_USER_GROUP, config.Name)
+		}
+	}
+
+	desiredGroups := toSet(desired.GroupNames())
+	for _, name := range managedGroups {
+		current, ok := existing[name]
+		if !ok || desiredGroups[name] {
+			continue
+		}
+		if err := c.DeleteGroup(ctx, current); err != nil {
+			return err
+		}
+		result.deleted(RESOURCE_TYPE_GROUP, name)
+	}
+
+	return nil
+}
+
+func isClientChanged(current ClientConfig, desired ClientConfig) bool {
+	if !IsManagedClient(current) ||
+		current.DirectAccessGrantsEnabled != desired.DirectAccessGrantsEnabled ||
+		current.ImplicitFlowEnabled != desired.ImplicitFlowEnabled {
+		return true
+	}
+
+	currentUris := append([]string{}, current.RedirectUris...)
+	desiredUris := append([]string{}, desired.RedirectUris...)
+	sort.Strings(currentUris)
+	sort.Strings(desiredUris)
+	return strings.Join(currentUris, ",") != strings.Join(desiredUris, ",")
+}
+
+// newProtocolMapperRepresentation 은 조회 결과와 비교할 수 있도록 config 의 값들을 문자열로 바꾼다.
+func newProtocolMapperRepresentation(config ProtocolMapperConfig) ProtocolMapperRepresentation {
+	values := map[string]interface{}{}
+	data, _ := json.Marshal(config.Config)
+	_ = json.Unmarshal(data, &values)
+
+	mapperConfig := map[string]string{}
+	for key, value := range values {
+		mapperConfig[key] = fmt.Sprint(value)
+	}
+
+	return ProtocolMapperRepresentation{
+		Name:           config.Name,
+		Protocol:       config.Protocol,
+		ProtocolMapper: config.ProtocolMapper,
+		Config:         mapperConfig,
+	}
+}
+
+// isProtocolMapperChanged 는 desired 에 지정한 config 값들이 달라졌는지 반환한다.
+func isProtocolMapperChanged(current ProtocolMapperRepresentation, desired ProtocolMapperRepresentation) bool {
+	if current.Protocol != desired.Protocol || current.ProtocolMapper != desired.ProtocolMapper {
+		return true
+	}
+	for key, value := range desired.Config {
+		if current.Config[key] != value {
+			return true
+		}
+	}
+	return false
+}
+
+func toSet(list []string) map[string]bool {
+	set := map[string]bool{}
+	for _, item := range list {
+		set[item] = true
+	}
+	return set
+}
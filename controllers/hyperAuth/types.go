@@ -14,25 +14,61 @@ limitations under the License.
 
 package hyperAuth
 
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// HyperAuthError 는 HyperAuth api 호출이 실패한 경우의 error 이다.
+// 리소스를 찾을 수 없는 경우 NotFound 를, api 가 실패 응답을 준 경우 StatusCode 를 채운다.
 type HyperAuthError struct {
-	NotFound bool
-	Type     string
-	Name     string
+	NotFound   bool
+	Type       string
+	Name       string
+	Operation  string
+	StatusCode int
+	Message    string
 }
 
 func (e HyperAuthError) Error() string {
-	return "Resource not found"
+	if e.StatusCode == 0 && e.NotFound {
+		return fmt.Sprintf("%s [%s] not found", e.Type, e.Name)
+	}
+	return fmt.Sprintf("hyperauth %s failed with status %d: %s", e.Operation, e.StatusCode, e.Message)
 }
 
 func IsNotFound(e error) bool {
-	if e == nil {
+	err := HyperAuthError{}
+	if !errors.As(e, &err) {
 		return false
 	}
-	if _, ok := e.(HyperAuthError); !ok {
+
+	return err.NotFound
+}
+
+// IsUnauthorized 는 admin 계정의 인증 또는 권한이 거부된 경우인지 반환한다.
+func IsUnauthorized(e error) bool {
+	err := HyperAuthError{}
+	if !errors.As(e, &err) {
+		return false
+	}
+
+	return err.StatusCode == http.StatusUnauthorized || err.StatusCode == http.StatusForbidden
+}
+
+// IsRetryable 은 일시적인 장애로 인해 나중에 다시 시도하면 성공할 수 있는 error 인지 반환한다.
+func IsRetryable(e error) bool {
+	if e == nil {
 		return false
 	}
+	err := HyperAuthError{}
+	if !errors.As(e, &err) {
+		// network error, timeout 등
+		return true
+	}
 
-	return e.(HyperAuthError).NotFound
+	return isRetryableStatus(err.StatusCode)
 }
 
 type ClientConfig struct {
@@ -42,6 +78,21 @@ type ClientConfig struct {
 	DirectAccessGrantsEnabled bool     `json:"directAccessGrantsEnabled,omitempty"`
 	ImplicitFlowEnabled       bool     `json:"implicitFlowEnabled,omitempty"`
 	RedirectUris              []string `json:"redirectUris,omitempty"`
+	// keycloak 의 client attribute. operator 가 만든 client 인지 표시하는 데 사용한다.
+	Attributes map[string]string `json:"attributes,omitempty"`
+}
+
+// ClientSecretConfig 는 module 이 사용할 client secret 을 만들 single cluster 의 secret 이다.
+type ClientSecretConfig struct {
+	ClientId  string
+	Name      string
+	Namespace string
+}
+
+// CredentialRepresentation 은 keycloak 에서 조회한 client 의 secret 이다.
+type CredentialRepresentation struct {
+	Type  string `json:"type,omitempty"`
+	Value string `json:"value,omitempty"`
 }
 
 type ClientLevelProtocolMapperConfig struct {
@@ -56,6 +107,16 @@ type ProtocolMapperConfig struct {
 	Config         MapperConfig `json:"config,omitempty"`
 }
 
+// ProtocolMapperRepresentation 은 keycloak 에서 조회한 protocol mapper 이다.
+// 조회 결과의 config 는 모든 값이 문자열이다.
+type ProtocolMapperRepresentation struct {
+	Id             string            `json:"id,omitempty"`
+	Name           string            `json:"name,omitempty"`
+	Protocol       string            `json:"protocol,omitempty"`
+	ProtocolMapper string            `json:"protocolMapper,omitempty"`
+	Config         map[string]string `json:"config,omitempty"`
+}
+
 type MapperConfig struct {
 	IncludedClientAudience string `json:"included.client.audience,omitempty"`
 	IncludedCustomAudience string `json:"included.custom.audience,omitempty"`
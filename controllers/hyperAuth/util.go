@@ -37,3 +37,18 @@ func IsOK(check int) bool {
 // 	hyperauthTlsCert = strings.ReplaceAll(hyperauthTlsCert, "\t", "\\t")
 // 	return hyperauthTlsCert
 // }
+
+// IsManagedClient 는 operator 가 만든 client 인지 반환한다.
+func IsManagedClient(config ClientConfig) bool {
+	return config.Attributes[HYPERAUTH_CLIENT_ATTRIBUTE_MANAGED_BY] == HYPERAUTH_CLIENT_MANAGED_BY
+}
+
+// managedClientAttributes 는 attributes 에 operator 가 만든 client 임을 표시하는 attribute 를 더한다.
+func managedClientAttributes(attributes map[string]string) map[string]string {
+	result := map[string]string{}
+	for key, value := range attributes {
+		result[key] = value
+	}
+	result[HYPERAUTH_CLIENT_ATTRIBUTE_MANAGED_BY] = HYPERAUTH_CLIENT_MANAGED_BY
+	return result
+}
@@ -19,7 +19,6 @@ import (
 	"encoding/json"
 	"regexp"
 	"strings"
-	"time"
 
 	"github.com/go-logr/logr"
 	clusterV1alpha1 "github.com/tmax-cloud/hypercloud-multi-operator/apis/cluster/v1alpha1"
@@ -30,7 +29,6 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
-	kerrors "k8s.io/apimachinery/pkg/util/errors"
 
 	"sigs.k8s.io/cluster-api/util/patch"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -50,21 +48,6 @@ type SecretReconciler struct {
 	Scheme *runtime.Scheme
 }
 
-// Cluster member information
-type ClusterMemberInfo struct {
-	Id          int64     `json:"Id"`
-	Namespace   string    `json:"Namespace"`
-	Cluster     string    `json:"Cluster"`
-	MemberId    string    `json:"MemberId"`
-	Groups      []string  `json:"Groups"`
-	MemberName  string    `json:"MemberName"`
-	Attribute   string    `json:"Attribute"`
-	Role        string    `json:"Role"`
-	Status      string    `json:"Status"`
-	CreatedTime time.Time `json:"CreatedTime"`
-	UpdatedTime time.Time `json:"UpdatedTime"`
-}
-
 // +kubebuilder:rbac:groups="",resources=secrets;namespaces;serviceaccounts,verbs=create;delete;get;list;patch;post;update;watch;
 
 func (r *SecretReconciler) Reconcile(ctx context.Context, req ctrl.Request) (_ ctrl.Result, reterr error) {
@@ -133,36 +116,55 @@ func (r *SecretReconciler) Reconcile(ctx context.Context, req ctrl.Request) (_ c
 
 // reconcile handles cluster reconciliation.
 func (r *SecretReconciler) reconcile(ctx context.Context, secret *coreV1.Secret) (ctrl.Result, error) {
-	phases := []func(context.Context, *coreV1.Secret) (ctrl.Result, error){
+	log := r.Log.WithValues("Secret", types.NamespacedName{Name: secret.Name, Namespace: secret.Namespace})
+	object := util.NewPhaseObject(secret)
+	bind := func(phase func(context.Context, *coreV1.Secret) (ctrl.Result, error)) func(context.Context) (ctrl.Result, error) {
+		return object.Bind(func(ctx context.Context, obj client.Object) (ctrl.Result, error) {
+			return phase(ctx, obj.(*coreV1.Secret))
+		})
+	}
+
+	// 아래 phase 들은 서로 의존성이 없으므로 모두 동시에 수행된다.
+	phases := []util.Phase{
 		// cluster manager 가 바라봐야 할 single cluster 의 api-server 를 설정해주는 작업을 진행한다.
 		// 해당 secret 으로 부터 kubeconfig data 를 가져와 kubeconfig 의 server 를 cluster manager 의 control plane endpoint 로 설정해준다.
-		r.UpdateClusterManagerControlPlaneEndpoint,
+		{
+			Name: "UpdateClusterManagerControlPlaneEndpoint",
+			Run:  bind(r.UpdateClusterManagerControlPlaneEndpoint),
+		},
+		// kubeconfig 의 내용이 바뀐 경우, kubeconfig 로 부터 만들어진 리소스들을 다시 만들 수 있도록 ClusterManager 의 status 를 초기화한다.
+		{
+			Name: "UpdateKubeconfigHash",
+			Precondition: func() bool {
+				return isKubeconfigSecret(secret)
+			},
+			Run: bind(r.UpdateKubeconfigHash),
+		},
 		// single cluster 에 admin/developer/guest 에 따른 cluster role 을 생성하고,
 		// cluster owner 에 대해 admin role 을 가지는 cluster rolebinding 을 생성한다.
-		r.DeployRBACResources,
+		{
+			Name: "DeployRBACResources",
+			Run:  bind(r.DeployRBACResources),
+		},
 		// single cluster 에 Argocd 연동을 위한 리소스 배포작업을 진행한다.
 		// Argocd 용 service account 를 생성하고,
 		// cluster role 과 cluster rolebinding 을 생성한다.
-		r.DeployArgocdResources,
+		{
+			Name: "DeployArgocdResources",
+			Run:  bind(r.DeployArgocdResources),
+		},
+		// single cluster 에 cluster proxy 가 사용자를 impersonate 하기 위한 service account 를 생성하고,
+		// impersonate 권한만 가지는 cluster role 과 cluster rolebinding 을 생성한다.
+		{
+			Name: "DeployImpersonatorResources",
+			Run:  bind(r.DeployImpersonatorResources),
+		},
 		// r.DeployOpensearchResources,
 	}
 
-	res := ctrl.Result{}
-	errs := []error{}
-	for _, phase := range phases {
-		// Call the inner reconciliation methods.
-		phaseResult, err := phase(ctx, secret)
-		if err != nil {
-			errs = append(errs, err)
-		}
-		if len(errs) > 0 {
-			continue
-		}
-
-		// Aggregate phases which requeued without err
-		res = util.LowestNonZeroResult(res, phaseResult)
-	}
-	return res, kerrors.NewAggregate(errs)
+	res, outcomes, err := util.RunPhases(ctx, phases)
+	util.LogPhaseOutcomes(log, outcomes)
+	return res, err
 }
 
 func (r *SecretReconciler) reconcileDelete(ctx context.Context, secret *coreV1.Secret) (reconcile.Result, error) {
@@ -289,6 +291,10 @@ func (r *SecretReconciler) reconcileDelete(ctx context.Context, secret *coreV1.S
 				Name:      adminServiceAccountName,
 				Namespace: util.KubeNamespace,
 			},
+			{
+				Name:      util.ImpersonatorServiceAccount,
+				Namespace: util.KubeNamespace,
+			},
 		}
 		for _, targetSa := range saList {
 			_, err := remoteClientset.
@@ -348,7 +354,7 @@ func (r *SecretReconciler) reconcileDelete(ctx context.Context, secret *coreV1.S
 
 		// db 로 부터 클러스터에 초대 된 member 들의 info 가져오기
 		jsonData, _ := util.List(clm.Namespace, clm.Name)
-		memberList := []ClusterMemberInfo{}
+		memberList := []util.ClusterMemberInfo{}
 		if err := json.Unmarshal(jsonData, &memberList); err != nil {
 			return ctrl.Result{}, err
 		}
@@ -357,6 +363,7 @@ func (r *SecretReconciler) reconcileDelete(ctx context.Context, secret *coreV1.S
 			"cluster-owner-crb-" + secret.Annotations[util.AnnotationKeyOwner],
 			"cluster-owner-sa-crb-" + secret.Annotations[util.AnnotationKeyOwner],
 			util.ArgoClusterRoleBinding,
+			util.ImpersonatorClusterRoleBinding,
 		}
 		for _, member := range memberList {
 			if member.Status == "invited" && member.Attribute == "user" {
@@ -394,6 +401,7 @@ func (r *SecretReconciler) reconcileDelete(ctx context.Context, secret *coreV1.S
 			"developer",
 			"guest",
 			util.ArgoClusterRole,
+			util.ImpersonatorClusterRole,
 		}
 		for _, targetCr := range crList {
 			_, err := remoteClientset.
@@ -492,7 +500,11 @@ func (r *SecretReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		WithEventFilter(
 			predicate.Funcs{
 				CreateFunc: func(e event.CreateEvent) bool {
-					return false
+					// operator 가 내려가 있는 동안 kubeconfig 가 바뀐 경우를 처리하기 위해,
+					// 이미 반영된 적이 있는 kubeconfig 의 내용이 달라진 경우에만 reconcile 한다.
+					secret := e.Object.(*coreV1.Secret)
+					hash, ok := secret.Annotations[util.AnnotationKeyKubeconfigHash]
+					return isKubeconfigSecret(secret) && ok && hash != util.KubeconfigHash(secret)
 				},
 				UpdateFunc: func(e event.UpdateEvent) bool {
 					oldSecret := e.ObjectOld.(*coreV1.Secret)
@@ -503,7 +515,11 @@ func (r *SecretReconciler) SetupWithManager(mgr ctrl.Manager) error {
 					isDelete := oldSecret.GetDeletionTimestamp().IsZero() && !newSecret.GetDeletionTimestamp().IsZero()
 					isFinalized := !controllerutil.ContainsFinalizer(oldSecret, clusterV1alpha1.ClusterManagerFinalizer) &&
 						controllerutil.ContainsFinalizer(newSecret, clusterV1alpha1.ClusterManagerFinalizer)
-					if isTarget && (isDelete || isFinalized) {
+					// 인증서 rotation 이나 api-server endpoint 변경으로 kubeconfig 의 내용이 바뀐 경우
+					isKubeconfigChanged := isKubeconfigSecret(newSecret) &&
+						newSecret.GetDeletionTimestamp().IsZero() &&
+						util.KubeconfigHash(oldSecret) != util.KubeconfigHash(newSecret)
+					if isTarget && (isDelete || isFinalized || isKubeconfigChanged) {
 						return true
 					}
 					return false
@@ -16,6 +16,7 @@ package controllers
 
 import (
 	"context"
+	"net/url"
 	"regexp"
 	"strings"
 
@@ -24,10 +25,10 @@ import (
 
 	coreV1 "k8s.io/api/core/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
-	"k8s.io/client-go/tools/clientcmd"
 
 	"sigs.k8s.io/cluster-api/util/patch"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -41,7 +42,7 @@ func (r *SecretReconciler) UpdateClusterManagerControlPlaneEndpoint(ctx context.
 	log := r.Log.WithValues("secret", key)
 	log.Info("Start to reconcile phase for UpdateClusterManagerControlPlaneEndpoint... ")
 
-	kubeConfig, err := clientcmd.Load(secret.Data["value"])
+	cluster, _, err := util.LoadKubeconfigEntries(secret)
 	if err != nil {
 		log.Error(err, "Failed to get kubeconfig data from secret")
 		return ctrl.Result{}, err
@@ -60,7 +61,7 @@ func (r *SecretReconciler) UpdateClusterManagerControlPlaneEndpoint(ctx context.
 		log.Error(err, "Failed to get clusterManager + ["+clm.Name+"]")
 		return ctrl.Result{}, err
 	} else {
-		server := kubeConfig.Clusters[kubeConfig.Contexts[kubeConfig.CurrentContext].Cluster].Server
+		server := cluster.Server
 		if !strings.EqualFold(clm.Status.ControlPlaneEndpoint, server) {
 			log.Info("Update clustermanager status. add ControlPlane endpoint")
 			helper, _ := patch.NewHelper(clm, r.Client)
@@ -76,6 +77,75 @@ func (r *SecretReconciler) UpdateClusterManagerControlPlaneEndpoint(ctx context.
 	return ctrl.Result{}, nil
 }
 
+// kubeconfig 의 내용이 바뀐 경우 (인증서 rotation, api-server endpoint 변경 등)
+// kubeconfig 로 부터 만들어진 Argo cluster secret, gateway service, ingress 를 다시 만들 수 있도록
+// ClusterManager 의 ArgoReady, GatewayReady, TraefikReady 를 초기화하고 api-server annotation 을 갱신한다.
+func (r *SecretReconciler) UpdateKubeconfigHash(ctx context.Context, secret *coreV1.Secret) (ctrl.Result, error) {
+	hash := util.KubeconfigHash(secret)
+	prevHash, ok := secret.Annotations[util.AnnotationKeyKubeconfigHash]
+	if prevHash == hash {
+		return ctrl.Result{}, nil
+	}
+	log := r.Log.WithValues("secret", types.NamespacedName{Name: secret.Name, Namespace: secret.Namespace})
+	log.Info("Start to reconcile phase for UpdateKubeconfigHash")
+
+	// 처음 반영하는 kubeconfig 라면 hash 값만 기록한다.
+	if ok {
+		cluster, _, err := util.LoadKubeconfigEntries(secret)
+		if err != nil {
+			log.Error(err, "Failed to get kubeconfig data from secret")
+			return ctrl.Result{}, err
+		}
+		server := cluster.Server
+		serverURL, err := url.Parse(server)
+		if err != nil {
+			log.Error(err, "Failed to parse server uri")
+			return ctrl.Result{}, err
+		}
+
+		key := types.NamespacedName{
+			Name:      strings.Split(secret.Name, util.KubeconfigSuffix)[0],
+			Namespace: secret.Namespace,
+		}
+		clm := &clusterV1alpha1.ClusterManager{}
+		if err := r.Get(context.TODO(), key, clm); errors.IsNotFound(err) {
+			log.Info("Cannot find clusterManager")
+		} else if err != nil {
+			log.Error(err, "Failed to get clusterManager")
+			return ctrl.Result{}, err
+		} else {
+			helper, err := patch.NewHelper(clm, r.Client)
+			if err != nil {
+				return ctrl.Result{}, err
+			}
+
+			// cluster claim 으로 생성한 cluster 는 host 만, cluster registration 으로 등록한 cluster 는 port 까지 annotation 으로 가진다.
+			apiserver := serverURL.Host
+			if clm.Labels[clusterV1alpha1.LabelKeyClmClusterType] == clusterV1alpha1.ClusterTypeCreated {
+				apiserver = serverURL.Hostname()
+			}
+			if clm.Annotations == nil {
+				clm.Annotations = map[string]string{}
+			}
+			clm.Annotations[clusterV1alpha1.AnnotationKeyClmApiserver] = apiserver
+			clm.Status.ArgoReady = false
+			clm.Status.GatewayReady = false
+			clm.Status.TraefikReady = false
+			if err := helper.Patch(context.TODO(), clm); err != nil {
+				log.Error(err, "Failed to patch ClusterManager")
+				return ctrl.Result{}, err
+			}
+			log.Info("Kubeconfig is changed. Reset sub resources of ClusterManager to be recreated")
+		}
+	}
+
+	if secret.Annotations == nil {
+		secret.Annotations = map[string]string{}
+	}
+	secret.Annotations[util.AnnotationKeyKubeconfigHash] = hash
+	return ctrl.Result{}, nil
+}
+
 func (r *SecretReconciler) DeployRBACResources(ctx context.Context, secret *coreV1.Secret) (ctrl.Result, error) {
 	log := r.Log.WithValues(
 		"secret",
@@ -204,33 +274,7 @@ func (r *SecretReconciler) DeployRBACResources(ctx context.Context, secret *core
 		return ctrl.Result{}, err
 	}
 
-	adminServiceAccountTokenSecret := &coreV1.Secret{
-		ObjectMeta: metav1.ObjectMeta{
-			Annotations: map[string]string{
-				coreV1.ServiceAccountNameKey: adminServiceAccount.Name,
-			},
-			Name: adminServiceAccount.Name + "-token",
-		},
-		Type: coreV1.SecretTypeServiceAccountToken,
-	}
-	_, err = remoteClientset.
-		CoreV1().
-		Secrets(util.KubeNamespace).
-		Get(context.TODO(), adminServiceAccountTokenSecret.Name, metav1.GetOptions{})
-	if errors.IsNotFound(err) {
-		_, err := remoteClientset.
-			CoreV1().
-			Secrets(util.KubeNamespace).
-			Create(context.TODO(), adminServiceAccountTokenSecret, metav1.CreateOptions{})
-		if err != nil {
-			log.Error(err, "Cannot create ServiceAccount token secret ["+adminServiceAccount.Name+"] to remote cluster")
-			return ctrl.Result{}, err
-		}
-		log.Info("Create ServiceAccount token secret [" + adminServiceAccount.Name + "] to remote cluster successfully")
-	} else if err != nil {
-		log.Error(err, "Failed to get ServiceAccount token secret ["+adminServiceAccount.Name+"] from remote cluster")
-		return ctrl.Result{}, err
-	}
+	// token 은 ClusterManager 에서 TokenRequest 로 발급받으므로, 만료되지 않는 token secret 은 생성하지 않는다.
 
 	adminServiceAccountCRB := &rbacv1.ClusterRoleBinding{
 		ObjectMeta: metav1.ObjectMeta{
@@ -275,13 +319,13 @@ func (r *SecretReconciler) DeployArgocdResources(ctx context.Context, secret *co
 	log := r.Log.WithValues("secret", types.NamespacedName{Name: secret.Name, Namespace: secret.Namespace})
 	log.Info("Start to reconcile phase for Deploy argocd resources to remote")
 
-	kubeConfig, err := clientcmd.Load(secret.Data["value"])
+	cluster, _, err := util.LoadKubeconfigEntries(secret)
 	if err != nil {
 		log.Error(err, "Failed to get secret")
 		return ctrl.Result{}, err
 	}
 
-	serverURI := kubeConfig.Clusters[kubeConfig.Contexts[kubeConfig.CurrentContext].Cluster].Server
+	serverURI := cluster.Server
 	argoSecretName, err := util.URIToSecretName("cluster", serverURI)
 	if err != nil {
 		log.Error(err, "Failed to parse server uri")
@@ -322,35 +366,7 @@ func (r *SecretReconciler) DeployArgocdResources(ctx context.Context, secret *co
 		return ctrl.Result{}, err
 	}
 
-	// service account 생성시 token secret이 자동으로 생성되지만
-	// random suffix가 붙기때문에 조회하는 process가 번잡하므로 시크릿을 수동으로 생성
-	argocdManagerTokenSecret := &coreV1.Secret{
-		ObjectMeta: metav1.ObjectMeta{
-			Annotations: map[string]string{
-				coreV1.ServiceAccountNameKey: util.ArgoServiceAccount,
-			},
-			Name: util.ArgoServiceAccountTokenSecret,
-		},
-		Type: coreV1.SecretTypeServiceAccountToken,
-	}
-	_, err = remoteClientset.
-		CoreV1().
-		Secrets(util.KubeNamespace).
-		Get(context.TODO(), argocdManagerTokenSecret.Name, metav1.GetOptions{})
-	if errors.IsNotFound(err) {
-		_, err := remoteClientset.
-			CoreV1().
-			Secrets(util.KubeNamespace).
-			Create(context.TODO(), argocdManagerTokenSecret, metav1.CreateOptions{})
-		if err != nil {
-			log.Error(err, "Cannot create ServiceAccount token secret for argocd ["+argocdManagerTokenSecret.Name+"] to remote cluster")
-			return ctrl.Result{}, err
-		}
-		log.Info("Create ServiceAccount token secret for argocd [" + argocdManagerTokenSecret.Name + "] to remote cluster successfully")
-	} else if err != nil {
-		log.Error(err, "Failed to get ServiceAccount token secret for argocd ["+argocdManagerTokenSecret.Name+"] from remote cluster")
-		return ctrl.Result{}, err
-	}
+	// token 은 ClusterManager 에서 TokenRequest 로 발급받으므로, 만료되지 않는 token secret 은 생성하지 않는다.
 
 	argocdManagerRole := &rbacv1.ClusterRole{
 		ObjectMeta: metav1.ObjectMeta{
@@ -425,3 +441,120 @@ func (r *SecretReconciler) DeployArgocdResources(ctx context.Context, secret *co
 
 	return ctrl.Result{}, nil
 }
+
+func (r *SecretReconciler) DeployImpersonatorResources(ctx context.Context, secret *coreV1.Secret) (ctrl.Result, error) {
+	log := r.Log.WithValues("secret", types.NamespacedName{Name: secret.Name, Namespace: secret.Namespace})
+	log.Info("Start to reconcile phase for Deploy impersonator resources to remote")
+
+	remoteClientset, err := util.GetRemoteK8sClient(secret)
+	if err != nil {
+		log.Error(err, "Failed to get remoteK8sClient")
+		return ctrl.Result{}, err
+	}
+
+	impersonatorSA := &coreV1.ServiceAccount{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: util.ImpersonatorServiceAccount,
+		},
+	}
+	_, err = remoteClientset.
+		CoreV1().
+		ServiceAccounts(util.KubeNamespace).
+		Get(context.TODO(), impersonatorSA.Name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		_, err := remoteClientset.
+			CoreV1().
+			ServiceAccounts(util.KubeNamespace).
+			Create(context.TODO(), impersonatorSA, metav1.CreateOptions{})
+		if err != nil {
+			log.Error(err, "Cannot create ServiceAccount for impersonator ["+impersonatorSA.Name+"] to remote cluster")
+			return ctrl.Result{}, err
+		}
+		log.Info("Create ServiceAccount for impersonator [" + impersonatorSA.Name + "] to remote cluster successfully")
+	} else if err != nil {
+		log.Error(err, "Failed to get ServiceAccount for impersonator ["+impersonatorSA.Name+"] from remote cluster")
+		return ctrl.Result{}, err
+	}
+
+	// impersonator 는 사용자로 impersonate 하는 권한만 가지고, 실제 권한은 impersonate 한 사용자의 rbac 을 따른다.
+	impersonatorRole := &rbacv1.ClusterRole{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: util.ImpersonatorClusterRole,
+		},
+		Rules: []rbacv1.PolicyRule{
+			{
+				APIGroups: []string{""},
+				Resources: []string{"users", "groups"},
+				Verbs:     []string{"impersonate"},
+			},
+		},
+	}
+	currentRole, err := remoteClientset.
+		RbacV1().
+		ClusterRoles().
+		Get(context.TODO(), impersonatorRole.Name, metav1.GetOptions{})
+	if err == nil && !equality.Semantic.DeepEqual(currentRole.Rules, impersonatorRole.Rules) {
+		// 이전 버전에서 만든 role 의 권한을 줄인다.
+		currentRole.Rules = impersonatorRole.Rules
+		_, err := remoteClientset.
+			RbacV1().
+			ClusterRoles().
+			Update(context.TODO(), currentRole, metav1.UpdateOptions{})
+		if err != nil {
+			log.Error(err, "Cannot update ClusterRole for impersonator ["+impersonatorRole.Name+"] to remote cluster")
+			return ctrl.Result{}, err
+		}
+		log.Info("Update ClusterRole for impersonator [" + impersonatorRole.Name + "] to remote cluster successfully")
+	} else if errors.IsNotFound(err) {
+		_, err := remoteClientset.
+			RbacV1().
+			ClusterRoles().
+			Create(context.TODO(), impersonatorRole, metav1.CreateOptions{})
+		if err != nil {
+			log.Error(err, "Cannot create ClusterRole for impersonator ["+impersonatorRole.Name+"] to remote cluster")
+			return ctrl.Result{}, err
+		}
+		log.Info("Create ClusterRole for impersonator [" + impersonatorRole.Name + "] to remote cluster successfully")
+	} else if err != nil {
+		log.Error(err, "Failed to get ClusterRole for impersonator ["+impersonatorRole.Name+"] from remote cluster")
+		return ctrl.Result{}, err
+	}
+
+	impersonatorRoleBinding := &rbacv1.ClusterRoleBinding{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: util.ImpersonatorClusterRoleBinding,
+		},
+		RoleRef: rbacv1.RoleRef{
+			APIGroup: rbacv1.GroupName,
+			Kind:     "ClusterRole",
+			Name:     util.ImpersonatorClusterRole,
+		},
+		Subjects: []rbacv1.Subject{
+			{
+				Kind:      rbacv1.ServiceAccountKind,
+				Name:      util.ImpersonatorServiceAccount,
+				Namespace: util.KubeNamespace,
+			},
+		},
+	}
+	_, err = remoteClientset.
+		RbacV1().
+		ClusterRoleBindings().
+		Get(context.TODO(), impersonatorRoleBinding.Name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		_, err := remoteClientset.
+			RbacV1().
+			ClusterRoleBindings().
+			Create(context.TODO(), impersonatorRoleBinding, metav1.CreateOptions{})
+		if err != nil {
+			log.Error(err, "Cannot create ClusterRoleBinding for impersonator ["+impersonatorRoleBinding.Name+"] to remote cluster")
+			return ctrl.Result{}, err
+		}
+		log.Info("Create ClusterRoleBinding for impersonator [" + impersonatorRoleBinding.Name + "] to remote cluster successfully")
+	} else if err != nil {
+		log.Error(err, "Failed to get ClusterRoleBinding for impersonator ["+impersonatorRoleBinding.Name+"] from remote cluster")
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{}, nil
+}
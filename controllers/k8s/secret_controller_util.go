@@ -15,10 +15,24 @@ limitations under the License.
 package controllers
 
 import (
+	"strings"
+
+	util "github.com/tmax-cloud/hypercloud-multi-operator/controllers/util"
+	coreV1 "k8s.io/api/core/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+// ClusterManager 의 single cluster 에 접근하기 위한 kubeconfig secret 인지 확인한다.
+// capi 에 의해 생성된 kubeconfig secret 은 처음에는 secret type label 이 없으므로 capi label 로도 확인한다.
+func isKubeconfigSecret(secret *coreV1.Secret) bool {
+	if secret.Labels[util.LabelKeyClmSecretType] == util.ClmSecretTypeKubeconfig {
+		return true
+	}
+	_, isCapiKubeconfig := secret.Labels[util.LabelKeyCapiClusterName]
+	return isCapiKubeconfig && strings.HasSuffix(secret.Name, util.KubeconfigSuffix)
+}
+
 func CreateClusterRole(name string, targetGroup []string, verbList []string) *rbacv1.ClusterRole {
 	clusterRole := &rbacv1.ClusterRole{
 		ObjectMeta: metav1.ObjectMeta{
@@ -0,0 +1,337 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package proxy
+
+import (
+	"context"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	clusterV1alpha1 "github.com/tmax-cloud/hypercloud-multi-operator/apis/cluster/v1alpha1"
+	"github.com/tmax-cloud/hypercloud-multi-operator/controllers/util"
+
+	coreV1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	restclient "k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/transport"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// ingress 를 통해 전달되는 single cluster api 의 path: /api/<namespace>/<cluster>/api/kubernetes/...
+	kubernetesPathSegment = "/api/kubernetes"
+)
+
+// ClusterProxy 는 console 에서 single cluster 로 향하는 kubernetes api 요청을 받아,
+// HyperAuth token 을 검증한 뒤 요청한 사용자로 impersonate 하여 single cluster 의 api-server 로 전달한다.
+// single cluster 에는 impersonate 권한만 가지는 service account 의 token 으로 접근하므로,
+// single cluster 의 rbac 과 audit log 에는 실제 사용자가 기록된다.
+type ClusterProxy struct {
+	client.Client
+	Log         logr.Logger
+	BindAddress string
+
+	mu         sync.Mutex
+	transports map[types.NamespacedName]*transportEntry
+	// cluster 마다 HyperAuth realm 이 다를 수 있으므로 issuer 별로 verifier 를 가진다.
+	verifiers map[string]*TokenVerifier
+}
+
+// transportEntry 는 single cluster 의 transport 를 만드는 동안 다른 cluster 의 요청이 기다리지 않도록
+// cluster 별로 lock 을 가진다.
+type transportEntry struct {
+	mu        sync.Mutex
+	transport *clusterTransport
+}
+
+// clusterTransport 는 single cluster 별 api-server 주소와 impersonator token 을 가지는 transport 이다.
+type clusterTransport struct {
+	kubeconfigHash string
+	expiration     time.Time
+	target         *url.URL
+	roundTripper   http.RoundTripper
+}
+
+// NeedLeaderElection 은 모든 replica 가 요청을 처리할 수 있도록 false 를 반환한다.
+func (p *ClusterProxy) NeedLeaderElection() bool {
+	return false
+}
+
+// Start 는 manager 가 시작될 때 호출되며, ctx 가 끝날 때 까지 proxy server 를 동작시킨다.
+// proxy 는 bearer token 을 평문 http 로 받으므로 TLS 를 종료하는 multicluster ingress 를 통해서만 접근해야 하며,
+// proxy 의 port 는 외부로 노출하지 않아야 한다.
+func (p *ClusterProxy) Start(ctx context.Context) error {
+	server := &http.Server{
+		Addr:    p.BindAddress,
+		Handler: p,
+	}
+
+	errChan := make(chan error, 1)
+	go func() {
+		p.Log.Info("Starting cluster proxy. It must only be reached through the TLS ingress", "address", p.BindAddress)
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			errChan <- err
+		}
+		close(errChan)
+	}()
+
+	select {
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		return server.Shutdown(shutdownCtx)
+	case err := <-errChan:
+		return err
+	}
+}
+
+func (p *ClusterProxy) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	key, rest, ok := parsePath(req.URL.Path)
+	if !ok {
+		http.NotFound(w, req)
+		return
+	}
+	log := p.Log.WithValues("clustermanager", key)
+
+	rawToken := strings.TrimSpace(strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer "))
+	if rawToken == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// 인증 전에는 cluster 의 존재 여부를 알 수 없도록 찾지 못한 경우에도 Unauthorized 를 반환한다.
+	clm := &clusterV1alpha1.ClusterManager{}
+	if err := p.Get(req.Context(), key, clm); errors.IsNotFound(err) {
+		p.evictTransport(key)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	} else if err != nil {
+		log.Error(err, "Failed to get ClusterManager")
+		http.Error(w, "Bad Gateway", http.StatusBadGateway)
+		return
+	}
+	realm, err := util.GetHyperAuthRealmFor(req.Context(), p.Client, clm)
+	if err != nil {
+		log.Error(err, "Failed to get HyperAuth realm")
+		http.Error(w, "Bad Gateway", http.StatusBadGateway)
+		return
+	}
+	claims, err := p.getVerifier(realm).Verify(rawToken)
+	if err != nil {
+		log.Info("Failed to verify token", "reason", err.Error())
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	ct, err := p.getTransport(key)
+	if errors.IsNotFound(err) {
+		http.NotFound(w, req)
+		return
+	} else if err != nil {
+		log.Error(err, "Failed to get transport for remote cluster")
+		http.Error(w, "Bad Gateway", http.StatusBadGateway)
+		return
+	}
+
+	impersonate := transport.NewImpersonatingRoundTripper(
+		transport.ImpersonationConfig{
+			UserName: claims.UserName(),
+			Groups:   claims.GroupNames(),
+		},
+		ct.roundTripper,
+	)
+
+	reverseProxy := &httputil.ReverseProxy{
+		Director: func(out *http.Request) {
+			rewriteRequest(out, ct.target, rest)
+		},
+		Transport: impersonate,
+		// watch 요청의 event 를 바로 전달한다.
+		FlushInterval: -1,
+		ErrorHandler: func(w http.ResponseWriter, req *http.Request, err error) {
+			log.Error(err, "Failed to proxy request to remote cluster")
+			w.WriteHeader(http.StatusBadGateway)
+		},
+	}
+	reverseProxy.ServeHTTP(w, req)
+}
+
+// getVerifier 는 realm 의 issuer 로 token 을 검증하는 verifier 를 반환한다.
+func (p *ClusterProxy) getVerifier(realm string) *TokenVerifier {
+	issuer := util.GetHyperAuthIssuer(realm)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.verifiers == nil {
+		p.verifiers = map[string]*TokenVerifier{}
+	}
+	if v, ok := p.verifiers[issuer]; ok {
+		return v
+	}
+	v := NewTokenVerifier(issuer, util.GetClusterProxyAudiences())
+	p.verifiers[issuer] = v
+	return v
+}
+
+// getTransport 는 single cluster 의 transport 를 반환한다.
+// kubeconfig 가 바뀌었거나 impersonator token 이 만료될 때가 된 경우 새로 만들고,
+// ClusterManager 나 kubeconfig secret 이 삭제된 경우 캐시에서 지운다.
+func (p *ClusterProxy) getTransport(key types.NamespacedName) (*clusterTransport, error) {
+	clm := &clusterV1alpha1.ClusterManager{}
+	if err := p.Get(context.TODO(), key, clm); errors.IsNotFound(err) {
+		p.evictTransport(key)
+		return nil, err
+	} else if err != nil {
+		return nil, err
+	}
+
+	secret := &coreV1.Secret{}
+	secretKey := types.NamespacedName{
+		Name:      key.Name + util.KubeconfigSuffix,
+		Namespace: key.Namespace,
+	}
+	if err := p.Get(context.TODO(), secretKey, secret); errors.IsNotFound(err) {
+		p.evictTransport(key)
+		return nil, err
+	} else if err != nil {
+		return nil, err
+	}
+	hash := util.KubeconfigHash(secret)
+
+	// TokenRequest 는 single cluster 로의 요청이므로 cluster 의 lock 만 잡은 채로 수행한다.
+	entry := p.getTransportEntry(key)
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+
+	if ct := entry.transport; ct != nil && ct.kubeconfigHash == hash &&
+		time.Until(ct.expiration) > util.ServiceAccountTokenRefreshBefore {
+		return ct, nil
+	}
+
+	ct, err := newClusterTransport(secret)
+	if err != nil {
+		return nil, err
+	}
+	ct.kubeconfigHash = hash
+	entry.transport = ct
+	p.Log.Info("Create transport for remote cluster", "clustermanager", key)
+	return ct, nil
+}
+
+func (p *ClusterProxy) getTransportEntry(key types.NamespacedName) *transportEntry {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.transports == nil {
+		p.transports = map[types.NamespacedName]*transportEntry{}
+	}
+	entry, ok := p.transports[key]
+	if !ok {
+		entry = &transportEntry{}
+		p.transports[key] = entry
+	}
+	return entry
+}
+
+func (p *ClusterProxy) evictTransport(key types.NamespacedName) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, ok := p.transports[key]; ok {
+		delete(p.transports, key)
+		p.Log.Info("Delete transport for removed remote cluster", "clustermanager", key)
+	}
+}
+
+func newClusterTransport(secret *coreV1.Secret) (*clusterTransport, error) {
+	restConfig, err := clientcmd.RESTConfigFromKubeConfig(secret.Data["value"])
+	if err != nil {
+		return nil, err
+	}
+
+	remoteClientset, err := util.GetRemoteK8sClient(secret)
+	if err != nil {
+		return nil, err
+	}
+	token, expiration, err := util.RequestServiceAccountToken(remoteClientset, util.KubeNamespace, util.ImpersonatorServiceAccount)
+	if err != nil {
+		return nil, err
+	}
+
+	// kubeconfig 의 인증 정보는 제외하고, api-server 의 주소와 ca 만 사용한다.
+	impersonatorConfig := restclient.AnonymousClientConfig(restConfig)
+	impersonatorConfig.BearerToken = token
+	roundTripper, err := restclient.TransportFor(impersonatorConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	target, err := url.Parse(restConfig.Host)
+	if err != nil {
+		return nil, err
+	}
+
+	return &clusterTransport{
+		expiration:   expiration,
+		target:       target,
+		roundTripper: roundTripper,
+	}, nil
+}
+
+// rewriteRequest 는 요청을 single cluster 의 api-server 로 향하도록 바꾼다.
+// 사용자의 token 과 임의로 지정한 impersonate header 는 single cluster 로 전달하지 않는다.
+func rewriteRequest(out *http.Request, target *url.URL, rest string) {
+	out.URL.Scheme = target.Scheme
+	out.URL.Host = target.Host
+	out.URL.Path = strings.TrimSuffix(target.Path, "/") + rest
+	out.URL.RawPath = ""
+	out.Host = target.Host
+
+	out.Header.Del("Authorization")
+	for header := range out.Header {
+		if strings.HasPrefix(header, "Impersonate-") {
+			out.Header.Del(header)
+		}
+	}
+}
+
+// parsePath 는 /api/<namespace>/<cluster>/api/kubernetes/<rest> 형태의 path 로 부터
+// ClusterManager 와 single cluster 로 전달할 path 를 구한다.
+func parsePath(path string) (types.NamespacedName, string, bool) {
+	parts := strings.SplitN(strings.TrimPrefix(path, "/"), "/", 4)
+	if len(parts) < 4 || parts[0] != "api" || parts[1] == "" || parts[2] == "" {
+		return types.NamespacedName{}, "", false
+	}
+
+	rest := "/" + parts[3]
+	if rest != kubernetesPathSegment && !strings.HasPrefix(rest, kubernetesPathSegment+"/") {
+		return types.NamespacedName{}, "", false
+	}
+	rest = strings.TrimPrefix(rest, kubernetesPathSegment)
+	if rest == "" {
+		rest = "/"
+	}
+
+	return types.NamespacedName{Namespace: parts[1], Name: parts[2]}, rest, true
+}
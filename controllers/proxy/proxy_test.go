@@ -0,0 +1,290 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package proxy
+
+import (
+	"crypto/rsa"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+
+	clusterV1alpha1 "github.com/tmax-cloud/hypercloud-multi-operator/apis/cluster/v1alpha1"
+	"github.com/tmax-cloud/hypercloud-multi-operator/controllers/util"
+
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestParsePath(t *testing.T) {
+	tests := []struct {
+		name     string
+		path     string
+		wantKey  types.NamespacedName
+		wantRest string
+		wantOk   bool
+	}{
+		{
+			name:     "kubernetes api",
+			path:     "/api/ns/cluster/api/kubernetes/api/v1/pods",
+			wantKey:  types.NamespacedName{Namespace: "ns", Name: "cluster"},
+			wantRest: "/api/v1/pods",
+			wantOk:   true,
+		},
+		{
+			name:     "kubernetes root",
+			path:     "/api/ns/cluster/api/kubernetes",
+			wantKey:  types.NamespacedName{Namespace: "ns", Name: "cluster"},
+			wantRest: "/",
+			wantOk:   true,
+		},
+		{
+			name:   "other api",
+			path:   "/api/ns/cluster/api/prometheus/query",
+			wantOk: false,
+		},
+		{
+			name:   "api with kubernetes prefix",
+			path:   "/api/ns/cluster/api/kubernetes-dashboard/",
+			wantOk: false,
+		},
+		{
+			name:   "empty namespace",
+			path:   "/api//cluster/api/kubernetes/api",
+			wantOk: false,
+		},
+		{
+			name:   "not api path",
+			path:   "/apis/ns/cluster/api/kubernetes/api",
+			wantOk: false,
+		},
+		{
+			name:   "too short",
+			path:   "/api/ns/cluster",
+			wantOk: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, rest, ok := parsePath(tt.path)
+			if ok != tt.wantOk {
+				t.Fatalf("parsePath() ok = %v, want %v", ok, tt.wantOk)
+			}
+			if !ok {
+				return
+			}
+			if key != tt.wantKey || rest != tt.wantRest {
+				t.Errorf("parsePath() = %v, %q, want %v, %q", key, rest, tt.wantKey, tt.wantRest)
+			}
+		})
+	}
+}
+
+func TestRewriteRequest(t *testing.T) {
+	target, _ := url.Parse("https://10.0.0.1:6443/prefix/")
+	req := httptest.NewRequest(http.MethodGet, "http://multicluster.tmaxcloud.org/api/ns/cluster/api/kubernetes/api/v1/pods", nil)
+	req.Header.Set("Authorization", "Bearer user-token")
+	req.Header.Set("Impersonate-User", "system:admin")
+	req.Header.Set("Impersonate-Group", "system:masters")
+	req.Header.Set("Impersonate-Extra-Scopes", "all")
+	req.Header.Set("Accept", "application/json")
+
+	rewriteRequest(req, target, "/api/v1/pods")
+
+	if got := req.URL.String(); got != "https://10.0.0.1:6443/prefix/api/v1/pods" {
+		t.Errorf("URL = %s, want %s", got, "https://10.0.0.1:6443/prefix/api/v1/pods")
+	}
+	if req.Host != "10.0.0.1:6443" {
+		t.Errorf("Host = %s, want %s", req.Host, "10.0.0.1:6443")
+	}
+	for _, header := range []string{"Authorization", "Impersonate-User", "Impersonate-Group", "Impersonate-Extra-Scopes"} {
+		if value := req.Header.Get(header); value != "" {
+			t.Errorf("header %s = %q, want removed", header, value)
+		}
+	}
+	if req.Header.Get("Accept") != "application/json" {
+		t.Errorf("header Accept is removed")
+	}
+}
+
+// testBackend 는 proxy 가 전달한 요청의 header 를 기록하는 single cluster 의 api-server 이다.
+type testBackend struct {
+	server *httptest.Server
+	header http.Header
+	path   string
+}
+
+func newTestBackend(t *testing.T) *testBackend {
+	backend := &testBackend{}
+	backend.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		backend.header = req.Header.Clone()
+		backend.path = req.URL.Path
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(backend.server.Close)
+	return backend
+}
+
+func newTestProxy(t *testing.T, issuer *testIssuer, backend *testBackend) *ClusterProxy {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := clusterV1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	key := types.NamespacedName{Namespace: "ns", Name: "cluster"}
+	clm := &clusterV1alpha1.ClusterManager{
+		ObjectMeta: metav1.ObjectMeta{Name: key.Name, Namespace: key.Namespace},
+	}
+	secret := &coreV1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: key.Name + util.KubeconfigSuffix, Namespace: key.Namespace},
+		Data:       map[string][]byte{"value": []byte("kubeconfig")},
+	}
+	target, _ := url.Parse(backend.server.URL)
+
+	// realm 의 jwks 대신 test issuer 의 key 를 사용하고, single cluster 의 transport 는 미리 만들어 둔다.
+	issuerURL := util.GetHyperAuthIssuer(util.GetHyperAuthRealm())
+	verifier := NewTokenVerifier(issuerURL, util.GetClusterProxyAudiences())
+	verifier.keys = map[string]*rsa.PublicKey{testKeyID: &issuer.key.PublicKey}
+	verifier.lastFetched = time.Now()
+
+	return &ClusterProxy{
+		Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(clm, secret).Build(),
+		Log:    ctrl.Log.WithName("ClusterProxy"),
+		transports: map[types.NamespacedName]*transportEntry{
+			key: {
+				transport: &clusterTransport{
+					kubeconfigHash: util.KubeconfigHash(secret),
+					expiration:     time.Now().Add(24 * time.Hour),
+					target:         target,
+					roundTripper:   http.DefaultTransport,
+				},
+			},
+		},
+		verifiers: map[string]*TokenVerifier{issuerURL: verifier},
+	}
+}
+
+func TestClusterProxyServeHTTP(t *testing.T) {
+	issuer := newTestIssuer(t)
+	issuerURL := util.GetHyperAuthIssuer(util.GetHyperAuthRealm())
+	validToken := func() string {
+		claims := issuer.validClaims()
+		claims["iss"] = issuerURL
+		claims["aud"] = util.GetClusterProxyAudiences()[0]
+		claims["groups"] = []string{"developers", "system:masters"}
+		return issuer.sign(t, rs256Header(), claims)
+	}
+
+	tests := []struct {
+		name       string
+		path       string
+		header     map[string]string
+		wantStatus int
+		wantUser   string
+		wantGroups []string
+	}{
+		{
+			name:       "impersonate verified user",
+			path:       "/api/ns/cluster/api/kubernetes/api/v1/pods",
+			header:     map[string]string{"Authorization": "Bearer " + validToken()},
+			wantStatus: http.StatusOK,
+			wantUser:   "user@tmax.co.kr",
+			wantGroups: []string{"developers"},
+		},
+		{
+			name: "client supplied impersonate headers are ignored",
+			path: "/api/ns/cluster/api/kubernetes/api/v1/pods",
+			header: map[string]string{
+				"Authorization":     "Bearer " + validToken(),
+				"Impersonate-User":  "system:admin",
+				"Impersonate-Group": "system:masters",
+			},
+			wantStatus: http.StatusOK,
+			wantUser:   "user@tmax.co.kr",
+			wantGroups: []string{"developers"},
+		},
+		{
+			name:       "no token",
+			path:       "/api/ns/cluster/api/kubernetes/api/v1/pods",
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "invalid token",
+			path:       "/api/ns/cluster/api/kubernetes/api/v1/pods",
+			header:     map[string]string{"Authorization": "Bearer invalid"},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "unknown cluster",
+			path:       "/api/ns/unknown/api/kubernetes/api/v1/pods",
+			header:     map[string]string{"Authorization": "Bearer " + validToken()},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "not kubernetes api",
+			path:       "/api/ns/cluster/api/prometheus/query",
+			header:     map[string]string{"Authorization": "Bearer " + validToken()},
+			wantStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := newTestBackend(t)
+			proxy := newTestProxy(t, issuer, backend)
+
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			for key, value := range tt.header {
+				req.Header.Set(key, value)
+			}
+			recorder := httptest.NewRecorder()
+			proxy.ServeHTTP(recorder, req)
+
+			if recorder.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", recorder.Code, tt.wantStatus)
+			}
+			if tt.wantStatus != http.StatusOK {
+				if backend.header != nil {
+					t.Errorf("request is forwarded to remote cluster")
+				}
+				return
+			}
+			if backend.path != "/api/v1/pods" {
+				t.Errorf("forwarded path = %s, want /api/v1/pods", backend.path)
+			}
+			if got := backend.header.Get("Authorization"); got != "" {
+				t.Errorf("Authorization = %q, want removed", got)
+			}
+			if got := backend.header.Get("Impersonate-User"); got != tt.wantUser {
+				t.Errorf("Impersonate-User = %q, want %q", got, tt.wantUser)
+			}
+			if got := backend.header.Values("Impersonate-Group"); !reflect.DeepEqual(got, tt.wantGroups) {
+				t.Errorf("Impersonate-Group = %v, want %v", got, tt.wantGroups)
+			}
+		})
+	}
+}
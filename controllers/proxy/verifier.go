@@ -0,0 +1,275 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package proxy
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// 알 수 없는 kid 로 인해 jwks 를 다시 가져오는 최소 간격
+	jwksRefreshInterval = time.Minute
+	// exp, nbf 검증시 허용하는 시간 오차
+	clockSkew = 30 * time.Second
+	// kubernetes 가 예약한 user, group 이름의 prefix
+	systemPrefix = "system:"
+)
+
+// Claims 는 proxy 가 사용하는 HyperAuth id token 의 claim 들이다.
+type Claims struct {
+	Issuer            string   `json:"iss"`
+	Subject           string   `json:"sub"`
+	Audience          audience `json:"aud,omitempty"`
+	AuthorizedParty   string   `json:"azp,omitempty"`
+	Expiry            int64    `json:"exp"`
+	NotBefore         int64    `json:"nbf,omitempty"`
+	Email             string   `json:"email,omitempty"`
+	PreferredUsername string   `json:"preferred_username,omitempty"`
+	Group             []string `json:"group,omitempty"`
+	Groups            []string `json:"groups,omitempty"`
+}
+
+// audience 는 문자열 하나 또는 문자열 배열로 올 수 있는 aud claim 이다.
+type audience []string
+
+func (a *audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = audience{single}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	*a = list
+	return nil
+}
+
+// UserName 은 single cluster 에 impersonate 할 user 이름을 반환한다.
+// hypercloud 는 cluster owner/member 의 rolebinding subject 로 email 을 사용하므로 HyperAuth 의 preferred_username(email) 을 따른다.
+func (c *Claims) UserName() string {
+	if c.PreferredUsername != "" {
+		return c.PreferredUsername
+	}
+	return c.Email
+}
+
+// GroupNames 는 single cluster 에 impersonate 할 group 목록을 반환한다.
+// realm 의 group 이름으로 system:masters 와 같은 kubernetes 의 system group 을 얻지 못하도록 system: group 은 제외한다.
+func (c *Claims) GroupNames() []string {
+	groups := c.Groups
+	if len(groups) == 0 {
+		groups = c.Group
+	}
+
+	result := []string{}
+	for _, group := range groups {
+		if !strings.HasPrefix(group, systemPrefix) {
+			result = append(result, group)
+		}
+	}
+	return result
+}
+
+// hasAudience 는 token 이 audiences 중 하나를 위해 발급되었는지 반환한다.
+func (c *Claims) hasAudience(audiences []string) bool {
+	for _, expected := range audiences {
+		if c.AuthorizedParty == expected {
+			return true
+		}
+		for _, aud := range c.Audience {
+			if aud == expected {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// TokenVerifier 는 HyperAuth 가 발급한 RS256 jwt 를 realm 의 jwks 로 검증한다.
+// realm 의 다른 module client 를 위해 발급된 token 은 받지 않도록 aud 또는 azp 가 Audiences 중 하나여야 한다.
+type TokenVerifier struct {
+	Issuer    string
+	Audiences []string
+
+	client *http.Client
+
+	mu          sync.Mutex
+	keys        map[string]*rsa.PublicKey
+	lastFetched time.Time
+}
+
+func NewTokenVerifier(issuer string, audiences []string) *TokenVerifier {
+	return &TokenVerifier{
+		Issuer:    issuer,
+		Audiences: audiences,
+		client:    &http.Client{Timeout: 10 * time.Second},
+		keys:      map[string]*rsa.PublicKey{},
+	}
+}
+
+type jwtHeader struct {
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid"`
+}
+
+type jsonWebKey struct {
+	KeyID     string `json:"kid"`
+	KeyType   string `json:"kty"`
+	Use       string `json:"use"`
+	Modulus   string `json:"n"`
+	Exponent  string `json:"e"`
+	Algorithm string `json:"alg"`
+}
+
+// Verify 는 token 의 서명, issuer, audience, 유효기간을 검증하고 claim 들을 반환한다.
+func (v *TokenVerifier) Verify(rawToken string) (*Claims, error) {
+	parts := strings.Split(rawToken, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("malformed jwt")
+	}
+
+	header := &jwtHeader{}
+	if err := decodeSegment(parts[0], header); err != nil {
+		return nil, fmt.Errorf("failed to decode jwt header: %w", err)
+	}
+	if header.Algorithm != "RS256" {
+		return nil, fmt.Errorf("unsupported jwt algorithm %q", header.Algorithm)
+	}
+
+	key, err := v.getKey(header.KeyID)
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode jwt signature: %w", err)
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature); err != nil {
+		return nil, fmt.Errorf("invalid jwt signature: %w", err)
+	}
+
+	claims := &Claims{}
+	if err := decodeSegment(parts[1], claims); err != nil {
+		return nil, fmt.Errorf("failed to decode jwt claims: %w", err)
+	}
+
+	now := time.Now()
+	if claims.Issuer != v.Issuer {
+		return nil, fmt.Errorf("unexpected issuer %q", claims.Issuer)
+	}
+	if !claims.hasAudience(v.Audiences) {
+		return nil, fmt.Errorf("unexpected audience %q (azp %q)", []string(claims.Audience), claims.AuthorizedParty)
+	}
+	if now.After(time.Unix(claims.Expiry, 0).Add(clockSkew)) {
+		return nil, fmt.Errorf("token is expired")
+	}
+	if claims.NotBefore != 0 && now.Add(clockSkew).Before(time.Unix(claims.NotBefore, 0)) {
+		return nil, fmt.Errorf("token is not valid yet")
+	}
+	if claims.UserName() == "" {
+		return nil, fmt.Errorf("token does not have user name")
+	}
+	if strings.HasPrefix(claims.UserName(), systemPrefix) {
+		return nil, fmt.Errorf("user name %q is reserved", claims.UserName())
+	}
+
+	return claims, nil
+}
+
+// getKey 는 kid 에 해당하는 public key 를 반환한다.
+// HyperAuth 의 key rotation 에 대응하기 위해, 알 수 없는 kid 인 경우 jwks 를 다시 가져온다.
+func (v *TokenVerifier) getKey(kid string) (*rsa.PublicKey, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if key, ok := v.keys[kid]; ok {
+		return key, nil
+	}
+
+	if time.Since(v.lastFetched) < jwksRefreshInterval {
+		return nil, fmt.Errorf("unknown jwt key id %q", kid)
+	}
+	if err := v.fetchKeys(); err != nil {
+		return nil, err
+	}
+
+	if key, ok := v.keys[kid]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown jwt key id %q", kid)
+}
+
+func (v *TokenVerifier) fetchKeys() error {
+	v.lastFetched = time.Now()
+
+	resp, err := v.client.Get(v.Issuer + "/protocol/openid-connect/certs")
+	if err != nil {
+		return fmt.Errorf("failed to get jwks: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to get jwks: %s", resp.Status)
+	}
+
+	jwks := struct {
+		Keys []jsonWebKey `json:"keys"`
+	}{}
+	if err := json.NewDecoder(resp.Body).Decode(&jwks); err != nil {
+		return fmt.Errorf("failed to decode jwks: %w", err)
+	}
+
+	keys := map[string]*rsa.PublicKey{}
+	for _, jwk := range jwks.Keys {
+		if jwk.KeyType != "RSA" || (jwk.Use != "" && jwk.Use != "sig") {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(jwk.Modulus)
+		if err != nil {
+			continue
+		}
+		e, err := base64.RawURLEncoding.DecodeString(jwk.Exponent)
+		if err != nil {
+			continue
+		}
+		keys[jwk.KeyID] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+	v.keys = keys
+	return nil
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(segment, "="))
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
@@ -0,0 +1,323 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package proxy
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	testKeyID    = "test-key"
+	testAudience = "hypercloud5"
+)
+
+// testIssuer 는 jwks 를 제공하는 HyperAuth realm 을 흉내낸다.
+type testIssuer struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+}
+
+func newTestIssuer(t *testing.T) *testIssuer {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	issuer := &testIssuer{key: key}
+	issuer.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/protocol/openid-connect/certs" {
+			http.NotFound(w, req)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []interface{}{
+				map[string]string{
+					"kid": testKeyID,
+					"kty": "RSA",
+					"use": "sig",
+					"alg": "RS256",
+					"n":   base64.RawURLEncoding.EncodeToString(key.PublicKey.N.Bytes()),
+					"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.PublicKey.E)).Bytes()),
+				},
+			},
+		})
+	}))
+	t.Cleanup(issuer.server.Close)
+	return issuer
+}
+
+func (i *testIssuer) URL() string {
+	return i.server.URL
+}
+
+// validClaims 는 검증을 통과하는 claim 을 만든다.
+func (i *testIssuer) validClaims() map[string]interface{} {
+	return map[string]interface{}{
+		"iss":                i.URL(),
+		"sub":                "user-id",
+		"aud":                testAudience,
+		"exp":                time.Now().Add(time.Hour).Unix(),
+		"preferred_username": "user@tmax.co.kr",
+		"group":              []string{"developers"},
+	}
+}
+
+func (i *testIssuer) sign(t *testing.T, header map[string]interface{}, claims map[string]interface{}) string {
+	signingInput := encodeSegment(t, header) + "." + encodeSegment(t, claims)
+	digest := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, i.key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func encodeSegment(t *testing.T, v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("failed to marshal segment: %v", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func rs256Header() map[string]interface{} {
+	return map[string]interface{}{"alg": "RS256", "kid": testKeyID, "typ": "JWT"}
+}
+
+func TestTokenVerifierVerify(t *testing.T) {
+	issuer := newTestIssuer(t)
+	otherIssuer := newTestIssuer(t)
+
+	tests := []struct {
+		name    string
+		token   func() string
+		wantErr string
+	}{
+		{
+			name: "valid token",
+			token: func() string {
+				return issuer.sign(t, rs256Header(), issuer.validClaims())
+			},
+		},
+		{
+			name: "audience in array",
+			token: func() string {
+				claims := issuer.validClaims()
+				claims["aud"] = []string{"account", testAudience}
+				return issuer.sign(t, rs256Header(), claims)
+			},
+		},
+		{
+			name: "audience in azp",
+			token: func() string {
+				claims := issuer.validClaims()
+				claims["aud"] = "account"
+				claims["azp"] = testAudience
+				return issuer.sign(t, rs256Header(), claims)
+			},
+		},
+		{
+			name: "signed by other key",
+			token: func() string {
+				claims := issuer.validClaims()
+				return otherIssuer.sign(t, rs256Header(), claims)
+			},
+			wantErr: "invalid jwt signature",
+		},
+		{
+			name: "tampered claims",
+			token: func() string {
+				parts := strings.Split(issuer.sign(t, rs256Header(), issuer.validClaims()), ".")
+				claims := issuer.validClaims()
+				claims["preferred_username"] = "admin@tmax.co.kr"
+				return parts[0] + "." + encodeSegment(t, claims) + "." + parts[2]
+			},
+			wantErr: "invalid jwt signature",
+		},
+		{
+			name: "alg none",
+			token: func() string {
+				header := map[string]interface{}{"alg": "none", "kid": testKeyID}
+				return encodeSegment(t, header) + "." + encodeSegment(t, issuer.validClaims()) + "."
+			},
+			wantErr: "unsupported jwt algorithm",
+		},
+		{
+			name: "alg HS256 signed with public key",
+			token: func() string {
+				header := map[string]interface{}{"alg": "HS256", "kid": testKeyID}
+				signingInput := encodeSegment(t, header) + "." + encodeSegment(t, issuer.validClaims())
+				mac := hmac.New(sha256.New, issuer.key.PublicKey.N.Bytes())
+				mac.Write([]byte(signingInput))
+				return signingInput + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+			},
+			wantErr: "unsupported jwt algorithm",
+		},
+		{
+			name: "wrong issuer",
+			token: func() string {
+				claims := issuer.validClaims()
+				claims["iss"] = otherIssuer.URL()
+				return issuer.sign(t, rs256Header(), claims)
+			},
+			wantErr: "unexpected issuer",
+		},
+		{
+			name: "wrong audience and azp",
+			token: func() string {
+				claims := issuer.validClaims()
+				claims["aud"] = "grafana"
+				claims["azp"] = "grafana"
+				return issuer.sign(t, rs256Header(), claims)
+			},
+			wantErr: "unexpected audience",
+		},
+		{
+			name: "expired token",
+			token: func() string {
+				claims := issuer.validClaims()
+				claims["exp"] = time.Now().Add(-time.Hour).Unix()
+				return issuer.sign(t, rs256Header(), claims)
+			},
+			wantErr: "token is expired",
+		},
+		{
+			name: "nbf in the future",
+			token: func() string {
+				claims := issuer.validClaims()
+				claims["nbf"] = time.Now().Add(time.Hour).Unix()
+				return issuer.sign(t, rs256Header(), claims)
+			},
+			wantErr: "token is not valid yet",
+		},
+		{
+			name: "unknown kid",
+			token: func() string {
+				header := rs256Header()
+				header["kid"] = "unknown"
+				return issuer.sign(t, header, issuer.validClaims())
+			},
+			wantErr: "unknown jwt key id",
+		},
+		{
+			name: "system user",
+			token: func() string {
+				claims := issuer.validClaims()
+				claims["preferred_username"] = "system:admin"
+				return issuer.sign(t, rs256Header(), claims)
+			},
+			wantErr: "is reserved",
+		},
+		{
+			name: "no user name",
+			token: func() string {
+				claims := issuer.validClaims()
+				delete(claims, "preferred_username")
+				return issuer.sign(t, rs256Header(), claims)
+			},
+			wantErr: "does not have user name",
+		},
+		{
+			name: "malformed token",
+			token: func() string {
+				return "not-a-jwt"
+			},
+			wantErr: "malformed jwt",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verifier := NewTokenVerifier(issuer.URL(), []string{testAudience})
+			claims, err := verifier.Verify(tt.token())
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Verify() error = %v, want nil", err)
+				}
+				if claims.UserName() != "user@tmax.co.kr" {
+					t.Errorf("UserName() = %q, want %q", claims.UserName(), "user@tmax.co.kr")
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Verify() error = %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTokenVerifierRefetchInterval(t *testing.T) {
+	issuer := newTestIssuer(t)
+	verifier := NewTokenVerifier(issuer.URL(), []string{testAudience})
+
+	header := rs256Header()
+	header["kid"] = "unknown"
+	if _, err := verifier.Verify(issuer.sign(t, header, issuer.validClaims())); err == nil {
+		t.Fatalf("Verify() error = nil, want unknown kid error")
+	}
+	// 알 수 없는 kid 로 jwks 를 가져온 직후에는 다시 가져오지 않지만, 이미 가져온 key 는 사용할 수 있다.
+	if _, err := verifier.Verify(issuer.sign(t, rs256Header(), issuer.validClaims())); err != nil {
+		t.Fatalf("Verify() error = %v, want nil", err)
+	}
+}
+
+func TestClaimsGroupNames(t *testing.T) {
+	tests := []struct {
+		name   string
+		claims Claims
+		want   []string
+	}{
+		{
+			name:   "groups claim is preferred",
+			claims: Claims{Group: []string{"a"}, Groups: []string{"b"}},
+			want:   []string{"b"},
+		},
+		{
+			name:   "group claim is used without groups claim",
+			claims: Claims{Group: []string{"a"}},
+			want:   []string{"a"},
+		},
+		{
+			name:   "system groups are removed",
+			claims: Claims{Groups: []string{"system:masters", "developers", "system:authenticated"}},
+			want:   []string{"developers"},
+		},
+		{
+			name:   "no group",
+			claims: Claims{},
+			want:   []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.claims.GroupNames(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GroupNames() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
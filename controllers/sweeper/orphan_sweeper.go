@@ -0,0 +1,449 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sweeper
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	argocdV1alpha1 "github.com/argoproj/argo-cd/v2/pkg/apis/application/v1alpha1"
+	"github.com/go-logr/logr"
+	clusterV1alpha1 "github.com/tmax-cloud/hypercloud-multi-operator/apis/cluster/v1alpha1"
+	hyperauthCaller "github.com/tmax-cloud/hypercloud-multi-operator/controllers/hyperAuth"
+	"github.com/tmax-cloud/hypercloud-multi-operator/controllers/util"
+
+	coreV1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+type Mode string
+
+const (
+	// ModeDisabled 는 sweeper 를 동작시키지 않는다.
+	ModeDisabled Mode = "disabled"
+	// ModeDryRun 은 orphan 리소스를 찾아 로그로 보고만 하고 삭제하지 않는다.
+	ModeDryRun Mode = "dry-run"
+	// ModeEnabled 는 grace period 동안 orphan 상태가 유지된 리소스를 삭제한다.
+	ModeEnabled Mode = "enabled"
+)
+
+const (
+	kindHyperAuthClient = "HyperAuthClient"
+	kindHyperAuthGroup  = "HyperAuthGroup"
+	kindArgoSecret      = "ArgoClusterSecret"
+	kindApplication     = "Application"
+)
+
+// ParseMode 는 flag 로 전달된 mode 를 검증한다.
+func ParseMode(mode string) (Mode, error) {
+	switch Mode(mode) {
+	case ModeDisabled, ModeDryRun, ModeEnabled:
+		return Mode(mode), nil
+	}
+	return "", fmt.Errorf("unknown orphan sweeper mode %q", mode)
+}
+
+// OrphanSweeper 는 ClusterManager 가 강제로 제거되었거나 삭제 과정이 중간에 실패하여 남겨진 리소스를 주기적으로 찾는다.
+// HyperAuth client/group, argocd cluster secret, argocd application 을 이름과 label 규칙으로 찾고,
+// 해당하는 ClusterManager 가 없는 경우 orphan 으로 보고한다.
+// ModeEnabled 인 경우, GracePeriod 동안 계속 orphan 으로 확인된 리소스를 삭제한다.
+type OrphanSweeper struct {
+	client.Client
+	Log         logr.Logger
+	Mode        Mode
+	Interval    time.Duration
+	GracePeriod time.Duration
+
+	// HyperAuth module configmap 을 읽는 reader. manager 의 cache 에는 module configmap 이 없다.
+	ModuleReader client.Reader
+
+	// orphan 으로 처음 확인된 시각. leader 가 바뀌면 다시 grace period 를 기다린다.
+	firstSeen map[string]time.Time
+}
+
+// orphan 은 sweeper 가 찾은 orphan 리소스 하나를 나타낸다.
+type orphan struct {
+	kind      string
+	namespace string
+	name      string
+	// orphan 을 판단한 ClusterManager 의 prefix(<namespace>-<name>)
+	prefix string
+	delete func() error
+}
+
+func (o *orphan) key() string {
+	return o.kind + "/" + o.namespace + "/" + o.name
+}
+
+// Start 는 manager 가 시작될 때 호출되며, ctx 가 끝날 때 까지 주기적으로 orphan 리소스를 정리한다.
+// leader 만 삭제를 수행하도록 leader election 이 필요한 runnable 로 동작한다.
+func (s *OrphanSweeper) Start(ctx context.Context) error {
+	s.Log.Info("Starting orphan sweeper", "mode", s.Mode, "interval", s.Interval, "gracePeriod", s.GracePeriod)
+	s.firstSeen = map[string]time.Time{}
+	wait.UntilWithContext(ctx, s.sweep, s.Interval)
+	return nil
+}
+
+func (s *OrphanSweeper) sweep(ctx context.Context) {
+	clmList := &clusterV1alpha1.ClusterManagerList{}
+	if err := s.List(ctx, clmList); err != nil {
+		s.Log.Error(err, "Failed to list ClusterManagers")
+		return
+	}
+	prefixes := map[string]bool{}
+	realms := map[string]bool{util.GetHyperAuthRealm(): true}
+	for _, clm := range clmList.Items {
+		prefixes[clm.GetNamespacedPrefix()] = true
+		if clm.Status.HyperAuth != nil && clm.Status.HyperAuth.Realm != "" {
+			realms[clm.Status.HyperAuth.Realm] = true
+		}
+	}
+
+	orphans := []*orphan{}
+	found, remainingApps, err := s.findApplications(ctx, prefixes)
+	if err != nil {
+		s.Log.Error(err, "Failed to find orphan Applications")
+		return
+	}
+	orphans = append(orphans, found...)
+
+	found, err = s.findArgoSecrets(ctx, prefixes, remainingApps)
+	if err != nil {
+		s.Log.Error(err, "Failed to find orphan argocd cluster secrets")
+		return
+	}
+	orphans = append(orphans, found...)
+
+	found, err = s.findHyperAuthResources(ctx, prefixes, realms)
+	if err != nil {
+		// HyperAuth 에 접근할 수 없더라도 k8s 리소스는 정리한다.
+		s.Log.Error(err, "Failed to find orphan HyperAuth resources")
+	}
+	orphans = append(orphans, found...)
+
+	now := time.Now()
+	seen := map[string]time.Time{}
+	deleted := 0
+	for _, o := range orphans {
+		log := s.Log.WithValues("kind", o.kind, "namespace", o.namespace, "name", o.name, "clusterPrefix", o.prefix)
+
+		firstSeen, ok := s.firstSeen[o.key()]
+		if !ok {
+			firstSeen = now
+		}
+		seen[o.key()] = firstSeen
+		orphanedFor := now.Sub(firstSeen).Round(time.Second)
+
+		if s.Mode != ModeEnabled || orphanedFor < s.GracePeriod {
+			log.Info("Found orphan resource", "orphanedFor", orphanedFor.String(), "deleteAfter", s.GracePeriod.String(), "dryRun", s.Mode != ModeEnabled)
+			continue
+		}
+
+		if err := o.delete(); err != nil {
+			log.Error(err, "Failed to delete orphan resource")
+			continue
+		}
+		delete(seen, o.key())
+		deleted++
+		log.Info("Delete orphan resource successfully", "orphanedFor", orphanedFor.String())
+	}
+	// 더 이상 orphan 이 아니거나 삭제된 리소스는 잊는다.
+	s.firstSeen = seen
+
+	s.Log.Info("Orphan sweep finished", "orphans", len(orphans), "deleted", deleted)
+}
+
+// findApplications 는 대상 cluster 의 ClusterManager 가 없는 app-of-apps application 을 찾는다.
+// argocd cluster secret 은 application 이 모두 삭제된 이후에 삭제해야 하므로, application 이 남아 있는 prefix 들도 반환한다.
+func (s *OrphanSweeper) findApplications(ctx context.Context, prefixes map[string]bool) ([]*orphan, map[string]bool, error) {
+	appList := &argocdV1alpha1.ApplicationList{}
+	if err := s.List(ctx, appList, client.InNamespace(util.ArgoNamespace), client.HasLabels{util.LabelKeyArgoTargetCluster}); err != nil {
+		return nil, nil, err
+	}
+
+	orphans := []*orphan{}
+	remainingApps := map[string]bool{}
+	for i := range appList.Items {
+		app := &appList.Items[i]
+		prefix := app.Labels[util.LabelKeyArgoTargetCluster]
+		remainingApps[prefix] = true
+		if prefixes[prefix] || !app.GetDeletionTimestamp().IsZero() {
+			continue
+		}
+		// cluster label 은 다른 용도로도 사용될 수 있으므로, operator 가 만든 app-of-apps application 만 대상으로 한다.
+		// 하위 application 들은 app-of-apps application 이 삭제될 때 함께 삭제된다.
+		_, hasClmLabel := app.Labels[clusterV1alpha1.LabelKeyClmName]
+		if !hasClmLabel && app.Name != prefix+"-applications" {
+			continue
+		}
+		orphans = append(orphans, &orphan{
+			kind:      kindApplication,
+			namespace: app.Namespace,
+			name:      app.Name,
+			prefix:    prefix,
+			delete: func() error {
+				return client.IgnoreNotFound(s.Delete(ctx, app))
+			},
+		})
+	}
+	return orphans, remainingApps, nil
+}
+
+// findArgoSecrets 는 operator 가 만든 argocd cluster secret 중 ClusterManager 가 없는 secret 을 찾는다.
+// label 로 ClusterManager 를 알 수 없는 이전 버전의 secret 은 kubeconfig secret 에서 참조하지 않는 경우 orphan 으로 본다.
+func (s *OrphanSweeper) findArgoSecrets(ctx context.Context, prefixes map[string]bool, remainingApps map[string]bool) ([]*orphan, error) {
+	secretList := &coreV1.SecretList{}
+	opts := []client.ListOption{
+		client.InNamespace(util.ArgoNamespace),
+		client.MatchingLabels{util.LabelKeyClmSecretType: util.ClmSecretTypeArgo},
+	}
+	if err := s.List(ctx, secretList, opts...); err != nil {
+		return nil, err
+	}
+
+	referenced, err := s.getReferencedArgoSecrets(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	orphans := []*orphan{}
+	for i := range secretList.Items {
+		secret := &secretList.Items[i]
+		if !strings.HasPrefix(secret.Name, "cluster-") {
+			continue
+		}
+
+		prefix := ""
+		clmName := secret.Labels[clusterV1alpha1.LabelKeyClmName]
+		clmNamespace := secret.Labels[clusterV1alpha1.LabelKeyClmNamespace]
+		if clmName != "" && clmNamespace != "" {
+			prefix = clmNamespace + "-" + clmName
+			if prefixes[prefix] {
+				continue
+			}
+		} else if referenced[secret.Name] {
+			continue
+		}
+		// application 의 finalizer 가 cluster secret 을 사용하므로, application 이 먼저 삭제되기를 기다린다.
+		if prefix != "" && remainingApps[prefix] {
+			continue
+		}
+
+		orphans = append(orphans, &orphan{
+			kind:      kindArgoSecret,
+			namespace: secret.Namespace,
+			name:      secret.Name,
+			prefix:    prefix,
+			delete: func() error {
+				return s.deleteArgoSecret(ctx, secret)
+			},
+		})
+	}
+	return orphans, nil
+}
+
+// getReferencedArgoSecrets 는 kubeconfig secret 들이 참조하는 argocd cluster secret 의 이름들을 반환한다.
+func (s *OrphanSweeper) getReferencedArgoSecrets(ctx context.Context) (map[string]bool, error) {
+	kubeconfigSecretList := &coreV1.SecretList{}
+	if err := s.List(ctx, kubeconfigSecretList, client.MatchingLabels{util.LabelKeyClmSecretType: util.ClmSecretTypeKubeconfig}); err != nil {
+		return nil, err
+	}
+
+	referenced := map[string]bool{}
+	for _, secret := range kubeconfigSecretList.Items {
+		if name := secret.Annotations[util.AnnotationKeyArgoClusterSecret]; name != "" {
+			referenced[name] = true
+		}
+	}
+	return referenced, nil
+}
+
+// deleteArgoSecret 은 argocd cluster secret 을 삭제한다.
+// secret controller 는 ClusterManager 가 없으면 finalizer 를 제거하지 않으므로 직접 제거한다.
+func (s *OrphanSweeper) deleteArgoSecret(ctx context.Context, secret *coreV1.Secret) error {
+	if controllerutil.ContainsFinalizer(secret, clusterV1alpha1.ClusterManagerFinalizer) {
+		controllerutil.RemoveFinalizer(secret, clusterV1alpha1.ClusterManagerFinalizer)
+		if err := s.Update(ctx, secret); err != nil {
+			return client.IgnoreNotFound(err)
+		}
+	}
+	return client.IgnoreNotFound(s.Delete(ctx, secret))
+}
+
+// findHyperAuthResources 는 <namespace>-<cluster>-<module> 형태의 이름을 가지는 HyperAuth client 와 group 중
+// ClusterManager 가 없는 것들을 찾는다.
+// 기본 realm 과 ClusterManager 들이 사용하는 realm 외에 namespace 의 annotation 으로 지정한 realm 도 찾는다.
+func (s *OrphanSweeper) findHyperAuthResources(ctx context.Context, prefixes map[string]bool, realms map[string]bool) ([]*orphan, error) {
+	key := types.NamespacedName{
+		Name:      "passwords",
+		Namespace: "hyperauth",
+	}
+	secret := &coreV1.Secret{}
+	if err := s.Get(ctx, key, secret); errors.IsNotFound(err) {
+		s.Log.Info("HyperAuth password secret is not found. Skip HyperAuth resources")
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	namespaceList := &coreV1.NamespaceList{}
+	if err := s.List(ctx, namespaceList); err != nil {
+		return nil, err
+	}
+	for _, namespace := range namespaceList.Items {
+		realm := namespace.Annotations[clusterV1alpha1.AnnotationKeyClmHyperAuthRealm]
+		if realm != "" && util.ValidateHyperAuthRealm(realm) == nil {
+			realms[realm] = true
+		}
+	}
+
+	// module 의 template 에 prefix 를 넣지 않으면 "-<module>" 형태의 suffix 를 얻을 수 있다.
+	modules, err := hyperauthCaller.LoadModules(ctx, s.ModuleReader)
+	if err != nil {
+		return nil, err
+	}
+	preset, err := hyperauthCaller.NewDesiredState(modules, hyperauthCaller.ModuleTemplateParams{}, "")
+	if err != nil {
+		return nil, err
+	}
+	clientSuffixes := filterModuleSuffixes(preset.ClientIds())
+	groupSuffixes := filterModuleSuffixes(preset.GroupNames())
+
+	orphans := []*orphan{}
+	for realm := range realms {
+		found, err := s.findHyperAuthResourcesInRealm(ctx, hyperauthCaller.GetHyperAuthClient(secret).ForRealm(realm), prefixes, clientSuffixes, groupSuffixes)
+		orphans = append(orphans, found...)
+		if err != nil {
+			return orphans, err
+		}
+	}
+
+	return orphans, nil
+}
+
+// findHyperAuthResourcesInRealm 은 realm 에서 ClusterManager 가 없는 HyperAuth client 와 group 을 찾는다.
+// realm 마다 같은 이름의 리소스가 있을 수 있으므로 orphan 의 namespace 로 realm 을 사용한다.
+// 이름이 같은 다른 client 를 지우지 않도록 operator 가 만든 것으로 표시된 client 만 찾고,
+// group 은 attribute 로 구분할 수 없으므로 찾은 client 와 prefix 가 같은 것만 찾는다.
+func (s *OrphanSweeper) findHyperAuthResourcesInRealm(ctx context.Context, hyperAuthClient *hyperauthCaller.HyperAuthClient, prefixes map[string]bool, clientSuffixes []string, groupSuffixes []string) ([]*orphan, error) {
+	realm := hyperAuthClient.Realm()
+
+	orphans := []*orphan{}
+	clients, err := hyperAuthClient.ListClients(ctx)
+	if err != nil {
+		return nil, err
+	}
+	orphanPrefixes := map[string]bool{}
+	for _, config := range clients {
+		if !hyperauthCaller.IsManagedClient(config) {
+			continue
+		}
+		candidates := trimClusterSuffix(config.ClientId, clientSuffixes)
+		if len(candidates) == 0 || containsAny(prefixes, candidates) {
+			continue
+		}
+		for _, candidate := range candidates {
+			orphanPrefixes[candidate] = true
+		}
+		prefix := candidates[0]
+		clientId := config.ClientId
+		orphans = append(orphans, &orphan{
+			kind:      kindHyperAuthClient,
+			namespace: realm,
+			name:      clientId,
+			prefix:    prefix,
+			delete: func() error {
+				return hyperAuthClient.DeleteClient(ctx, hyperauthCaller.ClientConfig{ClientId: clientId})
+			},
+		})
+	}
+
+	groups, err := hyperAuthClient.ListGroups(ctx)
+	if err != nil {
+		return orphans, err
+	}
+	for _, config := range groups {
+		candidates := trimClusterSuffix(config.Name, groupSuffixes)
+		if len(candidates) == 0 || containsAny(prefixes, candidates) || !containsAny(orphanPrefixes, candidates) {
+			continue
+		}
+		prefix := candidates[0]
+		name := config.Name
+		orphans = append(orphans, &orphan{
+			kind:      kindHyperAuthGroup,
+			namespace: realm,
+			name:      name,
+			prefix:    prefix,
+			delete: func() error {
+				return hyperAuthClient.DeleteGroup(ctx, hyperauthCaller.GroupConfig{Name: name})
+			},
+		})
+	}
+
+	return orphans, nil
+}
+
+// filterModuleSuffixes 는 "{{ .Prefix }}-<module>" 형태의 template 으로 만든 이름의 suffix 만 반환한다.
+// 다른 형태의 이름은 ClusterManager 를 구분할 수 없으므로 orphan 으로 판단하지 않는다.
+func filterModuleSuffixes(names []string) []string {
+	suffixes := []string{}
+	for _, name := range names {
+		if len(name) > 1 && strings.HasPrefix(name, "-") {
+			suffixes = append(suffixes, name)
+		}
+	}
+	return suffixes
+}
+
+// trimClusterSuffix 는 HyperAuth 리소스 이름에서 module suffix 를 제거하여 ClusterManager 의 prefix 가 될 수 있는 값들을 구한다.
+// module 이름에도 '-' 가 있을 수 있으므로(ex. "a-b-c-d" 는 "a-b" 의 "-c-d" 이거나 "a-b-c" 의 "-d"),
+// 일치하는 모든 suffix 에 대해 구하며, 긴 suffix 로 구한 prefix 부터 반환한다.
+// prefix 는 <namespace>-<cluster> 형태이므로, '-' 를 포함하지 않는 경우 hub cluster 의 리소스로 보고 제외한다.
+func trimClusterSuffix(name string, suffixes []string) []string {
+	candidates := []string{}
+	for _, suffix := range suffixes {
+		if !strings.HasSuffix(name, suffix) {
+			continue
+		}
+		prefix := strings.TrimSuffix(name, suffix)
+		if !strings.Contains(prefix, "-") {
+			continue
+		}
+		candidates = append(candidates, prefix)
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return len(candidates[i]) < len(candidates[j])
+	})
+	return candidates
+}
+
+// containsAny 는 prefix 후보 중 하나라도 set 에 있는지 확인한다.
+// 이름만으로 ClusterManager 를 구분할 수 없는 경우 사용중인 리소스를 지우지 않도록 사용한다.
+func containsAny(set map[string]bool, candidates []string) bool {
+	for _, candidate := range candidates {
+		if set[candidate] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,272 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sweeper
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	argocdV1alpha1 "github.com/argoproj/argo-cd/v2/pkg/apis/application/v1alpha1"
+	clusterV1alpha1 "github.com/tmax-cloud/hypercloud-multi-operator/apis/cluster/v1alpha1"
+	"github.com/tmax-cloud/hypercloud-multi-operator/controllers/util"
+
+	coreV1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestFilterModuleSuffixes(t *testing.T) {
+	got := filterModuleSuffixes([]string{"-grafana", "-kube-prometheus", "hyperregistry", "-", "", "prefix-kiali"})
+	want := []string{"-grafana", "-kube-prometheus"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("filterModuleSuffixes() = %v, want %v", got, want)
+	}
+}
+
+func TestTrimClusterSuffix(t *testing.T) {
+	tests := []struct {
+		name     string
+		resource string
+		suffixes []string
+		want     []string
+	}{
+		{
+			name:     "module suffix",
+			resource: "ns-cluster-grafana",
+			suffixes: []string{"-grafana", "-kiali"},
+			want:     []string{"ns-cluster"},
+		},
+		{
+			name:     "hyphenated namespace and cluster",
+			resource: "team-a-my-cluster-grafana",
+			suffixes: []string{"-grafana"},
+			want:     []string{"team-a-my-cluster"},
+		},
+		{
+			name:     "hyphenated module",
+			resource: "ns-cluster-kube-prometheus",
+			suffixes: []string{"-kube-prometheus"},
+			want:     []string{"ns-cluster"},
+		},
+		{
+			name:     "ambiguous suffix",
+			resource: "a-b-c-d",
+			suffixes: []string{"-d", "-c-d"},
+			want:     []string{"a-b", "a-b-c"},
+		},
+		{
+			name:     "resource of hub cluster",
+			resource: "hub-grafana",
+			suffixes: []string{"-grafana"},
+			want:     []string{},
+		},
+		{
+			name:     "unknown module",
+			resource: "ns-cluster-unknown",
+			suffixes: []string{"-grafana"},
+			want:     []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := trimClusterSuffix(tt.resource, tt.suffixes); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("trimClusterSuffix() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestContainsAny(t *testing.T) {
+	// "a-b-c-d" 는 "a-b-c" cluster 의 "-d" module 일 수 있으므로, "a-b" cluster 가 없더라도 orphan 이 아니다.
+	prefixes := map[string]bool{"a-b-c": true}
+	if !containsAny(prefixes, trimClusterSuffix("a-b-c-d", []string{"-d", "-c-d"})) {
+		t.Errorf("containsAny() = false, want true for ambiguous resource of existing cluster")
+	}
+	if containsAny(prefixes, trimClusterSuffix("x-y-c-d", []string{"-d", "-c-d"})) {
+		t.Errorf("containsAny() = true, want false for resource without cluster")
+	}
+}
+
+func newTestScheme(t *testing.T) *runtime.Scheme {
+	scheme := runtime.NewScheme()
+	for _, addToScheme := range []func(*runtime.Scheme) error{
+		clientgoscheme.AddToScheme,
+		clusterV1alpha1.AddToScheme,
+		argocdV1alpha1.AddToScheme,
+	} {
+		if err := addToScheme(scheme); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return scheme
+}
+
+func newArgoSecret(name string, labels map[string]string) *coreV1.Secret {
+	secretLabels := map[string]string{util.LabelKeyClmSecretType: util.ClmSecretTypeArgo}
+	for k, v := range labels {
+		secretLabels[k] = v
+	}
+	return &coreV1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: util.ArgoNamespace, Labels: secretLabels},
+	}
+}
+
+func clmLabels(namespace, name string) map[string]string {
+	return map[string]string{
+		clusterV1alpha1.LabelKeyClmName:      name,
+		clusterV1alpha1.LabelKeyClmNamespace: namespace,
+	}
+}
+
+func TestFindArgoSecrets(t *testing.T) {
+	kubeconfigSecret := &coreV1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "legacy-kubeconfig",
+			Namespace:   "ns",
+			Labels:      map[string]string{util.LabelKeyClmSecretType: util.ClmSecretTypeKubeconfig},
+			Annotations: map[string]string{util.AnnotationKeyArgoClusterSecret: "cluster-referenced"},
+		},
+	}
+	objects := []client.Object{
+		kubeconfigSecret,
+		newArgoSecret("cluster-live", clmLabels("team-a", "my-cluster")),
+		newArgoSecret("cluster-orphan", clmLabels("team-a", "old-cluster")),
+		newArgoSecret("cluster-waiting", clmLabels("ns", "deleting")),
+		newArgoSecret("cluster-referenced", nil),
+		newArgoSecret("cluster-unreferenced", nil),
+		newArgoSecret("repo-orphan", clmLabels("ns", "gone")),
+	}
+	s := &OrphanSweeper{
+		Client: fake.NewClientBuilder().WithScheme(newTestScheme(t)).WithObjects(objects...).Build(),
+		Log:    ctrl.Log.WithName("OrphanSweeper"),
+	}
+
+	prefixes := map[string]bool{"team-a-my-cluster": true}
+	remainingApps := map[string]bool{"ns-deleting": true}
+	orphans, err := s.findArgoSecrets(context.Background(), prefixes, remainingApps)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := map[string]string{}
+	for _, o := range orphans {
+		got[o.name] = o.prefix
+	}
+	want := map[string]string{
+		"cluster-orphan":       "team-a-old-cluster",
+		"cluster-unreferenced": "",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("findArgoSecrets() = %v, want %v", got, want)
+	}
+}
+
+func TestSweepGracePeriod(t *testing.T) {
+	application := &argocdV1alpha1.Application{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "ns-gone-applications",
+			Namespace: util.ArgoNamespace,
+			Labels:    map[string]string{util.LabelKeyArgoTargetCluster: "ns-gone"},
+		},
+	}
+	key := types.NamespacedName{Name: application.Name, Namespace: application.Namespace}
+	appKey := (&orphan{kind: kindApplication, namespace: key.Namespace, name: key.Name}).key()
+
+	newSweeper := func(mode Mode) *OrphanSweeper {
+		return &OrphanSweeper{
+			Client:      fake.NewClientBuilder().WithScheme(newTestScheme(t)).WithObjects(application.DeepCopy()).Build(),
+			Log:         ctrl.Log.WithName("OrphanSweeper"),
+			Mode:        mode,
+			GracePeriod: time.Hour,
+			firstSeen:   map[string]time.Time{},
+		}
+	}
+	exists := func(s *OrphanSweeper) bool {
+		err := s.Get(context.Background(), key, &argocdV1alpha1.Application{})
+		if err != nil && !errors.IsNotFound(err) {
+			t.Fatal(err)
+		}
+		return err == nil
+	}
+
+	t.Run("first seen is kept across sweeps", func(t *testing.T) {
+		s := newSweeper(ModeEnabled)
+		s.sweep(context.Background())
+		firstSeen, ok := s.firstSeen[appKey]
+		if !ok || !exists(s) {
+			t.Fatalf("orphan must be recorded and kept during grace period")
+		}
+		s.sweep(context.Background())
+		if !s.firstSeen[appKey].Equal(firstSeen) || !exists(s) {
+			t.Errorf("first seen time is reset or orphan is deleted before grace period")
+		}
+	})
+
+	t.Run("delete after grace period", func(t *testing.T) {
+		s := newSweeper(ModeEnabled)
+		s.sweep(context.Background())
+		s.firstSeen[appKey] = time.Now().Add(-2 * time.Hour)
+		s.sweep(context.Background())
+		if exists(s) {
+			t.Errorf("orphan is not deleted after grace period")
+		}
+		if _, ok := s.firstSeen[appKey]; ok {
+			t.Errorf("deleted orphan is not forgotten")
+		}
+	})
+
+	t.Run("dry run does not delete", func(t *testing.T) {
+		s := newSweeper(ModeDryRun)
+		s.sweep(context.Background())
+		s.firstSeen[appKey] = time.Now().Add(-2 * time.Hour)
+		s.sweep(context.Background())
+		if !exists(s) {
+			t.Errorf("orphan is deleted in dry run mode")
+		}
+	})
+
+	t.Run("forget resource that is no longer orphan", func(t *testing.T) {
+		s := newSweeper(ModeEnabled)
+		s.sweep(context.Background())
+		s.firstSeen[appKey] = time.Now().Add(-2 * time.Hour)
+
+		clm := &clusterV1alpha1.ClusterManager{ObjectMeta: metav1.ObjectMeta{Name: "gone", Namespace: "ns"}}
+		if err := s.Create(context.Background(), clm); err != nil {
+			t.Fatal(err)
+		}
+		s.sweep(context.Background())
+		if _, ok := s.firstSeen[appKey]; ok || !exists(s) {
+			t.Fatalf("resource of existing cluster must be forgotten and kept")
+		}
+
+		// cluster 가 다시 삭제되면 grace period 를 처음부터 기다린다.
+		if err := s.Delete(context.Background(), clm); err != nil {
+			t.Fatal(err)
+		}
+		s.sweep(context.Background())
+		if !exists(s) {
+			t.Errorf("orphan is deleted without waiting grace period again")
+		}
+	})
+}
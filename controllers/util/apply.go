@@ -0,0 +1,37 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// IsModifiedByOthers 는 object 를 마지막으로 수정한 field manager 가 operator 가 아닌지 반환한다.
+// status subresource 의 변경(ex. cert-manager 의 certificate status 갱신)은 제외한다.
+// operator 는 apply 외의 방법으로 수정하는 경우에도 FieldOwner 를 field manager 로 사용해야 한다.
+// operator 가 server-side apply 하기 전에 만들어진 object 도 true 를 반환한다.
+func IsModifiedByOthers(obj metav1.Object) bool {
+	entries := obj.GetManagedFields()
+	var latest *metav1.ManagedFieldsEntry
+	for i := range entries {
+		if entries[i].Subresource == "status" || entries[i].Time == nil {
+			continue
+		}
+		if latest == nil || !entries[i].Time.Before(latest.Time) {
+			latest = &entries[i]
+		}
+	}
+	return latest != nil && latest.Manager != FieldOwner
+}
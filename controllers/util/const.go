@@ -14,6 +14,8 @@ limitations under the License.
 
 package util
 
+import "time"
+
 const (
 	KubeNamespace          = "kube-system"
 	ApiGatewayNamespace    = "api-gateway-system"
@@ -21,6 +23,13 @@ const (
 	ArgoNamespace          = "argocd"
 	HyperregistryNamespace = "hyperregistry"
 	OpenSearchNamespace    = "kube-logging"
+	MonitoringNamespace    = "monitoring"
+	IstioNamespace         = "istio-system"
+)
+
+const (
+	// hub cluster 의 리소스를 server-side apply 할 때 사용하는 field manager
+	FieldOwner = "hypercloud-multi-operator"
 )
 
 const (
@@ -30,8 +39,16 @@ const (
 	IngressNginxName = "ingress-nginx-controller"
 )
 
+const (
+	// single cluster 의 gateway 가 사용하는 serving certificate 의 secret
+	ApiGatewayTLSSecret = "gateway-tls"
+	SecretKeyCA         = "ca.crt"
+)
+
 const (
 	KubeconfigSuffix = "-kubeconfig"
+	// cluster 의 HyperAuth client 들의 secret 을 보관하는 secret 이름의 suffix
+	HyperAuthClientSecretSuffix = "-hyperauth-clients"
 	// HypercloudIngressClass          = "tmax-cloud"
 	// HypercloudMultiIngressClass     = "multicluster"
 	// HypercloudMultiIngressSubdomain = "multicluster"
@@ -51,6 +68,8 @@ const (
 	ClmSecretTypeKubeconfig = "kubeconfig"
 	ClmSecretTypeArgo       = "argocd"
 	ClmSecretTypeSAToken    = "token"
+	// cluster 의 HyperAuth client 들의 secret 을 보관하는 secret
+	ClmSecretTypeHyperAuthClient = "hyperauth-client"
 )
 
 const (
@@ -60,9 +79,38 @@ const (
 	ArgoClusterRole               = "argocd-manager-role"
 	ArgoClusterRoleBinding        = "argocd-manager-role-binding"
 	ArgoSecretTypeCluster         = "cluster"
-	ArgoSecretTypeGit             = "repo"
+	ArgoSecretTypeRepository      = "repository"
 	ArgoAppTypeAppOfApp           = "app-of-apps"
+	ArgoAppOfAppsProjectSuffix    = "-app-of-apps"
 	ArgoIngressName               = "argocd-server-ingress"
+	// cluster 별 AppProject 의 role 이름. hypercloud 의 cluster member role 과 같다.
+	ArgoProjectRoleAdmin     = "admin"
+	ArgoProjectRoleDeveloper = "developer"
+	ArgoProjectRoleGuest     = "guest"
+	// argocd application 의 health 상태와 operation 결과
+	ArgoHealthStatusHealthy  = "Healthy"
+	ArgoHealthStatusDegraded = "Degraded"
+	ArgoHealthStatusMissing  = "Missing"
+	ArgoOperationPhaseFailed = "Failed"
+	ArgoOperationPhaseError  = "Error"
+	// sync-now annotation 으로 시작한 sync operation 의 사용자 이름
+	ArgoOperationInitiator = "hypercloud-multi-operator"
+	// app-of-apps 의 repository 를 연동하는 argocd repository secret 이름의 suffix
+	ArgoRepositorySecretSuffix = "-repository"
+	// argocd repository secret 의 type
+	ArgoRepositoryTypeGit  = "git"
+	ArgoRepositoryTypeHelm = "helm"
+)
+
+const (
+	// cluster proxy 가 사용자를 impersonate 하기 위해 사용하는 single cluster 의 service account
+	ImpersonatorServiceAccount     = "hypercloud-multi-impersonator"
+	ImpersonatorClusterRole        = "hypercloud-multi-impersonator-role"
+	ImpersonatorClusterRoleBinding = "hypercloud-multi-impersonator-role-binding"
+
+	// cluster proxy 의 service 주소 (CLUSTER_PROXY_HOST 환경변수로 변경할 수 있다.)
+	DefaultClusterProxyHost = "hypercloud-multi-operator-cluster-proxy-service.hypercloud5-system.svc"
+	ClusterProxyPort        = 8090
 )
 
 const (
@@ -72,6 +120,19 @@ const (
 	AnnotationKeyArgoClusterSecret = "argocd.argoproj.io/cluster.secret"
 	AnnotationKeyArgoManagedBy     = "managed-by"
 
+	// 마지막으로 반영한 kubeconfig 의 hash 값. kubeconfig 가 바뀌었는지 판단하기 위해 사용한다.
+	AnnotationKeyKubeconfigHash = "cluster.tmax.io/kubeconfig-hash"
+	// ingress 와 certificate 에 반영된 설정의 hash 값
+	AnnotationKeyIngressConfigHash = "cluster.tmax.io/ingress-config-hash"
+	// single cluster 의 api 들을 노출하기 위해 사용한 backend
+	AnnotationKeyExposureBackend = "cluster.tmax.io/exposure-backend"
+	// gateway service 에 반영된 single cluster 의 gateway 주소 목록
+	AnnotationKeyGatewayAddresses = "cluster.tmax.io/gateway-addresses"
+	// TokenRequest 로 발급받은 service account token 의 만료 시간 (RFC3339)
+	AnnotationKeyTokenExpiration = "cluster.tmax.io/token-expiration"
+	// argocd cluster secret 에 반영된 인증 방식
+	AnnotationKeyArgoClusterAuthMode = "cluster.tmax.io/argo-cluster-auth-mode"
+
 	AnnotationKeyTraefikServerTransport = "traefik.ingress.kubernetes.io/service.serverstransport"
 	AnnotationKeyTraefikEntrypoints     = "traefik.ingress.kubernetes.io/router.entrypoints"
 	AnnotationKeyTraefikMiddlewares     = "traefik.ingress.kubernetes.io/router.middlewares"
@@ -89,6 +150,9 @@ const (
 	// LabelKeyArgoTargetCluster = "cluster.tmax.io/cluster"
 	LabelKeyArgoTargetCluster = "cluster"
 	LabelKeyArgoAppType       = "appType"
+	// argocd 가 application 이 관리하는 리소스에 다는 label 과 annotation
+	LabelKeyArgoInstance        = "app.kubernetes.io/instance"
+	AnnotationKeyArgoTrackingID = "argocd.argoproj.io/tracking-id"
 )
 
 const (
@@ -118,16 +182,143 @@ const (
 // multi-operator bootstrap을 위해 필요한 초기 환경변수
 // 변수 추가시 GetRequiredEnvPreset에 추가해야 함
 const (
-	HC_DOMAIN          = "HC_DOMAIN"
-	AUTH_CLIENT_SECRET = "AUTH_CLIENT_SECRET"
-	AUTH_SUBDOMAIN     = "AUTH_SUBDOMAIN"
+	HC_DOMAIN      = "HC_DOMAIN"
+	AUTH_SUBDOMAIN = "AUTH_SUBDOMAIN"
 	// AUDIT_WEBHOOK_SERVER_PATH = "AUDIT_WEBHOOK_SERVER_PATH"
 )
 
+// 설정하지 않으면 기본값을 사용하는 환경변수
+const (
+	CLUSTER_PROXY_HOST = "CLUSTER_PROXY_HOST"
+	// cluster proxy 가 받을 token 의 audience(client id) 목록 (comma separated)
+	CLUSTER_PROXY_AUDIENCES = "CLUSTER_PROXY_AUDIENCES"
+	// multicluster ingress 의 ingress class
+	INGRESS_CLASS = "INGRESS_CLASS"
+	// multicluster ingress 의 certificate 를 발급할 ClusterIssuer
+	CLUSTER_ISSUER = "CLUSTER_ISSUER"
+	// single cluster 의 api 들을 노출하는 host 의 subdomain
+	MULTICLUSTER_SUBDOMAIN = "MULTICLUSTER_SUBDOMAIN"
+	// gateway 를 통해 노출할 single cluster 의 api 이름 목록 (comma separated)
+	MULTICLUSTER_EXPOSED_PATHS = "MULTICLUSTER_EXPOSED_PATHS"
+	// single cluster 의 api 들을 노출하는 방식 (traefik, gateway-api)
+	EXPOSURE_BACKEND = "EXPOSURE_BACKEND"
+	// gateway-api backend 를 사용하는 경우 HTTPRoute 가 연결될 Gateway (<namespace>/<name>)
+	GATEWAY_API_PARENT = "GATEWAY_API_PARENT"
+	// gateway-api backend 를 사용하는 경우 single cluster 의 api 요청을 jwt 로 인증하는 Gateway 의 policy (<kind>.<group>/<namespace>/<name>)
+	// gateway-api 에는 traefik 의 jwt-decode middleware 에 해당하는 filter 가 없으므로, 설정하지 않으면 api 들을 노출하지 않는다.
+	// operator 의 service account 에 policy 를 조회(get)할 권한을 추가해야 한다.
+	// (ex. SecurityPolicy.gateway.envoyproxy.io/api-gateway-system/jwt-decode-auth)
+	GATEWAY_API_AUTH_POLICY = "GATEWAY_API_AUTH_POLICY"
+	// cluster 별 argocd AppProject 에서 허용할 source repository 목록 (comma separated)
+	ARGO_PROJECT_SOURCE_REPOS = "ARGO_PROJECT_SOURCE_REPOS"
+	// operator 가 배포된 namespace. 이 namespace 의 default ClusterAddonProfile 은 모든 cluster 에 적용된다.
+	OPERATOR_NAMESPACE = "OPERATOR_NAMESPACE"
+	// app-of-apps application 의 자동 sync 여부 (true, false). ClusterManager 에 지정하지 않은 경우 사용한다.
+	ARGO_AUTO_SYNC = "ARGO_AUTO_SYNC"
+	// 자동 sync 할 때 git repository 에서 삭제된 리소스를 지울지 여부 (true, false)
+	ARGO_AUTO_SYNC_PRUNE = "ARGO_AUTO_SYNC_PRUNE"
+	// 자동 sync 할 때 cluster 에서 수정된 리소스를 되돌릴지 여부 (true, false)
+	ARGO_AUTO_SYNC_SELF_HEAL = "ARGO_AUTO_SYNC_SELF_HEAL"
+	// 이 prefix 로 시작하는 ClusterManager 의 label 을 argocd cluster secret 에 전파한다. (ex. fleet.tmax.io/environment)
+	ARGO_CLUSTER_LABEL_PREFIX = "ARGO_CLUSTER_LABEL_PREFIX"
+	// argocd cluster secret 의 exec 인증 방식에서 실행을 허용할 command 목록 (comma separated)
+	ARGO_CLUSTER_EXEC_COMMANDS = "ARGO_CLUSTER_EXEC_COMMANDS"
+	// awsIAM, exec 인증 방식에서 kubeconfig 에 지정된 대로 사용할 수 있는 IAM role 목록 (comma separated)
+	// 목록에 없는 role 은 kubeconfig 에 있더라도 사용하지 않는다. ClusterManager 에 지정한 role 은 목록과 관계없이 사용할 수 있다.
+	ARGO_CLUSTER_AWS_ROLE_ARNS = "ARGO_CLUSTER_AWS_ROLE_ARNS"
+	// cluster 를 삭제할 때 application 이 single cluster 의 리소스를 정리하기를 기다리는 최대 시간 (ex. 10m)
+	// 시간이 지나거나 single cluster 에 연결할 수 없으면 application 의 finalizer 를 제거한다.
+	ARGO_APPLICATION_DELETION_TIMEOUT = "ARGO_APPLICATION_DELETION_TIMEOUT"
+	// cluster member 를 조회하여 argocd AppProject 의 role 에 반영하는 주기 (ex. 5m)
+	ARGO_PROJECT_SYNC_INTERVAL = "ARGO_PROJECT_SYNC_INTERVAL"
+	// HyperAuth 의 client, role, group 등을 desired state 와 비교하여 동기화하는 주기 (ex. 10m)
+	HYPERAUTH_SYNC_INTERVAL = "HYPERAUTH_SYNC_INTERVAL"
+	// HyperAuth 와 연동할 module 목록을 가지는 operator namespace 의 configmap 이름
+	HYPERAUTH_MODULE_CONFIGMAP = "HYPERAUTH_MODULE_CONFIGMAP"
+	// cluster 의 HyperAuth 리소스를 만들고 oidc issuer 로 사용할 realm.
+	// namespace 나 ClusterManager 의 annotation 으로 덮어쓸 수 있다.
+	HYPERAUTH_REALM = "HYPERAUTH_REALM"
+)
+
+const (
+	ExposureBackendTraefik    = "traefik"
+	ExposureBackendGatewayAPI = "gateway-api"
+
+	// gateway-api backend 에서 console 의 조회용으로만 만드는 ingress 의 class.
+	// 이 class 를 처리하는 ingress controller 가 없으므로 요청은 HTTPRoute 로만 전달된다.
+	DiscoveryIngressClass = "hypercloud-multicluster-discovery"
+)
+
+const (
+	DefaultIngressClass             = "tmax-cloud"
+	DefaultClusterProxyAudiences    = "hypercloud5"
+	DefaultClusterIssuer            = "tmaxcloud-issuer"
+	DefaultMulticlusterSubdomain    = "multicluster"
+	DefaultExposedPaths             = "prometheus"
+	DefaultArgoProjectSourceRepos   = "*"
+	DefaultOperatorNamespace        = "hypercloud5-system"
+	DefaultArgoAutoSync             = false
+	DefaultArgoAutoSyncPrune        = false
+	DefaultArgoAutoSyncSelfHeal     = false
+	DefaultArgoClusterLabelPrefix   = "fleet.tmax.io/"
+	DefaultArgoClusterExecCommands  = "aws,aws-iam-authenticator,argocd-k8s-auth"
+	DefaultArgoAppDeletionTimeout   = 10 * time.Minute
+	DefaultArgoProjectSyncInterval  = 5 * time.Minute
+	DefaultHyperAuthSyncInterval    = 10 * time.Minute
+	DefaultHyperAuthModuleConfigMap = "hyperauth-modules"
+	DefaultHyperAuthRealm           = "tmax"
+)
+
+// exec 인증 방식에서 kubeconfig 의 exec provider 로 부터 argocd cluster secret 으로 복사하는 환경변수 목록
+// 그 외의 환경변수는 argocd 가 실행하는 command 의 동작을 바꿀 수 있으므로 복사하지 않는다.
+var ArgoClusterExecEnvs = []string{
+	"AWS_REGION",
+	"AWS_DEFAULT_REGION",
+	"AWS_STS_REGIONAL_ENDPOINTS",
+}
+
+// exec 인증 방식에서 command 별로 허용하는 args 의 형태
+// 여기에 없는 command 는 args 없이만 사용할 수 있다.
+var ArgoClusterExecArgs = map[string]ArgoClusterExecArgsRule{
+	"aws": {
+		Subcommand: []string{"eks", "get-token"},
+		Flags:      []string{"--cluster-name", "--cluster-id", "--role-arn", "--region"},
+		RoleFlags:  []string{"--role-arn"},
+	},
+	"aws-iam-authenticator": {
+		Subcommand: []string{"token"},
+		Flags:      []string{"-i", "--cluster-id", "-r", "--role", "--region"},
+		RoleFlags:  []string{"-r", "--role"},
+	},
+	"argocd-k8s-auth": {
+		Subcommand: []string{"aws"},
+		Flags:      []string{"--cluster-name", "--role-arn"},
+		RoleFlags:  []string{"--role-arn"},
+	},
+}
+
+// ArgoClusterExecArgsRule 은 exec provider 의 args 가 가질 수 있는 subcommand 와 flag 이다.
+type ArgoClusterExecArgsRule struct {
+	// args 의 맨 앞에 있어야 하는 subcommand
+	Subcommand []string
+	// subcommand 뒤에 올 수 있는 flag. 모두 값을 가진다.
+	Flags []string
+	// IAM role 을 지정하는 flag. 값은 ARGO_CLUSTER_AWS_ROLE_ARNS 에 있거나 ClusterManager 에 지정한 role 이어야 한다.
+	RoleFlags []string
+}
+
+// repository credentials secret 에서 argocd repository secret 으로 복사하는 key 목록
+var ArgoRepositoryCredentialFields = []string{
+	"username",
+	"password",
+	"sshPrivateKey",
+	"tlsClientCertData",
+	"tlsClientCertKey",
+}
+
 func GetRequiredEnvPreset() []string {
 	return []string{
 		HC_DOMAIN,
-		AUTH_CLIENT_SECRET,
 		AUTH_SUBDOMAIN,
 		// AUDIT_WEBHOOK_SERVER_PATH,
 	}
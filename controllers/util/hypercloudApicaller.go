@@ -4,10 +4,12 @@ import (
 	"bytes"
 	"crypto/tls"
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
 	"log"
 	"net/http"
 	"strings"
+	"time"
 
 	clusterV1alpha1 "github.com/tmax-cloud/hypercloud-multi-operator/apis/cluster/v1alpha1"
 )
@@ -85,3 +87,45 @@ func List(namespace, cluster string) ([]byte, error) {
 	bytes, _ := ioutil.ReadAll(resp.Body)
 	return bytes, nil
 }
+
+// Cluster member information
+type ClusterMemberInfo struct {
+	Id          int64     `json:"Id"`
+	Namespace   string    `json:"Namespace"`
+	Cluster     string    `json:"Cluster"`
+	MemberId    string    `json:"MemberId"`
+	Groups      []string  `json:"Groups"`
+	MemberName  string    `json:"MemberName"`
+	Attribute   string    `json:"Attribute"`
+	Role        string    `json:"Role"`
+	Status      string    `json:"Status"`
+	CreatedTime time.Time `json:"CreatedTime"`
+	UpdatedTime time.Time `json:"UpdatedTime"`
+}
+
+// ListClusterMembers 는 db 로 부터 클러스터에 초대된 member 들의 info 를 가져온다.
+// List 와 달리 api 호출에 실패해도 operator 를 종료하지 않고 error 를 반환한다.
+func ListClusterMembers(namespace, cluster string) ([]ClusterMemberInfo, error) {
+	url := "https://hypercloud5-api-server-service.hypercloud5-system.svc.cluster.local/namespaces/{namespace}/clustermanagers/{clustermanager}/member/{member}"
+	tr := &http.Transport{
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+	}
+	url = strings.Replace(url, "{namespace}", namespace, -1)
+	url = strings.Replace(url, "{clustermanager}", cluster, -1)
+	url = strings.Replace(url, "{member}", "all", -1)
+	client := &http.Client{Transport: tr, Timeout: 10 * time.Second}
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to list cluster members: %s", resp.Status)
+	}
+
+	memberList := []ClusterMemberInfo{}
+	if err := json.NewDecoder(resp.Body).Decode(&memberList); err != nil {
+		return nil, err
+	}
+	return memberList, nil
+}
@@ -0,0 +1,356 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	kerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+type PhaseState string
+
+const (
+	// phase 를 수행하였고 requeue 없이 완료된 상태
+	PhaseStateSucceeded = PhaseState("Succeeded")
+	// Done 이 true 라서 phase 를 수행하지 않은 상태
+	PhaseStateDone = PhaseState("Done")
+	// phase 를 수행하였지만 requeue 가 필요한 상태
+	PhaseStateRequeued = PhaseState("Requeued")
+	// phase 수행중 error 가 발생한 상태
+	PhaseStateFailed = PhaseState("Failed")
+	// precondition 을 만족하지 않거나 exclusive phase 에 의해 수행하지 않은 상태
+	PhaseStateSkipped = PhaseState("Skipped")
+	// 의존하는 phase 가 완료되지 않아 수행하지 않은 상태
+	PhaseStatePending = PhaseState("Pending")
+)
+
+// Phase 는 reconcile 의 한 단계를 선언한다.
+// 엔진은 DependsOn 으로 phase 들을 단계(level)별로 나누고, 같은 단계의 phase 들은 동시에 수행한다.
+// 같은 단계의 phase 들이 reconcile 하는 object 를 함께 수정하는 경우에는 PhaseObject 로 Run 을 만들어야 한다.
+// Precondition 과 Done 은 phase 들이 수행되지 않는 동안에만 호출된다.
+type Phase struct {
+	Name string
+	// 먼저 완료되어야 하는 phase 들의 이름
+	DependsOn []string
+	// false 를 반환하면 phase 를 수행하지 않는다. nil 이면 항상 수행한다.
+	Precondition func() bool
+	// true 를 반환하면 이미 완료된 것으로 보고 phase 를 수행하지 않는다. (idempotency check)
+	// Done 이 있는 phase 는 수행 후에도 Done 이 true 여야 의존하는 phase 들이 수행된다.
+	Done func() bool
+	Run  func(ctx context.Context) (ctrl.Result, error)
+	// Exclusive phase 의 precondition 을 만족하면, 그 reconcile 에서는 Exclusive phase 들만 수행한다.
+	// (upgrade, scaling 처럼 다른 작업과 함께 수행되면 안되는 경우)
+	Exclusive bool
+}
+
+// PhaseOutcome 은 phase 하나의 수행 결과이다.
+type PhaseOutcome struct {
+	Name     string
+	State    PhaseState
+	Result   ctrl.Result
+	Err      error
+	Duration time.Duration
+	Message  string
+}
+
+// RunPhases 는 선언된 phase 들을 의존성 순서에 따라 수행하고, phase 별 수행 결과를 선언된 순서대로 반환한다.
+// 반환하는 result 는 error 없이 requeue 를 요청한 phase 들 중 가장 짧은 requeue 를 따르고,
+// error 는 모든 phase 의 error 를 aggregate 하여 반환한다.
+func RunPhases(ctx context.Context, phases []Phase) (ctrl.Result, []PhaseOutcome, error) {
+	levels, err := sortPhases(phases)
+	if err != nil {
+		return ctrl.Result{}, nil, err
+	}
+
+	outcomes := map[string]*PhaseOutcome{}
+	index := map[string]*Phase{}
+	for i := range phases {
+		index[phases[i].Name] = &phases[i]
+	}
+
+	// exclusive phase 가 수행되어야 하는 경우 나머지 phase 는 모두 skip 한다.
+	exclusive := []string{}
+	for _, phase := range phases {
+		if phase.Exclusive && checkPhase(phase.Precondition, true) && !checkPhase(phase.Done, false) {
+			exclusive = append(exclusive, phase.Name)
+		}
+	}
+
+	for _, level := range levels {
+		// 앞 단계의 phase 가 상태를 바꿨을 수 있으므로 단계를 시작할 때 precondition 을 확인한다.
+		runnable := []*Phase{}
+		for _, name := range level {
+			phase := index[name]
+			if outcome := preparePhase(phase, index, outcomes, exclusive); outcome != nil {
+				outcomes[name] = outcome
+				continue
+			}
+			runnable = append(runnable, phase)
+		}
+
+		results := make([]*PhaseOutcome, len(runnable))
+		wg := sync.WaitGroup{}
+		for i, phase := range runnable {
+			wg.Add(1)
+			go func(i int, phase *Phase) {
+				defer wg.Done()
+				results[i] = runPhase(ctx, phase)
+			}(i, phase)
+		}
+		wg.Wait()
+		for i, phase := range runnable {
+			outcomes[phase.Name] = results[i]
+		}
+	}
+
+	res := ctrl.Result{}
+	errs := []error{}
+	result := []PhaseOutcome{}
+	for _, phase := range phases {
+		outcome := outcomes[phase.Name]
+		result = append(result, *outcome)
+		if outcome.Err != nil {
+			errs = append(errs, fmt.Errorf("phase %s: %w", phase.Name, outcome.Err))
+			continue
+		}
+		res = LowestNonZeroResult(res, outcome.Result)
+	}
+
+	return res, result, kerrors.NewAggregate(errs)
+}
+
+// PhaseObject 는 같은 단계에서 동시에 수행되는 phase 들이 함께 수정하는 object 를 보호한다.
+// 각 phase 는 수행을 시작할 때의 object 복사본을 수정하고, 수행을 마치면 바꾼 부분만 원본에 merge 한다.
+// 여러 phase 가 같은 field 를 바꾼 경우에는 나중에 끝난 phase 의 값이 남는다.
+type PhaseObject struct {
+	mu  sync.Mutex
+	obj client.Object
+}
+
+func NewPhaseObject(obj client.Object) *PhaseObject {
+	return &PhaseObject{obj: obj}
+}
+
+// Bind 는 object 의 복사본으로 phase 를 수행하는 Run 을 만든다.
+func (p *PhaseObject) Bind(run func(ctx context.Context, obj client.Object) (ctrl.Result, error)) func(ctx context.Context) (ctrl.Result, error) {
+	return func(ctx context.Context) (ctrl.Result, error) {
+		p.mu.Lock()
+		base := p.obj.DeepCopyObject().(client.Object)
+		p.mu.Unlock()
+
+		modified := base.DeepCopyObject().(client.Object)
+		res, err := run(ctx, modified)
+		if mergeErr := p.merge(base, modified); mergeErr != nil {
+			return res, kerrors.NewAggregate([]error{err, mergeErr})
+		}
+		return res, err
+	}
+}
+
+// merge 는 base 로 부터 modified 까지 바뀐 부분을 원본에 적용한다.
+func (p *PhaseObject) merge(base, modified client.Object) error {
+	baseJSON, err := json.Marshal(base)
+	if err != nil {
+		return err
+	}
+	modifiedJSON, err := json.Marshal(modified)
+	if err != nil {
+		return err
+	}
+	patch, err := strategicpatch.CreateTwoWayMergePatch(baseJSON, modifiedJSON, modified)
+	if err != nil {
+		return fmt.Errorf("failed to create patch for %s: %w", modified.GetName(), err)
+	}
+	if string(patch) == "{}" {
+		return nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	currentJSON, err := json.Marshal(p.obj)
+	if err != nil {
+		return err
+	}
+	mergedJSON, err := strategicpatch.StrategicMergePatch(currentJSON, patch, p.obj)
+	if err != nil {
+		return fmt.Errorf("failed to merge changes of %s: %w", p.obj.GetName(), err)
+	}
+	// patch 로 지워진 field 가 남지 않도록 새 object 로 decode 한 후 원본을 교체한다.
+	value := reflect.ValueOf(p.obj).Elem()
+	merged := reflect.New(value.Type())
+	if err := json.Unmarshal(mergedJSON, merged.Interface()); err != nil {
+		return err
+	}
+	value.Set(merged.Elem())
+	return nil
+}
+
+// LogPhaseOutcomes 는 수행 결과 중 기록할 만한 것들(requeue, 실패, 대기)을 log 로 남긴다.
+func LogPhaseOutcomes(log logr.Logger, outcomes []PhaseOutcome) {
+	for _, outcome := range outcomes {
+		switch outcome.State {
+		case PhaseStateFailed:
+			log.Error(outcome.Err, "Phase failed", "phase", outcome.Name, "duration", outcome.Duration.String())
+		case PhaseStateRequeued:
+			log.Info("Phase requeued", "phase", outcome.Name, "requeueAfter", outcome.Result.RequeueAfter.String(), "duration", outcome.Duration.String())
+		case PhaseStatePending:
+			log.Info("Phase is pending", "phase", outcome.Name, "reason", outcome.Message)
+		}
+	}
+}
+
+// preparePhase 는 phase 를 수행하지 않아야 하는 경우 그 결과를 반환하고, 수행해야 하는 경우 nil 을 반환한다.
+func preparePhase(phase *Phase, index map[string]*Phase, outcomes map[string]*PhaseOutcome, exclusive []string) *PhaseOutcome {
+	if len(exclusive) > 0 && !phase.Exclusive {
+		return &PhaseOutcome{
+			Name:    phase.Name,
+			State:   PhaseStateSkipped,
+			Message: "blocked by " + strings.Join(exclusive, ", "),
+		}
+	}
+
+	waiting := []string{}
+	for _, dep := range phase.DependsOn {
+		if !isPhaseCompleted(index[dep], outcomes[dep]) {
+			waiting = append(waiting, dep)
+		}
+	}
+	if len(waiting) > 0 {
+		return &PhaseOutcome{
+			Name:    phase.Name,
+			State:   PhaseStatePending,
+			Message: "waiting for " + strings.Join(waiting, ", "),
+		}
+	}
+
+	if !checkPhase(phase.Precondition, true) {
+		return &PhaseOutcome{
+			Name:    phase.Name,
+			State:   PhaseStateSkipped,
+			Message: "precondition is not satisfied",
+		}
+	}
+
+	if checkPhase(phase.Done, false) {
+		return &PhaseOutcome{
+			Name:  phase.Name,
+			State: PhaseStateDone,
+		}
+	}
+
+	return nil
+}
+
+func runPhase(ctx context.Context, phase *Phase) *PhaseOutcome {
+	start := time.Now()
+	res, err := phase.Run(ctx)
+	outcome := &PhaseOutcome{
+		Name:     phase.Name,
+		Result:   res,
+		Err:      err,
+		Duration: time.Since(start),
+	}
+
+	switch {
+	case err != nil:
+		outcome.State = PhaseStateFailed
+		outcome.Message = err.Error()
+	case !res.IsZero():
+		outcome.State = PhaseStateRequeued
+	default:
+		outcome.State = PhaseStateSucceeded
+	}
+	return outcome
+}
+
+func isPhaseCompleted(phase *Phase, outcome *PhaseOutcome) bool {
+	if outcome == nil {
+		return false
+	}
+	if outcome.State != PhaseStateSucceeded && outcome.State != PhaseStateDone {
+		return false
+	}
+	// 수행은 했지만 아직 완료되지 않은 경우 (ex. 리소스 생성을 기다리는 중)
+	return checkPhase(phase.Done, true)
+}
+
+func checkPhase(check func() bool, defaultValue bool) bool {
+	if check == nil {
+		return defaultValue
+	}
+	return check()
+}
+
+// sortPhases 는 의존성에 따라 phase 들을 단계별로 나눈다. 같은 단계 안에서는 선언된 순서를 유지한다.
+func sortPhases(phases []Phase) ([][]string, error) {
+	declared := map[string]bool{}
+	for _, phase := range phases {
+		if phase.Name == "" {
+			return nil, fmt.Errorf("phase name must not be empty")
+		}
+		if declared[phase.Name] {
+			return nil, fmt.Errorf("phase %s is declared more than once", phase.Name)
+		}
+		declared[phase.Name] = true
+	}
+
+	level := map[string]int{}
+	remains := len(phases)
+	levels := [][]string{}
+	for remains > 0 {
+		current := []string{}
+		for _, phase := range phases {
+			if _, ok := level[phase.Name]; ok {
+				continue
+			}
+
+			ready := true
+			for _, dep := range phase.DependsOn {
+				if !declared[dep] {
+					return nil, fmt.Errorf("phase %s depends on undeclared phase %s", phase.Name, dep)
+				}
+				if _, ok := level[dep]; !ok {
+					ready = false
+					break
+				}
+			}
+			if ready {
+				current = append(current, phase.Name)
+			}
+		}
+
+		if len(current) == 0 {
+			return nil, fmt.Errorf("phases have circular dependency")
+		}
+		for _, name := range current {
+			level[name] = len(levels)
+		}
+		levels = append(levels, current)
+		remains -= len(current)
+	}
+
+	return levels, nil
+}
@@ -0,0 +1,367 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// testPhase 는 수행 여부를 기록하는 phase 를 만든다.
+type testPhase struct {
+	name         string
+	dependsOn    []string
+	precondition *bool
+	done         *bool
+	doneAfterRun *bool
+	exclusive    bool
+	result       ctrl.Result
+	err          error
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
+
+// 같은 단계의 phase 들은 동시에 수행되므로 수행 기록은 lock 으로 보호한다.
+func buildPhases(specs []testPhase, ran *[]string) []Phase {
+	mu := sync.Mutex{}
+	phases := []Phase{}
+	for _, spec := range specs {
+		spec := spec
+		hasRun := false
+		phase := Phase{
+			Name:      spec.name,
+			DependsOn: spec.dependsOn,
+			Exclusive: spec.exclusive,
+			Run: func(ctx context.Context) (ctrl.Result, error) {
+				mu.Lock()
+				defer mu.Unlock()
+				hasRun = true
+				*ran = append(*ran, spec.name)
+				return spec.result, spec.err
+			},
+		}
+		if spec.precondition != nil {
+			phase.Precondition = func() bool {
+				return *spec.precondition
+			}
+		}
+		if spec.done != nil || spec.doneAfterRun != nil {
+			phase.Done = func() bool {
+				if hasRun && spec.doneAfterRun != nil {
+					return *spec.doneAfterRun
+				}
+				return spec.done != nil && *spec.done
+			}
+		}
+		phases = append(phases, phase)
+	}
+	return phases
+}
+
+func TestRunPhases(t *testing.T) {
+	tests := []struct {
+		name       string
+		phases     []testPhase
+		wantRan    []string
+		wantStates []PhaseState
+		wantResult ctrl.Result
+		wantErr    bool
+	}{
+		{
+			name: "dependencies run before dependents regardless of declared order",
+			phases: []testPhase{
+				{name: "c", dependsOn: []string{"b"}},
+				{name: "b", dependsOn: []string{"a"}},
+				{name: "a"},
+			},
+			wantRan:    []string{"a", "b", "c"},
+			wantStates: []PhaseState{PhaseStateSucceeded, PhaseStateSucceeded, PhaseStateSucceeded},
+		},
+		{
+			name: "outcomes keep declared order",
+			phases: []testPhase{
+				{name: "a"},
+				{name: "c", dependsOn: []string{"a"}},
+				{name: "b", dependsOn: []string{"a"}},
+			},
+			wantRan:    []string{"a", "b", "c"},
+			wantStates: []PhaseState{PhaseStateSucceeded, PhaseStateSucceeded, PhaseStateSucceeded},
+		},
+		{
+			name: "requeued dependency keeps dependents pending",
+			phases: []testPhase{
+				{name: "a", result: ctrl.Result{RequeueAfter: time.Minute}},
+				{name: "b", dependsOn: []string{"a"}},
+			},
+			wantRan:    []string{"a"},
+			wantStates: []PhaseState{PhaseStateRequeued, PhaseStatePending},
+			wantResult: ctrl.Result{RequeueAfter: time.Minute},
+		},
+		{
+			name: "unsatisfied precondition skips phase and keeps dependents pending",
+			phases: []testPhase{
+				{name: "a", precondition: boolPtr(false)},
+				{name: "b", dependsOn: []string{"a"}},
+			},
+			wantRan:    []string{},
+			wantStates: []PhaseState{PhaseStateSkipped, PhaseStatePending},
+		},
+		{
+			name: "done phase is not run and dependents run",
+			phases: []testPhase{
+				{name: "a", done: boolPtr(true)},
+				{name: "b", dependsOn: []string{"a"}},
+			},
+			wantRan:    []string{"b"},
+			wantStates: []PhaseState{PhaseStateDone, PhaseStateSucceeded},
+		},
+		{
+			name: "phase that is not done after run keeps dependents pending",
+			phases: []testPhase{
+				{name: "a", done: boolPtr(false), doneAfterRun: boolPtr(false)},
+				{name: "b", dependsOn: []string{"a"}},
+			},
+			wantRan:    []string{"a"},
+			wantStates: []PhaseState{PhaseStateSucceeded, PhaseStatePending},
+		},
+		{
+			name: "phase that is done after run lets dependents run",
+			phases: []testPhase{
+				{name: "a", done: boolPtr(false), doneAfterRun: boolPtr(true)},
+				{name: "b", dependsOn: []string{"a"}},
+			},
+			wantRan:    []string{"a", "b"},
+			wantStates: []PhaseState{PhaseStateSucceeded, PhaseStateSucceeded},
+		},
+		{
+			name: "exclusive phase blocks other phases",
+			phases: []testPhase{
+				{name: "a"},
+				{name: "upgrade", exclusive: true, precondition: boolPtr(true)},
+				{name: "scaling", exclusive: true, precondition: boolPtr(false)},
+			},
+			wantRan:    []string{"upgrade"},
+			wantStates: []PhaseState{PhaseStateSkipped, PhaseStateSucceeded, PhaseStateSkipped},
+		},
+		{
+			name: "lowest requeue is returned",
+			phases: []testPhase{
+				{name: "a", result: ctrl.Result{RequeueAfter: 5 * time.Minute}},
+				{name: "b", result: ctrl.Result{RequeueAfter: time.Minute}},
+				{name: "c"},
+			},
+			wantRan:    []string{"a", "b", "c"},
+			wantStates: []PhaseState{PhaseStateRequeued, PhaseStateRequeued, PhaseStateSucceeded},
+			wantResult: ctrl.Result{RequeueAfter: time.Minute},
+		},
+		{
+			name: "failed phase does not stop independent phases and its result is ignored",
+			phases: []testPhase{
+				{name: "a", err: fmt.Errorf("failed"), result: ctrl.Result{RequeueAfter: time.Second}},
+				{name: "b", dependsOn: []string{"a"}},
+				{name: "c", result: ctrl.Result{RequeueAfter: time.Minute}},
+			},
+			wantRan:    []string{"a", "c"},
+			wantStates: []PhaseState{PhaseStateFailed, PhaseStatePending, PhaseStateRequeued},
+			wantResult: ctrl.Result{RequeueAfter: time.Minute},
+			wantErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ran := []string{}
+			res, outcomes, err := RunPhases(context.Background(), buildPhases(tt.phases, &ran))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("RunPhases() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			// 같은 단계의 phase 들이 수행되는 순서는 정해져 있지 않으므로 이름 순으로 비교한다.
+			sort.Strings(ran)
+			if !reflect.DeepEqual(ran, tt.wantRan) {
+				t.Errorf("ran = %v, want %v", ran, tt.wantRan)
+			}
+			if res != tt.wantResult {
+				t.Errorf("result = %+v, want %+v", res, tt.wantResult)
+			}
+			states := []PhaseState{}
+			for i, outcome := range outcomes {
+				if outcome.Name != tt.phases[i].name {
+					t.Errorf("outcome[%d] = %s, want %s", i, outcome.Name, tt.phases[i].name)
+				}
+				states = append(states, outcome.State)
+			}
+			if !reflect.DeepEqual(states, tt.wantStates) {
+				t.Errorf("states = %v, want %v", states, tt.wantStates)
+			}
+		})
+	}
+}
+
+func TestRunPhasesInvalidDeclaration(t *testing.T) {
+	tests := []struct {
+		name   string
+		phases []testPhase
+	}{
+		{
+			name:   "empty name",
+			phases: []testPhase{{name: ""}},
+		},
+		{
+			name:   "duplicated name",
+			phases: []testPhase{{name: "a"}, {name: "a"}},
+		},
+		{
+			name:   "undeclared dependency",
+			phases: []testPhase{{name: "a", dependsOn: []string{"b"}}},
+		},
+		{
+			name: "circular dependency",
+			phases: []testPhase{
+				{name: "a", dependsOn: []string{"b"}},
+				{name: "b", dependsOn: []string{"a"}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ran := []string{}
+			if _, _, err := RunPhases(context.Background(), buildPhases(tt.phases, &ran)); err == nil {
+				t.Errorf("RunPhases() error = nil, want error")
+			}
+			if len(ran) != 0 {
+				t.Errorf("ran = %v, want no phase to run", ran)
+			}
+		})
+	}
+}
+
+func TestRunPhasesConcurrently(t *testing.T) {
+	// a, b 는 서로를 기다리므로 동시에 수행되어야만 완료되고, c 는 a, b 가 끝난 후에 수행되어야 한다.
+	started := sync.WaitGroup{}
+	started.Add(2)
+	finished := map[string]bool{}
+	mu := sync.Mutex{}
+	waitEachOther := func(name string) func(ctx context.Context) (ctrl.Result, error) {
+		return func(ctx context.Context) (ctrl.Result, error) {
+			started.Done()
+			done := make(chan struct{})
+			go func() {
+				started.Wait()
+				close(done)
+			}()
+			select {
+			case <-done:
+			case <-time.After(5 * time.Second):
+				return ctrl.Result{}, fmt.Errorf("phase %s is not run concurrently", name)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			finished[name] = true
+			return ctrl.Result{}, nil
+		}
+	}
+
+	phases := []Phase{
+		{Name: "a", Run: waitEachOther("a")},
+		{Name: "b", Run: waitEachOther("b")},
+		{
+			Name:      "c",
+			DependsOn: []string{"a", "b"},
+			Run: func(ctx context.Context) (ctrl.Result, error) {
+				mu.Lock()
+				defer mu.Unlock()
+				if !finished["a"] || !finished["b"] {
+					return ctrl.Result{}, fmt.Errorf("phase c is run before its dependencies finish")
+				}
+				return ctrl.Result{}, nil
+			},
+		},
+	}
+
+	if _, _, err := RunPhases(context.Background(), phases); err != nil {
+		t.Fatalf("RunPhases() error = %v", err)
+	}
+}
+
+func TestPhaseObject(t *testing.T) {
+	obj := &coreV1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "test",
+			Annotations: map[string]string{"removed": "true", "kept": "true"},
+			Finalizers:  []string{"removed", "kept"},
+		},
+		Data: map[string]string{"kept": "true"},
+	}
+	object := NewPhaseObject(obj)
+	bind := func(run func(cm *coreV1.ConfigMap)) func(ctx context.Context) (ctrl.Result, error) {
+		return object.Bind(func(ctx context.Context, obj client.Object) (ctrl.Result, error) {
+			run(obj.(*coreV1.ConfigMap))
+			return ctrl.Result{}, nil
+		})
+	}
+
+	phases := []Phase{}
+	for i := 0; i < 10; i++ {
+		key := fmt.Sprintf("phase-%d", i)
+		phases = append(phases, Phase{
+			Name: key,
+			Run: bind(func(cm *coreV1.ConfigMap) {
+				cm.Annotations[key] = "true"
+				cm.Data[key] = "true"
+			}),
+		})
+	}
+	phases = append(phases, Phase{
+		Name: "remove",
+		Run: bind(func(cm *coreV1.ConfigMap) {
+			delete(cm.Annotations, "removed")
+			cm.Finalizers = []string{"kept"}
+		}),
+	})
+
+	if _, _, err := RunPhases(context.Background(), phases); err != nil {
+		t.Fatalf("RunPhases() error = %v", err)
+	}
+
+	// 동시에 수행된 phase 들이 바꾼 내용이 모두 원본에 반영되어야 한다.
+	wantAnnotations := map[string]string{"kept": "true"}
+	wantData := map[string]string{"kept": "true"}
+	for i := 0; i < 10; i++ {
+		wantAnnotations[fmt.Sprintf("phase-%d", i)] = "true"
+		wantData[fmt.Sprintf("phase-%d", i)] = "true"
+	}
+	if !reflect.DeepEqual(obj.Annotations, wantAnnotations) {
+		t.Errorf("annotations = %v, want %v", obj.Annotations, wantAnnotations)
+	}
+	if !reflect.DeepEqual(obj.Data, wantData) {
+		t.Errorf("data = %v, want %v", obj.Data, wantData)
+	}
+	if !reflect.DeepEqual(obj.Finalizers, []string{"kept"}) {
+		t.Errorf("finalizers = %v, want %v", obj.Finalizers, []string{"kept"})
+	}
+}
@@ -0,0 +1,308 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	clusterV1alpha1 "github.com/tmax-cloud/hypercloud-multi-operator/apis/cluster/v1alpha1"
+
+	coreV1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/tools/cache"
+
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+const (
+	ApiGatewayServiceName = "gateway"
+	// remote api-server 의 list/watch 에 실패한 뒤 이 시간 동안은 informer 가 동작하지 않는 것으로 본다.
+	remoteWatchErrorCooldown = time.Minute
+)
+
+// RemoteWatchManager는 member cluster마다 operator가 기다리는 리소스만 바라보는 informer를 띄우고,
+// remote 리소스에 변경이 생기면 해당 cluster를 소유한 ClusterManager를 바로 reconcile queue에 넣어준다.
+// polling(RequeueAfter)으로 remote api-server를 반복 조회하던 방식을 대체하기 위해 사용한다.
+type RemoteWatchManager struct {
+	Log logr.Logger
+
+	mu      sync.Mutex
+	watches map[types.NamespacedName]*remoteWatch
+	events  chan event.GenericEvent
+}
+
+type remoteWatch struct {
+	// kubeconfig 와 watch 하는 service account 들로 만든 key. 바뀌면 informer 를 재시작한다.
+	key    string
+	cancel context.CancelFunc
+	// informer 들의 cache 가 모두 동기화되었는지 여부
+	synced bool
+	// 마지막으로 remote api-server 의 list/watch 에 실패한 시각
+	lastError time.Time
+}
+
+func NewRemoteWatchManager(log logr.Logger) *RemoteWatchManager {
+	return &RemoteWatchManager{
+		Log:     log,
+		watches: map[types.NamespacedName]*remoteWatch{},
+		events:  make(chan event.GenericEvent, 1024),
+	}
+}
+
+// Source는 remote 리소스의 변경을 GenericEvent로 전달하는 source를 반환한다.
+// 전달되는 object는 owner ClusterManager의 name/namespace만 채워져 있다.
+func (m *RemoteWatchManager) Source() source.Source {
+	return &source.Channel{Source: m.events}
+}
+
+// Watch는 kubeconfig secret을 이용하여 owner ClusterManager에 대한 remote informer를 시작한다.
+// 이미 같은 kubeconfig와 service account로 watch 중이라면 아무것도 하지 않고, 바뀐 경우에는 informer를 재시작한다.
+// 모든 informer 는 name field selector 로 operator 가 기다리는 리소스만 바라보며,
+// node 는 이름으로 거를 수 없으므로 gateway 가 NodePort 인 경우에만 watch 한다.
+func (m *RemoteWatchManager) Watch(owner types.NamespacedName, kubeconfigSecret *coreV1.Secret, serviceAccounts []string) error {
+	key := KubeconfigHash(kubeconfigSecret) + "/" + strings.Join(serviceAccounts, ",")
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if w, ok := m.watches[owner]; ok {
+		if w.key == key {
+			return nil
+		}
+		w.cancel()
+		delete(m.watches, owner)
+	}
+
+	remoteClientset, err := GetRemoteK8sClient(kubeconfigSecret)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	w := &remoteWatch{
+		key:    key,
+		cancel: cancel,
+	}
+	handler := m.eventHandler(ctx, owner)
+	informerList := []cache.SharedIndexInformer{}
+
+	// token 을 발급받을 service account (argocd-manager, cluster owner의 service account)
+	for _, name := range serviceAccounts {
+		if name == "" {
+			continue
+		}
+		informer := informers.NewSharedInformerFactoryWithOptions(
+			remoteClientset,
+			0,
+			informers.WithNamespace(KubeNamespace),
+			informers.WithTweakListOptions(nameSelector(name)),
+		).Core().V1().ServiceAccounts().Informer()
+		informer.AddEventHandler(handler)
+		informerList = append(informerList, informer)
+	}
+
+	// gateway 가 NodePort 인 경우 gateway 주소로 사용하는 node
+	// node 의 status 는 주기적으로 갱신되므로 주소나 ready 상태가 바뀐 경우에만 reconcile 한다.
+	nodeInformer := informers.NewSharedInformerFactory(remoteClientset, 0).Core().V1().Nodes().Informer()
+	nodeInformer.AddEventHandler(nodeEventHandler(handler))
+	if err := nodeInformer.SetWatchErrorHandler(m.watchErrorHandler(w)); err != nil {
+		cancel()
+		return err
+	}
+	var startNodeInformer sync.Once
+
+	// api-gateway-system 네임스페이스의 gateway service
+	gatewayInformer := informers.NewSharedInformerFactoryWithOptions(
+		remoteClientset,
+		0,
+		informers.WithNamespace(ApiGatewayNamespace),
+		informers.WithTweakListOptions(nameSelector(ApiGatewayServiceName)),
+	).Core().V1().Services().Informer()
+	gatewayInformer.AddEventHandler(gatewayEventHandler(handler, func() {
+		startNodeInformer.Do(func() {
+			go nodeInformer.Run(ctx.Done())
+		})
+	}))
+	informerList = append(informerList, gatewayInformer)
+
+	hasSynced := []cache.InformerSynced{}
+	for _, informer := range informerList {
+		if err := informer.SetWatchErrorHandler(m.watchErrorHandler(w)); err != nil {
+			cancel()
+			return err
+		}
+		go informer.Run(ctx.Done())
+		hasSynced = append(hasSynced, informer.HasSynced)
+	}
+	// cache 가 동기화되기 전에는 remote 리소스의 변경을 놓칠 수 있으므로 watch 중인 것으로 보지 않는다.
+	go func() {
+		if !cache.WaitForCacheSync(ctx.Done(), hasSynced...) {
+			return
+		}
+		m.mu.Lock()
+		w.synced = true
+		m.mu.Unlock()
+		m.Log.Info("Remote informers are synced", "clustermanager", owner)
+	}()
+
+	m.watches[owner] = w
+	m.Log.Info("Start to watch remote resources", "clustermanager", owner)
+	return nil
+}
+
+// IsWatching은 owner ClusterManager에 대한 remote informer가 동작 중인지 반환한다.
+// cache 가 아직 동기화되지 않았거나 최근에 remote api-server 에 연결하지 못한 경우에는 false 를 반환하여
+// 호출하는 쪽에서 짧은 주기로 polling 하도록 한다.
+func (m *RemoteWatchManager) IsWatching(owner types.NamespacedName) bool {
+	if m == nil {
+		return false
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	w, ok := m.watches[owner]
+	return ok && w.synced && time.Since(w.lastError) >= remoteWatchErrorCooldown
+}
+
+// Stop은 owner ClusterManager에 대한 remote informer를 중지한다.
+func (m *RemoteWatchManager) Stop(owner types.NamespacedName) {
+	if m == nil {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if w, ok := m.watches[owner]; ok {
+		w.cancel()
+		delete(m.watches, owner)
+		m.Log.Info("Stop to watch remote resources", "clustermanager", owner)
+	}
+}
+
+// watchErrorHandler는 remote api-server 의 list/watch 에 실패한 시각을 기록한다.
+// watch 가 정상적으로 끝나거나 resource version 이 만료된 경우는 실패로 보지 않는다.
+func (m *RemoteWatchManager) watchErrorHandler(w *remoteWatch) cache.WatchErrorHandler {
+	return func(r *cache.Reflector, err error) {
+		if err != io.EOF && !errors.IsResourceExpired(err) && !errors.IsGone(err) {
+			m.mu.Lock()
+			w.lastError = time.Now()
+			m.mu.Unlock()
+		}
+		cache.DefaultWatchErrorHandler(r, err)
+	}
+}
+
+func (m *RemoteWatchManager) eventHandler(ctx context.Context, owner types.NamespacedName) cache.ResourceEventHandler {
+	enqueue := func() {
+		clm := &clusterV1alpha1.ClusterManager{}
+		clm.Name = owner.Name
+		clm.Namespace = owner.Namespace
+		select {
+		case m.events <- event.GenericEvent{Object: clm}:
+		case <-ctx.Done():
+		}
+	}
+
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			enqueue()
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			enqueue()
+		},
+		DeleteFunc: func(obj interface{}) {
+			enqueue()
+		},
+	}
+}
+
+// nameSelector는 이름이 name 인 리소스만 조회하도록 list option 을 바꾼다.
+func nameSelector(name string) func(opts *metav1.ListOptions) {
+	return func(opts *metav1.ListOptions) {
+		opts.FieldSelector = fields.OneTermEqualSelector("metadata.name", name).String()
+	}
+}
+
+// gatewayEventHandler는 gateway service 가 NodePort 이면 node 의 watch 를 시작하고 handler 로 전달한다.
+func gatewayEventHandler(handler cache.ResourceEventHandler, watchNodes func()) cache.ResourceEventHandler {
+	checkNodePort := func(obj interface{}) {
+		if service, ok := obj.(*coreV1.Service); ok && service.Spec.Type == coreV1.ServiceTypeNodePort {
+			watchNodes()
+		}
+	}
+
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			checkNodePort(obj)
+			handler.OnAdd(obj)
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			checkNodePort(newObj)
+			handler.OnUpdate(oldObj, newObj)
+		},
+		DeleteFunc: handler.OnDelete,
+	}
+}
+
+// nodeEventHandler는 node 의 주소나 ready 상태가 바뀐 경우에만 handler 로 전달한다.
+func nodeEventHandler(handler cache.ResourceEventHandler) cache.ResourceEventHandler {
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc: handler.OnAdd,
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldNode, ok := oldObj.(*coreV1.Node)
+			if !ok {
+				return
+			}
+			newNode, ok := newObj.(*coreV1.Node)
+			if !ok {
+				return
+			}
+			if !equality.Semantic.DeepEqual(oldNode.Status.Addresses, newNode.Status.Addresses) ||
+				IsNodeReady(oldNode) != IsNodeReady(newNode) {
+				handler.OnUpdate(oldObj, newObj)
+			}
+		},
+		DeleteFunc: handler.OnDelete,
+	}
+}
+
+// IsNodeReady는 node 의 Ready condition 이 True 인지 반환한다.
+func IsNodeReady(node *coreV1.Node) bool {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == coreV1.NodeReady {
+			return condition.Status == coreV1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// KubeconfigHash는 kubeconfig secret의 내용으로 부터 hash 값을 계산한다.
+func KubeconfigHash(kubeconfigSecret *coreV1.Secret) string {
+	sum := sha256.Sum256(kubeconfigSecret.Data["value"])
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,62 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"context"
+	"time"
+
+	authenticationV1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// TokenRequest 로 발급받는 service account token 의 유효기간
+	ServiceAccountTokenExpiration = 24 * time.Hour
+	// 남은 유효기간이 이보다 짧아지면 token 을 다시 발급받는다.
+	ServiceAccountTokenRefreshBefore = 8 * time.Hour
+)
+
+// RequestServiceAccountToken 은 single cluster 의 TokenRequest API 를 통해
+// 유효기간이 정해진 service account token 을 발급받아, token 과 만료 시간을 반환한다.
+func RequestServiceAccountToken(remoteClientset *kubernetes.Clientset, namespace, name string) (string, time.Time, error) {
+	expirationSeconds := int64(ServiceAccountTokenExpiration.Seconds())
+	tokenRequest := &authenticationV1.TokenRequest{
+		Spec: authenticationV1.TokenRequestSpec{
+			ExpirationSeconds: &expirationSeconds,
+		},
+	}
+
+	tokenRequest, err := remoteClientset.
+		CoreV1().
+		ServiceAccounts(namespace).
+		CreateToken(context.TODO(), name, tokenRequest, metav1.CreateOptions{})
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	return tokenRequest.Status.Token, tokenRequest.Status.ExpirationTimestamp.Time, nil
+}
+
+// IsTokenExpiring 은 token 만료 시간 annotation 을 보고 token 을 다시 발급받아야 하는지 반환한다.
+// 만료 시간이 기록되어 있지 않은 경우 (legacy token secret 에서 복사된 token) 에도 다시 발급받는다.
+func IsTokenExpiring(annotations map[string]string) bool {
+	expiration, err := time.Parse(time.RFC3339, annotations[AnnotationKeyTokenExpiration])
+	if err != nil {
+		return true
+	}
+	return time.Until(expiration) < ServiceAccountTokenRefreshBefore
+}
@@ -1,22 +1,35 @@
 package util
 
 import (
+	"context"
 	"fmt"
 	"hash/fnv"
 	"math/rand"
 	"net/url"
 	"os"
+	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
+	clusterV1alpha1 "github.com/tmax-cloud/hypercloud-multi-operator/apis/cluster/v1alpha1"
 	coreV1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes"
 	restclient "k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
+var hyperAuthRealmRegexp = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+
+// keycloak 의 관리용 realm
+const hyperAuthMasterRealm = "master"
+
 // LowestNonZeroResult compares two reconciliation results
 // and returns the one with lowest requeue time.
 func LowestNonZeroResult(i, j ctrl.Result) ctrl.Result {
@@ -36,6 +49,29 @@ func LowestNonZeroResult(i, j ctrl.Result) ctrl.Result {
 	}
 }
 
+// LoadKubeconfigEntries 는 kubeconfig secret 의 current context 가 가리키는 cluster 와 user 를 반환한다.
+func LoadKubeconfigEntries(kubeconfigSecret *coreV1.Secret) (*clientcmdapi.Cluster, *clientcmdapi.AuthInfo, error) {
+	kubeConfig, err := clientcmd.Load(kubeconfigSecret.Data["value"])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	currentContext, ok := kubeConfig.Contexts[kubeConfig.CurrentContext]
+	if !ok {
+		return nil, nil, fmt.Errorf("current context [%s] not found in kubeconfig", kubeConfig.CurrentContext)
+	}
+	cluster, ok := kubeConfig.Clusters[currentContext.Cluster]
+	if !ok {
+		return nil, nil, fmt.Errorf("cluster [%s] not found in kubeconfig", currentContext.Cluster)
+	}
+	authInfo, ok := kubeConfig.AuthInfos[currentContext.AuthInfo]
+	if !ok {
+		authInfo = clientcmdapi.NewAuthInfo()
+	}
+
+	return cluster, authInfo, nil
+}
+
 func GetRemoteK8sClient(secret *coreV1.Secret) (*kubernetes.Clientset, error) {
 	value, ok := secret.Data["value"]
 	if !ok {
@@ -156,3 +192,179 @@ func CheckRequiredEnvPreset() error {
 	}
 	return nil
 }
+
+func getEnvOrDefault(env string, defaultValue string) string {
+	if value := os.Getenv(env); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+func getBoolEnvOrDefault(env string, defaultValue bool) bool {
+	value, err := strconv.ParseBool(os.Getenv(env))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+func getDurationEnvOrDefault(env string, defaultValue time.Duration) time.Duration {
+	value, err := time.ParseDuration(os.Getenv(env))
+	if err != nil || value <= 0 {
+		return defaultValue
+	}
+	return value
+}
+
+// GetClusterProxyHost 는 ingress 가 바라볼 cluster proxy 의 service 주소를 반환한다.
+func GetClusterProxyHost() string {
+	return getEnvOrDefault(CLUSTER_PROXY_HOST, DefaultClusterProxyHost)
+}
+
+// GetClusterProxyAudiences 는 cluster proxy 가 받을 token 의 audience 목록을 반환한다.
+func GetClusterProxyAudiences() []string {
+	return SplitList(getEnvOrDefault(CLUSTER_PROXY_AUDIENCES, DefaultClusterProxyAudiences))
+}
+
+func GetIngressClass() string {
+	return getEnvOrDefault(INGRESS_CLASS, DefaultIngressClass)
+}
+
+func GetClusterIssuer() string {
+	return getEnvOrDefault(CLUSTER_ISSUER, DefaultClusterIssuer)
+}
+
+func GetMulticlusterSubdomain() string {
+	return getEnvOrDefault(MULTICLUSTER_SUBDOMAIN, DefaultMulticlusterSubdomain)
+}
+
+func GetExposedPaths() []string {
+	return SplitList(getEnvOrDefault(MULTICLUSTER_EXPOSED_PATHS, DefaultExposedPaths))
+}
+
+func GetExposureBackend() string {
+	return getEnvOrDefault(EXPOSURE_BACKEND, ExposureBackendTraefik)
+}
+
+// GetArgoProjectSourceRepos 는 cluster 별 AppProject 에서 허용할 source repository 목록을 반환한다.
+func GetArgoProjectSourceRepos() []string {
+	return SplitList(getEnvOrDefault(ARGO_PROJECT_SOURCE_REPOS, DefaultArgoProjectSourceRepos))
+}
+
+func GetOperatorNamespace() string {
+	return getEnvOrDefault(OPERATOR_NAMESPACE, DefaultOperatorNamespace)
+}
+
+// GetArgoAutoSync 는 app-of-apps application 의 자동 sync 전역 설정(automated, prune, selfHeal)을 반환한다.
+func GetArgoAutoSync() (bool, bool, bool) {
+	return getBoolEnvOrDefault(ARGO_AUTO_SYNC, DefaultArgoAutoSync),
+		getBoolEnvOrDefault(ARGO_AUTO_SYNC_PRUNE, DefaultArgoAutoSyncPrune),
+		getBoolEnvOrDefault(ARGO_AUTO_SYNC_SELF_HEAL, DefaultArgoAutoSyncSelfHeal)
+}
+
+func GetArgoClusterLabelPrefix() string {
+	return getEnvOrDefault(ARGO_CLUSTER_LABEL_PREFIX, DefaultArgoClusterLabelPrefix)
+}
+
+// GetArgoClusterExecCommands 는 argocd 가 exec 인증 방식에서 실행할 수 있는 command 목록을 반환한다.
+func GetArgoClusterExecCommands() []string {
+	return SplitList(getEnvOrDefault(ARGO_CLUSTER_EXEC_COMMANDS, DefaultArgoClusterExecCommands))
+}
+
+// kubeconfig 에 지정된 대로 사용할 수 있는 IAM role 목록
+func GetArgoClusterAWSRoleARNs() []string {
+	return SplitList(os.Getenv(ARGO_CLUSTER_AWS_ROLE_ARNS))
+}
+
+func GetArgoApplicationDeletionTimeout() time.Duration {
+	return getDurationEnvOrDefault(ARGO_APPLICATION_DELETION_TIMEOUT, DefaultArgoAppDeletionTimeout)
+}
+
+func GetArgoProjectSyncInterval() time.Duration {
+	return getDurationEnvOrDefault(ARGO_PROJECT_SYNC_INTERVAL, DefaultArgoProjectSyncInterval)
+}
+
+func GetHyperAuthSyncInterval() time.Duration {
+	return getDurationEnvOrDefault(HYPERAUTH_SYNC_INTERVAL, DefaultHyperAuthSyncInterval)
+}
+
+func GetHyperAuthModuleConfigMap() string {
+	return getEnvOrDefault(HYPERAUTH_MODULE_CONFIGMAP, DefaultHyperAuthModuleConfigMap)
+}
+
+// GetGatewayAPIParent 는 HTTPRoute 가 연결될 Gateway 의 namespace 와 name 을 반환한다.
+func GetGatewayAPIParent() (string, string, error) {
+	parent := strings.Split(os.Getenv(GATEWAY_API_PARENT), "/")
+	if len(parent) != 2 || parent[0] == "" || parent[1] == "" {
+		return "", "", fmt.Errorf("%s env must be <namespace>/<name> of Gateway", GATEWAY_API_PARENT)
+	}
+	return parent[0], parent[1], nil
+}
+
+// GetGatewayAPIAuthPolicy 는 single cluster 의 api 요청을 인증하는 Gateway 의 policy 를 반환한다.
+func GetGatewayAPIAuthPolicy() (schema.GroupKind, string, string, error) {
+	policy := strings.Split(os.Getenv(GATEWAY_API_AUTH_POLICY), "/")
+	if len(policy) != 3 || policy[0] == "" || policy[1] == "" || policy[2] == "" {
+		return schema.GroupKind{}, "", "", fmt.Errorf("%s env must be <kind>.<group>/<namespace>/<name> of policy", GATEWAY_API_AUTH_POLICY)
+	}
+	return schema.ParseGroupKind(policy[0]), policy[1], policy[2], nil
+}
+
+// SplitList 는 comma 로 구분된 목록을 나누고, 빈 값은 제외한다.
+func SplitList(list string) []string {
+	result := []string{}
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+func GetHyperAuthRealm() string {
+	return getEnvOrDefault(HYPERAUTH_REALM, DefaultHyperAuthRealm)
+}
+
+// GetHyperAuthIssuer 는 HyperAuth 의 realm 이 발급하는 token 의 issuer 를 반환한다.
+func GetHyperAuthIssuer(realm string) string {
+	return "https://" + os.Getenv(AUTH_SUBDOMAIN) + "." + os.Getenv(HC_DOMAIN) + "/auth/realms/" + realm
+}
+
+// ValidateHyperAuthRealm 은 realm 이름을 url path 에 그대로 사용할 수 있는지 검증한다.
+// path 를 바꾸는 "." 과 ".." 및 keycloak 관리용 master realm 은 사용할 수 없다.
+func ValidateHyperAuthRealm(realm string) error {
+	if !hyperAuthRealmRegexp.MatchString(realm) {
+		return fmt.Errorf("invalid hyperauth realm [%s]: must consist of alphanumeric characters, '-', '_' or '.'", realm)
+	}
+	if realm == "." || realm == ".." || strings.EqualFold(realm, hyperAuthMasterRealm) {
+		return fmt.Errorf("invalid hyperauth realm [%s]: reserved realm name", realm)
+	}
+	return nil
+}
+
+// GetHyperAuthRealmFor 는 ClusterManager 가 사용할 HyperAuth realm 을 반환한다.
+// ClusterManager 의 annotation, ClusterManager namespace 의 annotation, operator 설정(HYPERAUTH_REALM) 순서로 찾는다.
+// 생성한 cluster 는 api server 의 oidc issuer 를 바꿀 수 없으므로 처음 기록한 realm 을 계속 사용한다.
+func GetHyperAuthRealmFor(ctx context.Context, c client.Reader, clusterManager *clusterV1alpha1.ClusterManager) (string, error) {
+	if status := clusterManager.Status.HyperAuth; status != nil && status.Realm != "" &&
+		clusterManager.Labels[clusterV1alpha1.LabelKeyClmClusterType] == clusterV1alpha1.ClusterTypeCreated {
+		return status.Realm, nil
+	}
+
+	realm := clusterManager.Annotations[clusterV1alpha1.AnnotationKeyClmHyperAuthRealm]
+	if realm == "" {
+		namespace := &coreV1.Namespace{}
+		if err := c.Get(ctx, types.NamespacedName{Name: clusterManager.Namespace}, namespace); err != nil && !errors.IsNotFound(err) {
+			return "", err
+		}
+		realm = namespace.Annotations[clusterV1alpha1.AnnotationKeyClmHyperAuthRealm]
+	}
+	if realm == "" {
+		realm = GetHyperAuthRealm()
+	}
+
+	if err := ValidateHyperAuthRealm(realm); err != nil {
+		return "", err
+	}
+	return realm, nil
+}
@@ -0,0 +1,144 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"context"
+	"testing"
+
+	clusterV1alpha1 "github.com/tmax-cloud/hypercloud-multi-operator/apis/cluster/v1alpha1"
+
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestValidateHyperAuthRealm(t *testing.T) {
+	tests := []struct {
+		realm   string
+		wantErr bool
+	}{
+		{realm: "tmax"},
+		{realm: "team-a.dev_1"},
+		{realm: "", wantErr: true},
+		{realm: ".", wantErr: true},
+		{realm: "..", wantErr: true},
+		{realm: "master", wantErr: true},
+		{realm: "Master", wantErr: true},
+		{realm: "tmax/../master", wantErr: true},
+		{realm: "tmax?x=1", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.realm, func(t *testing.T) {
+			if err := ValidateHyperAuthRealm(tt.realm); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateHyperAuthRealm(%q) error = %v, wantErr %v", tt.realm, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGetHyperAuthRealmFor(t *testing.T) {
+	newClusterManager := func(clusterType string, annotation string, statusRealm string) *clusterV1alpha1.ClusterManager {
+		clm := &clusterV1alpha1.ClusterManager{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "cluster",
+				Namespace:   "ns",
+				Labels:      map[string]string{clusterV1alpha1.LabelKeyClmClusterType: clusterType},
+				Annotations: map[string]string{},
+			},
+		}
+		if annotation != "" {
+			clm.Annotations[clusterV1alpha1.AnnotationKeyClmHyperAuthRealm] = annotation
+		}
+		if statusRealm != "" {
+			clm.Status.HyperAuth = &clusterV1alpha1.HyperAuthStatus{Realm: statusRealm}
+		}
+		return clm
+	}
+
+	tests := []struct {
+		name           string
+		clm            *clusterV1alpha1.ClusterManager
+		namespaceRealm string
+		want           string
+		wantErr        bool
+	}{
+		{
+			name: "operator realm",
+			clm:  newClusterManager(clusterV1alpha1.ClusterTypeRegistered, "", ""),
+			want: GetHyperAuthRealm(),
+		},
+		{
+			name:           "namespace realm",
+			clm:            newClusterManager(clusterV1alpha1.ClusterTypeRegistered, "", ""),
+			namespaceRealm: "team",
+			want:           "team",
+		},
+		{
+			name:           "cluster manager realm overrides namespace realm",
+			clm:            newClusterManager(clusterV1alpha1.ClusterTypeRegistered, "cluster", ""),
+			namespaceRealm: "team",
+			want:           "cluster",
+		},
+		{
+			name:    "reserved cluster manager realm",
+			clm:     newClusterManager(clusterV1alpha1.ClusterTypeRegistered, "master", ""),
+			wantErr: true,
+		},
+		{
+			name:           "reserved namespace realm",
+			clm:            newClusterManager(clusterV1alpha1.ClusterTypeRegistered, "", ""),
+			namespaceRealm: "..",
+			wantErr:        true,
+		},
+		{
+			name: "registered cluster follows changed realm",
+			clm:  newClusterManager(clusterV1alpha1.ClusterTypeRegistered, "new", "old"),
+			want: "new",
+		},
+		{
+			name: "created cluster keeps recorded realm",
+			clm:  newClusterManager(clusterV1alpha1.ClusterTypeCreated, "new", "old"),
+			want: "old",
+		},
+		{
+			name: "created cluster without recorded realm uses annotation",
+			clm:  newClusterManager(clusterV1alpha1.ClusterTypeCreated, "new", ""),
+			want: "new",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			namespace := &coreV1.Namespace{
+				ObjectMeta: metav1.ObjectMeta{Name: "ns", Annotations: map[string]string{}},
+			}
+			if tt.namespaceRealm != "" {
+				namespace.Annotations[clusterV1alpha1.AnnotationKeyClmHyperAuthRealm] = tt.namespaceRealm
+			}
+			c := fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(namespace).Build()
+
+			got, err := GetHyperAuthRealmFor(context.Background(), c, tt.clm)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetHyperAuthRealmFor() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("GetHyperAuthRealmFor() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -15,6 +15,7 @@ limitations under the License.
 package main
 
 import (
+	"context"
 	"flag"
 	"os"
 	"time"
@@ -31,11 +32,18 @@ import (
 	clusterV1alpha1 "github.com/tmax-cloud/hypercloud-multi-operator/apis/cluster/v1alpha1"
 	claimController "github.com/tmax-cloud/hypercloud-multi-operator/controllers/claim"
 	clusterController "github.com/tmax-cloud/hypercloud-multi-operator/controllers/cluster"
+	hyperauthCaller "github.com/tmax-cloud/hypercloud-multi-operator/controllers/hyperAuth"
 	k8scontroller "github.com/tmax-cloud/hypercloud-multi-operator/controllers/k8s"
+	clusterProxy "github.com/tmax-cloud/hypercloud-multi-operator/controllers/proxy"
+	"github.com/tmax-cloud/hypercloud-multi-operator/controllers/sweeper"
 	"github.com/tmax-cloud/hypercloud-multi-operator/controllers/util"
 	traefikV1alpha1 "github.com/traefik/traefik/v2/pkg/provider/kubernetes/crd/traefik/v1alpha1"
 
+	coreV1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/selection"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
@@ -43,6 +51,7 @@ import (
 	clusterV1alpha3 "sigs.k8s.io/cluster-api/api/v1alpha3"
 	controlplanev1 "sigs.k8s.io/cluster-api/controlplane/kubeadm/api/v1alpha3"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 )
 
@@ -68,10 +77,25 @@ func init() {
 func main() {
 	var metricsAddr string
 	var enableLeaderElection bool
+	var clusterProxyAddr string
+	var orphanSweeperMode string
+	var orphanSweepInterval time.Duration
+	var orphanGracePeriod time.Duration
 	flag.StringVar(&metricsAddr, "metrics-addr", ":8080", "The address the metric endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "enable-leader-election", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
+	flag.StringVar(&clusterProxyAddr, "cluster-proxy-bind-address", ":8090",
+		"The address the cluster proxy for single cluster kubernetes api binds to. "+
+			"The proxy serves plain HTTP and must only be reached through the TLS multicluster ingress. "+
+			"Set this to 0 to disable the cluster proxy.")
+	flag.StringVar(&orphanSweeperMode, "orphan-sweeper-mode", string(sweeper.ModeDryRun),
+		"Mode of the sweeper for HyperAuth clients, argocd secrets and applications left behind by deleted ClusterManagers. "+
+			"One of disabled, dry-run (report only) and enabled (delete after the grace period).")
+	flag.DurationVar(&orphanSweepInterval, "orphan-sweep-interval", 30*time.Minute,
+		"The interval at which the orphan sweeper looks for orphan resources.")
+	flag.DurationVar(&orphanGracePeriod, "orphan-grace-period", 24*time.Hour,
+		"How long a resource must stay orphaned before the orphan sweeper deletes it.")
 
 	opts := zap.Options{
 		// Development: false,
@@ -95,12 +119,27 @@ func main() {
 		Port:               9443,
 		LeaderElection:     enableLeaderElection,
 		LeaderElectionID:   "86810e1d.tmax.io",
+		NewCache:           cache.BuilderWithOptions(cache.Options{SelectorsByObject: newCacheSelectors()}),
 	})
 	if err != nil {
 		setupLog.Error(err, "unable to start manager")
 		os.Exit(1)
 	}
 
+	// HyperAuth module configmap 은 operator 가 만든 리소스의 label 이 없으므로, operator namespace 만 보는 cache 로 읽고 watch 한다.
+	operatorCache, err := cache.New(mgr.GetConfig(), cache.Options{
+		Scheme:    mgr.GetScheme(),
+		Mapper:    mgr.GetRESTMapper(),
+		Namespace: util.GetOperatorNamespace(),
+	})
+	if err == nil {
+		err = mgr.Add(operatorCache)
+	}
+	if err != nil {
+		setupLog.Error(err, "unable to create cache for operator namespace")
+		os.Exit(1)
+	}
+
 	if err = (&claimController.ClusterClaimReconciler{
 		Client: mgr.GetClient(),
 		Log:    ctrl.Log.WithName("controllers").WithName("ClusterClaim"),
@@ -110,9 +149,13 @@ func main() {
 		os.Exit(1)
 	}
 	if err = (&clusterController.ClusterManagerReconciler{
-		Client: mgr.GetClient(),
-		Log:    ctrl.Log.WithName("controllers").WithName("ClusterManager"),
-		Scheme: mgr.GetScheme(),
+		Client:        mgr.GetClient(),
+		Log:           ctrl.Log.WithName("controllers").WithName("ClusterManager"),
+		Scheme:        mgr.GetScheme(),
+		OperatorCache: operatorCache,
+		RemoteWatches: util.NewRemoteWatchManager(
+			ctrl.Log.WithName("controllers").WithName("RemoteWatch"),
+		),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "ClusterManager")
 		os.Exit(1)
@@ -125,6 +168,7 @@ func main() {
 		setupLog.Error(err, "unable to create webhook", "webhook", "ClusterManager")
 		os.Exit(1)
 	}
+	clusterController.SetupApplicationWebhookWithManager(mgr)
 
 	// if err = (&clusterController.ClusterReconciler{
 	// 	Client: mgr.GetClient(),
@@ -164,10 +208,50 @@ func main() {
 	}
 	// +kubebuilder:scaffold:builder
 
+	if clusterProxyAddr != "0" {
+		if err = mgr.Add(&clusterProxy.ClusterProxy{
+			Client:      mgr.GetClient(),
+			Log:         ctrl.Log.WithName("ClusterProxy"),
+			BindAddress: clusterProxyAddr,
+		}); err != nil {
+			setupLog.Error(err, "unable to add cluster proxy")
+			os.Exit(1)
+		}
+	}
+
+	mode, err := sweeper.ParseMode(orphanSweeperMode)
+	if err != nil {
+		setupLog.Error(err, "invalid orphan sweeper mode")
+		os.Exit(1)
+	}
+	if mode != sweeper.ModeDisabled {
+		if err = mgr.Add(&sweeper.OrphanSweeper{
+			Client:       mgr.GetClient(),
+			ModuleReader: operatorCache,
+			Log:          ctrl.Log.WithName("OrphanSweeper"),
+			Mode:         mode,
+			Interval:     orphanSweepInterval,
+			GracePeriod:  orphanGracePeriod,
+		}); err != nil {
+			setupLog.Error(err, "unable to add orphan sweeper")
+			os.Exit(1)
+		}
+	}
+
 	if err := util.CheckRequiredEnvPreset(); err != nil {
 		setupLog.Error(err, "not exist required environment variables")
 		os.Exit(1)
 	}
+	if err := util.ValidateHyperAuthRealm(util.GetHyperAuthRealm()); err != nil {
+		setupLog.Error(err, "invalid hyperauth realm")
+		os.Exit(1)
+	}
+
+	// cache 가 시작되기 전이므로 api server 에서 직접 읽어 module 목록을 검증한다.
+	if _, err := hyperauthCaller.LoadModules(context.Background(), mgr.GetAPIReader()); err != nil {
+		setupLog.Error(err, "invalid hyperauth modules")
+		os.Exit(1)
+	}
 
 	setupLog.Info("starting manager")
 	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
@@ -175,3 +259,25 @@ func main() {
 		os.Exit(1)
 	}
 }
+
+// newCacheSelectors 는 cluster 전체를 watch 하는 Secret 과 ConfigMap 의 cache 를 operator 가 사용하는 리소스로 제한한다.
+// Secret 은 operator 가 만들지 않는 service account token 과 helm release 를 제외하고,
+// ConfigMap 은 ClusterManager 의 label 을 가지는 리소스만 cache 한다. 제외된 리소스는 cache 된 client 로 읽을 수 없다.
+func newCacheSelectors() cache.SelectorsByObject {
+	clmName, err := labels.NewRequirement(clusterV1alpha1.LabelKeyClmName, selection.Exists, nil)
+	if err != nil {
+		panic(err)
+	}
+
+	return cache.SelectorsByObject{
+		&coreV1.Secret{}: {
+			Field: fields.AndSelectors(
+				fields.OneTermNotEqualSelector("type", string(coreV1.SecretTypeServiceAccountToken)),
+				fields.OneTermNotEqualSelector("type", "helm.sh/release.v1"),
+			),
+		},
+		&coreV1.ConfigMap{}: {
+			Label: labels.NewSelector().Add(*clmName),
+		},
+	}
+}